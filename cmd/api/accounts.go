@@ -0,0 +1,261 @@
+package api
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/hazzardr/baduk-online/internal/data"
+	"github.com/hazzardr/baduk-online/internal/validator"
+)
+
+// accountDeletionGracePeriod is how long a deleted account is kept before it is purged. Logging in during
+// the grace period cancels the deletion.
+const accountDeletionGracePeriod = 30 * 24 * time.Hour
+
+// handleDeleteUser schedules the logged in user's account for deletion, once they have confirmed their
+// password. The user is logged out everywhere, taken out of the automatch queue and their open challenges
+// are cancelled, and the account is purged once the grace period is over.
+func (api *API) handleDeleteUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := api.authenticatedUser(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		CurrentPassword string `json:"current_password"`
+	}
+
+	err := api.readJSON(w, r, &input)
+	if err != nil {
+		api.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	err = api.checkCurrentPassword(v, user, input.CurrentPassword)
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+		return
+	}
+	if !v.Valid() {
+		api.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = api.db.Users.ScheduleDeletion(r.Context(), user, api.clock.Now().Add(accountDeletionGracePeriod))
+	if err != nil {
+		if errors.Is(err, data.ErrEditConflict) {
+			api.dataConflictResponse(w, r, err)
+		} else {
+			api.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = api.withdrawUser(r.Context(), user)
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+		return
+	}
+	err = api.revokeSessionsForUser(r.Context(), user)
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+		return
+	}
+
+	resp := map[string]any{
+		"message":  "your account will be deleted, log in again before then to cancel",
+		"purge_at": user.PurgeAt,
+	}
+	err = api.writeJSON(w, http.StatusAccepted, resp, nil)
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+	}
+}
+
+// withdrawUser takes a user out of the automatch queue and cancels their open challenges.
+func (api *API) withdrawUser(ctx context.Context, user *data.User) error {
+	err := api.db.Automatch.Leave(ctx, int64(user.ID))
+	if err != nil && !errors.Is(err, data.ErrNotQueued) {
+		return err
+	}
+
+	challenges, err := api.db.Challenges.ListOpenForUser(ctx, int64(user.ID))
+	if err != nil {
+		return err
+	}
+	for _, challenge := range challenges {
+		if challenge.ChallengerID != int64(user.ID) {
+			continue
+		}
+		err := api.db.Challenges.Cancel(ctx, challenge)
+		if err != nil && !errors.Is(err, data.ErrChallengeClosed) {
+			return err
+		}
+		if challenge.OpponentID == nil {
+			api.publish(lobbyTopic, "seek_removed", challenge)
+		} else {
+			api.publish(userTopic(int(*challenge.OpponentID)), "challenge_cancelled", challenge)
+		}
+	}
+	return nil
+}
+
+// exportedGame is a game and its moves, as written to a data export.
+type exportedGame struct {
+	Game  *data.Game   `json:"game"`
+	Moves []*data.Move `json:"moves"`
+}
+
+// handleExportUser streams a ZIP archive of everything stored about the logged in user. Secrets such as
+// password and token hashes, passkey keys and the TOTP key are left out. Everything is loaded before the
+// response starts, so that a failure can still be reported with an error status.
+func (api *API) handleExportUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := api.authenticatedUser(w, r)
+	if !ok {
+		return
+	}
+	userID := int64(user.ID)
+
+	games, err := api.db.Games.ListForPlayer(r.Context(), userID)
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+		return
+	}
+	exportedGames := make([]exportedGame, 0, len(games))
+	for _, g := range games {
+		moves, err := api.db.Games.Moves(r.Context(), g.ID)
+		if err != nil {
+			api.serverErrorResponse(w, r, err)
+			return
+		}
+		exportedGames = append(exportedGames, exportedGame{Game: g, Moves: moves})
+	}
+	ratingHistory, err := api.db.Ratings.History(r.Context(), userID)
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+		return
+	}
+	challenges, err := api.db.Challenges.ListForUser(r.Context(), userID)
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+		return
+	}
+	chat, err := api.db.Chat.ListForUser(r.Context(), userID)
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+		return
+	}
+	passkeys, err := api.db.Passkeys.ListForUser(r.Context(), userID)
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+		return
+	}
+	identities, err := api.db.Identities.ListForUser(r.Context(), userID)
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+		return
+	}
+	apiTokens, err := api.db.APITokens.ListForUser(r.Context(), userID)
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+		return
+	}
+	sessions, err := api.db.UserSessions.ListForUser(r.Context(), userID, api.clock.Now())
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+		return
+	}
+	current := api.sessionManager.Token(r.Context())
+	for _, session := range sessions {
+		session.Current = session.Token == current
+	}
+	twoFactor, err := api.db.TwoFactor.Enabled(r.Context(), userID)
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+		return
+	}
+	recoveryCodes, err := api.db.TwoFactor.CountRecoveryCodes(r.Context(), userID)
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+		return
+	}
+
+	files := []struct {
+		name     string
+		contents any
+	}{
+		{"profile.json", map[string]any{"id": user.ID, "user": user}},
+		{"games.json", exportedGames},
+		{"ratings.json", ratingHistory},
+		{"challenges.json", challenges},
+		{"chat.json", chat},
+		{"passkeys.json", passkeys},
+		{"identities.json", identities},
+		{"api_tokens.json", apiTokens},
+		{"sessions.json", sessions},
+		{"two_factor.json", map[string]any{"enabled": twoFactor, "recovery_codes": recoveryCodes}},
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="baduk-online-%d.zip"`, user.ID))
+	w.WriteHeader(http.StatusOK)
+
+	archive := zip.NewWriter(w)
+	for _, file := range files {
+		f, err := archive.Create(file.name)
+		if err != nil {
+			slog.Error("failed to write data export", "user", user.Email, "err", err)
+			return
+		}
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "\t")
+		err = enc.Encode(file.contents)
+		if err != nil {
+			slog.Error("failed to write data export", "user", user.Email, "err", err)
+			return
+		}
+	}
+	err = archive.Close()
+	if err != nil {
+		slog.Error("failed to write data export", "user", user.Email, "err", err)
+	}
+}
+
+// StartAccountPurger periodically removes the accounts whose deletion grace period is over. It runs until
+// Shutdown is called.
+func (api *API) StartAccountPurger(interval time.Duration) {
+	api.background(func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-api.quit:
+				return
+			case <-ticker.C:
+				err := api.purgeAccounts(context.Background())
+				if err != nil {
+					slog.Error("failed to purge deleted accounts", "err", err)
+				}
+			}
+		}
+	})
+}
+
+// purgeAccounts removes every account due to be deleted.
+func (api *API) purgeAccounts(ctx context.Context) error {
+	purged, err := api.db.Users.PurgeDeleted(ctx, api.clock.Now())
+	if err != nil {
+		return err
+	}
+	if purged > 0 {
+		slog.Info("purged deleted accounts", "count", purged)
+	}
+	return nil
+}
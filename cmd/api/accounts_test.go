@@ -0,0 +1,337 @@
+package api
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hazzardr/baduk-online/internal/clock"
+	"github.com/hazzardr/baduk-online/internal/data"
+)
+
+func TestAccountDeletionIntegration(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	mailer := &mockMailer{db: db}
+	api := NewAPI("test", "1.0.0", db, mailer)
+	start := time.Now()
+	fake := clock.NewFake(start)
+	api.clock = fake
+	server := httptest.NewServer(api.Routes())
+	defer server.Close()
+
+	user := createTestUser(t, db, "Leaving User", "leaving@example.com", "password123", true)
+	if _, err := db.Registration.NewToken(ctx, int64(user.ID), time.Hour); err != nil {
+		t.Fatalf("failed to create registration token: %s", err)
+	}
+	seek := &data.Challenge{
+		ChallengerID: int64(user.ID),
+		BoardSize:    19,
+		Rules:        "japanese",
+		Komi:         6.5,
+		Color:        data.ColorRandom,
+	}
+	if err := db.Challenges.Insert(ctx, seek); err != nil {
+		t.Fatalf("failed to insert challenge: %s", err)
+	}
+
+	deleteUser := func(t *testing.T, client *http.Client, password string) int {
+		t.Helper()
+		body, _ := json.Marshal(map[string]string{"current_password": password})
+		req, _ := http.NewRequest(http.MethodDelete, server.URL+"/api/v1/user", bytes.NewBuffer(body))
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("failed to make request: %s", err)
+		}
+		defer resp.Body.Close()
+		return resp.StatusCode
+	}
+	getUser := func(t *testing.T, client *http.Client) int {
+		t.Helper()
+		resp, err := client.Get(server.URL + "/api/v1/user")
+		if err != nil {
+			t.Fatalf("failed to make request: %s", err)
+		}
+		defer resp.Body.Close()
+		return resp.StatusCode
+	}
+	loggedIn := func(t *testing.T) *http.Client {
+		t.Helper()
+		client := newTestClient(t)
+		if status := login(t, client, server.URL, "leaving@example.com", "password123"); status != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", status)
+		}
+		return client
+	}
+
+	t.Run("requires a session", func(t *testing.T) {
+		if status := deleteUser(t, newTestClient(t), "password123"); status != http.StatusUnauthorized {
+			t.Errorf("expected status 401, got %d", status)
+		}
+	})
+
+	t.Run("requires the password", func(t *testing.T) {
+		client := loggedIn(t)
+		if status := deleteUser(t, client, "wrongpassword"); status != http.StatusUnprocessableEntity {
+			t.Errorf("expected status 422, got %d", status)
+		}
+		if status := getUser(t, client); status != http.StatusOK {
+			t.Errorf("expected the session to survive, got %d", status)
+		}
+	})
+
+	t.Run("logging in cancels a deletion", func(t *testing.T) {
+		client, other := loggedIn(t), loggedIn(t)
+		if status := deleteUser(t, client, "password123"); status != http.StatusAccepted {
+			t.Fatalf("expected status 202, got %d", status)
+		}
+		for _, c := range []*http.Client{client, other} {
+			if status := getUser(t, c); status != http.StatusUnauthorized {
+				t.Errorf("expected sessions to be revoked, got %d", status)
+			}
+		}
+		got, err := db.Challenges.Get(ctx, seek.ID)
+		if err != nil {
+			t.Fatalf("failed to get challenge: %s", err)
+		}
+		if got.Status == data.ChallengeStatusOpen {
+			t.Errorf("expected open challenges to be cancelled")
+		}
+
+		loggedIn(t)
+		stored, err := db.Users.GetByID(ctx, int64(user.ID))
+		if err != nil {
+			t.Fatalf("failed to get user: %s", err)
+		}
+		if stored.PurgeAt != nil {
+			t.Errorf("expected the deletion to be cancelled, got purge at %s", stored.PurgeAt)
+		}
+
+		fake.Advance(2 * accountDeletionGracePeriod)
+		if err := api.purgeAccounts(ctx); err != nil {
+			t.Fatalf("failed to purge accounts: %s", err)
+		}
+		if _, err := db.Users.GetByID(ctx, int64(user.ID)); err != nil {
+			t.Errorf("expected the account to be kept, got %v", err)
+		}
+	})
+
+	t.Run("account is purged after the grace period", func(t *testing.T) {
+		client := loggedIn(t)
+		if status := deleteUser(t, client, "password123"); status != http.StatusAccepted {
+			t.Fatalf("expected status 202, got %d", status)
+		}
+		fake.Advance(accountDeletionGracePeriod - time.Minute)
+		if err := api.purgeAccounts(ctx); err != nil {
+			t.Fatalf("failed to purge accounts: %s", err)
+		}
+		if _, err := db.Users.GetByID(ctx, int64(user.ID)); err != nil {
+			t.Fatalf("expected the account to be kept during the grace period, got %v", err)
+		}
+
+		fake.Advance(2 * time.Minute)
+		if err := api.purgeAccounts(ctx); err != nil {
+			t.Fatalf("failed to purge accounts: %s", err)
+		}
+		if _, err := db.Users.GetByID(ctx, int64(user.ID)); err == nil {
+			t.Fatalf("expected the account to be purged")
+		}
+
+		var registrations int
+		err := db.Pool.QueryRow(ctx, "SELECT count(*) FROM registration WHERE user_id = $1", user.ID).Scan(&registrations)
+		if err != nil {
+			t.Fatalf("failed to count registration tokens: %s", err)
+		}
+		if registrations != 0 {
+			t.Errorf("expected registration tokens to be removed, got %d", registrations)
+		}
+		if status := getUser(t, client); status != http.StatusUnauthorized {
+			t.Errorf("expected the session to stay revoked, got %d", status)
+		}
+		status := login(t, newTestClient(t), server.URL, "leaving@example.com", "password123")
+		if status != http.StatusUnauthorized {
+			t.Errorf("expected login to fail, got %d", status)
+		}
+	})
+}
+
+func TestExportUserIntegration(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	mailer := &mockMailer{db: db}
+	api := NewAPI("test", "1.0.0", db, mailer)
+	server := httptest.NewServer(api.Routes())
+	defer server.Close()
+
+	user := createTestUser(t, db, "Exporting User", "export@example.com", "password123", true)
+	opponent := createTestUser(t, db, "Opponent", "opponent@example.com", "password123", true)
+	g := &data.Game{
+		BlackID:   int64(user.ID),
+		WhiteID:   int64(opponent.ID),
+		BoardSize: 9,
+		Rules:     "japanese",
+		Komi:      6.5,
+	}
+	if err := db.Games.Insert(ctx, g); err != nil {
+		t.Fatalf("failed to insert game: %s", err)
+	}
+	if err := db.Games.AppendMove(ctx, g, &data.Move{Color: data.ColorBlack, X: 2, Y: 2}); err != nil {
+		t.Fatalf("failed to append move: %s", err)
+	}
+	msg := &data.ChatMessage{GameID: g.ID, UserID: int64(user.ID), Channel: data.ChatChannelPublic, Body: "hello"}
+	if err := db.Chat.Insert(ctx, msg); err != nil {
+		t.Fatalf("failed to insert chat message: %s", err)
+	}
+	opponentID := int64(opponent.ID)
+	challenge := &data.Challenge{
+		ChallengerID: int64(user.ID),
+		OpponentID:   &opponentID,
+		BoardSize:    19,
+		Rules:        "japanese",
+		Komi:         6.5,
+		Color:        data.ColorRandom,
+	}
+	if err := db.Challenges.Insert(ctx, challenge); err != nil {
+		t.Fatalf("failed to insert challenge: %s", err)
+	}
+	if err := db.Challenges.Decline(ctx, challenge); err != nil {
+		t.Fatalf("failed to decline challenge: %s", err)
+	}
+	identity := &data.Identity{
+		Issuer:  "https://accounts.example.com",
+		Subject: "12345",
+		UserID:  int64(user.ID),
+		Email:   "export@example.com",
+	}
+	if err := db.Identities.Insert(ctx, identity); err != nil {
+		t.Fatalf("failed to insert identity: %s", err)
+	}
+
+	t.Run("requires a session", func(t *testing.T) {
+		resp, err := http.Get(server.URL + "/api/v1/user/export")
+		if err != nil {
+			t.Fatalf("failed to make request: %s", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("expected status 401, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("exports the user's data", func(t *testing.T) {
+		client := newTestClient(t)
+		if status := login(t, client, server.URL, "export@example.com", "password123"); status != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", status)
+		}
+		resp, err := client.Get(server.URL + "/api/v1/user/export")
+		if err != nil {
+			t.Fatalf("failed to make request: %s", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", resp.StatusCode)
+		}
+		if ct := resp.Header.Get("Content-Type"); ct != "application/zip" {
+			t.Errorf("expected a zip, got %q", ct)
+		}
+
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("failed to read response: %s", err)
+		}
+		archive, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+		if err != nil {
+			t.Fatalf("failed to open zip: %s", err)
+		}
+		files := make(map[string][]byte)
+		for _, f := range archive.File {
+			r, err := f.Open()
+			if err != nil {
+				t.Fatalf("failed to open %s: %s", f.Name, err)
+			}
+			files[f.Name], _ = io.ReadAll(r)
+			r.Close()
+		}
+		names := []string{
+			"profile.json", "games.json", "ratings.json", "challenges.json", "chat.json", "passkeys.json",
+			"identities.json", "api_tokens.json", "sessions.json", "two_factor.json",
+		}
+		for _, name := range names {
+			if _, ok := files[name]; !ok {
+				t.Errorf("expected %s in the export", name)
+			}
+		}
+
+		var profile struct {
+			ID   int       `json:"id"`
+			User data.User `json:"user"`
+		}
+		if err := json.Unmarshal(files["profile.json"], &profile); err != nil {
+			t.Fatalf("failed to decode profile: %s", err)
+		}
+		if profile.ID != user.ID || profile.User.Email != "export@example.com" {
+			t.Errorf("unexpected profile: %+v", profile)
+		}
+
+		var games []struct {
+			Game  data.Game    `json:"game"`
+			Moves []*data.Move `json:"moves"`
+		}
+		if err := json.Unmarshal(files["games.json"], &games); err != nil {
+			t.Fatalf("failed to decode games: %s", err)
+		}
+		if len(games) != 1 || games[0].Game.ID != g.ID || len(games[0].Moves) != 1 {
+			t.Errorf("unexpected games: %+v", games)
+		}
+
+		var chat []data.ChatMessage
+		if err := json.Unmarshal(files["chat.json"], &chat); err != nil {
+			t.Fatalf("failed to decode chat: %s", err)
+		}
+		if len(chat) != 1 || chat[0].Body != "hello" {
+			t.Errorf("unexpected chat: %+v", chat)
+		}
+
+		var challenges []data.Challenge
+		if err := json.Unmarshal(files["challenges.json"], &challenges); err != nil {
+			t.Fatalf("failed to decode challenges: %s", err)
+		}
+		if len(challenges) != 1 || challenges[0].Status != data.ChallengeStatusDeclined {
+			t.Errorf("expected the declined challenge, got %+v", challenges)
+		}
+
+		var identities []data.Identity
+		if err := json.Unmarshal(files["identities.json"], &identities); err != nil {
+			t.Fatalf("failed to decode identities: %s", err)
+		}
+		if len(identities) != 1 || identities[0].Issuer != "https://accounts.example.com" {
+			t.Errorf("unexpected identities: %+v", identities)
+		}
+
+		var sessions []data.UserSession
+		if err := json.Unmarshal(files["sessions.json"], &sessions); err != nil {
+			t.Fatalf("failed to decode sessions: %s", err)
+		}
+		if len(sessions) != 1 || !sessions[0].Current {
+			t.Errorf("expected the current session, got %+v", sessions)
+		}
+
+		for _, secret := range []string{"password", "hash", "subject", "public_key", "secret"} {
+			for name, contents := range files {
+				if bytes.Contains(contents, []byte(`"`+secret+`"`)) {
+					t.Errorf("expected no %s in %s", secret, name)
+				}
+			}
+		}
+	})
+}
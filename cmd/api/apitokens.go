@@ -0,0 +1,172 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hazzardr/baduk-online/internal/data"
+	"github.com/hazzardr/baduk-online/internal/validator"
+)
+
+// authenticateAPIToken resolves a personal API token sent as a bearer token to its user, who is then returned
+// by getUserFromContext instead of the session's. Requests without an Authorization header are left to the
+// session. Tokens need the read scope for requests that don't change anything, and the write scope for the
+// rest.
+func (api *API) authenticateAPIToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Authorization")
+
+		header := r.Header.Get("Authorization")
+		if header == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		scheme, plaintext, _ := strings.Cut(header, " ")
+		v := validator.New()
+		data.ValidateAPITokenPlaintext(v, plaintext)
+		if !strings.EqualFold(scheme, "Bearer") || !v.Valid() {
+			api.invalidAPITokenResponse(w, r)
+			return
+		}
+
+		token, err := api.db.APITokens.Use(r.Context(), plaintext, api.clock.Now())
+		if err != nil {
+			if errors.Is(err, data.ErrNoAPITokenFound) {
+				api.invalidAPITokenResponse(w, r)
+			} else {
+				api.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+		user, err := api.db.Users.GetByID(r.Context(), token.UserID)
+		if err != nil {
+			if errors.Is(err, data.ErrNoUserFound) {
+				api.invalidAPITokenResponse(w, r)
+			} else {
+				api.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+		// Accounts being deleted can only be used again by logging in, which cancels the deletion.
+		if !user.Validated || user.PurgeAt != nil {
+			api.invalidAPITokenResponse(w, r)
+			return
+		}
+
+		scope := data.APIScopeWrite
+		if r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
+			scope = data.APIScopeRead
+		}
+		if !token.HasScope(scope) {
+			api.notPermittedResponse(w, r, "this API token does not have the "+scope+" scope")
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), apiTokenContextKey, token)
+		ctx = context.WithValue(ctx, userContextKey, user)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// requireSession rejects requests authenticated with an API token, so that a leaked token can't be used to
+// take over, export or delete the account.
+func (api *API) requireSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Value(apiTokenContextKey).(*data.APIToken); ok {
+			api.notPermittedResponse(w, r, "API tokens can't be used for this, please log in")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handleCreateAPIToken creates a personal API token for the logged in user. The token itself is only
+// returned now, as just its hash is stored.
+func (api *API) handleCreateAPIToken(w http.ResponseWriter, r *http.Request) {
+	user, ok := api.authenticatedUser(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Name   string    `json:"name"`
+		Scopes []string  `json:"scopes"`
+		Expiry time.Time `json:"expiry"`
+	}
+
+	err := api.readJSON(w, r, &input)
+	if err != nil {
+		api.badRequestResponse(w, r, err)
+		return
+	}
+
+	token := &data.APIToken{
+		UserID: int64(user.ID),
+		Name:   input.Name,
+		Scopes: input.Scopes,
+		Expiry: input.Expiry,
+	}
+	v := validator.New()
+	data.ValidateAPIToken(v, token, api.clock.Now())
+	if !v.Valid() {
+		api.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = api.db.APITokens.Insert(r.Context(), token)
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = api.writeJSON(w, http.StatusCreated, token, nil)
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+	}
+}
+
+// handleListAPITokens returns the logged in user's API tokens, without the tokens themselves.
+func (api *API) handleListAPITokens(w http.ResponseWriter, r *http.Request) {
+	user, ok := api.authenticatedUser(w, r)
+	if !ok {
+		return
+	}
+
+	tokens, err := api.db.APITokens.ListForUser(r.Context(), int64(user.ID))
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+		return
+	}
+	err = api.writeJSON(w, http.StatusOK, map[string]any{"tokens": tokens}, nil)
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+	}
+}
+
+// handleDeleteAPIToken revokes one of the logged in user's API tokens.
+func (api *API) handleDeleteAPIToken(w http.ResponseWriter, r *http.Request) {
+	user, ok := api.authenticatedUser(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := api.readIDParam(r)
+	if err != nil {
+		api.notFoundResponse(w, r)
+		return
+	}
+
+	err = api.db.APITokens.Delete(r.Context(), int64(user.ID), id)
+	if err != nil {
+		if errors.Is(err, data.ErrNoAPITokenFound) {
+			api.notFoundResponse(w, r)
+		} else {
+			api.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
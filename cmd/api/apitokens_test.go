@@ -0,0 +1,177 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hazzardr/baduk-online/internal/clock"
+	"github.com/hazzardr/baduk-online/internal/data"
+)
+
+func TestAPITokensIntegration(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	mailer := &mockMailer{db: db}
+	api := NewAPI("test", "1.0.0", db, mailer)
+	fake := clock.NewFake(time.Now())
+	api.clock = fake
+	server := httptest.NewServer(api.Routes())
+	defer server.Close()
+
+	createTestUser(t, db, "Script User", "script@example.com", "password123", true)
+
+	// send makes a request, authenticated with the bearer token if one is given.
+	send := func(t *testing.T, client *http.Client, bearer, method, path string, input any) (int, []byte, http.Header) {
+		t.Helper()
+		body, _ := json.Marshal(input)
+		req, _ := http.NewRequest(method, server.URL+"/api/v1"+path, bytes.NewBuffer(body))
+		if bearer != "" {
+			req.Header.Set("Authorization", "Bearer "+bearer)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("failed to make request: %s", err)
+		}
+		defer resp.Body.Close()
+		respBody, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, respBody, resp.Header
+	}
+	client := newTestClient(t)
+	if status := login(t, client, server.URL, "script@example.com", "password123"); status != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", status)
+	}
+	getUser := func(t *testing.T, bearer string) int {
+		t.Helper()
+		status, _, _ := send(t, http.DefaultClient, bearer, http.MethodGet, "/user", nil)
+		return status
+	}
+	create := func(t *testing.T, scopes ...string) *data.APIToken {
+		t.Helper()
+		input := map[string]any{"name": "Bot", "scopes": scopes, "expiry": fake.Now().Add(24 * time.Hour)}
+		status, body, _ := send(t, client, "", http.MethodPost, "/user/tokens", input)
+		if status != http.StatusCreated {
+			t.Fatalf("expected status 201, got %d: %s", status, body)
+		}
+		var token data.APIToken
+		if err := json.Unmarshal(body, &token); err != nil {
+			t.Fatalf("failed to decode token: %s", err)
+		}
+		return &token
+	}
+
+	t.Run("creating a token requires a session", func(t *testing.T) {
+		input := map[string]any{"name": "Bot", "scopes": []string{"read"}, "expiry": fake.Now().Add(time.Hour)}
+		status, _, _ := send(t, newTestClient(t), "", http.MethodPost, "/user/tokens", input)
+		if status != http.StatusUnauthorized {
+			t.Errorf("expected status 401, got %d", status)
+		}
+	})
+
+	t.Run("token requests are validated", func(t *testing.T) {
+		input := map[string]any{"name": "Bot", "scopes": []string{"admin"}, "expiry": fake.Now().Add(time.Hour)}
+		status, _, _ := send(t, client, "", http.MethodPost, "/user/tokens", input)
+		if status != http.StatusUnprocessableEntity {
+			t.Errorf("expected status 422, got %d", status)
+		}
+	})
+
+	token := create(t, data.APIScopeRead, data.APIScopeWrite)
+
+	t.Run("a token authenticates as its user", func(t *testing.T) {
+		if len(token.Plaintext) != 26 {
+			t.Fatalf("expected the token to be returned, got %+v", token)
+		}
+		status, body, _ := send(t, http.DefaultClient, token.Plaintext, http.MethodGet, "/user", nil)
+		if status != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", status, body)
+		}
+		var user data.User
+		if err := json.Unmarshal(body, &user); err != nil || user.Email != "script@example.com" {
+			t.Errorf("expected the token's user, got %s", body)
+		}
+
+		input := map[string]string{"name": "Scripted User"}
+		status, body, _ = send(t, http.DefaultClient, token.Plaintext, http.MethodPatch, "/user", input)
+		if status != http.StatusOK {
+			t.Errorf("expected status 200, got %d: %s", status, body)
+		}
+	})
+
+	t.Run("listing tokens hides them", func(t *testing.T) {
+		status, body, _ := send(t, client, "", http.MethodGet, "/user/tokens", nil)
+		var list struct {
+			Tokens []data.APIToken `json:"tokens"`
+		}
+		if err := json.Unmarshal(body, &list); err != nil || status != http.StatusOK {
+			t.Fatalf("failed to list tokens, got status %d: %s", status, body)
+		}
+		if len(list.Tokens) != 1 || list.Tokens[0].ID != token.ID {
+			t.Fatalf("unexpected tokens %+v", list.Tokens)
+		}
+		if list.Tokens[0].Plaintext != "" || list.Tokens[0].LastUsedAt == nil {
+			t.Errorf("expected no plaintext and a last use, got %+v", list.Tokens[0])
+		}
+	})
+
+	t.Run("unknown tokens are rejected", func(t *testing.T) {
+		for _, bearer := range []string{"ABCDEFGHIJKLMNOPQRSTUVWXYZ", "short"} {
+			status, _, header := send(t, http.DefaultClient, bearer, http.MethodGet, "/user", nil)
+			if status != http.StatusUnauthorized || header.Get("WWW-Authenticate") != "Bearer" {
+				t.Errorf("expected status 401 with a challenge, got %d", status)
+			}
+		}
+	})
+
+	t.Run("tokens are limited to their scopes", func(t *testing.T) {
+		readOnly := create(t, data.APIScopeRead)
+		if status := getUser(t, readOnly.Plaintext); status != http.StatusOK {
+			t.Errorf("expected status 200, got %d", status)
+		}
+		input := map[string]string{"name": "Read Only"}
+		status, _, _ := send(t, http.DefaultClient, readOnly.Plaintext, http.MethodPatch, "/user", input)
+		if status != http.StatusForbidden {
+			t.Errorf("expected status 403, got %d", status)
+		}
+	})
+
+	t.Run("tokens can't manage the account", func(t *testing.T) {
+		input := map[string]any{"name": "Another", "scopes": []string{"read"}, "expiry": fake.Now().Add(time.Hour)}
+		status, _, _ := send(t, http.DefaultClient, token.Plaintext, http.MethodPost, "/user/tokens", input)
+		if status != http.StatusForbidden {
+			t.Errorf("expected status 403, got %d", status)
+		}
+		input = map[string]any{"current_password": "password123", "password": "password456"}
+		status, _, _ = send(t, http.DefaultClient, token.Plaintext, http.MethodPut, "/user/password", input)
+		if status != http.StatusForbidden {
+			t.Errorf("expected status 403, got %d", status)
+		}
+	})
+
+	t.Run("revoked tokens stop working", func(t *testing.T) {
+		revoked := create(t, data.APIScopeRead)
+		path := fmt.Sprintf("/user/tokens/%d", revoked.ID)
+		if status, _, _ := send(t, client, "", http.MethodDelete, path, nil); status != http.StatusNoContent {
+			t.Fatalf("expected status 204, got %d", status)
+		}
+		if status, _, _ := send(t, client, "", http.MethodDelete, path, nil); status != http.StatusNotFound {
+			t.Errorf("expected status 404, got %d", status)
+		}
+		if status := getUser(t, revoked.Plaintext); status != http.StatusUnauthorized {
+			t.Errorf("expected status 401, got %d", status)
+		}
+	})
+
+	t.Run("expired tokens stop working", func(t *testing.T) {
+		fake.Advance(25 * time.Hour)
+		if status := getUser(t, token.Plaintext); status != http.StatusUnauthorized {
+			t.Errorf("expected status 401, got %d", status)
+		}
+	})
+}
@@ -1,13 +1,35 @@
 package api
 
 import (
+	"context"
+	"log/slog"
 	"sync"
 	"time"
 
 	"github.com/alexedwards/scs/pgxstore"
 	"github.com/alexedwards/scs/v2"
+	"github.com/hazzardr/baduk-online/internal/clock"
 	"github.com/hazzardr/baduk-online/internal/data"
+	"github.com/hazzardr/baduk-online/internal/events"
 	"github.com/hazzardr/baduk-online/internal/mail"
+	"github.com/hazzardr/baduk-online/internal/oidc"
+	"github.com/hazzardr/baduk-online/internal/totp"
+	"github.com/hazzardr/baduk-online/internal/webauthn"
+)
+
+const (
+	// eventHistorySize is how many messages per topic are kept so that reconnecting clients can resume.
+	eventHistorySize = 256
+	// eventBufferSize is how many messages may be waiting for a client before it is disconnected.
+	eventBufferSize = 64
+	// eventDrainTimeout bounds how long a graceful shutdown waits for clients to receive their messages.
+	eventDrainTimeout = 5 * time.Second
+	// chatRateLimit is how many chat messages a user may post within chatRateWindow.
+	chatRateLimit  = 5
+	chatRateWindow = 10 * time.Second
+	// twoFactorRateLimit is how many second factors a user may try within twoFactorRateWindow.
+	twoFactorRateLimit  = 5
+	twoFactorRateWindow = 5 * time.Minute
 )
 
 type API struct {
@@ -16,6 +38,16 @@ type API struct {
 	db             *data.Database
 	mailer         mail.Mailer
 	sessionManager *scs.SessionManager
+	hub            *events.Hub
+	clock          clock.Clock
+	chatLimiter    *rateLimiter
+	totpLimiter    *rateLimiter
+	totpSealer     *totp.Sealer
+	relyingParty   *webauthn.RelyingParty
+	oidcProvider   *oidc.Provider
+	oidcName       string
+	quit           chan struct{}
+	quitOnce       sync.Once
 	wg             sync.WaitGroup
 }
 
@@ -30,11 +62,57 @@ func NewAPI(environment, version string, db *data.Database, mailer mail.Mailer)
 		db:             db,
 		mailer:         mailer,
 		sessionManager: sm,
+		hub:            events.NewHub(eventHistorySize, eventBufferSize),
+		clock:          clock.Real{},
+		chatLimiter:    newRateLimiter(chatRateLimit, chatRateWindow),
+		totpLimiter:    newRateLimiter(twoFactorRateLimit, twoFactorRateWindow),
+		quit:           make(chan struct{}),
 	}
 }
 
-// Shutdown allows the caller to wait for the background tasks in our application to be completed before returning.
+// SetTOTPKey sets the key two-factor secrets are encrypted with. Two-factor authentication can't be turned
+// on until a key is set.
+func (api *API) SetTOTPKey(key []byte) error {
+	sealer, err := totp.NewSealer(key)
+	if err != nil {
+		return err
+	}
+	api.totpSealer = sealer
+	return nil
+}
+
+// SetWebAuthnOrigin sets the origin the frontend is served from, such as "https://baduk.online", which
+// passkeys are bound to. Passkeys can't be used until an origin is set.
+func (api *API) SetWebAuthnOrigin(origin string) error {
+	rp, err := webauthn.NewRelyingParty("baduk.online", origin)
+	if err != nil {
+		return err
+	}
+	api.relyingParty = rp
+	return nil
+}
+
+// SetOIDCProvider enables logging in with an OpenID Connect provider, shown to users under the given name.
+func (api *API) SetOIDCProvider(provider *oidc.Provider, name string) {
+	api.oidcProvider = provider
+	api.oidcName = name
+}
+
+// Shutdown stops background workers, disconnects event subscribers and allows the caller to wait for the
+// background tasks in our application to be completed before returning. When graceful, subscribers are first
+// given a chance to receive the messages already queued for them.
 func (api *API) Shutdown(graceful bool) {
+	api.quitOnce.Do(func() { close(api.quit) })
+
+	ctx, cancel := context.WithTimeout(context.Background(), eventDrainTimeout)
+	defer cancel()
+	if !graceful {
+		cancel()
+	}
+	err := api.hub.Shutdown(ctx)
+	if err != nil && graceful {
+		slog.Warn("timed out draining event subscribers", "err", err)
+	}
 	if graceful {
 		api.wg.Wait()
 	}
@@ -0,0 +1,219 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"time"
+
+	"github.com/hazzardr/baduk-online/internal/automatch"
+	"github.com/hazzardr/baduk-online/internal/clock"
+	"github.com/hazzardr/baduk-online/internal/data"
+	"github.com/hazzardr/baduk-online/internal/game"
+	"github.com/hazzardr/baduk-online/internal/handicap"
+	"github.com/hazzardr/baduk-online/internal/ratings"
+	"github.com/hazzardr/baduk-online/internal/scoring"
+	"github.com/hazzardr/baduk-online/internal/validator"
+)
+
+// handleJoinAutomatch puts the logged in user in the automatch queue, or updates their preferences if they
+// are already waiting.
+func (api *API) handleJoinAutomatch(w http.ResponseWriter, r *http.Request) {
+	user, ok := api.authenticatedUser(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		BoardSizes []int           `json:"board_sizes"`
+		Speed      automatch.Speed `json:"speed"`
+		Rules      scoring.Ruleset `json:"rules"`
+		Rated      *bool           `json:"rated"`
+		MinRank    string          `json:"min_rank"`
+		MaxRank    string          `json:"max_rank"`
+	}
+
+	err := api.readJSON(w, r, &input)
+	if err != nil {
+		api.badRequestResponse(w, r, err)
+		return
+	}
+
+	entry := &automatch.Entry{
+		UserID:     int64(user.ID),
+		BoardSizes: input.BoardSizes,
+		Speed:      input.Speed,
+		MinRank:    ratings.MinRank,
+		MaxRank:    ratings.MaxRank,
+		Rules:      input.Rules,
+		Rated:      true,
+	}
+	if entry.Rules == "" {
+		entry.Rules = scoring.Ruleset(user.PreferredRules)
+	}
+	if input.Rated != nil {
+		entry.Rated = *input.Rated
+	}
+
+	v := validator.New()
+	if input.MinRank != "" {
+		entry.MinRank, err = ratings.ParseRank(input.MinRank)
+		if err != nil {
+			v.AddError("min_rank", "must be a rank between 30k and 9d")
+		}
+	}
+	if input.MaxRank != "" {
+		entry.MaxRank, err = ratings.ParseRank(input.MaxRank)
+		if err != nil {
+			v.AddError("max_rank", "must be a rank between 30k and 9d")
+		}
+	}
+	if automatch.ValidateEntry(v, entry); !v.Valid() {
+		api.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = api.db.Automatch.Join(r.Context(), entry)
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = api.writeJSON(w, http.StatusOK, map[string]any{"automatch": entry}, nil)
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+	}
+}
+
+// handleGetAutomatch shows the logged in user's place in the automatch queue.
+func (api *API) handleGetAutomatch(w http.ResponseWriter, r *http.Request) {
+	user, ok := api.authenticatedUser(w, r)
+	if !ok {
+		return
+	}
+
+	entry, err := api.db.Automatch.Get(r.Context(), int64(user.ID))
+	if err != nil {
+		if errors.Is(err, data.ErrNotQueued) {
+			api.notFoundResponse(w, r)
+		} else {
+			api.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = api.writeJSON(w, http.StatusOK, map[string]any{"automatch": entry}, nil)
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+	}
+}
+
+// handleLeaveAutomatch takes the logged in user out of the automatch queue.
+func (api *API) handleLeaveAutomatch(w http.ResponseWriter, r *http.Request) {
+	user, ok := api.authenticatedUser(w, r)
+	if !ok {
+		return
+	}
+
+	err := api.db.Automatch.Leave(r.Context(), int64(user.ID))
+	if err != nil {
+		if errors.Is(err, data.ErrNotQueued) {
+			api.notFoundResponse(w, r)
+		} else {
+			api.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// StartMatchmaker periodically pairs the players waiting in the automatch queue and starts their games. It
+// runs until Shutdown is called.
+func (api *API) StartMatchmaker(interval time.Duration) {
+	api.background(func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-api.quit:
+				return
+			case <-ticker.C:
+				err := api.matchPlayers(context.Background())
+				if err != nil {
+					slog.Error("failed to match players", "err", err)
+				}
+			}
+		}
+	})
+}
+
+// matchPlayers runs a single round of matchmaking.
+func (api *API) matchPlayers(ctx context.Context) error {
+	entries, err := api.db.Automatch.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := api.clock.Now()
+	for _, pair := range automatch.Match(entries, now, automatch.DefaultWindow) {
+		g, err := newAutomatchGame(pair, now)
+		if err != nil {
+			return err
+		}
+
+		err = api.db.Automatch.Pair(ctx, pair, g)
+		if err != nil {
+			// One of the players left or changed their mind; anyone still waiting is considered next round.
+			if errors.Is(err, data.ErrQueueChanged) {
+				continue
+			}
+			return err
+		}
+
+		slog.Info("automatch paired players", "game_id", g.ID, "black_id", g.BlackID, "white_id", g.WhiteID)
+		for _, id := range []int64{g.BlackID, g.WhiteID} {
+			api.publish(userTopic(int(id)), "automatch_found", map[string]any{"game": g})
+		}
+	}
+	return nil
+}
+
+// newAutomatchGame sets up the game for a pairing. The weaker player takes black, with handicap and komi
+// recommended for the difference in rank, and the rules and rating the players queued with. Colors are
+// chosen at random between players of the same rank.
+func newAutomatchGame(pair automatch.Pair, now time.Time) (*data.Game, error) {
+	black, white := pair.A, pair.B
+	if rand.IntN(2) == 1 { //nolint:gosec // choosing colors does not need a secure source
+		black, white = white, black
+	}
+	rec, err := handicap.Recommend(black.Rank, white.Rank, pair.BoardSize, black.Rules)
+	if err != nil {
+		return nil, err
+	}
+	if rec.SwapColors {
+		black, white = white, black
+	}
+
+	g := &data.Game{
+		BlackID:   black.UserID,
+		WhiteID:   white.UserID,
+		BoardSize: pair.BoardSize,
+		Rules:     string(black.Rules),
+		Komi:      rec.Komi,
+		Handicap:  rec.Handicap,
+		Rated:     black.Rated,
+		Clock:     clock.New(black.Speed.TimeControl()),
+	}
+	// White moves first once handicap stones have been placed.
+	first := game.Black
+	if g.Handicap > 1 {
+		first = game.White
+	}
+	err = g.Clock.Start(now, first)
+	if err != nil {
+		return nil, err
+	}
+	return g, nil
+}
@@ -0,0 +1,192 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hazzardr/baduk-online/internal/automatch"
+	"github.com/hazzardr/baduk-online/internal/data"
+	"github.com/hazzardr/baduk-online/internal/ratings"
+	"github.com/hazzardr/baduk-online/internal/scoring"
+)
+
+func TestAutomatchIntegration(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	mailer := &mockMailer{db: db}
+	api := NewAPI("test", "1.0.0", db, mailer)
+	server := httptest.NewServer(api.Routes())
+	defer server.Close()
+
+	first := createTestUser(t, db, "First", "first@example.com", "password123", true)
+	second := createTestUser(t, db, "Second", "second@example.com", "password123", true)
+
+	firstClient := loggedIn(t, server.URL, "first@example.com")
+	secondClient := loggedIn(t, server.URL, "second@example.com")
+
+	do := func(t *testing.T, client *http.Client, method string, payload any) int {
+		t.Helper()
+		var body bytes.Buffer
+		if payload != nil {
+			_ = json.NewEncoder(&body).Encode(payload)
+		}
+		req, _ := http.NewRequest(method, server.URL+"/api/v1/automatch", &body)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("failed to make request: %s", err)
+		}
+		defer resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	preferences := map[string]any{"board_sizes": []int{9, 19}, "speed": "live", "min_rank": "25k", "max_rank": "1d"}
+
+	t.Run("requires authentication", func(t *testing.T) {
+		if status := do(t, newTestClient(t), http.MethodPut, preferences); status != http.StatusUnauthorized {
+			t.Errorf("expected status 401, got %d", status)
+		}
+	})
+
+	t.Run("rejects invalid preferences", func(t *testing.T) {
+		invalid := map[string]any{"board_sizes": []int{21}, "speed": "bullet", "min_rank": "40k"}
+		if status := do(t, firstClient, http.MethodPut, invalid); status != http.StatusUnprocessableEntity {
+			t.Errorf("expected status 422, got %d", status)
+		}
+	})
+
+	t.Run("join and leave", func(t *testing.T) {
+		if status := do(t, firstClient, http.MethodPut, preferences); status != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", status)
+		}
+		if status := do(t, firstClient, http.MethodGet, nil); status != http.StatusOK {
+			t.Errorf("expected status 200, got %d", status)
+		}
+		if status := do(t, firstClient, http.MethodDelete, nil); status != http.StatusNoContent {
+			t.Errorf("expected status 204, got %d", status)
+		}
+		if status := do(t, firstClient, http.MethodGet, nil); status != http.StatusNotFound {
+			t.Errorf("expected status 404, got %d", status)
+		}
+		if status := do(t, firstClient, http.MethodDelete, nil); status != http.StatusNotFound {
+			t.Errorf("expected status 404, got %d", status)
+		}
+	})
+
+	t.Run("matchmaker pairs compatible players", func(t *testing.T) {
+		if status := do(t, firstClient, http.MethodPut, preferences); status != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", status)
+		}
+		if err := api.matchPlayers(ctx); err != nil {
+			t.Fatalf("failed to match players: %s", err)
+		}
+		if status := do(t, firstClient, http.MethodGet, nil); status != http.StatusOK {
+			t.Fatalf("expected a lone player to keep waiting, got %d", status)
+		}
+
+		nineOnly := map[string]any{"board_sizes": []int{9}, "speed": "live"}
+		if status := do(t, secondClient, http.MethodPut, nineOnly); status != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", status)
+		}
+		if err := api.matchPlayers(ctx); err != nil {
+			t.Fatalf("failed to match players: %s", err)
+		}
+
+		for _, client := range []*http.Client{firstClient, secondClient} {
+			if status := do(t, client, http.MethodGet, nil); status != http.StatusNotFound {
+				t.Errorf("expected paired player to leave the queue, got %d", status)
+			}
+		}
+		games, err := db.Games.ListForPlayer(ctx, int64(first.ID))
+		if err != nil {
+			t.Fatalf("failed to list games: %s", err)
+		}
+		if len(games) != 1 {
+			t.Fatalf("expected one game, got %d", len(games))
+		}
+		g := games[0]
+		if g.BoardSize != 9 || g.Rules != "japanese" || !g.Rated || g.Clock == nil || g.Status != data.GameStatusActive {
+			t.Errorf("unexpected game: %+v", g)
+		}
+		if g.BlackID != int64(second.ID) && g.WhiteID != int64(second.ID) {
+			t.Errorf("expected game against the second player, got %+v", g)
+		}
+	})
+}
+
+func TestNewAutomatchGame(t *testing.T) {
+	rank := func(s string) ratings.Rank {
+		r, err := ratings.ParseRank(s)
+		if err != nil {
+			t.Fatalf("ParseRank(%q) error = %v", s, err)
+		}
+		return r
+	}
+	queued := func(id int64, r string, rules scoring.Ruleset, rated bool) automatch.Entry {
+		return automatch.Entry{UserID: id, Speed: automatch.Live, Rank: rank(r), Rules: rules, Rated: rated}
+	}
+
+	tests := []struct {
+		name string
+		a, b automatch.Entry
+		size int
+		// wantBlack is zero if either player may take black.
+		wantBlack    int64
+		wantHandicap int
+		wantKomi     float64
+	}{
+		{
+			name: "Even game",
+			a:    queued(1, "5k", scoring.Japanese, true), b: queued(2, "5k", scoring.Japanese, true), size: 19,
+			wantHandicap: 0, wantKomi: 6.5,
+		},
+		{
+			name: "Weaker player takes black",
+			a:    queued(1, "3k", scoring.Japanese, true), b: queued(2, "7k", scoring.Japanese, true), size: 19,
+			wantBlack: 2, wantHandicap: 4, wantKomi: 0.5,
+		},
+		{
+			name: "Area scoring compensates white",
+			a:    queued(1, "7k", scoring.Chinese, false), b: queued(2, "3k", scoring.Chinese, false), size: 19,
+			wantBlack: 1, wantHandicap: 4, wantKomi: 4.5,
+		},
+		{
+			name: "One rank is played without komi",
+			a:    queued(1, "4k", scoring.Japanese, true), b: queued(2, "5k", scoring.Japanese, true), size: 19,
+			wantBlack: 2, wantHandicap: 0, wantKomi: 0.5,
+		},
+		{
+			name: "Stones are worth more on small boards",
+			a:    queued(1, "1d", scoring.Japanese, true), b: queued(2, "7k", scoring.Japanese, true), size: 9,
+			wantBlack: 2, wantHandicap: 2, wantKomi: 0.5,
+		},
+	}
+
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := newAutomatchGame(automatch.Pair{A: tt.a, B: tt.b, BoardSize: tt.size}, now)
+			if err != nil {
+				t.Fatalf("newAutomatchGame() error = %v", err)
+			}
+			if tt.wantBlack != 0 && g.BlackID != tt.wantBlack {
+				t.Errorf("black = %d, want %d", g.BlackID, tt.wantBlack)
+			}
+			if g.Handicap != tt.wantHandicap || g.Komi != tt.wantKomi {
+				t.Errorf("handicap, komi = %d, %v, want %d, %v", g.Handicap, g.Komi, tt.wantHandicap, tt.wantKomi)
+			}
+			if g.Rules != string(tt.a.Rules) || g.Rated != tt.a.Rated || g.BoardSize != tt.size {
+				t.Errorf("unexpected game settings: %+v", g)
+			}
+			if g.Clock == nil {
+				t.Error("expected the clock to be started")
+			}
+		})
+	}
+}
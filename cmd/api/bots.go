@@ -0,0 +1,303 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/hazzardr/baduk-online/internal/bot"
+	"github.com/hazzardr/baduk-online/internal/clock"
+	"github.com/hazzardr/baduk-online/internal/data"
+	"github.com/hazzardr/baduk-online/internal/game"
+	"github.com/hazzardr/baduk-online/internal/gtp"
+	"github.com/hazzardr/baduk-online/internal/handicap"
+	"github.com/hazzardr/baduk-online/internal/scoring"
+	"github.com/hazzardr/baduk-online/internal/superko"
+	"github.com/hazzardr/baduk-online/internal/validator"
+)
+
+// botPlayer chooses the moves bot accounts play.
+type botPlayer interface {
+	nextMove(ctx context.Context, g *data.Game, moves []*data.Move) (bot.Move, error)
+}
+
+// StartBotPlayer periodically has the engine play for bot accounts in the games where it is their turn. It
+// runs until Shutdown is called, and the caller remains responsible for closing the engine afterwards.
+func (api *API) StartBotPlayer(engine *gtp.Client, interval time.Duration) {
+	api.startBots(engineBot{engine: engine}, interval)
+}
+
+// StartHeuristicBot periodically has a built in player move for bot accounts in the games where it is their
+// turn. It runs until Shutdown is called.
+func (api *API) StartHeuristicBot(player bot.Player, interval time.Duration) {
+	api.startBots(heuristicBot{player: player}, interval)
+}
+
+func (api *API) startBots(player botPlayer, interval time.Duration) {
+	api.background(func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-api.quit:
+				return
+			case <-ticker.C:
+				err := api.playBotMoves(context.Background(), player)
+				if err != nil {
+					slog.Error("failed to play bot moves", "err", err)
+				}
+			}
+		}
+	})
+}
+
+// playBotMoves plays one move in every game waiting on a bot. A game the player can't move in is logged and
+// skipped, so that it doesn't hold up the others.
+func (api *API) playBotMoves(ctx context.Context, player botPlayer) error {
+	games, err := api.db.Games.ListAwaitingBot(ctx)
+	if err != nil {
+		return err
+	}
+	for _, g := range games {
+		err := api.playBotMove(ctx, player, g)
+		if err != nil {
+			slog.Error("bot failed to move", "game_id", g.ID, "err", err)
+		}
+	}
+	return nil
+}
+
+// playBotMove asks the player for its move in a game and records it. The bot passes instead of playing a
+// move that is not legal in the game, and resigns a game whose handicap stones have no fixed placement,
+// since it could never set up the position.
+func (api *API) playBotMove(ctx context.Context, player botPlayer, g *data.Game) error {
+	moves, err := api.db.Games.Moves(ctx, g.ID)
+	if err != nil {
+		return err
+	}
+	var generated bot.Move
+	if g.Handicap >= handicap.MinStones && !validator.PermittedValue(g.BoardSize, handicap.SupportedSizes...) {
+		slog.Warn("bot resigned a game it can't place the handicap stones of", "game_id", g.ID)
+		generated.Resign = true
+	} else {
+		generated, err = player.nextMove(ctx, g, moves)
+		if err != nil {
+			return err
+		}
+	}
+	color := colorOf(g.ToMove())
+
+	if generated.Resign {
+		err = api.db.Games.Resign(ctx, g, g.ToMove())
+		if err != nil {
+			return ignoreStaleGame(err)
+		}
+		api.publish(gameTopic(g.ID), "finished", g)
+		return api.rateGame(ctx, g)
+	}
+
+	move := &data.Move{
+		Color: g.ToMove(),
+		X:     generated.Point.X,
+		Y:     generated.Point.Y,
+		Pass:  generated.Pass,
+	}
+	err = checkBotMove(g, moves, move)
+	if err != nil {
+		slog.Warn("bot chose an illegal move, passing instead", "game_id", g.ID, "x", move.X, "y", move.Y, "err", err)
+		move = &data.Move{Color: g.ToMove(), Pass: true}
+	}
+
+	if g.Clock != nil {
+		err := g.Clock.Press(api.clock.Now(), color)
+		if errors.Is(err, clock.ErrTimeout) {
+			// The timeout sweeper ends the game.
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	err = api.db.Games.AppendMove(ctx, g, move)
+	if err != nil {
+		return ignoreStaleGame(err)
+	}
+	api.publish(gameTopic(g.ID), "move", map[string]any{"move": move, "clock": g.Clock})
+	return nil
+}
+
+// checkBotMove reports why a move may not be played in a game. The game is replayed from its handicap stones
+// so that suicide, ko and, under rules that have it, superko are checked as well as the bounds of the board.
+func checkBotMove(g *data.Game, moves []*data.Move, move *data.Move) error {
+	v := validator.New()
+	if data.ValidateMove(v, g, move); !v.Valid() {
+		return fmt.Errorf("invalid move: %v", v.Errors)
+	}
+
+	board, err := game.NewBoard(g.BoardSize)
+	if err != nil {
+		return err
+	}
+	var history *superko.History
+	if rule, ok := superko.ForRuleset(scoring.Ruleset(g.Rules)); ok {
+		history, err = superko.New(g.BoardSize, rule, false)
+		if err != nil {
+			return err
+		}
+	}
+	if g.Handicap >= handicap.MinStones {
+		stones, err := handicap.Placement(g.BoardSize, g.Handicap)
+		if err != nil {
+			return err
+		}
+		for _, p := range stones {
+			err := board.Setup(game.Black, p)
+			if err != nil {
+				return err
+			}
+			if history != nil {
+				err = history.Setup(superko.Black, superko.Point(p))
+				if err != nil {
+					return err
+				}
+			}
+		}
+		if history != nil {
+			err = history.SetToMove(superko.White)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, m := range append(moves[:len(moves):len(moves)], move) {
+		err := replayMove(board, history, m)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// replayMove plays a stored move on the board, and in the history if the game has one.
+func replayMove(board *game.Board, history *superko.History, m *data.Move) error {
+	c := colorOf(m.Color)
+	if m.Pass {
+		if history != nil {
+			err := history.Pass(superko.Color(c))
+			if err != nil {
+				return err
+			}
+		}
+		return board.Pass(c)
+	}
+
+	p := game.Point{X: m.X, Y: m.Y}
+	_, err := board.Play(c, p)
+	if err != nil {
+		return err
+	}
+	if history != nil {
+		return history.Play(superko.Color(c), superko.Point(p))
+	}
+	return nil
+}
+
+// engineBot plays the moves generated by a GTP engine.
+type engineBot struct {
+	engine *gtp.Client
+}
+
+func (b engineBot) nextMove(ctx context.Context, g *data.Game, moves []*data.Move) (bot.Move, error) {
+	err := setupEngine(ctx, b.engine, g, moves)
+	if err != nil {
+		return bot.Move{}, err
+	}
+	generated, err := b.engine.GenMove(ctx, colorOf(g.ToMove()))
+	if err != nil {
+		return bot.Move{}, err
+	}
+	return bot.Move{Point: generated.Point, Pass: generated.Pass, Resign: generated.Resign}, nil
+}
+
+// heuristicBot plays the moves chosen by a built in player.
+type heuristicBot struct {
+	player bot.Player
+}
+
+func (b heuristicBot) nextMove(_ context.Context, g *data.Game, moves []*data.Move) (bot.Move, error) {
+	position := bot.Position{Size: g.BoardSize, ToMove: colorOf(g.ToMove()), Rules: scoring.Ruleset(g.Rules)}
+	if g.Handicap >= handicap.MinStones {
+		stones, err := handicap.Placement(g.BoardSize, g.Handicap)
+		if err != nil {
+			return bot.Move{}, err
+		}
+		position.Setup = stones
+	}
+	for _, m := range moves {
+		position.Moves = append(position.Moves, game.Move{
+			Color: colorOf(m.Color),
+			Point: game.Point{X: m.X, Y: m.Y},
+			Pass:  m.Pass,
+		})
+	}
+	return b.player.NextMove(position), nil
+}
+
+// setupEngine puts the engine's board into the position of a game.
+func setupEngine(ctx context.Context, engine *gtp.Client, g *data.Game, moves []*data.Move) error {
+	err := engine.BoardSize(ctx, g.BoardSize)
+	if err != nil {
+		return err
+	}
+	err = engine.ClearBoard(ctx)
+	if err != nil {
+		return err
+	}
+	err = engine.Komi(ctx, g.Komi)
+	if err != nil {
+		return err
+	}
+	if g.Handicap >= handicap.MinStones {
+		stones, err := handicap.Placement(g.BoardSize, g.Handicap)
+		if err != nil {
+			return err
+		}
+		err = engine.SetFreeHandicap(ctx, stones)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, m := range moves {
+		var err error
+		if m.Pass {
+			err = engine.Pass(ctx, colorOf(m.Color))
+		} else {
+			err = engine.Play(ctx, colorOf(m.Color), game.Point{X: m.X, Y: m.Y})
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// colorOf converts a stored color to a stone color.
+func colorOf(color string) game.Color {
+	if color == data.ColorWhite {
+		return game.White
+	}
+	return game.Black
+}
+
+// ignoreStaleGame drops the errors caused by a game changing while the bot was thinking. The next round sees
+// the new position.
+func ignoreStaleGame(err error) error {
+	if errors.Is(err, data.ErrEditConflict) || errors.Is(err, data.ErrGameFinished) {
+		return nil
+	}
+	return err
+}
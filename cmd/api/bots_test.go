@@ -0,0 +1,251 @@
+package api
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hazzardr/baduk-online/internal/bot"
+	"github.com/hazzardr/baduk-online/internal/clock"
+	"github.com/hazzardr/baduk-online/internal/data"
+	"github.com/hazzardr/baduk-online/internal/game"
+	"github.com/hazzardr/baduk-online/internal/gtp"
+)
+
+// startFakeEngine builds the gtp package's test engine and starts it.
+func startFakeEngine(t *testing.T) *gtp.Client {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "fakeengine")
+	build := exec.Command("go", "build", "-o", path, "../../internal/gtp/testdata/fakeengine")
+	build.Stderr = os.Stderr
+	if err := build.Run(); err != nil {
+		t.Fatalf("failed to build fake engine: %s", err)
+	}
+	engine, err := gtp.Start(gtp.Config{Path: path, Timeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("failed to start fake engine: %s", err)
+	}
+	t.Cleanup(func() { _ = engine.Close() })
+	return engine
+}
+
+// fixedBot always chooses the same move.
+type fixedBot struct {
+	move bot.Move
+}
+
+func (b fixedBot) nextMove(context.Context, *data.Game, []*data.Move) (bot.Move, error) {
+	return b.move, nil
+}
+
+func TestCheckBotMove(t *testing.T) {
+	// Black captures a white stone in a ko at (2, 1), leaving white to retake at (1, 1).
+	ko := [][2]int{{1, 0}, {2, 0}, {0, 1}, {3, 1}, {1, 2}, {2, 2}, {5, 5}, {1, 1}, {2, 1}}
+	played := func(points [][2]int, passes int) []*data.Move {
+		var moves []*data.Move
+		for i, p := range points {
+			moves = append(moves, &data.Move{Color: []string{data.ColorBlack, data.ColorWhite}[i%2], X: p[0], Y: p[1]})
+		}
+		for i := range passes {
+			moves = append(moves, &data.Move{Color: []string{data.ColorWhite, data.ColorBlack}[i%2], Pass: true})
+		}
+		return moves
+	}
+
+	tests := []struct {
+		name     string
+		rules    string
+		handicap int
+		moves    []*data.Move
+		move     data.Move
+		wantErr  bool
+	}{
+		{"Empty point", "japanese", 0, nil, data.Move{Color: data.ColorBlack, X: 4, Y: 4}, false},
+		{"Pass", "japanese", 0, played(ko, 0), data.Move{Color: data.ColorWhite, Pass: true}, false},
+		{"Off the board", "japanese", 0, nil, data.Move{Color: data.ColorBlack, X: 9, Y: 0}, true},
+		{"Occupied", "japanese", 0, played(ko, 0), data.Move{Color: data.ColorWhite, X: 5, Y: 5}, true},
+		{"Handicap stone", "japanese", 2, nil, data.Move{Color: data.ColorWhite, X: 6, Y: 2}, true},
+		{"Ko", "japanese", 0, played(ko, 0), data.Move{Color: data.ColorWhite, X: 1, Y: 1}, true},
+		{"Ko after passes", "japanese", 0, played(ko, 2), data.Move{Color: data.ColorWhite, X: 1, Y: 1}, false},
+		{"Positional superko", "chinese", 0, played(ko, 2), data.Move{Color: data.ColorWhite, X: 1, Y: 1}, true},
+		{"Situational superko", "aga", 0, played(ko, 2), data.Move{Color: data.ColorWhite, X: 1, Y: 1}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &data.Game{BoardSize: 9, Rules: tt.rules, Handicap: tt.handicap}
+			err := checkBotMove(g, tt.moves, &tt.move)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkBotMove() error = %v, want error %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBotPlayerIntegration(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	mailer := &mockMailer{db: db}
+	api := NewAPI("test", "1.0.0", db, mailer)
+	engine := startFakeEngine(t)
+
+	human := createTestUser(t, db, "Human", "human@example.com", "password123", true)
+	botUser := createTestUser(t, db, "Bot", "bot@example.com", "password123", true)
+	if _, err := db.Pool.Exec(ctx, "UPDATE users SET bot = true WHERE id = $1", botUser.ID); err != nil {
+		t.Fatalf("failed to make bot: %s", err)
+	}
+
+	t.Run("bot replies to a move", func(t *testing.T) {
+		g := &data.Game{
+			BlackID:   int64(human.ID),
+			WhiteID:   int64(botUser.ID),
+			BoardSize: 9,
+			Rules:     "japanese",
+			Komi:      6.5,
+			Clock:     clock.New(clock.TimeControl{Kind: clock.Absolute, MainTime: time.Hour}),
+		}
+		if err := g.Clock.Start(api.clock.Now(), game.Black); err != nil {
+			t.Fatalf("failed to start clock: %s", err)
+		}
+		if err := db.Games.Insert(ctx, g); err != nil {
+			t.Fatalf("failed to insert game: %s", err)
+		}
+
+		if err := api.playBotMoves(ctx, engineBot{engine: engine}); err != nil {
+			t.Fatalf("failed to play bot moves: %s", err)
+		}
+		if moves, _ := db.Games.Moves(ctx, g.ID); len(moves) != 0 {
+			t.Fatalf("expected the bot to wait for black, got %d moves", len(moves))
+		}
+
+		if err := g.Clock.Press(api.clock.Now(), game.Black); err != nil {
+			t.Fatalf("failed to press clock: %s", err)
+		}
+		if err := db.Games.AppendMove(ctx, g, &data.Move{Color: data.ColorBlack, X: 0, Y: 0}); err != nil {
+			t.Fatalf("failed to append move: %s", err)
+		}
+		if err := api.playBotMoves(ctx, engineBot{engine: engine}); err != nil {
+			t.Fatalf("failed to play bot moves: %s", err)
+		}
+
+		moves, err := db.Games.Moves(ctx, g.ID)
+		if err != nil {
+			t.Fatalf("failed to list moves: %s", err)
+		}
+		if len(moves) != 2 || moves[1].Color != data.ColorWhite || moves[1].X != 1 || moves[1].Y != 0 {
+			t.Fatalf("unexpected moves: %+v", moves)
+		}
+		got, _ := db.Games.Get(ctx, g.ID)
+		if got.Clock.ToMove != game.Black {
+			t.Errorf("expected the bot to press its clock, got %+v", got.Clock)
+		}
+	})
+
+	t.Run("bot plays white first in handicap games", func(t *testing.T) {
+		g := &data.Game{
+			BlackID:   int64(human.ID),
+			WhiteID:   int64(botUser.ID),
+			BoardSize: 9,
+			Rules:     "japanese",
+			Komi:      0.5,
+			Handicap:  2,
+		}
+		if err := db.Games.Insert(ctx, g); err != nil {
+			t.Fatalf("failed to insert game: %s", err)
+		}
+		if err := api.playBotMoves(ctx, engineBot{engine: engine}); err != nil {
+			t.Fatalf("failed to play bot moves: %s", err)
+		}
+		moves, _ := db.Games.Moves(ctx, g.ID)
+		if len(moves) != 1 || moves[0].Color != data.ColorWhite {
+			t.Errorf("expected white to move first, got %+v", moves)
+		}
+	})
+
+	t.Run("heuristic bot replies to a move", func(t *testing.T) {
+		g := &data.Game{
+			BlackID:   int64(human.ID),
+			WhiteID:   int64(botUser.ID),
+			BoardSize: 9,
+			Rules:     "japanese",
+			Komi:      6.5,
+		}
+		if err := db.Games.Insert(ctx, g); err != nil {
+			t.Fatalf("failed to insert game: %s", err)
+		}
+		if err := db.Games.AppendMove(ctx, g, &data.Move{Color: data.ColorBlack, X: 4, Y: 4}); err != nil {
+			t.Fatalf("failed to append move: %s", err)
+		}
+		if err := api.playBotMoves(ctx, heuristicBot{player: bot.NewHeuristic(1)}); err != nil {
+			t.Fatalf("failed to play bot moves: %s", err)
+		}
+
+		moves, err := db.Games.Moves(ctx, g.ID)
+		if err != nil {
+			t.Fatalf("failed to list moves: %s", err)
+		}
+		if len(moves) != 2 || moves[1].Color != data.ColorWhite || moves[1].Pass {
+			t.Fatalf("unexpected moves: %+v", moves)
+		}
+		if moves[1].X == 4 && moves[1].Y == 4 {
+			t.Errorf("expected the bot to play on an empty point")
+		}
+	})
+
+	t.Run("bot passes instead of an illegal move", func(t *testing.T) {
+		g := &data.Game{
+			BlackID:   int64(human.ID),
+			WhiteID:   int64(botUser.ID),
+			BoardSize: 9,
+			Rules:     "japanese",
+			Komi:      6.5,
+		}
+		if err := db.Games.Insert(ctx, g); err != nil {
+			t.Fatalf("failed to insert game: %s", err)
+		}
+		if err := db.Games.AppendMove(ctx, g, &data.Move{Color: data.ColorBlack, X: 4, Y: 4}); err != nil {
+			t.Fatalf("failed to append move: %s", err)
+		}
+		if err := api.playBotMoves(ctx, fixedBot{move: bot.Move{Point: game.Point{X: 4, Y: 4}}}); err != nil {
+			t.Fatalf("failed to play bot moves: %s", err)
+		}
+
+		moves, err := db.Games.Moves(ctx, g.ID)
+		if err != nil {
+			t.Fatalf("failed to list moves: %s", err)
+		}
+		if len(moves) != 2 || moves[1].Color != data.ColorWhite || !moves[1].Pass {
+			t.Fatalf("expected the bot to pass, got %+v", moves)
+		}
+	})
+
+	t.Run("bot resigns when the handicap can't be placed", func(t *testing.T) {
+		g := &data.Game{
+			BlackID:   int64(human.ID),
+			WhiteID:   int64(botUser.ID),
+			BoardSize: 7,
+			Rules:     "japanese",
+			Komi:      0.5,
+			Handicap:  4,
+		}
+		if err := db.Games.Insert(ctx, g); err != nil {
+			t.Fatalf("failed to insert game: %s", err)
+		}
+		if err := api.playBotMoves(ctx, heuristicBot{player: bot.NewHeuristic(1)}); err != nil {
+			t.Fatalf("failed to play bot moves: %s", err)
+		}
+
+		got, err := db.Games.Get(ctx, g.ID)
+		if err != nil {
+			t.Fatalf("failed to get game: %s", err)
+		}
+		if got.Status != data.GameStatusFinished || got.Result != "B+R" {
+			t.Errorf("expected the bot to resign, got %+v", got)
+		}
+	})
+}
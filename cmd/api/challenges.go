@@ -0,0 +1,306 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+
+	"github.com/hazzardr/baduk-online/internal/clock"
+	"github.com/hazzardr/baduk-online/internal/data"
+	"github.com/hazzardr/baduk-online/internal/game"
+	"github.com/hazzardr/baduk-online/internal/validator"
+)
+
+// handleCreateChallenge creates an open seek, or a direct challenge if an opponent is given.
+func (api *API) handleCreateChallenge(w http.ResponseWriter, r *http.Request) {
+	user, ok := api.authenticatedUser(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		OpponentID  *int64             `json:"opponent_id"`
+		BoardSize   int                `json:"board_size"`
+		Rules       string             `json:"rules"`
+		Komi        float64            `json:"komi"`
+		Handicap    int                `json:"handicap"`
+		Color       string             `json:"color"`
+		TimeControl *clock.TimeControl `json:"time_control"`
+		Rated       bool               `json:"rated"`
+	}
+
+	err := api.readJSON(w, r, &input)
+	if err != nil {
+		api.badRequestResponse(w, r, err)
+		return
+	}
+
+	challenge := &data.Challenge{
+		ChallengerID: int64(user.ID),
+		OpponentID:   input.OpponentID,
+		BoardSize:    input.BoardSize,
+		Rules:        input.Rules,
+		Komi:         input.Komi,
+		Handicap:     input.Handicap,
+		Color:        input.Color,
+		TimeControl:  input.TimeControl,
+		Rated:        input.Rated,
+	}
+	if challenge.Color == "" {
+		challenge.Color = data.ColorRandom
+	}
+
+	v := validator.New()
+	if data.ValidateChallenge(v, challenge); !v.Valid() {
+		api.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if challenge.OpponentID != nil {
+		_, err := api.db.Users.GetByID(r.Context(), *challenge.OpponentID)
+		if err != nil {
+			if errors.Is(err, data.ErrNoUserFound) {
+				v.AddError("opponent_id", "must be an existing user")
+				api.failedValidationResponse(w, r, v.Errors)
+				return
+			}
+			api.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	err = api.db.Challenges.Insert(r.Context(), challenge)
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if challenge.OpponentID == nil {
+		api.publish(lobbyTopic, "seek_created", challenge)
+	} else {
+		api.publish(userTopic(int(*challenge.OpponentID)), "challenge_received", challenge)
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/api/v1/challenges/%d", challenge.ID))
+	err = api.writeJSON(w, http.StatusCreated, map[string]any{"challenge": challenge}, headers)
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+	}
+}
+
+// handleListChallenges lists the open seeks that anyone may accept.
+func (api *API) handleListChallenges(w http.ResponseWriter, r *http.Request) {
+	challenges, err := api.db.Challenges.ListOpenSeeks(r.Context())
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+		return
+	}
+	err = api.writeJSON(w, http.StatusOK, map[string]any{"challenges": challenges}, nil)
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+	}
+}
+
+// handleListUserChallenges lists the open challenges the logged in user has sent or received.
+func (api *API) handleListUserChallenges(w http.ResponseWriter, r *http.Request) {
+	user, ok := api.authenticatedUser(w, r)
+	if !ok {
+		return
+	}
+	challenges, err := api.db.Challenges.ListOpenForUser(r.Context(), int64(user.ID))
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+		return
+	}
+	err = api.writeJSON(w, http.StatusOK, map[string]any{"challenges": challenges}, nil)
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+	}
+}
+
+// handleGetChallenge shows a single challenge.
+func (api *API) handleGetChallenge(w http.ResponseWriter, r *http.Request) {
+	challenge, ok := api.challengeFromRequest(w, r)
+	if !ok {
+		return
+	}
+	err := api.writeJSON(w, http.StatusOK, map[string]any{"challenge": challenge}, nil)
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+	}
+}
+
+// handleAcceptChallenge starts the game proposed by a challenge.
+func (api *API) handleAcceptChallenge(w http.ResponseWriter, r *http.Request) {
+	user, ok := api.authenticatedUser(w, r)
+	if !ok {
+		return
+	}
+	challenge, ok := api.challengeFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	userID := int64(user.ID)
+	switch {
+	case challenge.ChallengerID == userID:
+		api.notPermittedResponse(w, r, "you cannot accept your own challenge")
+		return
+	case challenge.OpponentID != nil && *challenge.OpponentID != userID:
+		api.notPermittedResponse(w, r, "this challenge was sent to another user")
+		return
+	case challenge.Status != data.ChallengeStatusOpen:
+		api.challengeClosedResponse(w, r)
+		return
+	}
+	seek := challenge.OpponentID == nil
+
+	g := &data.Game{
+		BoardSize: challenge.BoardSize,
+		Rules:     challenge.Rules,
+		Komi:      challenge.Komi,
+		Handicap:  challenge.Handicap,
+		Rated:     challenge.Rated,
+	}
+	challengerColor := challenge.Color
+	if challengerColor == data.ColorRandom {
+		challengerColor = data.ColorBlack
+		if rand.IntN(2) == 1 { //nolint:gosec // choosing colors does not need a secure source
+			challengerColor = data.ColorWhite
+		}
+	}
+	if challengerColor == data.ColorBlack {
+		g.BlackID, g.WhiteID = challenge.ChallengerID, userID
+	} else {
+		g.BlackID, g.WhiteID = userID, challenge.ChallengerID
+	}
+	if challenge.TimeControl != nil {
+		// White moves first once handicap stones have been placed.
+		first := game.Black
+		if challenge.Handicap > 1 {
+			first = game.White
+		}
+		g.Clock = clock.New(*challenge.TimeControl)
+		err := g.Clock.Start(api.clock.Now(), first)
+		if err != nil {
+			api.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	err := api.db.Challenges.Accept(r.Context(), challenge, userID, g)
+	if err != nil {
+		if errors.Is(err, data.ErrChallengeClosed) {
+			api.challengeClosedResponse(w, r)
+			return
+		}
+		api.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if seek {
+		api.publish(lobbyTopic, "seek_removed", challenge)
+	}
+	api.publish(userTopic(int(challenge.ChallengerID)), "challenge_accepted", map[string]any{
+		"challenge": challenge,
+		"game":      g,
+	})
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/api/v1/games/%d", g.ID))
+	err = api.writeJSON(w, http.StatusCreated, map[string]any{"challenge": challenge, "game": g}, headers)
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+	}
+}
+
+// handleDeclineChallenge lets the opponent of a direct challenge turn it down.
+func (api *API) handleDeclineChallenge(w http.ResponseWriter, r *http.Request) {
+	user, ok := api.authenticatedUser(w, r)
+	if !ok {
+		return
+	}
+	challenge, ok := api.challengeFromRequest(w, r)
+	if !ok {
+		return
+	}
+	if challenge.OpponentID == nil || *challenge.OpponentID != int64(user.ID) {
+		api.notPermittedResponse(w, r, "only the challenged user can decline a challenge")
+		return
+	}
+
+	err := api.db.Challenges.Decline(r.Context(), challenge)
+	if err != nil {
+		if errors.Is(err, data.ErrChallengeClosed) {
+			api.challengeClosedResponse(w, r)
+			return
+		}
+		api.serverErrorResponse(w, r, err)
+		return
+	}
+
+	api.publish(userTopic(int(challenge.ChallengerID)), "challenge_declined", challenge)
+	err = api.writeJSON(w, http.StatusOK, map[string]any{"challenge": challenge}, nil)
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+	}
+}
+
+// handleCancelChallenge lets a challenger withdraw their challenge.
+func (api *API) handleCancelChallenge(w http.ResponseWriter, r *http.Request) {
+	user, ok := api.authenticatedUser(w, r)
+	if !ok {
+		return
+	}
+	challenge, ok := api.challengeFromRequest(w, r)
+	if !ok {
+		return
+	}
+	if challenge.ChallengerID != int64(user.ID) {
+		api.notPermittedResponse(w, r, "only the challenger can cancel a challenge")
+		return
+	}
+
+	err := api.db.Challenges.Cancel(r.Context(), challenge)
+	if err != nil {
+		if errors.Is(err, data.ErrChallengeClosed) {
+			api.challengeClosedResponse(w, r)
+			return
+		}
+		api.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if challenge.OpponentID == nil {
+		api.publish(lobbyTopic, "seek_removed", challenge)
+	} else {
+		api.publish(userTopic(int(*challenge.OpponentID)), "challenge_cancelled", challenge)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// challengeFromRequest loads the challenge named by the "id" URL parameter. If it can't, the appropriate
+// error response is written and false is returned.
+func (api *API) challengeFromRequest(w http.ResponseWriter, r *http.Request) (*data.Challenge, bool) {
+	id, err := api.readIDParam(r)
+	if err != nil {
+		api.notFoundResponse(w, r)
+		return nil, false
+	}
+	challenge, err := api.db.Challenges.Get(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, data.ErrNoChallengeFound) {
+			api.notFoundResponse(w, r)
+		} else {
+			api.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+	return challenge, true
+}
+
+func (api *API) challengeClosedResponse(w http.ResponseWriter, r *http.Request) {
+	api.errorResponse(w, r, http.StatusConflict, "challenge is no longer open")
+}
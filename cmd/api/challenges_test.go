@@ -0,0 +1,199 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hazzardr/baduk-online/internal/data"
+)
+
+func TestChallengeIntegration(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	mailer := &mockMailer{db: db}
+	api := NewAPI("test", "1.0.0", db, mailer)
+	server := httptest.NewServer(api.Routes())
+	defer server.Close()
+
+	createTestUser(t, db, "Challenger", "challenger@example.com", "password123", true)
+	opponent := createTestUser(t, db, "Opponent", "opponent@example.com", "password123", true)
+	createTestUser(t, db, "Bystander", "bystander@example.com", "password123", true)
+
+	challengerClient := loggedIn(t, server.URL, "challenger@example.com")
+	opponentClient := loggedIn(t, server.URL, "opponent@example.com")
+	bystanderClient := loggedIn(t, server.URL, "bystander@example.com")
+
+	do := func(
+		t *testing.T, client *http.Client, method, path string, payload any,
+	) (*http.Response, map[string]json.RawMessage) {
+		t.Helper()
+		var body bytes.Buffer
+		if payload != nil {
+			_ = json.NewEncoder(&body).Encode(payload)
+		}
+		req, _ := http.NewRequest(method, server.URL+path, &body)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("failed to make request: %s", err)
+		}
+		defer resp.Body.Close()
+		var decoded map[string]json.RawMessage
+		_ = json.NewDecoder(resp.Body).Decode(&decoded)
+		return resp, decoded
+	}
+
+	create := func(t *testing.T, payload map[string]any) *data.Challenge {
+		t.Helper()
+		resp, body := do(t, challengerClient, http.MethodPost, "/api/v1/challenges", payload)
+		if resp.StatusCode != http.StatusCreated {
+			t.Fatalf("expected status 201, got %d: %s", resp.StatusCode, body["error"])
+		}
+		var c data.Challenge
+		if err := json.Unmarshal(body["challenge"], &c); err != nil {
+			t.Fatalf("failed to decode challenge: %s", err)
+		}
+		return &c
+	}
+
+	seek := map[string]any{
+		"board_size": 19,
+		"rules":      "japanese",
+		"komi":       6.5,
+		"color":      "white",
+		"rated":      true,
+		"time_control": map[string]any{
+			"kind":           "byoyomi",
+			"main_time_ms":   600000,
+			"periods":        5,
+			"period_time_ms": 30000,
+		},
+	}
+
+	t.Run("requires authentication", func(t *testing.T) {
+		resp, _ := do(t, newTestClient(t), http.MethodPost, "/api/v1/challenges", seek)
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("expected status 401, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("rejects invalid settings", func(t *testing.T) {
+		invalid := map[string]any{"board_size": 30, "rules": "ing", "komi": 6.5, "color": "purple"}
+		resp, body := do(t, challengerClient, http.MethodPost, "/api/v1/challenges", invalid)
+		if resp.StatusCode != http.StatusUnprocessableEntity {
+			t.Fatalf("expected status 422, got %d", resp.StatusCode)
+		}
+		var errs map[string]string
+		_ = json.Unmarshal(body["error"], &errs)
+		for _, key := range []string{"board_size", "rules", "color"} {
+			if _, ok := errs[key]; !ok {
+				t.Errorf("expected error for %s, got %v", key, errs)
+			}
+		}
+	})
+
+	t.Run("rejects unknown opponent", func(t *testing.T) {
+		payload := map[string]any{"board_size": 9, "rules": "chinese", "komi": 7.5, "opponent_id": 999999}
+		resp, _ := do(t, challengerClient, http.MethodPost, "/api/v1/challenges", payload)
+		if resp.StatusCode != http.StatusUnprocessableEntity {
+			t.Errorf("expected status 422, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("accept open seek", func(t *testing.T) {
+		c := create(t, seek)
+
+		resp, body := do(t, server.Client(), http.MethodGet, "/api/v1/challenges", nil)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", resp.StatusCode)
+		}
+		var seeks []data.Challenge
+		_ = json.Unmarshal(body["challenges"], &seeks)
+		if len(seeks) != 1 || seeks[0].ID != c.ID {
+			t.Errorf("expected seek to be listed, got %+v", seeks)
+		}
+
+		path := fmt.Sprintf("/api/v1/challenges/%d/accept", c.ID)
+		resp, _ = do(t, challengerClient, http.MethodPost, path, nil)
+		if resp.StatusCode != http.StatusForbidden {
+			t.Errorf("expected status 403 accepting own seek, got %d", resp.StatusCode)
+		}
+
+		resp, body = do(t, opponentClient, http.MethodPost, path, nil)
+		if resp.StatusCode != http.StatusCreated {
+			t.Fatalf("expected status 201, got %d: %s", resp.StatusCode, body["error"])
+		}
+		var g data.Game
+		if err := json.Unmarshal(body["game"], &g); err != nil {
+			t.Fatalf("failed to decode game: %s", err)
+		}
+		if g.BlackID != int64(opponent.ID) || !g.Rated || g.Clock == nil || !g.Clock.Running {
+			t.Errorf("unexpected game: %+v", g)
+		}
+
+		resp, _ = do(t, bystanderClient, http.MethodPost, path, nil)
+		if resp.StatusCode != http.StatusConflict {
+			t.Errorf("expected status 409 accepting a taken seek, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("direct challenge", func(t *testing.T) {
+		direct := map[string]any{"board_size": 13, "rules": "aga", "komi": 7.5, "opponent_id": opponent.ID}
+		c := create(t, direct)
+
+		resp, body := do(t, opponentClient, http.MethodGet, "/api/v1/user/challenges", nil)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", resp.StatusCode)
+		}
+		var received []data.Challenge
+		_ = json.Unmarshal(body["challenges"], &received)
+		if len(received) != 1 || received[0].ID != c.ID {
+			t.Errorf("expected direct challenge to be listed, got %+v", received)
+		}
+
+		resp, _ = do(t, bystanderClient, http.MethodPost, fmt.Sprintf("/api/v1/challenges/%d/accept", c.ID), nil)
+		if resp.StatusCode != http.StatusForbidden {
+			t.Errorf("expected status 403, got %d", resp.StatusCode)
+		}
+
+		resp, body = do(t, opponentClient, http.MethodPost, fmt.Sprintf("/api/v1/challenges/%d/decline", c.ID), nil)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", resp.StatusCode, body["error"])
+		}
+		resp, _ = do(t, challengerClient, http.MethodDelete, fmt.Sprintf("/api/v1/challenges/%d", c.ID), nil)
+		if resp.StatusCode != http.StatusConflict {
+			t.Errorf("expected status 409 cancelling a declined challenge, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		c := create(t, seek)
+		path := fmt.Sprintf("/api/v1/challenges/%d", c.ID)
+
+		resp, _ := do(t, opponentClient, http.MethodDelete, path, nil)
+		if resp.StatusCode != http.StatusForbidden {
+			t.Errorf("expected status 403, got %d", resp.StatusCode)
+		}
+		resp, _ = do(t, challengerClient, http.MethodDelete, path, nil)
+		if resp.StatusCode != http.StatusNoContent {
+			t.Errorf("expected status 204, got %d", resp.StatusCode)
+		}
+		resp, body := do(t, server.Client(), http.MethodGet, path, nil)
+		var got data.Challenge
+		_ = json.Unmarshal(body["challenge"], &got)
+		if resp.StatusCode != http.StatusOK || got.Status != data.ChallengeStatusCancelled {
+			t.Errorf("expected cancelled challenge, got %d %+v", resp.StatusCode, got)
+		}
+	})
+
+	t.Run("unknown challenge", func(t *testing.T) {
+		resp, _ := do(t, server.Client(), http.MethodGet, "/api/v1/challenges/999999", nil)
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("expected status 404, got %d", resp.StatusCode)
+		}
+	})
+}
@@ -0,0 +1,206 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/hazzardr/baduk-online/internal/data"
+	"github.com/hazzardr/baduk-online/internal/validator"
+)
+
+// handleListGameChat returns a page of a game's chat, newest first. Anyone may read the public channel, but
+// the players channel is only available to the two players and moderators.
+func (api *API) handleListGameChat(w http.ResponseWriter, r *http.Request) {
+	user, ok := api.optionalUser(w, r)
+	if !ok {
+		return
+	}
+	g, ok := api.gameFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	qs := r.URL.Query()
+	v := validator.New()
+	channel := api.readString(qs, "channel", data.ChatChannelPublic)
+	before := api.readInt(qs, "before", 0, v)
+	limit := api.readInt(qs, "limit", 50, v)
+
+	data.ValidateChatChannel(v, channel)
+	v.Check(before >= 0, "before", "must not be negative")
+	v.Check(limit >= 1 && limit <= data.MaxChatPageSize, "limit", "must be between 1 and 100")
+	if !v.Valid() {
+		api.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if channel == data.ChatChannelPlayers {
+		if user == nil {
+			api.unauthenticatedResponse(w, r)
+			return
+		}
+		if !isPlayer(g, user) && !user.Moderator {
+			api.notPermittedResponse(w, r, "only the players may read this channel")
+			return
+		}
+	}
+
+	messages, err := api.db.Chat.List(r.Context(), g.ID, channel, int64(before), limit)
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = api.writeJSON(w, http.StatusOK, map[string]any{"messages": messages}, nil)
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+	}
+}
+
+// handlePostGameChat posts a message to one of a game's chat channels. Only the players may write in the
+// players channel, while any logged in user may write in the public one.
+func (api *API) handlePostGameChat(w http.ResponseWriter, r *http.Request) {
+	user, ok := api.authenticatedUser(w, r)
+	if !ok {
+		return
+	}
+	g, ok := api.gameFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Channel string `json:"channel"`
+		Body    string `json:"body"`
+	}
+	err := api.readJSON(w, r, &input)
+	if err != nil {
+		api.badRequestResponse(w, r, err)
+		return
+	}
+
+	msg := &data.ChatMessage{
+		GameID:  g.ID,
+		UserID:  int64(user.ID),
+		Channel: input.Channel,
+		Body:    input.Body,
+	}
+	if msg.Channel == "" {
+		msg.Channel = data.ChatChannelPublic
+	}
+
+	v := validator.New()
+	if data.ValidateChatMessage(v, msg); !v.Valid() {
+		api.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	if msg.Channel == data.ChatChannelPlayers && !isPlayer(g, user) {
+		api.notPermittedResponse(w, r, "only the players may write in this channel")
+		return
+	}
+	if !api.chatLimiter.Allow(msg.UserID, api.clock.Now()) {
+		api.rateLimitExceededResponse(w, r)
+		return
+	}
+
+	err = api.db.Chat.Insert(r.Context(), msg)
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+		return
+	}
+	api.publishChat(g, msg.Channel, "chat_message", msg)
+
+	err = api.writeJSON(w, http.StatusCreated, map[string]any{"message": msg}, nil)
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+	}
+}
+
+// handleDeleteGameChat lets a moderator remove a chat message. The message is kept in the database but no
+// longer shown to anyone.
+func (api *API) handleDeleteGameChat(w http.ResponseWriter, r *http.Request) {
+	user, ok := api.authenticatedUser(w, r)
+	if !ok {
+		return
+	}
+	if !user.Moderator {
+		api.notPermittedResponse(w, r, "only moderators may delete chat messages")
+		return
+	}
+	g, ok := api.gameFromRequest(w, r)
+	if !ok {
+		return
+	}
+	messageID, err := api.readInt64Param(r, "messageID")
+	if err != nil {
+		api.notFoundResponse(w, r)
+		return
+	}
+
+	msg, err := api.db.Chat.Get(r.Context(), messageID)
+	if err != nil {
+		if errors.Is(err, data.ErrNoChatMessageFound) {
+			api.notFoundResponse(w, r)
+		} else {
+			api.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	if msg.GameID != g.ID {
+		api.notFoundResponse(w, r)
+		return
+	}
+
+	err = api.db.Chat.Delete(r.Context(), msg.ID, int64(user.ID))
+	if err != nil {
+		if errors.Is(err, data.ErrNoChatMessageFound) {
+			api.notFoundResponse(w, r)
+		} else {
+			api.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	api.publishChat(g, msg.Channel, "chat_message_deleted", map[string]any{
+		"id":      msg.ID,
+		"game_id": msg.GameID,
+		"channel": msg.Channel,
+	})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// publishChat sends a chat event to whoever may read the channel: the game's topic for the public channel,
+// and each player's own topic for the players channel.
+func (api *API) publishChat(g *data.Game, channel, typ string, payload any) {
+	if channel == data.ChatChannelPublic {
+		api.publish(gameTopic(g.ID), typ, payload)
+		return
+	}
+	api.publish(userTopic(int(g.BlackID)), typ, payload)
+	api.publish(userTopic(int(g.WhiteID)), typ, payload)
+}
+
+// gameFromRequest loads the game named by the "id" URL parameter. If it can't, the appropriate error response
+// is written and false is returned.
+func (api *API) gameFromRequest(w http.ResponseWriter, r *http.Request) (*data.Game, bool) {
+	id, err := api.readIDParam(r)
+	if err != nil {
+		api.notFoundResponse(w, r)
+		return nil, false
+	}
+	g, err := api.db.Games.Get(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, data.ErrNoGameFound) {
+			api.notFoundResponse(w, r)
+		} else {
+			api.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+	return g, true
+}
+
+// isPlayer reports whether the user is black or white in the game.
+func isPlayer(g *data.Game, user *data.User) bool {
+	id := int64(user.ID)
+	return g.BlackID == id || g.WhiteID == id
+}
@@ -0,0 +1,182 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hazzardr/baduk-online/internal/data"
+)
+
+func TestGameChatIntegration(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	mailer := &mockMailer{db: db}
+	api := NewAPI("test", "1.0.0", db, mailer)
+	server := httptest.NewServer(api.Routes())
+	defer server.Close()
+
+	black := createTestUser(t, db, "Black", "black@example.com", "password123", true)
+	white := createTestUser(t, db, "White", "white@example.com", "password123", true)
+	createTestUser(t, db, "Kibitzer", "kibitzer@example.com", "password123", true)
+	moderator := createTestUser(t, db, "Moderator", "moderator@example.com", "password123", true)
+	if _, err := db.Pool.Exec(ctx, "UPDATE users SET moderator = true WHERE id = $1", moderator.ID); err != nil {
+		t.Fatalf("failed to make moderator: %s", err)
+	}
+
+	g := &data.Game{BlackID: int64(black.ID), WhiteID: int64(white.ID), BoardSize: 19, Rules: "japanese", Komi: 6.5}
+	if err := db.Games.Insert(ctx, g); err != nil {
+		t.Fatalf("failed to insert game: %s", err)
+	}
+	chatURL := fmt.Sprintf("%s/api/v1/games/%d/chat", server.URL, g.ID)
+
+	clientFor := func(t *testing.T, email string) *http.Client {
+		t.Helper()
+		client := newTestClient(t)
+		if status := login(t, client, server.URL, email, "password123"); status != http.StatusOK {
+			t.Fatalf("failed to log in as %s: %d", email, status)
+		}
+		return client
+	}
+	blackClient := clientFor(t, "black@example.com")
+	kibitzerClient := clientFor(t, "kibitzer@example.com")
+	moderatorClient := clientFor(t, "moderator@example.com")
+
+	post := func(t *testing.T, client *http.Client, channel, body string) (int, data.ChatMessage) {
+		t.Helper()
+		payload, _ := json.Marshal(map[string]string{"channel": channel, "body": body})
+		resp, err := client.Post(chatURL, "application/json", bytes.NewBuffer(payload))
+		if err != nil {
+			t.Fatalf("failed to make request: %s", err)
+		}
+		defer resp.Body.Close()
+		var result struct {
+			Message data.ChatMessage `json:"message"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&result)
+		return resp.StatusCode, result.Message
+	}
+	list := func(t *testing.T, client *http.Client, query string) (int, []data.ChatMessage) {
+		t.Helper()
+		resp, err := client.Get(chatURL + query)
+		if err != nil {
+			t.Fatalf("failed to make request: %s", err)
+		}
+		defer resp.Body.Close()
+		var result struct {
+			Messages []data.ChatMessage `json:"messages"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&result)
+		return resp.StatusCode, result.Messages
+	}
+
+	t.Run("posting requires authentication", func(t *testing.T) {
+		if status, _ := post(t, newTestClient(t), "public", "hello"); status != http.StatusUnauthorized {
+			t.Errorf("expected status 401, got %d", status)
+		}
+	})
+
+	var publicMsg data.ChatMessage
+	t.Run("anyone logged in may post publicly", func(t *testing.T) {
+		status, msg := post(t, kibitzerClient, "public", "nice move")
+		if status != http.StatusCreated {
+			t.Fatalf("expected status 201, got %d", status)
+		}
+		if msg.UserName != "Kibitzer" || msg.CreatedAt.IsZero() {
+			t.Errorf("unexpected message: %+v", msg)
+		}
+		publicMsg = msg
+	})
+
+	t.Run("only players may post in the players channel", func(t *testing.T) {
+		if status, _ := post(t, kibitzerClient, "players", "psst"); status != http.StatusForbidden {
+			t.Errorf("expected status 403, got %d", status)
+		}
+		if status, _ := post(t, blackClient, "players", "good luck"); status != http.StatusCreated {
+			t.Errorf("expected status 201, got %d", status)
+		}
+	})
+
+	t.Run("rejects invalid messages", func(t *testing.T) {
+		if status, _ := post(t, blackClient, "public", ""); status != http.StatusUnprocessableEntity {
+			t.Errorf("expected status 422, got %d", status)
+		}
+		if status, _ := post(t, blackClient, "team", "hi"); status != http.StatusUnprocessableEntity {
+			t.Errorf("expected status 422, got %d", status)
+		}
+	})
+
+	t.Run("anonymous users may only read the public channel", func(t *testing.T) {
+		anon := newTestClient(t)
+		status, messages := list(t, anon, "")
+		if status != http.StatusOK || len(messages) != 1 || messages[0].Body != "nice move" {
+			t.Errorf("unexpected public chat: %d %+v", status, messages)
+		}
+		if status, _ := list(t, anon, "?channel=players"); status != http.StatusUnauthorized {
+			t.Errorf("expected status 401, got %d", status)
+		}
+		if status, _ := list(t, kibitzerClient, "?channel=players"); status != http.StatusForbidden {
+			t.Errorf("expected status 403, got %d", status)
+		}
+		status, messages = list(t, blackClient, "?channel=players")
+		if status != http.StatusOK || len(messages) != 1 || messages[0].Body != "good luck" {
+			t.Errorf("unexpected players chat: %d %+v", status, messages)
+		}
+	})
+
+	t.Run("posting is rate limited", func(t *testing.T) {
+		limited := false
+		for range chatRateLimit + 1 {
+			status, _ := post(t, blackClient, "public", "spam")
+			limited = limited || status == http.StatusTooManyRequests
+		}
+		if !limited {
+			t.Error("expected to be rate limited")
+		}
+	})
+
+	t.Run("moderators delete messages", func(t *testing.T) {
+		deleteURL := fmt.Sprintf("%s/%d", chatURL, publicMsg.ID)
+		del := func(client *http.Client) int {
+			req, _ := http.NewRequest(http.MethodDelete, deleteURL, nil)
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("failed to make request: %s", err)
+			}
+			resp.Body.Close()
+			return resp.StatusCode
+		}
+		if status := del(kibitzerClient); status != http.StatusForbidden {
+			t.Errorf("expected status 403, got %d", status)
+		}
+		if status := del(moderatorClient); status != http.StatusNoContent {
+			t.Errorf("expected status 204, got %d", status)
+		}
+		if status := del(moderatorClient); status != http.StatusNotFound {
+			t.Errorf("expected status 404, got %d", status)
+		}
+		_, messages := list(t, newTestClient(t), "")
+		for _, m := range messages {
+			if m.ID == publicMsg.ID {
+				t.Error("deleted message is still listed")
+			}
+		}
+	})
+
+	t.Run("missing game", func(t *testing.T) {
+		resp, err := http.Get(server.URL + "/api/v1/games/999999/chat")
+		if err != nil {
+			t.Fatalf("failed to make request: %s", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("expected status 404, got %d", resp.StatusCode)
+		}
+	})
+}
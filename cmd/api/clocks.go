@@ -0,0 +1,70 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/hazzardr/baduk-online/internal/data"
+	"github.com/hazzardr/baduk-online/internal/game"
+)
+
+// StartTimeoutSweeper periodically ends games in which the player to move has run out of time. Flag-falls
+// are otherwise only noticed when a player tries to move, which may never happen once a client disconnects.
+// It runs until Shutdown is called.
+func (api *API) StartTimeoutSweeper(interval time.Duration) {
+	api.background(func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-api.quit:
+				return
+			case <-ticker.C:
+				err := api.sweepTimeouts(context.Background())
+				if err != nil {
+					slog.Error("failed to sweep game clocks", "err", err)
+				}
+			}
+		}
+	})
+}
+
+// sweepTimeouts finalizes every active game whose clock has run out, awarding it to the other player.
+func (api *API) sweepTimeouts(ctx context.Context) error {
+	now := api.clock.Now()
+	games, err := api.db.Games.ListExpiredClocks(ctx, now)
+	if err != nil {
+		return err
+	}
+
+	for _, g := range games {
+		flagged, ok := g.Clock.CheckTimeout(now)
+		if !ok {
+			continue
+		}
+		result := "B+T"
+		if flagged == game.Black {
+			result = "W+T"
+		}
+
+		err := api.db.Games.Finalize(ctx, g, result)
+		if err != nil {
+			// A move or resignation got in first; the next sweep will see the new clock.
+			if errors.Is(err, data.ErrEditConflict) || errors.Is(err, data.ErrGameFinished) {
+				continue
+			}
+			return err
+		}
+
+		slog.Info("game lost on time", "game_id", g.ID, "result", result)
+		api.publish(gameTopic(g.ID), "finished", g)
+
+		err = api.rateGame(ctx, g)
+		if err != nil {
+			slog.Error("failed to rate game", "game_id", g.ID, "err", err)
+		}
+	}
+	return nil
+}
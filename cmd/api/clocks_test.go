@@ -0,0 +1,102 @@
+package api
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hazzardr/baduk-online/internal/clock"
+	"github.com/hazzardr/baduk-online/internal/data"
+	"github.com/hazzardr/baduk-online/internal/game"
+)
+
+func TestTimeoutSweeperIntegration(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	mailer := &mockMailer{db: db}
+	api := NewAPI("test", "1.0.0", db, mailer)
+	start := time.Now().Truncate(time.Millisecond)
+	fake := clock.NewFake(start)
+	api.clock = fake
+
+	black := createTestUser(t, db, "Black", "black@example.com", "password123", true)
+	white := createTestUser(t, db, "White", "white@example.com", "password123", true)
+
+	g := &data.Game{
+		BlackID:   int64(black.ID),
+		WhiteID:   int64(white.ID),
+		BoardSize: 19,
+		Rules:     "japanese",
+		Komi:      6.5,
+		Clock: clock.New(clock.TimeControl{
+			Kind:       clock.ByoYomi,
+			MainTime:   time.Minute,
+			Periods:    2,
+			PeriodTime: 10 * time.Second,
+		}),
+	}
+	if err := g.Clock.Start(fake.Now(), game.Black); err != nil {
+		t.Fatalf("failed to start clock: %s", err)
+	}
+	if err := db.Games.Insert(ctx, g); err != nil {
+		t.Fatalf("failed to insert game: %s", err)
+	}
+
+	subscriber, err := api.hub.Register(int64(white.ID))
+	if err != nil {
+		t.Fatalf("failed to register subscriber: %s", err)
+	}
+	defer subscriber.Done()
+	if err := api.hub.Subscribe(subscriber, gameTopic(g.ID), 0); err != nil {
+		t.Fatalf("failed to subscribe: %s", err)
+	}
+	<-subscriber.Messages()
+
+	t.Run("game in overtime is left alone", func(t *testing.T) {
+		fake.Advance(75 * time.Second)
+		if err := api.sweepTimeouts(ctx); err != nil {
+			t.Fatalf("failed to sweep: %s", err)
+		}
+		got, _ := db.Games.Get(ctx, g.ID)
+		if got.Status != data.GameStatusActive {
+			t.Errorf("expected game to still be active, got %+v", got)
+		}
+	})
+
+	t.Run("game past its deadline is lost on time", func(t *testing.T) {
+		fake.Advance(5 * time.Second)
+		if err := api.sweepTimeouts(ctx); err != nil {
+			t.Fatalf("failed to sweep: %s", err)
+		}
+		got, _ := db.Games.Get(ctx, g.ID)
+		if got.Status != data.GameStatusFinished || got.Result != "W+T" {
+			t.Errorf("expected W+T, got %+v", got)
+		}
+		if got.Clock == nil || got.Clock.Flagged != game.Black || got.Clock.Running {
+			t.Errorf("expected stopped clock flagging black, got %+v", got.Clock)
+		}
+
+		select {
+		case msg := <-subscriber.Messages():
+			if msg.Type != "finished" {
+				t.Errorf("expected finished message, got %+v", msg)
+			}
+		default:
+			t.Error("expected result to be published")
+		}
+	})
+
+	t.Run("finished game is not swept again", func(t *testing.T) {
+		fake.Advance(time.Hour)
+		if err := api.sweepTimeouts(ctx); err != nil {
+			t.Fatalf("failed to sweep: %s", err)
+		}
+		select {
+		case msg := <-subscriber.Messages():
+			t.Errorf("unexpected message %+v", msg)
+		default:
+		}
+	})
+}
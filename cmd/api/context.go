@@ -5,3 +5,29 @@ type contextKey string
 // userContextKey is used as a key for getting and setting user information in the request
 // context.
 const userContextKey = contextKey("userEmail")
+
+// pendingUserContextKey holds the email of a user who has given their password but still has to give a
+// second factor, and pendingSinceContextKey the time they gave it.
+const (
+	pendingUserContextKey  = contextKey("pendingUserEmail")
+	pendingSinceContextKey = contextKey("pendingSince")
+)
+
+// passkeyRegistrationContextKey and passkeyLoginContextKey hold the challenge of a passkey ceremony in
+// progress, until the browser responds to it.
+const (
+	passkeyRegistrationContextKey = contextKey("passkeyRegistrationChallenge")
+	passkeyLoginContextKey        = contextKey("passkeyLoginChallenge")
+)
+
+// The OpenID Connect state, nonce and PKCE code verifier of a login in progress are kept in the session until
+// the provider sends the user back.
+const (
+	oidcStateContextKey    = contextKey("oidcState")
+	oidcNonceContextKey    = contextKey("oidcNonce")
+	oidcVerifierContextKey = contextKey("oidcVerifier")
+)
+
+// apiTokenContextKey holds the personal API token a request was authenticated with, in the request context
+// rather than the session. The token's user is kept under userContextKey.
+const apiTokenContextKey = contextKey("apiToken")
@@ -0,0 +1,142 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/hazzardr/baduk-online/internal/data"
+	"github.com/hazzardr/baduk-online/internal/validator"
+)
+
+// handleChangeEmail starts changing the logged in user's email address. The new address is only used once it
+// has been confirmed with the token sent to it, and the current address is told about the request.
+func (api *API) handleChangeEmail(w http.ResponseWriter, r *http.Request) {
+	user, ok := api.authenticatedUser(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Email           string `json:"email"`
+		CurrentPassword string `json:"current_password"`
+	}
+
+	err := api.readJSON(w, r, &input)
+	if err != nil {
+		api.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	data.ValidateEmail(v, input.Email)
+	v.Check(!strings.EqualFold(input.Email, user.Email), "email", "must be different from your current email")
+	err = api.checkCurrentPassword(v, user, input.CurrentPassword)
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+		return
+	}
+	if !v.Valid() {
+		api.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	_, err = api.db.Users.GetByEmail(r.Context(), input.Email)
+	switch {
+	case err == nil:
+		api.errorResponse(w, r, http.StatusConflict, "a user with this email address already exists")
+		return
+	case !errors.Is(err, data.ErrNoUserFound):
+		api.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = api.db.Users.SetPendingEmail(r.Context(), user, input.Email)
+	if err != nil {
+		if errors.Is(err, data.ErrEditConflict) {
+			api.dataConflictResponse(w, r, err)
+		} else {
+			api.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	api.background(func() {
+		err := api.mailer.SendEmailChangeEmail(context.Background(), user, input.Email)
+		if err != nil {
+			slog.Error("failed to send email change email", "user", user.Email, "err", err)
+		}
+	})
+
+	resp := map[string]string{
+		"message": "a confirmation code has been sent to your new email address",
+	}
+	err = api.writeJSON(w, http.StatusAccepted, resp, nil)
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+	}
+}
+
+// handleConfirmEmail exchanges an email change token for the change it was issued for. Sessions logged in
+// with the old address are carried over to the new one.
+func (api *API) handleConfirmEmail(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Token string `json:"token"`
+	}
+
+	err := api.readJSON(w, r, &input)
+	if err != nil {
+		api.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	if data.ValidateTokenPlaintext(v, input.Token); !v.Valid() {
+		api.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user, err := api.db.Tokens.GetUserForToken(r.Context(), data.ScopeEmailChange, input.Token)
+	if err != nil {
+		if errors.Is(err, data.ErrNoUserFound) {
+			v.AddError("token", "invalid or expired email change token")
+			api.failedValidationResponse(w, r, v.Errors)
+		} else {
+			api.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	oldEmail := user.Email
+	err = api.db.Users.ConfirmPendingEmail(r.Context(), user)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateEmail):
+			api.errorResponse(w, r, http.StatusConflict, "a user with this email address already exists")
+		case errors.Is(err, data.ErrEditConflict):
+			api.dataConflictResponse(w, r, err)
+		default:
+			api.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = api.db.Tokens.DeleteAllForUser(r.Context(), data.ScopeEmailChange, int64(user.ID))
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = api.renameSessionsForUser(r.Context(), user, oldEmail)
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = api.writeJSON(w, http.StatusOK, user, nil)
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+	}
+}
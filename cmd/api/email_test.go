@@ -0,0 +1,167 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hazzardr/baduk-online/internal/data"
+)
+
+func TestEmailChangeIntegration(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	mailer := &mockMailer{db: db}
+	api := NewAPI("test", "1.0.0", db, mailer)
+	server := httptest.NewServer(api.Routes())
+	defer server.Close()
+
+	user := createTestUser(t, db, "Moving User", "old@example.com", "password123", true)
+	createTestUser(t, db, "Other User", "taken@example.com", "password123", true)
+
+	client := newTestClient(t)
+	if status := login(t, client, server.URL, "old@example.com", "password123"); status != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", status)
+	}
+
+	changeEmail := func(t *testing.T, client *http.Client, email, password string) int {
+		t.Helper()
+		body, _ := json.Marshal(map[string]string{"email": email, "current_password": password})
+		req, _ := http.NewRequest(http.MethodPut, server.URL+"/api/v1/user/email", bytes.NewBuffer(body))
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("failed to make request: %s", err)
+		}
+		defer resp.Body.Close()
+		return resp.StatusCode
+	}
+	confirmEmail := func(t *testing.T, token string) int {
+		t.Helper()
+		body, _ := json.Marshal(map[string]string{"token": token})
+		req, _ := http.NewRequest(http.MethodPut, server.URL+"/api/v1/users/email", bytes.NewBuffer(body))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("failed to make request: %s", err)
+		}
+		defer resp.Body.Close()
+		return resp.StatusCode
+	}
+	newToken := func(t *testing.T) string {
+		t.Helper()
+		token, err := db.Tokens.New(ctx, int64(user.ID), time.Minute, data.ScopeEmailChange)
+		if err != nil {
+			t.Fatalf("failed to create token: %s", err)
+		}
+		return token.Plaintext
+	}
+
+	t.Run("requires a session", func(t *testing.T) {
+		status := changeEmail(t, newTestClient(t), "new@example.com", "password123")
+		if status != http.StatusUnauthorized {
+			t.Errorf("expected status 401, got %d", status)
+		}
+	})
+
+	t.Run("rejects invalid requests", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			email    string
+			password string
+			want     int
+		}{
+			{"wrong password", "new@example.com", "wrongpassword", http.StatusUnprocessableEntity},
+			{"missing password", "new@example.com", "", http.StatusUnprocessableEntity},
+			{"invalid email", "not-an-email", "password123", http.StatusUnprocessableEntity},
+			{"current email", "OLD@example.com", "password123", http.StatusUnprocessableEntity},
+			{"taken email", "taken@example.com", "password123", http.StatusConflict},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				if status := changeEmail(t, client, tt.email, tt.password); status != tt.want {
+					t.Errorf("expected status %d, got %d", tt.want, status)
+				}
+			})
+		}
+
+		mailer.mu.Lock()
+		defer mailer.mu.Unlock()
+		if len(mailer.emailChangesSent) != 0 {
+			t.Errorf("expected no email change emails, got %v", mailer.emailChangesSent)
+		}
+	})
+
+	t.Run("confirming swaps the email and keeps sessions", func(t *testing.T) {
+		if status := changeEmail(t, client, "new@example.com", "password123"); status != http.StatusAccepted {
+			t.Fatalf("expected status 202, got %d", status)
+		}
+		mailer.waitFor(1, func() int { return len(mailer.emailChangesSent) })
+		mailer.mu.Lock()
+		sent := mailer.emailChangesSent
+		mailer.mu.Unlock()
+		if len(sent) != 1 || sent[0] != "new@example.com" {
+			t.Fatalf("expected an email change email to new@example.com, got %v", sent)
+		}
+
+		// The old address stays in use until the change is confirmed.
+		status := login(t, newTestClient(t), server.URL, "new@example.com", "password123")
+		if status != http.StatusUnauthorized {
+			t.Errorf("expected unconfirmed email to be rejected, got %d", status)
+		}
+
+		if status := confirmEmail(t, "INVALIDTOKEN1234567890ABCD"); status != http.StatusUnprocessableEntity {
+			t.Errorf("expected status 422, got %d", status)
+		}
+		token := newToken(t)
+		if status := confirmEmail(t, token); status != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", status)
+		}
+		if status := confirmEmail(t, token); status != http.StatusUnprocessableEntity {
+			t.Errorf("expected reused token to be rejected, got %d", status)
+		}
+
+		resp, err := client.Get(server.URL + "/api/v1/user")
+		if err != nil {
+			t.Fatalf("failed to make request: %s", err)
+		}
+		defer resp.Body.Close()
+		var got data.User
+		if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+			t.Fatalf("failed to decode response: %s", err)
+		}
+		if resp.StatusCode != http.StatusOK || got.Email != "new@example.com" {
+			t.Errorf("expected the session to follow the new email, got status %d and %q", resp.StatusCode, got.Email)
+		}
+
+		status = login(t, newTestClient(t), server.URL, "old@example.com", "password123")
+		if status != http.StatusUnauthorized {
+			t.Errorf("expected old email to be rejected, got %d", status)
+		}
+		if status := login(t, newTestClient(t), server.URL, "new@example.com", "password123"); status != http.StatusOK {
+			t.Errorf("expected new email to be accepted, got %d", status)
+		}
+	})
+
+	t.Run("confirming an address taken in the meantime conflicts", func(t *testing.T) {
+		if status := changeEmail(t, client, "race@example.com", "password123"); status != http.StatusAccepted {
+			t.Fatalf("expected status 202, got %d", status)
+		}
+		createTestUser(t, db, "Quick User", "race@example.com", "password123", true)
+
+		if status := confirmEmail(t, newToken(t)); status != http.StatusConflict {
+			t.Errorf("expected status 409, got %d", status)
+		}
+		got, err := db.Users.GetByID(ctx, int64(user.ID))
+		if err != nil {
+			t.Fatalf("failed to get user: %s", err)
+		}
+		if got.Email != "new@example.com" {
+			t.Errorf("expected email to be unchanged, got %q", got.Email)
+		}
+	})
+}
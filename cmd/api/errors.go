@@ -4,4 +4,5 @@ import "errors"
 
 var (
 	errUserUnauthenticated = errors.New("user is not properly authenticated")
+	errUnverifiedEmail     = errors.New("email address is not verified by the identity provider")
 )
@@ -0,0 +1,70 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/hazzardr/baduk-online/internal/handicap"
+	"github.com/hazzardr/baduk-online/internal/ratings"
+	"github.com/hazzardr/baduk-online/internal/scoring"
+	"github.com/hazzardr/baduk-online/internal/validator"
+)
+
+// handleGetHandicap recommends the handicap and komi for a game between two ranks, along with where the
+// handicap stones go when they are placed on the star points.
+func (api *API) handleGetHandicap(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	v := validator.New()
+
+	rank := func(key string) ratings.Rank {
+		s := api.readString(qs, key, "")
+		if s == "" {
+			v.AddError(key, "must be provided")
+			return 0
+		}
+		rank, err := ratings.ParseRank(s)
+		if err != nil {
+			v.AddError(key, "must be a rank between 30k and 9d")
+		}
+		return rank
+	}
+	black := rank("black")
+	white := rank("white")
+	size := api.readInt(qs, "size", 19, v)
+	rules := scoring.Ruleset(api.readString(qs, "rules", string(scoring.Japanese)))
+
+	v.Check(validator.PermittedValue(size, handicap.SupportedSizes...), "size", "must be one of 9, 13 or 19")
+	scoring.ValidateRuleset(v, rules)
+	if !v.Valid() {
+		api.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	rec, err := handicap.Recommend(black, white, size, rules)
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+		return
+	}
+
+	resp := map[string]any{
+		"black":       black,
+		"white":       white,
+		"size":        size,
+		"rules":       rules,
+		"handicap":    rec.Handicap,
+		"komi":        rec.Komi,
+		"swap_colors": rec.SwapColors,
+	}
+	if rec.Handicap >= handicap.MinStones {
+		placement, err := handicap.Placement(size, rec.Handicap)
+		if err != nil {
+			api.serverErrorResponse(w, r, err)
+			return
+		}
+		resp["placement"] = placement
+	}
+
+	err = api.writeJSON(w, http.StatusOK, resp, nil)
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetHandicap(t *testing.T) {
+	tests := []struct {
+		name          string
+		query         string
+		wantStatus    int
+		wantHandicap  int
+		wantKomi      float64
+		wantPlacement int
+		wantErrorKey  string
+	}{
+		{"Handicap game", "?black=5k&white=1k&size=19", http.StatusOK, 4, 0.5, 4, ""},
+		{"Chinese compensation", "?black=5k&white=1k&rules=chinese", http.StatusOK, 4, 4.5, 4, ""},
+		{"Even game", "?black=3d&white=3d&size=9&rules=aga", http.StatusOK, 0, 7.5, 0, ""},
+		{"Missing rank", "?white=1k", http.StatusUnprocessableEntity, 0, 0, 0, "black"},
+		{"Bad rank", "?black=40k&white=1k", http.StatusUnprocessableEntity, 0, 0, 0, "black"},
+		{"Unsupported size", "?black=5k&white=1k&size=15", http.StatusUnprocessableEntity, 0, 0, 0, "size"},
+		{"Non numeric size", "?black=5k&white=1k&size=big", http.StatusUnprocessableEntity, 0, 0, 0, "size"},
+		{"Unknown rules", "?black=5k&white=1k&rules=ing", http.StatusUnprocessableEntity, 0, 0, 0, "rules"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := &API{}
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/handicap"+tt.query, nil)
+			rr := httptest.NewRecorder()
+
+			api.handleGetHandicap(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("status code = %d, want %d: %s", rr.Code, tt.wantStatus, rr.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				var resp struct {
+					Error map[string]string `json:"error"`
+				}
+				if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+					t.Fatalf("failed to decode response: %s", err)
+				}
+				if _, ok := resp.Error[tt.wantErrorKey]; !ok {
+					t.Errorf("expected error for %q, got %v", tt.wantErrorKey, resp.Error)
+				}
+				return
+			}
+			var resp struct {
+				Handicap  int              `json:"handicap"`
+				Komi      float64          `json:"komi"`
+				Placement []map[string]int `json:"placement"`
+			}
+			if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %s", err)
+			}
+			if resp.Handicap != tt.wantHandicap || resp.Komi != tt.wantKomi || len(resp.Placement) != tt.wantPlacement {
+				t.Errorf("unexpected response: %+v", resp)
+			}
+		})
+	}
+}
@@ -1,16 +1,22 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"maps"
+	"net"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/hazzardr/baduk-online/internal/data"
+	"github.com/hazzardr/baduk-online/internal/validator"
 )
 
 var OneMB int64 = 1_048_576
@@ -76,6 +82,61 @@ func (api *API) readJSON(w http.ResponseWriter, r *http.Request, inputStruct any
 	return nil
 }
 
+// readBody reads a raw request body subject to the same size limit as readJSON.
+func (api *API) readBody(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, OneMB)
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			return nil, fmt.Errorf("body must not be larger than %d bytes", maxBytesError.Limit)
+		}
+		return nil, err
+	}
+	if len(body) == 0 {
+		return nil, errors.New("body must not be empty")
+	}
+	return body, nil
+}
+
+// readIDParam reads the "id" URL parameter, which must be a positive integer.
+func (api *API) readIDParam(r *http.Request) (int64, error) {
+	return api.readInt64Param(r, "id")
+}
+
+// readInt64Param reads the named URL parameter, which must be a positive integer.
+func (api *API) readInt64Param(r *http.Request, name string) (int64, error) {
+	id, err := strconv.ParseInt(chi.URLParam(r, name), 10, 64)
+	if err != nil || id < 1 {
+		return 0, fmt.Errorf("invalid %s parameter", name)
+	}
+	return id, nil
+}
+
+// readString returns a string value from the query string, or the provided default if the key is missing.
+func (api *API) readString(qs url.Values, key string, defaultValue string) string {
+	s := qs.Get(key)
+	if s == "" {
+		return defaultValue
+	}
+	return s
+}
+
+// readInt returns an integer value from the query string, or the provided default if the key is missing.
+// A value that is not an integer is recorded in the validator.
+func (api *API) readInt(qs url.Values, key string, defaultValue int, v *validator.Validator) int {
+	s := qs.Get(key)
+	if s == "" {
+		return defaultValue
+	}
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		v.AddError(key, "must be an integer value")
+		return defaultValue
+	}
+	return i
+}
+
 func (api *API) badRequestResponse(w http.ResponseWriter, r *http.Request, err error) {
 	slog.Debug("bad request", "err", err)
 	api.errorResponse(w, r, http.StatusBadRequest, err.Error())
@@ -103,6 +164,31 @@ func (api *API) unauthenticatedResponse(w http.ResponseWriter, r *http.Request)
 	}
 }
 
+func (api *API) invalidCredentialsResponse(w http.ResponseWriter, r *http.Request) {
+	api.errorResponse(w, r, http.StatusUnauthorized, "invalid authentication credentials")
+}
+
+func (api *API) invalidAPITokenResponse(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("WWW-Authenticate", "Bearer")
+	api.errorResponse(w, r, http.StatusUnauthorized, "invalid or expired API token")
+}
+
+func (api *API) inactiveAccountResponse(w http.ResponseWriter, r *http.Request) {
+	api.errorResponse(w, r, http.StatusForbidden, "your user account must be activated to access this resource")
+}
+
+func (api *API) notFoundResponse(w http.ResponseWriter, r *http.Request) {
+	api.errorResponse(w, r, http.StatusNotFound, "the requested resource could not be found")
+}
+
+func (api *API) notPermittedResponse(w http.ResponseWriter, r *http.Request, message string) {
+	api.errorResponse(w, r, http.StatusForbidden, message)
+}
+
+func (api *API) rateLimitExceededResponse(w http.ResponseWriter, r *http.Request) {
+	api.errorResponse(w, r, http.StatusTooManyRequests, "rate limit exceeded")
+}
+
 func (api *API) serverErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
 	slog.Error("internal server error", "method", r.Method, "uri", r.URL.RequestURI(), "error", err)
 	api.errorResponse(w, r, http.StatusInternalServerError, "internal server error")
@@ -138,6 +224,9 @@ func (api *API) background(fn func()) {
 // Begin session helpers
 
 func (api *API) getUserFromContext(r *http.Request) (*data.User, error) {
+	if user, ok := r.Context().Value(userContextKey).(*data.User); ok {
+		return user, nil
+	}
 	exists := api.sessionManager.Exists(r.Context(), string(userContextKey))
 	if !exists {
 		return nil, errUserUnauthenticated
@@ -146,3 +235,153 @@ func (api *API) getUserFromContext(r *http.Request) (*data.User, error) {
 	user, err := api.db.Users.GetByEmail(r.Context(), email)
 	return user, err
 }
+
+// revokeSessionsForUser destroys every stored session belonging to the given user.
+func (api *API) revokeSessionsForUser(ctx context.Context, user *data.User) error {
+	return api.revokeOtherSessionsForUser(ctx, user, "")
+}
+
+// revokeOtherSessionsForUser destroys every stored session belonging to the given user, except for the one
+// with the token keep. The sessions are found through the index of the user's sessions, and only while
+// sessions that predate the index may still be live are all of the stored sessions searched as well.
+func (api *API) revokeOtherSessionsForUser(ctx context.Context, user *data.User, keep string) error {
+	tokens, err := api.db.UserSessions.DeleteOthersForUser(ctx, int64(user.ID), keep)
+	if err != nil {
+		return err
+	}
+	for _, token := range tokens {
+		err := api.sessionManager.Store.Delete(token)
+		if err != nil {
+			return err
+		}
+	}
+
+	unindexed, err := api.db.UserSessions.HasUnindexed(ctx, api.clock.Now(), api.sessionManager.Lifetime)
+	if err != nil || !unindexed {
+		return err
+	}
+	return api.sessionManager.Iterate(ctx, func(ctx context.Context) error {
+		if api.sessionManager.GetString(ctx, string(userContextKey)) != user.Email {
+			return nil
+		}
+		if keep != "" && api.sessionManager.Token(ctx) == keep {
+			return nil
+		}
+		return api.sessionManager.Destroy(ctx)
+	})
+}
+
+// renewSession gives the current session a new token, keeping its entry in the index of the user's sessions.
+func (api *API) renewSession(ctx context.Context) error {
+	old := api.sessionManager.Token(ctx)
+	err := api.sessionManager.RenewToken(ctx)
+	if err != nil {
+		return err
+	}
+	if old == "" {
+		return nil
+	}
+	return api.db.UserSessions.UpdateToken(ctx, old, api.sessionManager.Token(ctx))
+}
+
+// forgetSession removes the current session from the index of its user's sessions, before it is logged out
+// or logged in as someone else.
+func (api *API) forgetSession(ctx context.Context) error {
+	token := api.sessionManager.Token(ctx)
+	if token == "" {
+		return nil
+	}
+	return api.db.UserSessions.DeleteByToken(ctx, token)
+}
+
+// clientIP returns the IP address a request came from, as found by middleware.RealIP.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// renameSessionsForUser points the stored sessions of a user who was logged in as oldEmail at their current
+// email. Like revokeOtherSessionsForUser, it finds the sessions through the index of the user's sessions and
+// only searches all of the stored sessions while sessions that predate the index may still be live.
+func (api *API) renameSessionsForUser(ctx context.Context, user *data.User, oldEmail string) error {
+	sessions, err := api.db.UserSessions.ListForUser(ctx, int64(user.ID), api.clock.Now())
+	if err != nil {
+		return err
+	}
+	for _, session := range sessions {
+		err := api.renameSession(session.Token, oldEmail, user.Email)
+		if err != nil {
+			return err
+		}
+	}
+
+	unindexed, err := api.db.UserSessions.HasUnindexed(ctx, api.clock.Now(), api.sessionManager.Lifetime)
+	if err != nil || !unindexed {
+		return err
+	}
+	return api.sessionManager.Iterate(ctx, func(ctx context.Context) error {
+		if api.sessionManager.GetString(ctx, string(userContextKey)) != oldEmail {
+			return nil
+		}
+		api.sessionManager.Put(ctx, string(userContextKey), user.Email)
+		_, _, err := api.sessionManager.Commit(ctx)
+		return err
+	})
+}
+
+// renameSession points the stored session with the given token at newEmail if it is logged in as oldEmail.
+// The session is read from the store directly, as the session manager only loads one session per context.
+func (api *API) renameSession(token, oldEmail, newEmail string) error {
+	b, found, err := api.sessionManager.Store.Find(token)
+	if err != nil || !found {
+		return err
+	}
+	deadline, values, err := api.sessionManager.Codec.Decode(b)
+	if err != nil {
+		return err
+	}
+	if email, _ := values[string(userContextKey)].(string); email != oldEmail {
+		return nil
+	}
+	values[string(userContextKey)] = newEmail
+	b, err = api.sessionManager.Codec.Encode(deadline, values)
+	if err != nil {
+		return err
+	}
+	return api.sessionManager.Store.Commit(token, b, deadline)
+}
+
+// authenticatedUser returns the user associated with the current session. If there is no such
+// user, the appropriate error response is written and false is returned.
+func (api *API) authenticatedUser(w http.ResponseWriter, r *http.Request) (*data.User, bool) {
+	user, err := api.getUserFromContext(r)
+	if err != nil {
+		switch {
+		case errors.Is(err, errUserUnauthenticated), errors.Is(err, data.ErrNoUserFound):
+			api.unauthenticatedResponse(w, r)
+		default:
+			api.serverErrorResponse(w, r, errors.Join(errors.New("failed to retrieve user data from context"), err))
+		}
+		return nil, false
+	}
+	return user, true
+}
+
+// optionalUser returns the user associated with the current session, or nil for anonymous requests. If the
+// user can't be loaded, an error response is written and false is returned.
+func (api *API) optionalUser(w http.ResponseWriter, r *http.Request) (*data.User, bool) {
+	user, err := api.getUserFromContext(r)
+	if err != nil {
+		switch {
+		case errors.Is(err, errUserUnauthenticated), errors.Is(err, data.ErrNoUserFound):
+			return nil, true
+		default:
+			api.serverErrorResponse(w, r, errors.Join(errors.New("failed to retrieve user data from context"), err))
+		}
+		return nil, false
+	}
+	return user, true
+}
@@ -0,0 +1,200 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/hazzardr/baduk-online/internal/data"
+	"github.com/hazzardr/baduk-online/internal/oidc"
+	"github.com/hazzardr/baduk-online/internal/validator"
+)
+
+// oidcAvailable reports whether an OpenID Connect provider is configured, writing an error response if it is
+// not.
+func (api *API) oidcAvailable(w http.ResponseWriter, r *http.Request) bool {
+	if api.oidcProvider == nil {
+		api.errorResponse(w, r, http.StatusServiceUnavailable, "logging in with an external provider is not available")
+		return false
+	}
+	return true
+}
+
+// handleBeginOIDCLogin starts logging in with the OpenID Connect provider. The state, nonce and PKCE code
+// verifier are kept in the session, and the user is sent to the returned authorization URL.
+func (api *API) handleBeginOIDCLogin(w http.ResponseWriter, r *http.Request) {
+	if !api.oidcAvailable(w, r) {
+		return
+	}
+
+	values := make([]string, 3)
+	for i := range values {
+		value, err := oidc.NewRandom()
+		if err != nil {
+			api.serverErrorResponse(w, r, err)
+			return
+		}
+		values[i] = value
+	}
+	state, nonce, verifier := values[0], values[1], values[2]
+	api.sessionManager.Put(r.Context(), string(oidcStateContextKey), state)
+	api.sessionManager.Put(r.Context(), string(oidcNonceContextKey), nonce)
+	api.sessionManager.Put(r.Context(), string(oidcVerifierContextKey), verifier)
+
+	resp := map[string]string{
+		"provider":          api.oidcName,
+		"authorization_url": api.oidcProvider.AuthCodeURL(state, nonce, verifier),
+	}
+	err := api.writeJSON(w, http.StatusOK, resp, nil)
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+	}
+}
+
+// handleCreateOIDCSession finishes logging in with the code and state the provider sent the user back with.
+// The user is found by the identity they logged in with, or else by the provider's verified email, in which
+// case the identity is linked to them. A validated account is created for new users.
+func (api *API) handleCreateOIDCSession(w http.ResponseWriter, r *http.Request) {
+	if !api.oidcAvailable(w, r) {
+		return
+	}
+
+	var input struct {
+		Code  string `json:"code"`
+		State string `json:"state"`
+	}
+
+	err := api.readJSON(w, r, &input)
+	if err != nil {
+		api.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.Check(input.Code != "", "code", "must be provided")
+	v.Check(input.State != "", "state", "must be provided")
+	if !v.Valid() {
+		api.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	state := api.sessionManager.PopString(r.Context(), string(oidcStateContextKey))
+	nonce := api.sessionManager.PopString(r.Context(), string(oidcNonceContextKey))
+	verifier := api.sessionManager.PopString(r.Context(), string(oidcVerifierContextKey))
+	if state == "" {
+		api.errorResponse(w, r, http.StatusConflict, "login with "+api.oidcName+" has not been started")
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(state), []byte(input.State)) != 1 {
+		api.invalidCredentialsResponse(w, r)
+		return
+	}
+
+	claims, err := api.oidcProvider.Exchange(r.Context(), input.Code, nonce, verifier, api.clock.Now())
+	if err != nil {
+		switch {
+		case errors.Is(err, oidc.ErrExchange), errors.Is(err, oidc.ErrInvalidToken), errors.Is(err, oidc.ErrUnknownKey):
+			slog.Warn("rejected login with external provider", "err", err)
+			api.invalidCredentialsResponse(w, r)
+		default:
+			api.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	user, err := api.userForIdentity(r.Context(), claims)
+	if err != nil {
+		switch {
+		case errors.Is(err, errUnverifiedEmail):
+			api.notPermittedResponse(w, r, "your email address must be verified by "+api.oidcName)
+		case errors.Is(err, data.ErrDuplicateEmail), errors.Is(err, data.ErrDuplicateIdentity):
+			api.errorResponse(w, r, http.StatusConflict, "your account was changed in flight, please try again")
+		default:
+			api.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	if !user.Validated {
+		api.inactiveAccountResponse(w, r)
+		return
+	}
+
+	api.clearPendingLogin(r)
+	api.beginLogin(w, r, user)
+}
+
+// userForIdentity returns the user linked to the identity in claims. An unlinked identity is linked to the
+// user with the same email, as long as the provider has verified it, and a user is created if there is none.
+// Identities are never linked to users who haven't validated their own email, since whoever registered it
+// may not own it.
+func (api *API) userForIdentity(ctx context.Context, claims *oidc.Claims) (*data.User, error) {
+	identity, err := api.db.Identities.Get(ctx, claims.Issuer, claims.Subject)
+	if err == nil {
+		return api.db.Users.GetByID(ctx, identity.UserID)
+	}
+	if !errors.Is(err, data.ErrNoIdentityFound) {
+		return nil, err
+	}
+
+	v := validator.New()
+	data.ValidateEmail(v, claims.Email)
+	if !claims.EmailVerified || !v.Valid() {
+		return nil, errUnverifiedEmail
+	}
+
+	user, err := api.db.Users.GetByEmail(ctx, claims.Email)
+	if errors.Is(err, data.ErrNoUserFound) {
+		user, err = api.createUserForIdentity(ctx, claims)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !user.Validated {
+		return user, nil
+	}
+
+	err = api.db.Identities.Insert(ctx, &data.Identity{
+		Issuer:  claims.Issuer,
+		Subject: claims.Subject,
+		UserID:  int64(user.ID),
+		Email:   claims.Email,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// createUserForIdentity creates a validated user from the claims of an identity. They are given a random
+// password, which they can replace by resetting it if they want to log in without the provider.
+func (api *API) createUserForIdentity(ctx context.Context, claims *oidc.Claims) (*data.User, error) {
+	name := strings.TrimSpace(claims.Name)
+	v := validator.New()
+	data.ValidateName(v, name)
+	if !v.Valid() {
+		name, _, _ = strings.Cut(claims.Email, "@")
+		name = name[:min(len(name), 50)]
+	}
+
+	user := &data.User{
+		Name:      name,
+		Email:     claims.Email,
+		Validated: true,
+	}
+	password, err := oidc.NewRandom()
+	if err != nil {
+		return nil, err
+	}
+	err = user.Password.Set(password)
+	if err != nil {
+		return nil, err
+	}
+	err = api.db.Users.Insert(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
@@ -0,0 +1,228 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/hazzardr/baduk-online/internal/clock"
+	"github.com/hazzardr/baduk-online/internal/data"
+	"github.com/hazzardr/baduk-online/internal/oidc"
+	"github.com/hazzardr/baduk-online/internal/oidc/oidctest"
+)
+
+func TestOIDCIntegration(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	mailer := &mockMailer{db: db}
+	api := NewAPI("test", "1.0.0", db, mailer)
+	fake := clock.NewFake(time.Now())
+	api.clock = fake
+	server := httptest.NewServer(api.Routes())
+	defer server.Close()
+
+	provider := oidctest.NewProvider("baduk", "secret")
+	defer provider.Close()
+
+	createTestUser(t, db, "Linked User", "linked@example.com", "password123", true)
+	createTestUser(t, db, "Pending User", "pending@example.com", "password123", false)
+
+	send := func(t *testing.T, client *http.Client, method, path string, input any) (int, []byte) {
+		t.Helper()
+		body, _ := json.Marshal(input)
+		req, _ := http.NewRequest(method, server.URL+"/api/v1"+path, bytes.NewBuffer(body))
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("failed to make request: %s", err)
+		}
+		defer resp.Body.Close()
+		respBody, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, respBody
+	}
+	// authorize starts a login and follows the authorization URL to the provider, returning the code and
+	// state it sends the user back with.
+	authorize := func(t *testing.T, client *http.Client) (string, string) {
+		t.Helper()
+		status, body := send(t, client, http.MethodPost, "/sessions/oidc/options", nil)
+		if status != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", status, body)
+		}
+		var options struct {
+			AuthorizationURL string `json:"authorization_url"`
+		}
+		if err := json.Unmarshal(body, &options); err != nil {
+			t.Fatalf("failed to decode options: %s", err)
+		}
+		noRedirect := &http.Client{
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		}
+		resp, err := noRedirect.Get(options.AuthorizationURL)
+		if err != nil {
+			t.Fatalf("failed to authorize: %s", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusFound {
+			t.Fatalf("expected a redirect from the provider, got %d", resp.StatusCode)
+		}
+		callback, err := url.Parse(resp.Header.Get("Location"))
+		if err != nil {
+			t.Fatalf("failed to parse callback: %s", err)
+		}
+		return callback.Query().Get("code"), callback.Query().Get("state")
+	}
+	oidcLogin := func(t *testing.T, client *http.Client) (int, []byte) {
+		t.Helper()
+		code, state := authorize(t, client)
+		return send(t, client, http.MethodPost, "/sessions/oidc", map[string]string{"code": code, "state": state})
+	}
+	loggedInAs := func(t *testing.T, client *http.Client) *data.User {
+		t.Helper()
+		status, body := send(t, client, http.MethodGet, "/user", nil)
+		if status != http.StatusOK {
+			t.Fatalf("expected to be logged in, got status %d", status)
+		}
+		var user data.User
+		if err := json.Unmarshal(body, &user); err != nil {
+			t.Fatalf("failed to decode user: %s", err)
+		}
+		return &user
+	}
+
+	t.Run("a provider must be configured", func(t *testing.T) {
+		status, _ := send(t, newTestClient(t), http.MethodPost, "/sessions/oidc/options", nil)
+		if status != http.StatusServiceUnavailable {
+			t.Errorf("expected status 503, got %d", status)
+		}
+	})
+
+	p, err := oidc.Discover(context.Background(), oidc.Config{
+		Issuer:       provider.Issuer(),
+		ClientID:     "baduk",
+		ClientSecret: "secret",
+		RedirectURL:  server.URL + "/login/callback",
+		Scopes:       []string{"email", "profile"},
+	})
+	if err != nil {
+		t.Fatalf("failed to discover provider: %s", err)
+	}
+	api.SetOIDCProvider(p, "Example")
+
+	t.Run("new users are created validated", func(t *testing.T) {
+		provider.SetUser(oidctest.User{Subject: "new", Email: "new@example.com", EmailVerified: true, Name: "New User"})
+		client := newTestClient(t)
+		status, body := oidcLogin(t, client)
+		if status != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", status, body)
+		}
+		user := loggedInAs(t, client)
+		if user.Email != "new@example.com" || user.Name != "New User" || !user.Validated {
+			t.Errorf("unexpected user %+v", user)
+		}
+
+		again := newTestClient(t)
+		if status, body := oidcLogin(t, again); status != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", status, body)
+		}
+		if loggedInAs(t, again).ID != user.ID {
+			t.Error("expected logging in again to find the same user")
+		}
+	})
+
+	t.Run("existing users are linked by verified email", func(t *testing.T) {
+		provider.SetUser(oidctest.User{Subject: "linked", Email: "linked@example.com", EmailVerified: true})
+		client := newTestClient(t)
+		if status, body := oidcLogin(t, client); status != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", status, body)
+		}
+		if user := loggedInAs(t, client); user.Name != "Linked User" {
+			t.Errorf("expected the existing user, got %+v", user)
+		}
+
+		// The identity stays linked when the provider's email changes.
+		provider.SetUser(oidctest.User{Subject: "linked", Email: "changed@example.com", EmailVerified: true})
+		client = newTestClient(t)
+		if status, body := oidcLogin(t, client); status != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", status, body)
+		}
+		if user := loggedInAs(t, client); user.Email != "linked@example.com" {
+			t.Errorf("expected the linked user, got %+v", user)
+		}
+	})
+
+	t.Run("unverified emails are not trusted", func(t *testing.T) {
+		provider.SetUser(oidctest.User{Subject: "unverified", Email: "unverified@example.com"})
+		if status, _ := oidcLogin(t, newTestClient(t)); status != http.StatusForbidden {
+			t.Errorf("expected status 403, got %d", status)
+		}
+	})
+
+	t.Run("accounts that were never validated are not linked", func(t *testing.T) {
+		provider.SetUser(oidctest.User{Subject: "pending", Email: "pending@example.com", EmailVerified: true})
+		if status, _ := oidcLogin(t, newTestClient(t)); status != http.StatusForbidden {
+			t.Errorf("expected status 403, got %d", status)
+		}
+		if _, err := db.Identities.Get(context.Background(), provider.Issuer(), "pending"); err == nil {
+			t.Error("expected the identity not to be linked")
+		}
+	})
+
+	t.Run("the state must match", func(t *testing.T) {
+		provider.SetUser(oidctest.User{Subject: "new", Email: "new@example.com", EmailVerified: true})
+		client := newTestClient(t)
+		code, _ := authorize(t, client)
+		status, _ := send(t, client, http.MethodPost, "/sessions/oidc", map[string]string{"code": code, "state": "forged"})
+		if status != http.StatusUnauthorized {
+			t.Errorf("expected status 401, got %d", status)
+		}
+	})
+
+	t.Run("callbacks are single use", func(t *testing.T) {
+		client := newTestClient(t)
+		code, state := authorize(t, client)
+		input := map[string]string{"code": code, "state": state}
+		if status, body := send(t, client, http.MethodPost, "/sessions/oidc", input); status != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", status, body)
+		}
+		if status, _ := send(t, client, http.MethodPost, "/sessions/oidc", input); status != http.StatusConflict {
+			t.Errorf("expected status 409, got %d", status)
+		}
+	})
+
+	t.Run("two-factor authentication is still required", func(t *testing.T) {
+		user, err := db.Users.GetByEmail(context.Background(), "linked@example.com")
+		if err != nil {
+			t.Fatalf("failed to get user: %s", err)
+		}
+		tf := &data.TwoFactor{UserID: int64(user.ID), Secret: []byte("sealed")}
+		if err := db.TwoFactor.Enroll(context.Background(), tf); err != nil {
+			t.Fatalf("failed to enroll: %s", err)
+		}
+		if _, err := db.TwoFactor.Use(context.Background(), tf, 1); err != nil {
+			t.Fatalf("failed to enable two-factor: %s", err)
+		}
+
+		provider.SetUser(oidctest.User{Subject: "linked", Email: "linked@example.com", EmailVerified: true})
+		client := newTestClient(t)
+		if status, body := oidcLogin(t, client); status != http.StatusAccepted {
+			t.Fatalf("expected status 202, got %d: %s", status, body)
+		}
+		if status, _ := send(t, client, http.MethodGet, "/user", nil); status != http.StatusUnauthorized {
+			t.Errorf("expected no session before the second factor, got %d", status)
+		}
+	})
+
+	t.Run("expired ID tokens are rejected", func(t *testing.T) {
+		provider.SetUser(oidctest.User{Subject: "new", Email: "new@example.com", EmailVerified: true})
+		fake.Advance(2 * time.Hour)
+		if status, _ := oidcLogin(t, newTestClient(t)); status != http.StatusUnauthorized {
+			t.Errorf("expected status 401, got %d", status)
+		}
+	})
+}
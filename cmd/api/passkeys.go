@@ -0,0 +1,291 @@
+package api
+
+import (
+	"encoding/binary"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/hazzardr/baduk-online/internal/data"
+	"github.com/hazzardr/baduk-online/internal/validator"
+	"github.com/hazzardr/baduk-online/internal/webauthn"
+)
+
+// passkeysAvailable reports whether passkeys are configured, writing an error response if they are not.
+func (api *API) passkeysAvailable(w http.ResponseWriter, r *http.Request) bool {
+	if api.relyingParty == nil {
+		api.errorResponse(w, r, http.StatusServiceUnavailable, "passkeys are not available")
+		return false
+	}
+	return true
+}
+
+// userHandle is the opaque ID passkeys store for a user, from which we find the user when they log in.
+func userHandle(userID int64) []byte {
+	return binary.BigEndian.AppendUint64(nil, uint64(userID)) //nolint:gosec // IDs are never negative
+}
+
+// handleBeginPasskeyRegistration starts registering a passkey for the logged in user. The challenge is kept
+// in the session, and the options are passed to navigator.credentials.create.
+func (api *API) handleBeginPasskeyRegistration(w http.ResponseWriter, r *http.Request) {
+	user, ok := api.authenticatedUser(w, r)
+	if !ok {
+		return
+	}
+	if !api.passkeysAvailable(w, r) {
+		return
+	}
+
+	passkeys, err := api.db.Passkeys.ListForUser(r.Context(), int64(user.ID))
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+		return
+	}
+	exclude := make([][]byte, 0, len(passkeys))
+	for _, p := range passkeys {
+		exclude = append(exclude, p.CredentialID)
+	}
+
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+		return
+	}
+	api.sessionManager.Put(r.Context(), string(passkeyRegistrationContextKey), challenge)
+
+	entity := webauthn.UserEntity{
+		ID:          userHandle(int64(user.ID)),
+		Name:        user.Email,
+		DisplayName: user.Name,
+	}
+	options := api.relyingParty.CreationOptions(challenge, entity, exclude)
+	err = api.writeJSON(w, http.StatusOK, map[string]any{"public_key": options}, nil)
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+	}
+}
+
+// handleCreatePasskey finishes registering a passkey with the browser's response to the challenge from
+// handleBeginPasskeyRegistration. Each challenge can only be answered once.
+func (api *API) handleCreatePasskey(w http.ResponseWriter, r *http.Request) {
+	user, ok := api.authenticatedUser(w, r)
+	if !ok {
+		return
+	}
+	if !api.passkeysAvailable(w, r) {
+		return
+	}
+
+	var input struct {
+		Name       string                        `json:"name"`
+		Credential *webauthn.AttestationResponse `json:"credential"`
+	}
+
+	err := api.readJSON(w, r, &input)
+	if err != nil {
+		api.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	data.ValidatePasskeyName(v, input.Name)
+	v.Check(input.Credential != nil, "credential", "must be provided")
+	if !v.Valid() {
+		api.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	challenge := api.sessionManager.PopBytes(r.Context(), string(passkeyRegistrationContextKey))
+	if challenge == nil {
+		api.errorResponse(w, r, http.StatusConflict, "passkey registration has not been started")
+		return
+	}
+	cred, err := api.relyingParty.VerifyRegistration(challenge, input.Credential)
+	if err != nil {
+		if errors.Is(err, webauthn.ErrVerification) || errors.Is(err, webauthn.ErrUnsupportedKey) {
+			slog.Debug("rejected passkey registration", "user", user.Email, "err", err)
+			v.AddError("credential", "is invalid")
+			api.failedValidationResponse(w, r, v.Errors)
+		} else {
+			api.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	passkey := &data.Passkey{
+		UserID:       int64(user.ID),
+		CredentialID: cred.ID,
+		PublicKey:    cred.PublicKey,
+		SignCount:    cred.SignCount,
+		Name:         input.Name,
+	}
+	err = api.db.Passkeys.Insert(r.Context(), passkey)
+	if err != nil {
+		if errors.Is(err, data.ErrDuplicatePasskey) {
+			api.errorResponse(w, r, http.StatusConflict, "this passkey is already registered")
+		} else {
+			api.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = api.writeJSON(w, http.StatusCreated, passkey, nil)
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+	}
+}
+
+// handleListPasskeys returns the logged in user's passkeys.
+func (api *API) handleListPasskeys(w http.ResponseWriter, r *http.Request) {
+	user, ok := api.authenticatedUser(w, r)
+	if !ok {
+		return
+	}
+
+	passkeys, err := api.db.Passkeys.ListForUser(r.Context(), int64(user.ID))
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+		return
+	}
+	err = api.writeJSON(w, http.StatusOK, map[string]any{"passkeys": passkeys}, nil)
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+	}
+}
+
+// handleDeletePasskey revokes one of the logged in user's passkeys.
+func (api *API) handleDeletePasskey(w http.ResponseWriter, r *http.Request) {
+	user, ok := api.authenticatedUser(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := api.readIDParam(r)
+	if err != nil {
+		api.notFoundResponse(w, r)
+		return
+	}
+
+	err = api.db.Passkeys.Delete(r.Context(), int64(user.ID), id)
+	if err != nil {
+		if errors.Is(err, data.ErrNoPasskeyFound) {
+			api.notFoundResponse(w, r)
+		} else {
+			api.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleBeginPasskeyLogin starts logging in with a passkey. No email is needed: the user picks one of their
+// passkeys, which tells us who they are.
+func (api *API) handleBeginPasskeyLogin(w http.ResponseWriter, r *http.Request) {
+	if !api.passkeysAvailable(w, r) {
+		return
+	}
+
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+		return
+	}
+	api.sessionManager.Put(r.Context(), string(passkeyLoginContextKey), challenge)
+
+	options := api.relyingParty.RequestOptions(challenge, nil)
+	err = api.writeJSON(w, http.StatusOK, map[string]any{"public_key": options}, nil)
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+	}
+}
+
+// handleCreatePasskeySession logs a user in with the browser's response to the challenge from
+// handleBeginPasskeyLogin. Passkeys verify the user themselves, so no second factor is asked for.
+func (api *API) handleCreatePasskeySession(w http.ResponseWriter, r *http.Request) {
+	if !api.passkeysAvailable(w, r) {
+		return
+	}
+
+	var input struct {
+		Credential *webauthn.AssertionResponse `json:"credential"`
+	}
+
+	err := api.readJSON(w, r, &input)
+	if err != nil {
+		api.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.Check(input.Credential != nil, "credential", "must be provided")
+	if !v.Valid() {
+		api.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	challenge := api.sessionManager.PopBytes(r.Context(), string(passkeyLoginContextKey))
+	if challenge == nil {
+		api.errorResponse(w, r, http.StatusConflict, "passkey login has not been started")
+		return
+	}
+
+	passkey, err := api.db.Passkeys.GetByCredentialID(r.Context(), input.Credential.RawID)
+	if err != nil {
+		if errors.Is(err, data.ErrNoPasskeyFound) {
+			api.invalidCredentialsResponse(w, r)
+		} else {
+			api.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	handle := input.Credential.Response.UserHandle
+	if handle != nil && string(handle) != string(userHandle(passkey.UserID)) {
+		api.invalidCredentialsResponse(w, r)
+		return
+	}
+
+	cred := &webauthn.Credential{
+		ID:        passkey.CredentialID,
+		PublicKey: passkey.PublicKey,
+		SignCount: passkey.SignCount,
+	}
+	signCount, err := api.relyingParty.VerifyAssertion(challenge, input.Credential, cred)
+	if err != nil {
+		switch {
+		case errors.Is(err, webauthn.ErrSignCount):
+			slog.Warn("passkey signature counter went backwards, it may have been cloned", "passkey", passkey.ID)
+			api.invalidCredentialsResponse(w, r)
+		case errors.Is(err, webauthn.ErrVerification), errors.Is(err, webauthn.ErrUnsupportedKey):
+			api.invalidCredentialsResponse(w, r)
+		default:
+			api.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = api.db.Passkeys.RecordUse(r.Context(), passkey, signCount, api.clock.Now())
+	if err != nil {
+		if errors.Is(err, data.ErrEditConflict) {
+			api.invalidCredentialsResponse(w, r)
+		} else {
+			api.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	user, err := api.db.Users.GetByID(r.Context(), passkey.UserID)
+	if err != nil {
+		if errors.Is(err, data.ErrNoUserFound) {
+			api.invalidCredentialsResponse(w, r)
+		} else {
+			api.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	if !user.Validated {
+		api.inactiveAccountResponse(w, r)
+		return
+	}
+
+	api.clearPendingLogin(r)
+	api.completeLogin(w, r, user)
+}
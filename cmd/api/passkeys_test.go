@@ -0,0 +1,229 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hazzardr/baduk-online/internal/data"
+	"github.com/hazzardr/baduk-online/internal/webauthn"
+	"github.com/hazzardr/baduk-online/internal/webauthn/webauthntest"
+)
+
+func TestPasskeysIntegration(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	mailer := &mockMailer{db: db}
+	api := NewAPI("test", "1.0.0", db, mailer)
+	server := httptest.NewServer(api.Routes())
+	defer server.Close()
+
+	createTestUser(t, db, "Passkey User", "passkey@example.com", "password123", true)
+	createTestUser(t, db, "Other User", "other@example.com", "password123", true)
+
+	send := func(t *testing.T, client *http.Client, method, path string, input any) (int, []byte) {
+		t.Helper()
+		body, _ := json.Marshal(input)
+		req, _ := http.NewRequest(method, server.URL+"/api/v1"+path, bytes.NewBuffer(body))
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("failed to make request: %s", err)
+		}
+		defer resp.Body.Close()
+		respBody, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, respBody
+	}
+	loggedIn := func(t *testing.T, email string) *http.Client {
+		t.Helper()
+		client := newTestClient(t)
+		if status := login(t, client, server.URL, email, "password123"); status != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", status)
+		}
+		return client
+	}
+	register := func(t *testing.T, client *http.Client, authenticator *webauthntest.Authenticator) (int, []byte) {
+		t.Helper()
+		status, body := send(t, client, http.MethodPost, "/user/passkeys/options", nil)
+		if status != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", status, body)
+		}
+		var options struct {
+			PublicKey webauthn.CreationOptions `json:"public_key"`
+		}
+		if err := json.Unmarshal(body, &options); err != nil {
+			t.Fatalf("failed to decode options: %s", err)
+		}
+		credential, err := authenticator.Create(&options.PublicKey)
+		if err != nil {
+			t.Fatalf("failed to create credential: %s", err)
+		}
+		return send(t, client, http.MethodPost, "/user/passkeys", map[string]any{"name": "Laptop", "credential": credential})
+	}
+	getAssertion := func(
+		t *testing.T, client *http.Client, authenticator *webauthntest.Authenticator,
+	) *webauthn.AssertionResponse {
+		t.Helper()
+		status, body := send(t, client, http.MethodPost, "/sessions/passkey/options", nil)
+		if status != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", status, body)
+		}
+		var options struct {
+			PublicKey webauthn.RequestOptions `json:"public_key"`
+		}
+		if err := json.Unmarshal(body, &options); err != nil {
+			t.Fatalf("failed to decode options: %s", err)
+		}
+		credential, err := authenticator.Get(&options.PublicKey)
+		if err != nil {
+			t.Fatalf("failed to get assertion: %s", err)
+		}
+		return credential
+	}
+	passkeyLogin := func(t *testing.T, client *http.Client, authenticator *webauthntest.Authenticator) int {
+		t.Helper()
+		credential := getAssertion(t, client, authenticator)
+		status, _ := send(t, client, http.MethodPost, "/sessions/passkey", map[string]any{"credential": credential})
+		return status
+	}
+
+	t.Run("passkeys must be configured", func(t *testing.T) {
+		status, _ := send(t, newTestClient(t), http.MethodPost, "/sessions/passkey/options", nil)
+		if status != http.StatusServiceUnavailable {
+			t.Errorf("expected status 503, got %d", status)
+		}
+	})
+
+	if err := api.SetWebAuthnOrigin(server.URL); err != nil {
+		t.Fatalf("failed to set origin: %s", err)
+	}
+	client := loggedIn(t, "passkey@example.com")
+	authenticator := webauthntest.NewAuthenticator(server.URL)
+	var passkey data.Passkey
+
+	t.Run("registering requires a session", func(t *testing.T) {
+		status, _ := send(t, newTestClient(t), http.MethodPost, "/user/passkeys/options", nil)
+		if status != http.StatusUnauthorized {
+			t.Errorf("expected status 401, got %d", status)
+		}
+	})
+
+	t.Run("registering a passkey", func(t *testing.T) {
+		status, body := register(t, client, authenticator)
+		if status != http.StatusCreated {
+			t.Fatalf("expected status 201, got %d: %s", status, body)
+		}
+		if err := json.Unmarshal(body, &passkey); err != nil {
+			t.Fatalf("failed to decode passkey: %s", err)
+		}
+		if passkey.ID == 0 || passkey.Name != "Laptop" {
+			t.Errorf("unexpected passkey %+v", passkey)
+		}
+
+		status, body = send(t, client, http.MethodGet, "/user/passkeys", nil)
+		var list struct {
+			Passkeys []data.Passkey `json:"passkeys"`
+		}
+		if err := json.Unmarshal(body, &list); err != nil || status != http.StatusOK {
+			t.Fatalf("failed to list passkeys, got status %d: %s", status, body)
+		}
+		if len(list.Passkeys) != 1 || list.Passkeys[0].ID != passkey.ID {
+			t.Errorf("unexpected passkeys %+v", list.Passkeys)
+		}
+	})
+
+	t.Run("registration challenges are single use", func(t *testing.T) {
+		status, body := send(t, client, http.MethodPost, "/user/passkeys/options", nil)
+		if status != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", status)
+		}
+		var options struct {
+			PublicKey webauthn.CreationOptions `json:"public_key"`
+		}
+		if err := json.Unmarshal(body, &options); err != nil {
+			t.Fatalf("failed to decode options: %s", err)
+		}
+		if len(options.PublicKey.ExcludeCredentials) != 1 {
+			t.Errorf("expected the registered passkey to be excluded, got %v", options.PublicKey.ExcludeCredentials)
+		}
+		credential, err := webauthntest.NewAuthenticator(server.URL).Create(&options.PublicKey)
+		if err != nil {
+			t.Fatalf("failed to create credential: %s", err)
+		}
+		input := map[string]any{"name": "Phone", "credential": credential}
+		if status, _ := send(t, client, http.MethodPost, "/user/passkeys", input); status != http.StatusCreated {
+			t.Fatalf("expected status 201, got %d", status)
+		}
+		if status, _ := send(t, client, http.MethodPost, "/user/passkeys", input); status != http.StatusConflict {
+			t.Errorf("expected a reused challenge to be rejected, got %d", status)
+		}
+	})
+
+	t.Run("registration from another origin is rejected", func(t *testing.T) {
+		status, _ := register(t, client, webauthntest.NewAuthenticator("https://evil.example"))
+		if status != http.StatusUnprocessableEntity {
+			t.Errorf("expected status 422, got %d", status)
+		}
+	})
+
+	t.Run("logging in with a passkey", func(t *testing.T) {
+		other := newTestClient(t)
+		if status := passkeyLogin(t, other, authenticator); status != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", status)
+		}
+		status, body := send(t, other, http.MethodGet, "/user", nil)
+		var user data.User
+		if err := json.Unmarshal(body, &user); err != nil || status != http.StatusOK {
+			t.Fatalf("expected to be logged in, got status %d: %s", status, body)
+		}
+		if user.Email != "passkey@example.com" {
+			t.Errorf("expected to be logged in as passkey@example.com, got %q", user.Email)
+		}
+	})
+
+	t.Run("assertions can't be replayed", func(t *testing.T) {
+		other := newTestClient(t)
+		credential := getAssertion(t, other, authenticator)
+		input := map[string]any{"credential": credential}
+		if status, _ := send(t, other, http.MethodPost, "/sessions/passkey", input); status != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", status)
+		}
+
+		attacker := newTestClient(t)
+		getAssertion(t, attacker, webauthntest.NewAuthenticator(server.URL))
+		if status, _ := send(t, attacker, http.MethodPost, "/sessions/passkey", input); status != http.StatusUnauthorized {
+			t.Errorf("expected a replayed assertion to be rejected, got %d", status)
+		}
+		if status, _ := send(t, other, http.MethodPost, "/sessions/passkey", input); status != http.StatusConflict {
+			t.Errorf("expected a used challenge to be rejected, got %d", status)
+		}
+	})
+
+	t.Run("cloned passkeys are rejected", func(t *testing.T) {
+		clone := authenticator.Clone()
+		if status := passkeyLogin(t, newTestClient(t), authenticator); status != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", status)
+		}
+		if status := passkeyLogin(t, newTestClient(t), clone); status != http.StatusUnauthorized {
+			t.Errorf("expected status 401, got %d", status)
+		}
+	})
+
+	t.Run("revoking a passkey", func(t *testing.T) {
+		path := fmt.Sprintf("/user/passkeys/%d", passkey.ID)
+		other := loggedIn(t, "other@example.com")
+		if status, _ := send(t, other, http.MethodDelete, path, nil); status != http.StatusNotFound {
+			t.Errorf("expected another user's passkey to be hidden, got %d", status)
+		}
+		if status, _ := send(t, client, http.MethodDelete, path, nil); status != http.StatusNoContent {
+			t.Fatalf("expected status 204, got %d", status)
+		}
+		if status := passkeyLogin(t, newTestClient(t), authenticator); status != http.StatusUnauthorized {
+			t.Errorf("expected a revoked passkey to be rejected, got %d", status)
+		}
+	})
+}
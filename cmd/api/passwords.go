@@ -0,0 +1,207 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/hazzardr/baduk-online/internal/data"
+	"github.com/hazzardr/baduk-online/internal/validator"
+)
+
+// handleRequestPasswordReset emails a password reset token to the given address. The response is the same
+// whether or not an account exists so that the endpoint can't be used to discover registered emails.
+func (api *API) handleRequestPasswordReset(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Email string `json:"email"`
+	}
+
+	err := api.readJSON(w, r, &input)
+	if err != nil {
+		api.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	if data.ValidateEmail(v, input.Email); !v.Valid() {
+		api.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user, err := api.db.Users.GetByEmail(r.Context(), input.Email)
+	if err != nil && !errors.Is(err, data.ErrNoUserFound) {
+		api.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if user != nil && user.Validated {
+		api.background(func() {
+			err := api.mailer.SendPasswordResetEmail(context.Background(), user)
+			if err != nil {
+				slog.Error("failed to send password reset email", "user", user.Email, "err", err)
+			}
+		})
+	}
+
+	resp := map[string]string{
+		"message": "if an account exists for this email address, you will receive password reset instructions",
+	}
+	err = api.writeJSON(w, http.StatusAccepted, resp, nil)
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+	}
+}
+
+// handleResetPassword exchanges a password reset token for a new password. On success the token is consumed
+// and every existing session for the user is revoked.
+func (api *API) handleResetPassword(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+
+	err := api.readJSON(w, r, &input)
+	if err != nil {
+		api.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	data.ValidateTokenPlaintext(v, input.Token)
+	data.ValidatePasswordPlaintext(v, input.Password)
+	if !v.Valid() {
+		api.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user, err := api.db.Tokens.GetUserForToken(r.Context(), data.ScopePasswordReset, input.Token)
+	if err != nil {
+		if errors.Is(err, data.ErrNoUserFound) {
+			v.AddError("token", "invalid or expired password reset token")
+			api.failedValidationResponse(w, r, v.Errors)
+		} else {
+			api.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = user.Password.Set(input.Password)
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = api.db.Users.Update(r.Context(), user)
+	if err != nil {
+		if errors.Is(err, data.ErrEditConflict) {
+			api.dataConflictResponse(w, r, err)
+		} else {
+			api.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = api.db.Tokens.DeleteAllForUser(r.Context(), data.ScopePasswordReset, int64(user.ID))
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = api.revokeSessionsForUser(r.Context(), user)
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+		return
+	}
+
+	resp := map[string]string{
+		"message": "your password was successfully reset",
+	}
+	err = api.writeJSON(w, http.StatusOK, resp, nil)
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+	}
+}
+
+// handleChangePassword sets a new password for the logged in user, who must also give their current one.
+// Every other session for the user is revoked, and the current session is moved to a new token.
+func (api *API) handleChangePassword(w http.ResponseWriter, r *http.Request) {
+	user, ok := api.authenticatedUser(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		CurrentPassword string `json:"current_password"`
+		Password        string `json:"password"`
+	}
+
+	err := api.readJSON(w, r, &input)
+	if err != nil {
+		api.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	data.ValidatePasswordPlaintext(v, input.Password)
+	err = api.checkCurrentPassword(v, user, input.CurrentPassword)
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+		return
+	}
+	if !v.Valid() {
+		api.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = user.Password.Set(input.Password)
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = api.db.Users.Update(r.Context(), user)
+	if err != nil {
+		if errors.Is(err, data.ErrEditConflict) {
+			api.dataConflictResponse(w, r, err)
+		} else {
+			api.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = api.revokeOtherSessionsForUser(r.Context(), user, api.sessionManager.Token(r.Context()))
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+		return
+	}
+	err = api.renewSession(r.Context())
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+		return
+	}
+
+	resp := map[string]string{
+		"message": "your password was successfully changed",
+	}
+	err = api.writeJSON(w, http.StatusOK, resp, nil)
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+	}
+}
+
+// checkCurrentPassword adds a validation error unless plaintext is the user's current password. Actions
+// that change how the account is reached or secured ask for it again, so that an unattended session is not
+// enough to take the account over.
+func (api *API) checkCurrentPassword(v *validator.Validator, user *data.User, plaintext string) error {
+	v.Check(plaintext != "", "current_password", "must be provided")
+	if plaintext == "" {
+		return nil
+	}
+	match, err := user.Password.Matches(plaintext)
+	if err != nil {
+		return err
+	}
+	v.Check(match, "current_password", "is incorrect")
+	return nil
+}
@@ -0,0 +1,216 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hazzardr/baduk-online/internal/data"
+)
+
+func TestPasswordResetIntegration(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	mailer := &mockMailer{db: db}
+	api := NewAPI("test", "1.0.0", db, mailer)
+	server := httptest.NewServer(api.Routes())
+	defer server.Close()
+
+	user := createTestUser(t, db, "Forgetful User", "forgetful@example.com", "password123", true)
+
+	requestReset := func(t *testing.T, email string) int {
+		t.Helper()
+		body, _ := json.Marshal(map[string]string{"email": email})
+		resp, err := http.Post(server.URL+"/api/v1/users/password-reset", "application/json", bytes.NewBuffer(body))
+		if err != nil {
+			t.Fatalf("failed to make request: %s", err)
+		}
+		defer resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	resetPassword := func(t *testing.T, token, password string) int {
+		t.Helper()
+		body, _ := json.Marshal(map[string]string{"token": token, "password": password})
+		req, _ := http.NewRequest(http.MethodPut, server.URL+"/api/v1/users/password", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("failed to make request: %s", err)
+		}
+		defer resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	t.Run("request reset for known user sends email", func(t *testing.T) {
+		if status := requestReset(t, "forgetful@example.com"); status != http.StatusAccepted {
+			t.Fatalf("expected status 202, got %d", status)
+		}
+		sent := mailer.waitFor(1, func() int { return len(mailer.passwordResetsSent) })
+		if sent != 1 {
+			t.Errorf("expected 1 password reset email, got %d", sent)
+		}
+	})
+
+	t.Run("request reset for unknown user does not reveal account", func(t *testing.T) {
+		if status := requestReset(t, "unknown@example.com"); status != http.StatusAccepted {
+			t.Fatalf("expected status 202, got %d", status)
+		}
+		mailer.mu.Lock()
+		sent := len(mailer.passwordResetsSent)
+		mailer.mu.Unlock()
+		if sent != 1 {
+			t.Errorf("expected no additional password reset emails, got %d total", sent)
+		}
+	})
+
+	t.Run("reject invalid token", func(t *testing.T) {
+		if status := resetPassword(t, "INVALIDTOKEN1234567890ABCD", "newpassword123"); status != http.StatusUnprocessableEntity {
+			t.Errorf("expected status 422, got %d", status)
+		}
+	})
+
+	t.Run("reject registration token", func(t *testing.T) {
+		token, err := db.Registration.NewToken(context.Background(), int64(user.ID), time.Minute)
+		if err != nil {
+			t.Fatalf("failed to create token: %s", err)
+		}
+		if status := resetPassword(t, token.Plaintext, "newpassword123"); status != http.StatusUnprocessableEntity {
+			t.Errorf("expected status 422, got %d", status)
+		}
+	})
+
+	t.Run("reset password revokes sessions and consumes token", func(t *testing.T) {
+		client := newTestClient(t)
+		if status := login(t, client, server.URL, "forgetful@example.com", "password123"); status != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", status)
+		}
+
+		token, err := db.Tokens.New(context.Background(), int64(user.ID), time.Minute, data.ScopePasswordReset)
+		if err != nil {
+			t.Fatalf("failed to create token: %s", err)
+		}
+
+		if status := resetPassword(t, token.Plaintext, "newpassword123"); status != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", status)
+		}
+
+		resp, err := client.Get(server.URL + "/api/v1/user")
+		if err != nil {
+			t.Fatalf("failed to make request: %s", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("expected existing session to be revoked, got status %d", resp.StatusCode)
+		}
+
+		if status := login(t, newTestClient(t), server.URL, "forgetful@example.com", "password123"); status != http.StatusUnauthorized {
+			t.Errorf("expected old password to be rejected, got %d", status)
+		}
+		if status := login(t, newTestClient(t), server.URL, "forgetful@example.com", "newpassword123"); status != http.StatusOK {
+			t.Errorf("expected new password to be accepted, got %d", status)
+		}
+
+		if status := resetPassword(t, token.Plaintext, "anotherpassword123"); status != http.StatusUnprocessableEntity {
+			t.Errorf("expected reused token to be rejected, got %d", status)
+		}
+	})
+}
+
+func TestChangePasswordIntegration(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	mailer := &mockMailer{db: db}
+	api := NewAPI("test", "1.0.0", db, mailer)
+	server := httptest.NewServer(api.Routes())
+	defer server.Close()
+
+	createTestUser(t, db, "Careful User", "careful@example.com", "password123", true)
+
+	current, other := newTestClient(t), newTestClient(t)
+	for _, client := range []*http.Client{current, other} {
+		if status := login(t, client, server.URL, "careful@example.com", "password123"); status != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", status)
+		}
+	}
+
+	changePassword := func(t *testing.T, client *http.Client, currentPassword, password string) int {
+		t.Helper()
+		body, _ := json.Marshal(map[string]string{"current_password": currentPassword, "password": password})
+		req, _ := http.NewRequest(http.MethodPut, server.URL+"/api/v1/user/password", bytes.NewBuffer(body))
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("failed to make request: %s", err)
+		}
+		defer resp.Body.Close()
+		return resp.StatusCode
+	}
+	getUser := func(t *testing.T, client *http.Client) int {
+		t.Helper()
+		resp, err := client.Get(server.URL + "/api/v1/user")
+		if err != nil {
+			t.Fatalf("failed to make request: %s", err)
+		}
+		defer resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	t.Run("requires a session", func(t *testing.T) {
+		if status := changePassword(t, newTestClient(t), "password123", "newpassword123"); status != http.StatusUnauthorized {
+			t.Errorf("expected status 401, got %d", status)
+		}
+	})
+
+	t.Run("rejects invalid requests", func(t *testing.T) {
+		tests := []struct {
+			name            string
+			currentPassword string
+			password        string
+		}{
+			{"wrong current password", "wrongpassword", "newpassword123"},
+			{"missing current password", "", "newpassword123"},
+			{"short new password", "password123", "short"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				status := changePassword(t, current, tt.currentPassword, tt.password)
+				if status != http.StatusUnprocessableEntity {
+					t.Errorf("expected status 422, got %d", status)
+				}
+			})
+		}
+		if status := getUser(t, other); status != http.StatusOK {
+			t.Errorf("expected other sessions to survive failed changes, got %d", status)
+		}
+	})
+
+	t.Run("change password revokes other sessions", func(t *testing.T) {
+		before := sessionCookie(t, current, server.URL)
+		if status := changePassword(t, current, "password123", "newpassword123"); status != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", status)
+		}
+		if after := sessionCookie(t, current, server.URL); after == before {
+			t.Errorf("expected the current session token to be renewed")
+		}
+
+		if status := getUser(t, current); status != http.StatusOK {
+			t.Errorf("expected the current session to survive, got %d", status)
+		}
+		if status := getUser(t, other); status != http.StatusUnauthorized {
+			t.Errorf("expected other sessions to be revoked, got %d", status)
+		}
+
+		if status := login(t, newTestClient(t), server.URL, "careful@example.com", "password123"); status != http.StatusUnauthorized {
+			t.Errorf("expected old password to be rejected, got %d", status)
+		}
+		if status := login(t, newTestClient(t), server.URL, "careful@example.com", "newpassword123"); status != http.StatusOK {
+			t.Errorf("expected new password to be accepted, got %d", status)
+		}
+	})
+}
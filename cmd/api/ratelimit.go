@@ -0,0 +1,61 @@
+package api
+
+import (
+	"sync"
+	"time"
+)
+
+// rateLimiter allows each key a number of events within a sliding window of time.
+type rateLimiter struct {
+	mu     sync.Mutex
+	limit  int
+	window time.Duration
+	events map[int64][]time.Time
+	// swept is when keys without recent events were last forgotten.
+	swept time.Time
+}
+
+func newRateLimiter(limit int, window time.Duration) *rateLimiter {
+	return &rateLimiter{
+		limit:  limit,
+		window: window,
+		events: make(map[int64][]time.Time),
+	}
+}
+
+// Allow records an event for key at now and reports whether it is within the limit. Events that are refused
+// do not count towards the limit.
+func (l *rateLimiter) Allow(key int64, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.swept) > l.window {
+		for k := range l.events {
+			l.prune(k, now)
+		}
+		l.swept = now
+	}
+
+	recent := l.prune(key, now)
+	if len(recent) >= l.limit {
+		return false
+	}
+	l.events[key] = append(recent, now)
+	return true
+}
+
+// prune forgets the events for key that have left the window as of now, and returns those that remain.
+func (l *rateLimiter) prune(key int64, now time.Time) []time.Time {
+	events := l.events[key]
+	i := 0
+	for i < len(events) && !events[i].After(now.Add(-l.window)) {
+		i++
+	}
+	if i == len(events) {
+		delete(l.events, key)
+		return nil
+	}
+	events = events[i:]
+	l.events[key] = events
+	return events
+}
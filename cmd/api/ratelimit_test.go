@@ -0,0 +1,58 @@
+package api
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("allows up to the limit within the window", func(t *testing.T) {
+		l := newRateLimiter(3, 10*time.Second)
+		for i := range 3 {
+			if !l.Allow(1, start.Add(time.Duration(i)*time.Second)) {
+				t.Fatalf("event %d refused", i)
+			}
+		}
+		if l.Allow(1, start.Add(5*time.Second)) {
+			t.Error("expected fourth event to be refused")
+		}
+		if !l.Allow(2, start.Add(5*time.Second)) {
+			t.Error("expected another key to be allowed")
+		}
+	})
+
+	t.Run("events leave the window", func(t *testing.T) {
+		l := newRateLimiter(2, 10*time.Second)
+		l.Allow(1, start)
+		l.Allow(1, start.Add(time.Second))
+		if l.Allow(1, start.Add(10*time.Second-time.Millisecond)) {
+			t.Error("expected event inside the window to be refused")
+		}
+		if !l.Allow(1, start.Add(10*time.Second)) {
+			t.Error("expected event after the first left the window to be allowed")
+		}
+	})
+
+	t.Run("refused events do not count", func(t *testing.T) {
+		l := newRateLimiter(1, 10*time.Second)
+		l.Allow(1, start)
+		for i := range 5 {
+			l.Allow(1, start.Add(time.Duration(i+5)*time.Second))
+		}
+		if !l.Allow(1, start.Add(11*time.Second)) {
+			t.Error("expected refused events to be ignored")
+		}
+	})
+
+	t.Run("idle keys are forgotten", func(t *testing.T) {
+		l := newRateLimiter(1, 10*time.Second)
+		l.Allow(1, start)
+		l.Allow(2, start)
+		l.Allow(3, start.Add(time.Minute))
+		if len(l.events) != 1 {
+			t.Errorf("expected only the recent key to be kept, got %d", len(l.events))
+		}
+	})
+}
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/hazzardr/baduk-online/internal/data"
+)
+
+// handleGetUserRatings returns a user's current rating and how it has changed over time.
+func (api *API) handleGetUserRatings(w http.ResponseWriter, r *http.Request) {
+	id, err := api.readIDParam(r)
+	if err != nil {
+		api.notFoundResponse(w, r)
+		return
+	}
+
+	user, err := api.db.Users.GetByID(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, data.ErrNoUserFound) {
+			api.notFoundResponse(w, r)
+		} else {
+			api.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	history, err := api.db.Ratings.History(r.Context(), id)
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+		return
+	}
+
+	resp := map[string]any{
+		"user_id": id,
+		"current": map[string]any{
+			"rating":     user.Rating.Rating,
+			"deviation":  user.Rating.Deviation,
+			"volatility": user.Rating.Volatility,
+			"rank":       user.Rating.Rank(),
+		},
+		"history": history,
+	}
+	err = api.writeJSON(w, http.StatusOK, resp, nil)
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+	}
+}
+
+// rateGame applies the result of a finished rated game to its players' ratings. It is safe to call more than
+// once for the same game.
+func (api *API) rateGame(ctx context.Context, g *data.Game) error {
+	if !g.Rated || g.Status != data.GameStatusFinished {
+		return nil
+	}
+
+	var winner string
+	switch {
+	case strings.HasPrefix(g.Result, "B+"):
+		winner = data.ColorBlack
+	case strings.HasPrefix(g.Result, "W+"):
+		winner = data.ColorWhite
+	case g.Result == "0":
+		// A draw has no winner.
+	default:
+		// Void or unknown results don't affect ratings.
+		return nil
+	}
+
+	gameID := g.ID
+	applied, err := api.db.Ratings.Apply(ctx, data.RatedResult{
+		ID:       "game:" + strconv.FormatInt(g.ID, 10),
+		GameID:   &gameID,
+		BlackID:  g.BlackID,
+		WhiteID:  g.WhiteID,
+		Handicap: g.Handicap,
+		Winner:   winner,
+	})
+	if err != nil {
+		return err
+	}
+	if applied {
+		slog.Info("rated game", "game_id", g.ID, "result", g.Result)
+	}
+	return nil
+}
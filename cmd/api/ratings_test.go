@@ -0,0 +1,101 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hazzardr/baduk-online/internal/data"
+)
+
+func TestUserRatingsIntegration(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	mailer := &mockMailer{db: db}
+	api := NewAPI("test", "1.0.0", db, mailer)
+	server := httptest.NewServer(api.Routes())
+	defer server.Close()
+
+	black := createTestUser(t, db, "Black", "black@example.com", "password123", true)
+	white := createTestUser(t, db, "White", "white@example.com", "password123", true)
+
+	g := &data.Game{
+		BlackID:   int64(black.ID),
+		WhiteID:   int64(white.ID),
+		BoardSize: 19,
+		Rules:     "japanese",
+		Komi:      0.5,
+		Handicap:  3,
+		Rated:     true,
+	}
+	if err := db.Games.Insert(ctx, g); err != nil {
+		t.Fatalf("failed to insert game: %s", err)
+	}
+	if err := db.Games.Resign(ctx, g, data.ColorWhite); err != nil {
+		t.Fatalf("failed to resign: %s", err)
+	}
+
+	// Rating the same game twice must only count it once.
+	for range 2 {
+		if err := api.rateGame(ctx, g); err != nil {
+			t.Fatalf("failed to rate game: %s", err)
+		}
+	}
+
+	getRatings := func(t *testing.T, path string) (int, map[string]json.RawMessage) {
+		t.Helper()
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("failed to make request: %s", err)
+		}
+		defer resp.Body.Close()
+		var body map[string]json.RawMessage
+		_ = json.NewDecoder(resp.Body).Decode(&body)
+		return resp.StatusCode, body
+	}
+
+	t.Run("returns current rating and history", func(t *testing.T) {
+		status, body := getRatings(t, fmt.Sprintf("/api/v1/users/%d/ratings", black.ID))
+		if status != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", status)
+		}
+
+		var current struct {
+			Rating float64 `json:"rating"`
+			Rank   string  `json:"rank"`
+		}
+		if err := json.Unmarshal(body["current"], &current); err != nil {
+			t.Fatalf("failed to decode current rating: %s", err)
+		}
+		if current.Rating <= 1500 || current.Rank == "" {
+			t.Errorf("expected black's rating to rise, got %+v", current)
+		}
+
+		var history []data.RatingPoint
+		if err := json.Unmarshal(body["history"], &history); err != nil {
+			t.Fatalf("failed to decode history: %s", err)
+		}
+		if len(history) != 1 || history[0].GameID == nil || *history[0].GameID != g.ID {
+			t.Errorf("expected a single history entry for the game, got %+v", history)
+		}
+	})
+
+	t.Run("unknown user", func(t *testing.T) {
+		status, _ := getRatings(t, "/api/v1/users/999999/ratings")
+		if status != http.StatusNotFound {
+			t.Errorf("expected status 404, got %d", status)
+		}
+	})
+
+	t.Run("invalid id", func(t *testing.T) {
+		status, _ := getRatings(t, "/api/v1/users/abc/ratings")
+		if status != http.StatusNotFound {
+			t.Errorf("expected status 404, got %d", status)
+		}
+	})
+}
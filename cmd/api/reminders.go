@@ -0,0 +1,82 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/hazzardr/baduk-online/internal/data"
+)
+
+// reminderLead is how long before a player runs out of time they are reminded to move. Only turns longer
+// than this get a reminder, so players in live games are never emailed.
+const reminderLead = 24 * time.Hour
+
+// StartReminderScheduler periodically emails players whose move in a correspondence game is due soon. It runs
+// until Shutdown is called.
+func (api *API) StartReminderScheduler(interval time.Duration) {
+	api.background(func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-api.quit:
+				return
+			case <-ticker.C:
+				err := api.sendMoveReminders(context.Background())
+				if err != nil {
+					slog.Error("failed to send move reminders", "err", err)
+				}
+			}
+		}
+	})
+}
+
+// sendMoveReminders emails each player whose time will run out within reminderLead. Every reminder is
+// recorded before it is sent, so that a player is reminded at most once per move even if the server
+// restarts.
+func (api *API) sendMoveReminders(ctx context.Context) error {
+	now := api.clock.Now()
+	games, err := api.db.Games.ListClocksDueBy(ctx, now, now.Add(reminderLead))
+	if err != nil {
+		return err
+	}
+
+	for _, g := range games {
+		deadline, ok := g.Clock.Deadline()
+		if !ok || deadline.Sub(g.Clock.UpdatedAt) <= reminderLead {
+			continue
+		}
+		userID := playerToMove(g)
+
+		claimed, err := api.db.Reminders.Claim(ctx, g.ID, g.MoveCount, userID)
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			continue
+		}
+
+		err = api.sendMoveReminder(ctx, userID, g, deadline)
+		if err != nil {
+			slog.Error("failed to send move reminder", "game_id", g.ID, "user_id", userID, "err", err)
+			err = api.db.Reminders.Release(ctx, g.ID, g.MoveCount)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (api *API) sendMoveReminder(ctx context.Context, userID int64, g *data.Game, deadline time.Time) error {
+	user, err := api.db.Users.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, data.ErrNoUserFound) {
+			return nil
+		}
+		return err
+	}
+	return api.mailer.SendMoveReminderEmail(ctx, user, g, deadline)
+}
@@ -16,15 +16,77 @@ func (api *API) Routes() http.Handler {
 	r.Use(api.sessionManager.LoadAndSave)
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
-	r.Use(middleware.Timeout(10 * time.Second))
+	r.Use(api.trackSession)
 
 	// API routes
 	r.Route("/api/v1", func(r chi.Router) {
-		r.Get("/health", api.handleHealthCheck)
-		r.Post("/users", api.handleCreateUser)
-		r.Post("/users/register", api.handleSendRegistrationEmail)
-		r.Put("/users/activated", api.handleRegisterUser)
-		r.Get("/user", api.handleGetLoggedInUser)
+		r.Use(api.authenticateAPIToken)
+
+		// WebSockets are long-lived, so they must not be subject to the request timeout.
+		r.Get("/ws", api.handleWebSocket)
+
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.Timeout(10 * time.Second))
+			r.Get("/health", api.handleHealthCheck)
+			r.Post("/users", api.handleCreateUser)
+			r.Post("/users/register", api.handleSendRegistrationEmail)
+			r.Put("/users/activated", api.handleRegisterUser)
+			r.Post("/users/password-reset", api.handleRequestPasswordReset)
+			r.Put("/users/password", api.handleResetPassword)
+			r.Put("/users/email", api.handleConfirmEmail)
+			r.Get("/users/{id}/ratings", api.handleGetUserRatings)
+			r.Get("/user", api.handleGetLoggedInUser)
+			r.Patch("/user", api.handleUpdateUser)
+			r.Get("/user/challenges", api.handleListUserChallenges)
+			r.Put("/user/vacation", api.handleUpdateVacation)
+			r.Get("/automatch", api.handleGetAutomatch)
+			r.Put("/automatch", api.handleJoinAutomatch)
+			r.Delete("/automatch", api.handleLeaveAutomatch)
+			r.Post("/sessions", api.handleCreateSession)
+			r.Delete("/sessions", api.handleDeleteSession)
+			r.Post("/sessions/two-factor", api.handleCreateTwoFactorSession)
+			r.Post("/sessions/passkey", api.handleCreatePasskeySession)
+			r.Post("/sessions/passkey/options", api.handleBeginPasskeyLogin)
+			r.Post("/sessions/oidc", api.handleCreateOIDCSession)
+			r.Post("/sessions/oidc/options", api.handleBeginOIDCLogin)
+			r.Post("/scores", api.handleScorePosition)
+			r.Post("/sgf/validate", api.handleValidateSGF)
+			r.Post("/sgf/export", api.handleExportSGF)
+			r.Get("/handicap", api.handleGetHandicap)
+			r.Get("/games/{id}/chat", api.handleListGameChat)
+			r.Post("/games/{id}/chat", api.handlePostGameChat)
+			r.Delete("/games/{id}/chat/{messageID}", api.handleDeleteGameChat)
+			r.Get("/challenges", api.handleListChallenges)
+			r.Post("/challenges", api.handleCreateChallenge)
+			r.Get("/challenges/{id}", api.handleGetChallenge)
+			r.Post("/challenges/{id}/accept", api.handleAcceptChallenge)
+			r.Post("/challenges/{id}/decline", api.handleDeclineChallenge)
+			r.Delete("/challenges/{id}", api.handleCancelChallenge)
+
+			// Managing the account and how it is logged in to needs a session, not an API token.
+			r.Group(func(r chi.Router) {
+				r.Use(api.requireSession)
+				r.Delete("/user", api.handleDeleteUser)
+				r.Get("/user/export", api.handleExportUser)
+				r.Put("/user/password", api.handleChangePassword)
+				r.Put("/user/email", api.handleChangeEmail)
+				r.Get("/user/two-factor", api.handleGetTwoFactor)
+				r.Post("/user/two-factor", api.handleEnrollTwoFactor)
+				r.Put("/user/two-factor", api.handleConfirmTwoFactor)
+				r.Delete("/user/two-factor", api.handleDisableTwoFactor)
+				r.Post("/user/two-factor/recovery-codes", api.handleRegenerateRecoveryCodes)
+				r.Get("/user/passkeys", api.handleListPasskeys)
+				r.Post("/user/passkeys", api.handleCreatePasskey)
+				r.Post("/user/passkeys/options", api.handleBeginPasskeyRegistration)
+				r.Delete("/user/passkeys/{id}", api.handleDeletePasskey)
+				r.Get("/user/tokens", api.handleListAPITokens)
+				r.Post("/user/tokens", api.handleCreateAPIToken)
+				r.Delete("/user/tokens/{id}", api.handleDeleteAPIToken)
+				r.Get("/user/sessions", api.handleListSessions)
+				r.Delete("/user/sessions", api.handleDeleteOtherSessions)
+				r.Delete("/user/sessions/{id}", api.handleDeleteUserSession)
+			})
+		})
 	})
 	return r
 }
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/hazzardr/baduk-online/internal/game"
+	"github.com/hazzardr/baduk-online/internal/scoring"
+	"github.com/hazzardr/baduk-online/internal/validator"
+)
+
+// handleScorePosition counts an arbitrary finished position posted by a logged in user.
+func (api *API) handleScorePosition(w http.ResponseWriter, r *http.Request) {
+	_, ok := api.authenticatedUser(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Size          int             `json:"size"`
+		Black         []game.Point    `json:"black"`
+		White         []game.Point    `json:"white"`
+		Dead          []game.Point    `json:"dead"`
+		Rules         scoring.Ruleset `json:"rules"`
+		Komi          float64         `json:"komi"`
+		BlackCaptures int             `json:"black_captures"`
+		WhiteCaptures int             `json:"white_captures"`
+	}
+
+	err := api.readJSON(w, r, &input)
+	if err != nil {
+		api.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.Check(input.Size >= game.MinBoardSize && input.Size <= game.MaxBoardSize, "size", "must be between 2 and 25")
+	scoring.ValidateRuleset(v, input.Rules)
+	scoring.ValidateKomi(v, input.Komi)
+	v.Check(input.BlackCaptures >= 0, "black_captures", "must not be negative")
+	v.Check(input.WhiteCaptures >= 0, "white_captures", "must not be negative")
+	if !v.Valid() {
+		api.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	board, err := game.NewBoard(input.Size)
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+		return
+	}
+	for _, p := range input.Black {
+		if err := board.Setup(game.Black, p); err != nil {
+			v.AddError("black", err.Error())
+		}
+	}
+	for _, p := range input.White {
+		if err := board.Setup(game.White, p); err != nil {
+			v.AddError("white", err.Error())
+		}
+	}
+	if !v.Valid() {
+		api.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	res, err := scoring.Score(scoring.Position{
+		Board:         board,
+		Dead:          input.Dead,
+		BlackCaptures: input.BlackCaptures,
+		WhiteCaptures: input.WhiteCaptures,
+	}, input.Rules, input.Komi)
+	if err != nil {
+		if errors.Is(err, scoring.ErrDeadPointEmpty) {
+			v.AddError("dead", err.Error())
+			api.failedValidationResponse(w, r, v.Errors)
+		} else {
+			api.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = api.writeJSON(w, http.StatusOK, res, nil)
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestScorePositionIntegration(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	mailer := &mockMailer{db: db}
+	api := NewAPI("test", "1.0.0", db, mailer)
+	server := httptest.NewServer(api.Routes())
+	defer server.Close()
+
+	createTestUser(t, db, "Counter", "counter@example.com", "password123", true)
+
+	position := map[string]any{
+		"size":  5,
+		"black": []map[string]int{{"x": 1, "y": 0}, {"x": 1, "y": 1}, {"x": 1, "y": 2}, {"x": 1, "y": 3}, {"x": 1, "y": 4}},
+		"white": []map[string]int{{"x": 2, "y": 0}, {"x": 2, "y": 1}, {"x": 2, "y": 2}, {"x": 2, "y": 3}, {"x": 2, "y": 4}},
+		"rules": "japanese",
+		"komi":  6.5,
+	}
+
+	score := func(t *testing.T, client *http.Client, payload map[string]any) *http.Response {
+		t.Helper()
+		body, _ := json.Marshal(payload)
+		resp, err := client.Post(server.URL+"/api/v1/scores", "application/json", bytes.NewBuffer(body))
+		if err != nil {
+			t.Fatalf("failed to make request: %s", err)
+		}
+		return resp
+	}
+
+	t.Run("requires authentication", func(t *testing.T) {
+		resp := score(t, newTestClient(t), position)
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("expected status 401, got %d", resp.StatusCode)
+		}
+	})
+
+	client := newTestClient(t)
+	if status := login(t, client, server.URL, "counter@example.com", "password123"); status != http.StatusOK {
+		t.Fatalf("failed to log in: %d", status)
+	}
+
+	t.Run("scores a position", func(t *testing.T) {
+		resp := score(t, client, position)
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", resp.StatusCode)
+		}
+		var result map[string]any
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			t.Fatalf("failed to decode result: %s", err)
+		}
+		if result["winner"] != "white" {
+			t.Errorf("expected white to win, got %v", result["winner"])
+		}
+		if result["margin"] != 11.5 {
+			t.Errorf("expected margin 11.5, got %v", result["margin"])
+		}
+	})
+
+	t.Run("rejects invalid rules and komi", func(t *testing.T) {
+		invalid := map[string]any{"size": 5, "rules": "ing", "komi": 6.25}
+		resp := score(t, client, invalid)
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusUnprocessableEntity {
+			t.Errorf("expected status 422, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("rejects dead stones on empty points", func(t *testing.T) {
+		invalid := map[string]any{
+			"size":  5,
+			"rules": "chinese",
+			"komi":  7.5,
+			"dead":  []map[string]int{{"x": 0, "y": 0}},
+		}
+		resp := score(t, client, invalid)
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusUnprocessableEntity {
+			t.Errorf("expected status 422, got %d", resp.StatusCode)
+		}
+	})
+}
@@ -0,0 +1,279 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/hazzardr/baduk-online/internal/data"
+	"github.com/hazzardr/baduk-online/internal/validator"
+)
+
+// handleCreateSession logs a user in with their email and password. The session token is renewed
+// before the user is stored in the session to prevent session fixation.
+func (api *API) handleCreateSession(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}
+
+	err := api.readJSON(w, r, &input)
+	if err != nil {
+		api.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	data.ValidateEmail(v, input.Email)
+	data.ValidatePasswordPlaintext(v, input.Password)
+	if !v.Valid() {
+		api.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user, err := api.db.Users.GetByEmail(r.Context(), input.Email)
+	if err != nil {
+		if errors.Is(err, data.ErrNoUserFound) {
+			api.invalidCredentialsResponse(w, r)
+		} else {
+			api.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	match, err := user.Password.Matches(input.Password)
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+		return
+	}
+	if !match {
+		api.invalidCredentialsResponse(w, r)
+		return
+	}
+
+	if !user.Validated {
+		api.inactiveAccountResponse(w, r)
+		return
+	}
+
+	api.beginLogin(w, r, user)
+}
+
+// beginLogin logs in a user who has proven who they are with their password or an external provider. Users
+// with two-factor authentication are only marked as awaiting their second factor, see
+// handleCreateTwoFactorSession.
+func (api *API) beginLogin(w http.ResponseWriter, r *http.Request, user *data.User) {
+	enabled, err := api.db.TwoFactor.Enabled(r.Context(), int64(user.ID))
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+		return
+	}
+	if enabled {
+		err = api.forgetSession(r.Context())
+		if err != nil {
+			api.serverErrorResponse(w, r, err)
+			return
+		}
+		err = api.sessionManager.RenewToken(r.Context())
+		if err != nil {
+			api.serverErrorResponse(w, r, err)
+			return
+		}
+		api.sessionManager.Remove(r.Context(), string(userContextKey))
+		api.sessionManager.Put(r.Context(), string(pendingUserContextKey), user.Email)
+		api.sessionManager.Put(r.Context(), string(pendingSinceContextKey), api.clock.Now().Unix())
+
+		err = api.writeJSON(w, http.StatusAccepted, map[string]bool{"two_factor_required": true}, nil)
+		if err != nil {
+			api.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	api.completeLogin(w, r, user)
+}
+
+// completeLogin stores a fully authenticated user in the session and responds with them.
+func (api *API) completeLogin(w http.ResponseWriter, r *http.Request, user *data.User) {
+	// Logging in during the grace period cancels an account deletion.
+	if user.PurgeAt != nil {
+		err := api.db.Users.CancelDeletion(r.Context(), user)
+		if err != nil {
+			if errors.Is(err, data.ErrEditConflict) {
+				api.dataConflictResponse(w, r, err)
+			} else {
+				api.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+	}
+
+	err := api.forgetSession(r.Context())
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+		return
+	}
+	err = api.sessionManager.RenewToken(r.Context())
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+		return
+	}
+	api.sessionManager.Put(r.Context(), string(userContextKey), user.Email)
+
+	now := api.clock.Now()
+	err = api.db.UserSessions.Insert(r.Context(), &data.UserSession{
+		Token:      api.sessionManager.Token(r.Context()),
+		UserID:     int64(user.ID),
+		IP:         clientIP(r),
+		UserAgent:  r.UserAgent(),
+		CreatedAt:  now,
+		LastSeenAt: now,
+		Expiry:     api.sessionManager.Deadline(r.Context()),
+	})
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = api.writeJSON(w, http.StatusOK, user, nil)
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+	}
+}
+
+// handleDeleteSession logs the current user out by destroying their session. Logging out without
+// a session is not an error.
+func (api *API) handleDeleteSession(w http.ResponseWriter, r *http.Request) {
+	err := api.forgetSession(r.Context())
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+		return
+	}
+	err = api.sessionManager.Destroy(r.Context())
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// trackSession records activity on logged in sessions in the index of the user's sessions, along with the
+// IP address and user agent it came from.
+func (api *API) trackSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if api.sessionManager.Exists(r.Context(), string(userContextKey)) {
+			token := api.sessionManager.Token(r.Context())
+			err := api.db.UserSessions.Touch(r.Context(), token, clientIP(r), r.UserAgent(), api.clock.Now())
+			if err != nil {
+				slog.Warn("failed to record session activity", "err", err)
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handleListSessions returns the sessions the logged in user is logged in with, marking the current one.
+func (api *API) handleListSessions(w http.ResponseWriter, r *http.Request) {
+	user, ok := api.authenticatedUser(w, r)
+	if !ok {
+		return
+	}
+
+	sessions, err := api.db.UserSessions.ListForUser(r.Context(), int64(user.ID), api.clock.Now())
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+		return
+	}
+	current := api.sessionManager.Token(r.Context())
+	for _, session := range sessions {
+		session.Current = session.Token == current
+	}
+	err = api.writeJSON(w, http.StatusOK, map[string]any{"sessions": sessions}, nil)
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+	}
+}
+
+// handleDeleteUserSession logs one of the logged in user's sessions out. Logging the current session out
+// this way is the same as handleDeleteSession.
+func (api *API) handleDeleteUserSession(w http.ResponseWriter, r *http.Request) {
+	user, ok := api.authenticatedUser(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := api.readIDParam(r)
+	if err != nil {
+		api.notFoundResponse(w, r)
+		return
+	}
+
+	session, err := api.db.UserSessions.Delete(r.Context(), int64(user.ID), id)
+	if err != nil {
+		if errors.Is(err, data.ErrNoUserSessionFound) {
+			api.notFoundResponse(w, r)
+		} else {
+			api.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	if session.Token == api.sessionManager.Token(r.Context()) {
+		err = api.sessionManager.Destroy(r.Context())
+	} else {
+		err = api.sessionManager.Store.Delete(session.Token)
+	}
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleDeleteOtherSessions logs the logged in user out of every session but the current one.
+func (api *API) handleDeleteOtherSessions(w http.ResponseWriter, r *http.Request) {
+	user, ok := api.authenticatedUser(w, r)
+	if !ok {
+		return
+	}
+
+	err := api.revokeOtherSessionsForUser(r.Context(), user, api.sessionManager.Token(r.Context()))
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// StartSessionSweeper periodically removes expired sessions from the index of users' sessions. The session
+// manager removes the sessions themselves. It runs until Shutdown is called.
+func (api *API) StartSessionSweeper(interval time.Duration) {
+	api.background(func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-api.quit:
+				return
+			case <-ticker.C:
+				err := api.sweepSessions(context.Background())
+				if err != nil {
+					slog.Error("failed to remove expired sessions", "err", err)
+				}
+			}
+		}
+	})
+}
+
+// sweepSessions removes every expired session from the index of users' sessions.
+func (api *API) sweepSessions(ctx context.Context) error {
+	swept, err := api.db.UserSessions.DeleteExpired(ctx, api.clock.Now())
+	if err != nil {
+		return err
+	}
+	if swept > 0 {
+		slog.Debug("removed expired sessions", "count", swept)
+	}
+	return nil
+}
@@ -0,0 +1,368 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hazzardr/baduk-online/internal/clock"
+	"github.com/hazzardr/baduk-online/internal/data"
+)
+
+// createTestUser inserts a user directly into the database, bypassing the registration flow.
+func createTestUser(t *testing.T, db *data.Database, name, email, password string, validated bool) *data.User {
+	t.Helper()
+	user := &data.User{
+		Name:      name,
+		Email:     email,
+		Validated: validated,
+	}
+	if err := user.Password.Set(password); err != nil {
+		t.Fatalf("failed to set password: %s", err)
+	}
+	if err := db.Users.Insert(context.Background(), user); err != nil {
+		t.Fatalf("failed to insert user: %s", err)
+	}
+	return user
+}
+
+// newTestClient returns an HTTP client with a cookie jar so that session cookies persist between requests.
+func newTestClient(t *testing.T) *http.Client {
+	t.Helper()
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatalf("failed to create cookie jar: %s", err)
+	}
+	return &http.Client{Jar: jar}
+}
+
+// login creates a session for the given credentials and returns the response status code.
+func login(t *testing.T, client *http.Client, serverURL, email, password string) int {
+	t.Helper()
+	body, _ := json.Marshal(map[string]string{
+		"email":    email,
+		"password": password,
+	})
+	resp, err := client.Post(serverURL+"/api/v1/sessions", "application/json", bytes.NewBuffer(body))
+	if err != nil {
+		t.Fatalf("failed to make login request: %s", err)
+	}
+	defer resp.Body.Close()
+	return resp.StatusCode
+}
+
+// loggedIn returns a client logged in as the given user, whose password must be "password123".
+func loggedIn(t *testing.T, serverURL, email string) *http.Client {
+	t.Helper()
+	client := newTestClient(t)
+	if status := login(t, client, serverURL, email, "password123"); status != http.StatusOK {
+		t.Fatalf("failed to log in as %s: %d", email, status)
+	}
+	return client
+}
+
+func TestSessionIntegration(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	mailer := &mockMailer{db: db}
+	api := NewAPI("test", "1.0.0", db, mailer)
+	server := httptest.NewServer(api.Routes())
+	defer server.Close()
+
+	createTestUser(t, db, "Active User", "active@example.com", "password123", true)
+	createTestUser(t, db, "Inactive User", "inactive@example.com", "password123", false)
+
+	t.Run("reject unknown email", func(t *testing.T) {
+		client := newTestClient(t)
+		if status := login(t, client, server.URL, "nobody@example.com", "password123"); status != http.StatusUnauthorized {
+			t.Errorf("expected status 401, got %d", status)
+		}
+	})
+
+	t.Run("reject wrong password", func(t *testing.T) {
+		client := newTestClient(t)
+		if status := login(t, client, server.URL, "active@example.com", "wrongpassword"); status != http.StatusUnauthorized {
+			t.Errorf("expected status 401, got %d", status)
+		}
+	})
+
+	t.Run("reject invalid input", func(t *testing.T) {
+		client := newTestClient(t)
+		if status := login(t, client, server.URL, "not-an-email", "short"); status != http.StatusUnprocessableEntity {
+			t.Errorf("expected status 422, got %d", status)
+		}
+	})
+
+	t.Run("reject unvalidated user", func(t *testing.T) {
+		client := newTestClient(t)
+		if status := login(t, client, server.URL, "inactive@example.com", "password123"); status != http.StatusForbidden {
+			t.Errorf("expected status 403, got %d", status)
+		}
+	})
+
+	t.Run("unauthenticated user cannot fetch profile", func(t *testing.T) {
+		client := newTestClient(t)
+		resp, err := client.Get(server.URL + "/api/v1/user")
+		if err != nil {
+			t.Fatalf("failed to make request: %s", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("expected status 401, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("login and logout", func(t *testing.T) {
+		client := newTestClient(t)
+		if status := login(t, client, server.URL, "active@example.com", "password123"); status != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", status)
+		}
+
+		resp, err := client.Get(server.URL + "/api/v1/user")
+		if err != nil {
+			t.Fatalf("failed to make request: %s", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", resp.StatusCode)
+		}
+		var user data.User
+		if err := json.NewDecoder(resp.Body).Decode(&user); err != nil {
+			t.Fatalf("failed to decode user: %s", err)
+		}
+		if user.Email != "active@example.com" {
+			t.Errorf("expected email 'active@example.com', got '%s'", user.Email)
+		}
+
+		req, _ := http.NewRequest(http.MethodDelete, server.URL+"/api/v1/sessions", nil)
+		logoutResp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("failed to make logout request: %s", err)
+		}
+		defer logoutResp.Body.Close()
+		if logoutResp.StatusCode != http.StatusNoContent {
+			t.Fatalf("expected status 204, got %d", logoutResp.StatusCode)
+		}
+
+		resp2, err := client.Get(server.URL + "/api/v1/user")
+		if err != nil {
+			t.Fatalf("failed to make request: %s", err)
+		}
+		defer resp2.Body.Close()
+		if resp2.StatusCode != http.StatusUnauthorized {
+			t.Errorf("expected status 401 after logout, got %d", resp2.StatusCode)
+		}
+	})
+
+	t.Run("login renews session token", func(t *testing.T) {
+		client := newTestClient(t)
+		if status := login(t, client, server.URL, "active@example.com", "password123"); status != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", status)
+		}
+		first := sessionCookie(t, client, server.URL)
+
+		if status := login(t, client, server.URL, "active@example.com", "password123"); status != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", status)
+		}
+		second := sessionCookie(t, client, server.URL)
+
+		if first == "" || second == "" {
+			t.Fatal("expected a session cookie to be set")
+		}
+		if first == second {
+			t.Error("expected session token to change on login")
+		}
+	})
+}
+
+func TestUserSessionsIntegration(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	mailer := &mockMailer{db: db}
+	api := NewAPI("test", "1.0.0", db, mailer)
+	fake := clock.NewFake(time.Now())
+	api.clock = fake
+	server := httptest.NewServer(api.Routes())
+	defer server.Close()
+
+	user := createTestUser(t, db, "Busy User", "busy@example.com", "password123", true)
+	otherUser := createTestUser(t, db, "Other User", "other@example.com", "password123", true)
+
+	send := func(t *testing.T, client *http.Client, userAgent, method, path string, input any) (int, []byte) {
+		t.Helper()
+		body, _ := json.Marshal(input)
+		req, _ := http.NewRequest(method, server.URL+"/api/v1"+path, bytes.NewBuffer(body))
+		req.Header.Set("User-Agent", userAgent)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("failed to make request: %s", err)
+		}
+		defer resp.Body.Close()
+		respBody, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, respBody
+	}
+	loggedIn := func(t *testing.T, email, userAgent string) *http.Client {
+		t.Helper()
+		client := newTestClient(t)
+		input := map[string]string{"email": email, "password": "password123"}
+		if status, body := send(t, client, userAgent, http.MethodPost, "/sessions", input); status != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", status, body)
+		}
+		return client
+	}
+	list := func(t *testing.T, client *http.Client) []data.UserSession {
+		t.Helper()
+		status, body := send(t, client, "Laptop", http.MethodGet, "/user/sessions", nil)
+		var resp struct {
+			Sessions []data.UserSession `json:"sessions"`
+		}
+		if err := json.Unmarshal(body, &resp); err != nil || status != http.StatusOK {
+			t.Fatalf("failed to list sessions, got status %d: %s", status, body)
+		}
+		return resp.Sessions
+	}
+	getUser := func(t *testing.T, client *http.Client) int {
+		t.Helper()
+		status, _ := send(t, client, "Laptop", http.MethodGet, "/user", nil)
+		return status
+	}
+
+	laptop := loggedIn(t, "busy@example.com", "Laptop")
+	phone := loggedIn(t, "busy@example.com", "Phone")
+	other := loggedIn(t, "other@example.com", "Laptop")
+
+	t.Run("sessions are listed with where they are from", func(t *testing.T) {
+		sessions := list(t, laptop)
+		if len(sessions) != 2 {
+			t.Fatalf("expected 2 sessions, got %+v", sessions)
+		}
+		agents := map[string]bool{}
+		for _, s := range sessions {
+			agents[s.UserAgent] = s.Current
+			if s.IP != "127.0.0.1" || s.CreatedAt.IsZero() || s.Expiry.IsZero() {
+				t.Errorf("unexpected session %+v", s)
+			}
+		}
+		if current, ok := agents["Laptop"]; !ok || !current || agents["Phone"] {
+			t.Errorf("expected the laptop session to be current, got %+v", sessions)
+		}
+	})
+
+	t.Run("logging in again replaces the session", func(t *testing.T) {
+		input := map[string]string{"email": "busy@example.com", "password": "password123"}
+		if status, _ := send(t, laptop, "Laptop", http.MethodPost, "/sessions", input); status != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", status)
+		}
+		if sessions := list(t, laptop); len(sessions) != 2 {
+			t.Errorf("expected 2 sessions, got %+v", sessions)
+		}
+	})
+
+	t.Run("activity is recorded", func(t *testing.T) {
+		fake.Advance(2 * time.Minute)
+		if status, _ := send(t, phone, "Tablet", http.MethodGet, "/user", nil); status != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", status)
+		}
+		for _, s := range list(t, laptop) {
+			if s.Current {
+				continue
+			}
+			if s.UserAgent != "Tablet" || !s.LastSeenAt.After(s.CreatedAt) {
+				t.Errorf("expected the phone's activity to be recorded, got %+v", s)
+			}
+		}
+	})
+
+	t.Run("revoking one session", func(t *testing.T) {
+		var phoneID int64
+		for _, s := range list(t, laptop) {
+			if !s.Current {
+				phoneID = s.ID
+			}
+		}
+		otherID := list(t, other)[0].ID
+		path := fmt.Sprintf("/user/sessions/%d", otherID)
+		if status, _ := send(t, laptop, "Laptop", http.MethodDelete, path, nil); status != http.StatusNotFound {
+			t.Errorf("expected status 404 for another user's session, got %d", status)
+		}
+
+		path = fmt.Sprintf("/user/sessions/%d", phoneID)
+		if status, _ := send(t, laptop, "Laptop", http.MethodDelete, path, nil); status != http.StatusNoContent {
+			t.Fatalf("expected status 204, got %d", status)
+		}
+		if status := getUser(t, phone); status != http.StatusUnauthorized {
+			t.Errorf("expected the phone to be logged out, got %d", status)
+		}
+		if status := getUser(t, laptop); status != http.StatusOK {
+			t.Errorf("expected the laptop to stay logged in, got %d", status)
+		}
+		if status, _ := send(t, laptop, "Laptop", http.MethodDelete, path, nil); status != http.StatusNotFound {
+			t.Errorf("expected status 404, got %d", status)
+		}
+	})
+
+	t.Run("revoking all other sessions", func(t *testing.T) {
+		desktop := loggedIn(t, "busy@example.com", "Desktop")
+		if status, _ := send(t, laptop, "Laptop", http.MethodDelete, "/user/sessions", nil); status != http.StatusNoContent {
+			t.Fatalf("expected status 204, got %d", status)
+		}
+		if status := getUser(t, desktop); status != http.StatusUnauthorized {
+			t.Errorf("expected the desktop to be logged out, got %d", status)
+		}
+		if status := getUser(t, other); status != http.StatusOK {
+			t.Errorf("expected other users to stay logged in, got %d", status)
+		}
+		sessions := list(t, laptop)
+		if len(sessions) != 1 || !sessions[0].Current {
+			t.Errorf("expected only the current session, got %+v", sessions)
+		}
+	})
+
+	t.Run("logging out removes the session", func(t *testing.T) {
+		if status, _ := send(t, other, "Laptop", http.MethodDelete, "/sessions", nil); status != http.StatusNoContent {
+			t.Fatalf("expected status 204, got %d", status)
+		}
+		sessions, err := db.UserSessions.ListForUser(context.Background(), int64(otherUser.ID), time.Time{})
+		if err != nil {
+			t.Fatalf("failed to list sessions: %s", err)
+		}
+		if len(sessions) != 0 {
+			t.Errorf("expected no sessions, got %+v", sessions)
+		}
+	})
+
+	t.Run("expired sessions are swept", func(t *testing.T) {
+		fake.Advance(25 * time.Hour)
+		if err := api.sweepSessions(context.Background()); err != nil {
+			t.Fatalf("failed to sweep sessions: %s", err)
+		}
+		sessions, err := db.UserSessions.ListForUser(context.Background(), int64(user.ID), time.Time{})
+		if err != nil {
+			t.Fatalf("failed to list sessions: %s", err)
+		}
+		if len(sessions) != 0 {
+			t.Errorf("expected no sessions, got %+v", sessions)
+		}
+	})
+}
+
+// sessionCookie returns the value of the session cookie stored in the client's jar.
+func sessionCookie(t *testing.T, client *http.Client, serverURL string) string {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, serverURL, nil)
+	for _, c := range client.Jar.Cookies(req.URL) {
+		if c.Name == "session" {
+			return c.Value
+		}
+	}
+	return ""
+}
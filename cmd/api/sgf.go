@@ -0,0 +1,76 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/hazzardr/baduk-online/internal/sgf"
+)
+
+// handleValidateSGF parses an uploaded SGF file and returns its normalized tree along with the game
+// information from the first game in the file. Every game is replayed, so points off the board and moves
+// onto stones are reported as well as malformed SGF.
+func (api *API) handleValidateSGF(w http.ResponseWriter, r *http.Request) {
+	body, err := api.readBody(w, r)
+	if err != nil {
+		api.badRequestResponse(w, r, err)
+		return
+	}
+
+	collection, err := sgf.Parse(body)
+	if err != nil {
+		var syntaxErr *sgf.SyntaxError
+		if errors.As(err, &syntaxErr) || errors.Is(err, sgf.ErrEmpty) || errors.Is(err, sgf.ErrTooDeep) {
+			api.failedValidationResponse(w, r, map[string]string{"sgf": err.Error()})
+		} else {
+			api.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	info, err := collection[0].Sequence[0].GameInfo()
+	if err == nil {
+		err = collection.Replay()
+	}
+	if err != nil {
+		var propErr *sgf.PropertyError
+		if errors.As(err, &propErr) {
+			api.failedValidationResponse(w, r, map[string]string{propErr.Property: propErr.Msg})
+		} else {
+			api.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	resp := map[string]any{
+		"game_info":  info,
+		"collection": collection,
+	}
+	err = api.writeJSON(w, http.StatusOK, resp, nil)
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+	}
+}
+
+// handleExportSGF converts a JSON tree, as returned by handleValidateSGF, back into SGF text.
+func (api *API) handleExportSGF(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Collection sgf.Collection `json:"collection"`
+	}
+	err := api.readJSON(w, r, &input)
+	if err != nil {
+		api.badRequestResponse(w, r, err)
+		return
+	}
+
+	text, err := sgf.Marshal(input.Collection)
+	if err != nil {
+		api.failedValidationResponse(w, r, map[string]string{"collection": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-go-sgf")
+	w.Header().Set("Content-Disposition", `attachment; filename="game.sgf"`)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(text)
+}
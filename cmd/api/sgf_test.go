@@ -0,0 +1,110 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestValidateSGF(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{"Valid game", "(;GM[1]FF[4]SZ[9]PB[Black]PW[White]KM[6.5];B[ee];W[cc])", http.StatusOK},
+		{"Empty body", "", http.StatusBadRequest},
+		{"Malformed SGF", "(;B[ee]", http.StatusUnprocessableEntity},
+		{"Malformed game info", "(;SZ[nine])", http.StatusUnprocessableEntity},
+		{"Move off the board", "(;SZ[9];B[ee];W[jj])", http.StatusUnprocessableEntity},
+		{"Setup off the board", "(;SZ[9];B[ee](;AW[kk])(;W[cc]))", http.StatusUnprocessableEntity},
+		{"Illegal move in a variation", "(;SZ[9];B[ee](;W[cc])(;W[ee]))", http.StatusUnprocessableEntity},
+		{"Too large", "(;C[" + strings.Repeat("x", int(OneMB)) + "])", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := &API{}
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/sgf/validate", strings.NewReader(tt.body))
+			rr := httptest.NewRecorder()
+
+			api.handleValidateSGF(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("status code = %d, want %d: %s", rr.Code, tt.wantStatus, rr.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var resp struct {
+				GameInfo struct {
+					BlackPlayer string  `json:"black_player"`
+					Komi        float64 `json:"komi"`
+					Size        int     `json:"size"`
+				} `json:"game_info"`
+				Collection []struct {
+					Sequence []map[string][]string `json:"sequence"`
+				} `json:"collection"`
+			}
+			if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %s", err)
+			}
+			if resp.GameInfo.BlackPlayer != "Black" || resp.GameInfo.Komi != 6.5 || resp.GameInfo.Size != 9 {
+				t.Errorf("unexpected game info: %+v", resp.GameInfo)
+			}
+			if len(resp.Collection) != 1 || len(resp.Collection[0].Sequence) != 3 {
+				t.Errorf("unexpected collection: %+v", resp.Collection)
+			}
+		})
+	}
+}
+
+func TestExportSGF(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "Valid tree",
+			body:       `{"collection":[{"sequence":[{"GM":["1"],"FF":["4"]},{"B":["ee"]}],"variations":[{"sequence":[{"W":["cc"]}]},{"sequence":[{"W":["gg"]}]}]}]}`,
+			wantStatus: http.StatusOK,
+			wantBody:   "(;FF[4]GM[1];B[ee]\n(;W[cc])\n(;W[gg]))\n",
+		},
+		{
+			name:       "Invalid property identifier",
+			body:       `{"collection":[{"sequence":[{"b":["ee"]}]}]}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "Empty collection",
+			body:       `{"collection":[]}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "Malformed JSON",
+			body:       `{"collection":`,
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := &API{}
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/sgf/export", strings.NewReader(tt.body))
+			rr := httptest.NewRecorder()
+
+			api.handleExportSGF(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("status code = %d, want %d: %s", rr.Code, tt.wantStatus, rr.Body.String())
+			}
+			if tt.wantBody != "" && rr.Body.String() != tt.wantBody {
+				t.Errorf("body = %q, want %q", rr.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,350 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/hazzardr/baduk-online/internal/data"
+	"github.com/hazzardr/baduk-online/internal/totp"
+	"github.com/hazzardr/baduk-online/internal/validator"
+)
+
+const (
+	// twoFactorIssuer names the service in users' authenticator apps.
+	twoFactorIssuer = "baduk.online"
+	// twoFactorLoginTimeout is how long a user has to give their second factor after their password.
+	twoFactorLoginTimeout = 5 * time.Minute
+)
+
+// twoFactorAvailable reports whether two-factor authentication is configured, writing an error response if
+// it is not.
+func (api *API) twoFactorAvailable(w http.ResponseWriter, r *http.Request) bool {
+	if api.totpSealer == nil {
+		api.errorResponse(w, r, http.StatusServiceUnavailable, "two-factor authentication is not available")
+		return false
+	}
+	return true
+}
+
+// handleGetTwoFactor reports whether the logged in user has two-factor authentication turned on, and how
+// many recovery codes they have left.
+func (api *API) handleGetTwoFactor(w http.ResponseWriter, r *http.Request) {
+	user, ok := api.authenticatedUser(w, r)
+	if !ok {
+		return
+	}
+
+	enabled, err := api.db.TwoFactor.Enabled(r.Context(), int64(user.ID))
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+		return
+	}
+	remaining, err := api.db.TwoFactor.CountRecoveryCodes(r.Context(), int64(user.ID))
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+		return
+	}
+
+	resp := map[string]any{
+		"enabled":        enabled,
+		"recovery_codes": remaining,
+	}
+	err = api.writeJSON(w, http.StatusOK, resp, nil)
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+	}
+}
+
+// handleEnrollTwoFactor generates a new TOTP secret for the logged in user. The secret is returned as an
+// otpauth URI for authenticator apps, and isn't used until the user confirms it with handleConfirmTwoFactor.
+func (api *API) handleEnrollTwoFactor(w http.ResponseWriter, r *http.Request) {
+	user, ok := api.authenticatedUser(w, r)
+	if !ok {
+		return
+	}
+	if !api.twoFactorAvailable(w, r) {
+		return
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+		return
+	}
+	sealed, err := api.totpSealer.Seal(secret)
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = api.db.TwoFactor.Enroll(r.Context(), &data.TwoFactor{UserID: int64(user.ID), Secret: sealed})
+	if err != nil {
+		if errors.Is(err, data.ErrTwoFactorEnabled) {
+			api.errorResponse(w, r, http.StatusConflict, "two-factor authentication is already enabled")
+		} else {
+			api.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	resp := map[string]string{
+		"secret": totp.EncodeSecret(secret),
+		"uri":    totp.URI(twoFactorIssuer, user.Email, secret),
+	}
+	err = api.writeJSON(w, http.StatusCreated, resp, nil)
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+	}
+}
+
+// handleConfirmTwoFactor turns on two-factor authentication once the user has shown that their authenticator
+// app works by giving a first code. The user's recovery codes are returned, and can't be shown again.
+func (api *API) handleConfirmTwoFactor(w http.ResponseWriter, r *http.Request) {
+	user, ok := api.authenticatedUser(w, r)
+	if !ok {
+		return
+	}
+	if !api.twoFactorAvailable(w, r) {
+		return
+	}
+
+	var input struct {
+		Code string `json:"code"`
+	}
+
+	err := api.readJSON(w, r, &input)
+	if err != nil {
+		api.badRequestResponse(w, r, err)
+		return
+	}
+
+	tf, err := api.db.TwoFactor.Get(r.Context(), int64(user.ID))
+	if err != nil {
+		if errors.Is(err, data.ErrNoTwoFactor) {
+			api.errorResponse(w, r, http.StatusConflict, "two-factor authentication has not been set up")
+		} else {
+			api.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	if tf.Enabled {
+		api.errorResponse(w, r, http.StatusConflict, "two-factor authentication is already enabled")
+		return
+	}
+
+	v := validator.New()
+	err = api.checkTOTPCode(r, v, tf, input.Code)
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+		return
+	}
+	if !v.Valid() {
+		api.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	codes, err := api.db.TwoFactor.NewRecoveryCodes(r.Context(), int64(user.ID))
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+		return
+	}
+	err = api.writeJSON(w, http.StatusOK, map[string][]string{"recovery_codes": codes}, nil)
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+	}
+}
+
+// handleDisableTwoFactor turns off two-factor authentication for the logged in user, once they have
+// confirmed their password.
+func (api *API) handleDisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	user, ok := api.authenticatedUser(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		CurrentPassword string `json:"current_password"`
+	}
+
+	err := api.readJSON(w, r, &input)
+	if err != nil {
+		api.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	err = api.checkCurrentPassword(v, user, input.CurrentPassword)
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+		return
+	}
+	if !v.Valid() {
+		api.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = api.db.TwoFactor.Delete(r.Context(), int64(user.ID))
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleRegenerateRecoveryCodes replaces the logged in user's recovery codes, once they have confirmed their
+// password.
+func (api *API) handleRegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	user, ok := api.authenticatedUser(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		CurrentPassword string `json:"current_password"`
+	}
+
+	err := api.readJSON(w, r, &input)
+	if err != nil {
+		api.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	err = api.checkCurrentPassword(v, user, input.CurrentPassword)
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+		return
+	}
+	if !v.Valid() {
+		api.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	enabled, err := api.db.TwoFactor.Enabled(r.Context(), int64(user.ID))
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+		return
+	}
+	if !enabled {
+		api.errorResponse(w, r, http.StatusConflict, "two-factor authentication is not enabled")
+		return
+	}
+
+	codes, err := api.db.TwoFactor.NewRecoveryCodes(r.Context(), int64(user.ID))
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+		return
+	}
+	err = api.writeJSON(w, http.StatusOK, map[string][]string{"recovery_codes": codes}, nil)
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+	}
+}
+
+// handleCreateTwoFactorSession completes the login of a user whose password handleCreateSession has already
+// checked, given either a code from their authenticator app or one of their recovery codes.
+func (api *API) handleCreateTwoFactorSession(w http.ResponseWriter, r *http.Request) {
+	email := api.sessionManager.GetString(r.Context(), string(pendingUserContextKey))
+	since := time.Unix(api.sessionManager.GetInt64(r.Context(), string(pendingSinceContextKey)), 0)
+	if email == "" || api.clock.Now().Sub(since) > twoFactorLoginTimeout {
+		api.clearPendingLogin(r)
+		api.errorResponse(w, r, http.StatusUnauthorized, "log in with your password first")
+		return
+	}
+	if !api.twoFactorAvailable(w, r) {
+		return
+	}
+
+	var input struct {
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recovery_code"`
+	}
+
+	err := api.readJSON(w, r, &input)
+	if err != nil {
+		api.badRequestResponse(w, r, err)
+		return
+	}
+
+	user, err := api.db.Users.GetByEmail(r.Context(), email)
+	if err != nil {
+		if errors.Is(err, data.ErrNoUserFound) {
+			api.clearPendingLogin(r)
+			api.invalidCredentialsResponse(w, r)
+		} else {
+			api.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	if !api.totpLimiter.Allow(int64(user.ID), api.clock.Now()) {
+		api.rateLimitExceededResponse(w, r)
+		return
+	}
+
+	v := validator.New()
+	v.Check(input.Code != "" || input.RecoveryCode != "", "code", "must be provided")
+	v.Check(input.Code == "" || input.RecoveryCode == "", "recovery_code", "must not be given with a code")
+	if !v.Valid() {
+		api.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if input.RecoveryCode != "" {
+		used, err := api.db.TwoFactor.UseRecoveryCode(r.Context(), int64(user.ID), input.RecoveryCode)
+		if err != nil {
+			api.serverErrorResponse(w, r, err)
+			return
+		}
+		v.Check(used, "recovery_code", "is invalid")
+	} else {
+		tf, err := api.db.TwoFactor.Get(r.Context(), int64(user.ID))
+		if err != nil {
+			api.serverErrorResponse(w, r, err)
+			return
+		}
+		err = api.checkTOTPCode(r, v, tf, input.Code)
+		if err != nil {
+			api.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+	if !v.Valid() {
+		api.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	api.clearPendingLogin(r)
+	api.completeLogin(w, r, user)
+}
+
+// checkTOTPCode verifies a code against a user's TOTP secret and records it as used, adding a validation
+// error if it is wrong or has been used before.
+func (api *API) checkTOTPCode(r *http.Request, v *validator.Validator, tf *data.TwoFactor, code string) error {
+	v.Check(code != "", "code", "must be provided")
+	if code == "" {
+		return nil
+	}
+
+	secret, err := api.totpSealer.Open(tf.Secret)
+	if err != nil {
+		return err
+	}
+	counter, ok := totp.Verify(secret, code, api.clock.Now())
+	if !ok {
+		v.AddError("code", "is invalid")
+		return nil
+	}
+	used, err := api.db.TwoFactor.Use(r.Context(), tf, counter)
+	if err != nil {
+		return err
+	}
+	v.Check(used, "code", "has already been used")
+	return nil
+}
+
+// clearPendingLogin forgets a user who was waiting to give their second factor.
+func (api *API) clearPendingLogin(r *http.Request) {
+	api.sessionManager.Remove(r.Context(), string(pendingUserContextKey))
+	api.sessionManager.Remove(r.Context(), string(pendingSinceContextKey))
+}
@@ -0,0 +1,234 @@
+package api
+
+import (
+	"bytes"
+	"encoding/base32"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/hazzardr/baduk-online/internal/clock"
+	"github.com/hazzardr/baduk-online/internal/data"
+	"github.com/hazzardr/baduk-online/internal/totp"
+)
+
+func TestTwoFactorIntegration(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	mailer := &mockMailer{db: db}
+	api := NewAPI("test", "1.0.0", db, mailer)
+	if err := api.SetTOTPKey(bytes.Repeat([]byte{1}, totp.KeySize)); err != nil {
+		t.Fatalf("failed to set TOTP key: %s", err)
+	}
+	fake := clock.NewFake(time.Now())
+	api.clock = fake
+	server := httptest.NewServer(api.Routes())
+	defer server.Close()
+
+	createTestUser(t, db, "Careful User", "careful@example.com", "password123", true)
+
+	send := func(t *testing.T, client *http.Client, method, path string, input any) (int, []byte) {
+		t.Helper()
+		body, _ := json.Marshal(input)
+		req, _ := http.NewRequest(method, server.URL+"/api/v1"+path, bytes.NewBuffer(body))
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("failed to make request: %s", err)
+		}
+		defer resp.Body.Close()
+		respBody, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, respBody
+	}
+	getUser := func(t *testing.T, client *http.Client) int {
+		t.Helper()
+		status, _ := send(t, client, http.MethodGet, "/user", nil)
+		return status
+	}
+	recoveryCodes := func(t *testing.T, body []byte) []string {
+		t.Helper()
+		var resp struct {
+			RecoveryCodes []string `json:"recovery_codes"`
+		}
+		if err := json.Unmarshal(body, &resp); err != nil {
+			t.Fatalf("failed to decode response: %s", err)
+		}
+		if len(resp.RecoveryCodes) != data.RecoveryCodeCount {
+			t.Fatalf("expected %d recovery codes, got %v", data.RecoveryCodeCount, resp.RecoveryCodes)
+		}
+		return resp.RecoveryCodes
+	}
+
+	client := newTestClient(t)
+	if status := login(t, client, server.URL, "careful@example.com", "password123"); status != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", status)
+	}
+
+	var secret []byte
+	var codes []string
+
+	t.Run("enrolling requires a session", func(t *testing.T) {
+		status, _ := send(t, newTestClient(t), http.MethodPost, "/user/two-factor", nil)
+		if status != http.StatusUnauthorized {
+			t.Errorf("expected status 401, got %d", status)
+		}
+	})
+
+	t.Run("enrollment is confirmed with a first code", func(t *testing.T) {
+		status, body := send(t, client, http.MethodPost, "/user/two-factor", nil)
+		if status != http.StatusCreated {
+			t.Fatalf("expected status 201, got %d: %s", status, body)
+		}
+		var enrollment struct {
+			Secret string `json:"secret"`
+			URI    string `json:"uri"`
+		}
+		if err := json.Unmarshal(body, &enrollment); err != nil {
+			t.Fatalf("failed to decode response: %s", err)
+		}
+		uri, err := url.Parse(enrollment.URI)
+		if err != nil || uri.Scheme != "otpauth" || uri.Query().Get("secret") != enrollment.Secret {
+			t.Fatalf("unexpected URI %q", enrollment.URI)
+		}
+		secret, err = base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(enrollment.Secret)
+		if err != nil {
+			t.Fatalf("failed to decode secret: %s", err)
+		}
+
+		// Two-factor stays off until confirmed, so logging in still only takes a password.
+		if status := login(t, newTestClient(t), server.URL, "careful@example.com", "password123"); status != http.StatusOK {
+			t.Errorf("expected status 200, got %d", status)
+		}
+
+		status, _ = send(t, client, http.MethodPut, "/user/two-factor", map[string]string{"code": "000000"})
+		if status != http.StatusUnprocessableEntity {
+			t.Errorf("expected a wrong code to be rejected, got %d", status)
+		}
+		code := totp.Code(secret, totp.Counter(fake.Now()))
+		status, body = send(t, client, http.MethodPut, "/user/two-factor", map[string]string{"code": code})
+		if status != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", status, body)
+		}
+		codes = recoveryCodes(t, body)
+
+		if status, _ := send(t, client, http.MethodPost, "/user/two-factor", nil); status != http.StatusConflict {
+			t.Errorf("expected enrolling again to conflict, got %d", status)
+		}
+	})
+
+	t.Run("logging in requires a second factor", func(t *testing.T) {
+		other := newTestClient(t)
+		if status := login(t, other, server.URL, "careful@example.com", "password123"); status != http.StatusAccepted {
+			t.Fatalf("expected status 202, got %d", status)
+		}
+		if status := getUser(t, other); status != http.StatusUnauthorized {
+			t.Errorf("expected the password alone not to log in, got %d", status)
+		}
+
+		status, _ := send(t, other, http.MethodPost, "/sessions/two-factor", map[string]string{"code": "000000"})
+		if status != http.StatusUnprocessableEntity {
+			t.Errorf("expected a wrong code to be rejected, got %d", status)
+		}
+
+		// The code used to confirm the enrollment can't be used again.
+		code := totp.Code(secret, totp.Counter(fake.Now()))
+		status, _ = send(t, other, http.MethodPost, "/sessions/two-factor", map[string]string{"code": code})
+		if status != http.StatusUnprocessableEntity {
+			t.Errorf("expected a replayed code to be rejected, got %d", status)
+		}
+
+		fake.Advance(totp.Period)
+		code = totp.Code(secret, totp.Counter(fake.Now()))
+		status, body := send(t, other, http.MethodPost, "/sessions/two-factor", map[string]string{"code": code})
+		if status != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", status, body)
+		}
+		if status := getUser(t, other); status != http.StatusOK {
+			t.Errorf("expected to be logged in, got %d", status)
+		}
+	})
+
+	t.Run("the second step expires", func(t *testing.T) {
+		other := newTestClient(t)
+		if status := login(t, other, server.URL, "careful@example.com", "password123"); status != http.StatusAccepted {
+			t.Fatalf("expected status 202, got %d", status)
+		}
+		fake.Advance(twoFactorLoginTimeout + time.Minute)
+		code := totp.Code(secret, totp.Counter(fake.Now()))
+		status, _ := send(t, other, http.MethodPost, "/sessions/two-factor", map[string]string{"code": code})
+		if status != http.StatusUnauthorized {
+			t.Errorf("expected status 401, got %d", status)
+		}
+
+		status, _ = send(t, newTestClient(t), http.MethodPost, "/sessions/two-factor", map[string]string{"code": code})
+		if status != http.StatusUnauthorized {
+			t.Errorf("expected a second step without a password to be rejected, got %d", status)
+		}
+	})
+
+	t.Run("recovery codes are single use", func(t *testing.T) {
+		first := newTestClient(t)
+		if status := login(t, first, server.URL, "careful@example.com", "password123"); status != http.StatusAccepted {
+			t.Fatalf("expected status 202, got %d", status)
+		}
+		input := map[string]string{"recovery_code": codes[0]}
+		if status, body := send(t, first, http.MethodPost, "/sessions/two-factor", input); status != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", status, body)
+		}
+
+		second := newTestClient(t)
+		if status := login(t, second, server.URL, "careful@example.com", "password123"); status != http.StatusAccepted {
+			t.Fatalf("expected status 202, got %d", status)
+		}
+		status, _ := send(t, second, http.MethodPost, "/sessions/two-factor", input)
+		if status != http.StatusUnprocessableEntity {
+			t.Errorf("expected a used recovery code to be rejected, got %d", status)
+		}
+	})
+
+	t.Run("regenerating recovery codes requires the password", func(t *testing.T) {
+		path := "/user/two-factor/recovery-codes"
+		input := map[string]string{"current_password": "wrongpassword"}
+		if status, _ := send(t, client, http.MethodPost, path, input); status != http.StatusUnprocessableEntity {
+			t.Errorf("expected status 422, got %d", status)
+		}
+		status, body := send(t, client, http.MethodPost, path, map[string]string{"current_password": "password123"})
+		if status != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", status, body)
+		}
+		regenerated := recoveryCodes(t, body)
+
+		other := newTestClient(t)
+		if status := login(t, other, server.URL, "careful@example.com", "password123"); status != http.StatusAccepted {
+			t.Fatalf("expected status 202, got %d", status)
+		}
+		input = map[string]string{"recovery_code": codes[1]}
+		status, _ = send(t, other, http.MethodPost, "/sessions/two-factor", input)
+		if status != http.StatusUnprocessableEntity {
+			t.Errorf("expected old recovery codes to be discarded, got %d", status)
+		}
+		input = map[string]string{"recovery_code": regenerated[0]}
+		if status, _ := send(t, other, http.MethodPost, "/sessions/two-factor", input); status != http.StatusOK {
+			t.Errorf("expected a new recovery code to be accepted, got %d", status)
+		}
+	})
+
+	t.Run("disabling requires the password", func(t *testing.T) {
+		input := map[string]string{"current_password": "wrongpassword"}
+		status, _ := send(t, client, http.MethodDelete, "/user/two-factor", input)
+		if status != http.StatusUnprocessableEntity {
+			t.Errorf("expected status 422, got %d", status)
+		}
+		input = map[string]string{"current_password": "password123"}
+		if status, _ := send(t, client, http.MethodDelete, "/user/two-factor", input); status != http.StatusNoContent {
+			t.Fatalf("expected status 204, got %d", status)
+		}
+		if status := login(t, newTestClient(t), server.URL, "careful@example.com", "password123"); status != http.StatusOK {
+			t.Errorf("expected the password alone to log in again, got %d", status)
+		}
+	})
+}
@@ -5,22 +5,86 @@ import (
 	"errors"
 	"log/slog"
 	"net/http"
+	"strings"
 
 	"github.com/hazzardr/baduk-online/internal/data"
 	"github.com/hazzardr/baduk-online/internal/validator"
 )
 
 func (api *API) handleGetLoggedInUser(w http.ResponseWriter, r *http.Request) {
-	user, err := api.getUserFromContext(r)
+	user, ok := api.authenticatedUser(w, r)
+	if !ok {
+		return
+	}
+	// Show the vacation time the user has as of now rather than when it was last saved.
+	user.Vacation.Settle(api.clock.Now())
+	err := api.writeJSON(w, 200, user, nil)
 	if err != nil {
-		if errors.Is(err, data.ErrNoUserFound) {
-			api.unauthenticatedResponse(w, r)
+		api.serverErrorResponse(w, r, err)
+	}
+}
+
+// handleUpdateUser edits the logged in user's profile. Fields left out of the request are unchanged. If the
+// request gives the version of the profile it was based on, it fails with a conflict when that version is
+// stale.
+func (api *API) handleUpdateUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := api.authenticatedUser(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Name           *string `json:"name"`
+		Country        *string `json:"country"`
+		Bio            *string `json:"bio"`
+		PreferredRules *string `json:"preferred_rules"`
+		AvatarURL      *string `json:"avatar_url"`
+		Version        *int    `json:"version"`
+	}
+
+	err := api.readJSON(w, r, &input)
+	if err != nil {
+		api.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Version != nil {
+		user.Version = *input.Version
+	}
+
+	if input.Name != nil {
+		user.Name = *input.Name
+	}
+	if input.Country != nil {
+		user.Country = strings.ToUpper(*input.Country)
+	}
+	if input.Bio != nil {
+		user.Bio = *input.Bio
+	}
+	if input.PreferredRules != nil {
+		user.PreferredRules = *input.PreferredRules
+	}
+	if input.AvatarURL != nil {
+		user.AvatarURL = *input.AvatarURL
+	}
+
+	v := validator.New()
+	if data.ValidateProfile(v, user); !v.Valid() {
+		api.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = api.db.Users.UpdateProfile(r.Context(), user)
+	if err != nil {
+		if errors.Is(err, data.ErrEditConflict) {
+			api.dataConflictResponse(w, r, err)
 		} else {
-			api.serverErrorResponse(w, r, errors.Join(errors.New("failed to retrieve user data from context"), err))
+			api.serverErrorResponse(w, r, err)
 		}
 		return
 	}
-	err = api.writeJSON(w, 200, user, nil)
+
+	err = api.writeJSON(w, http.StatusOK, user, nil)
 	if err != nil {
 		api.serverErrorResponse(w, r, err)
 	}
@@ -83,16 +147,11 @@ func (api *API) handleCreateUser(w http.ResponseWriter, r *http.Request) {
 
 // handleSendRegistrationEmail sends a registration email based on the email address in the payload.
 func (api *API) handleSendRegistrationEmail(w http.ResponseWriter, r *http.Request) {
-	user, err := api.getUserFromContext(r)
-	if err != nil {
-		if errors.Is(err, data.ErrNoUserFound) {
-			api.unauthenticatedResponse(w, r)
-		} else {
-			api.serverErrorResponse(w, r, errors.Join(errors.New("failed to retrieve user data from context"), err))
-		}
+	user, ok := api.authenticatedUser(w, r)
+	if !ok {
 		return
 	}
-	err = api.mailer.SendRegistrationEmail(r.Context(), user)
+	err := api.mailer.SendRegistrationEmail(r.Context(), user)
 	if err != nil {
 		slog.Error("failed to send registration email", "user", user.Email, "err", err)
 		api.serverErrorResponse(w, r, err)
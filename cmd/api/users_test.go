@@ -5,6 +5,7 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"sync"
@@ -67,9 +68,12 @@ func setupTestDB(t *testing.T) (*data.Database, func()) {
 }
 
 type mockMailer struct {
-	emailsSent []*data.User
-	db         *data.Database
-	mu         sync.Mutex
+	emailsSent         []*data.User
+	passwordResetsSent []*data.User
+	remindersSent      []*data.Game
+	emailChangesSent   []string
+	db                 *data.Database
+	mu                 sync.Mutex
 }
 
 func (m *mockMailer) SendRegistrationEmail(_ context.Context, user *data.User) error {
@@ -79,6 +83,42 @@ func (m *mockMailer) SendRegistrationEmail(_ context.Context, user *data.User) e
 	return nil
 }
 
+func (m *mockMailer) SendPasswordResetEmail(_ context.Context, user *data.User) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.passwordResetsSent = append(m.passwordResetsSent, user)
+	return nil
+}
+
+// waitFor waits for the emails counted by sent to reach want, as they are sent in the background, and returns
+// how many there were. It gives up after a second.
+func (m *mockMailer) waitFor(want int, sent func() int) int {
+	deadline := time.Now().Add(time.Second)
+	for {
+		m.mu.Lock()
+		n := sent()
+		m.mu.Unlock()
+		if n >= want || time.Now().After(deadline) {
+			return n
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func (m *mockMailer) SendMoveReminderEmail(_ context.Context, _ *data.User, game *data.Game, _ time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.remindersSent = append(m.remindersSent, game)
+	return nil
+}
+
+func (m *mockMailer) SendEmailChangeEmail(_ context.Context, _ *data.User, newEmail string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.emailChangesSent = append(m.emailChangesSent, newEmail)
+	return nil
+}
+
 func (m *mockMailer) GetLastTokenForUser(ctx context.Context, userID int64) (string, error) {
 	token, err := m.db.Registration.NewToken(ctx, userID, 15*time.Minute)
 	if err != nil {
@@ -429,3 +469,129 @@ func TestRegistrationTokenWorkflow(t *testing.T) {
 		}
 	})
 }
+
+func TestUpdateProfileIntegration(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	mailer := &mockMailer{db: db}
+	api := NewAPI("test", "1.0.0", db, mailer)
+	server := httptest.NewServer(api.Routes())
+	defer server.Close()
+
+	createTestUser(t, db, "Profile User", "profile@example.com", "password123", true)
+	client := newTestClient(t)
+	if status := login(t, client, server.URL, "profile@example.com", "password123"); status != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", status)
+	}
+
+	patch := func(t *testing.T, client *http.Client, payload map[string]any) (int, data.User) {
+		t.Helper()
+		body, _ := json.Marshal(payload)
+		req, _ := http.NewRequest(http.MethodPatch, server.URL+"/api/v1/user", bytes.NewBuffer(body))
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("failed to make request: %s", err)
+		}
+		defer resp.Body.Close()
+		var user data.User
+		if resp.StatusCode == http.StatusOK {
+			if err := json.NewDecoder(resp.Body).Decode(&user); err != nil {
+				t.Fatalf("failed to decode response: %s", err)
+			}
+		}
+		return resp.StatusCode, user
+	}
+
+	t.Run("requires a session", func(t *testing.T) {
+		if status, _ := patch(t, newTestClient(t), map[string]any{"bio": "hello"}); status != http.StatusUnauthorized {
+			t.Errorf("expected status 401, got %d", status)
+		}
+	})
+
+	t.Run("defaults", func(t *testing.T) {
+		status, user := patch(t, client, map[string]any{})
+		if status != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", status)
+		}
+		if user.Name != "Profile User" || user.PreferredRules != "japanese" || user.Country != "" {
+			t.Errorf("unexpected default profile: %+v", user)
+		}
+	})
+
+	t.Run("updates only the given fields", func(t *testing.T) {
+		status, user := patch(t, client, map[string]any{
+			"country":         "kr",
+			"bio":             "Likes fighting games.",
+			"preferred_rules": "korean",
+			"avatar_url":      "https://example.com/avatar.png",
+		})
+		if status != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", status)
+		}
+		if user.Name != "Profile User" || user.Country != "KR" || user.Bio != "Likes fighting games." ||
+			user.PreferredRules != "korean" || user.AvatarURL != "https://example.com/avatar.png" {
+			t.Errorf("unexpected profile: %+v", user)
+		}
+
+		status, user = patch(t, client, map[string]any{"name": "Renamed User", "avatar_url": ""})
+		if status != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", status)
+		}
+		if user.Name != "Renamed User" || user.Country != "KR" || user.AvatarURL != "" {
+			t.Errorf("unexpected profile: %+v", user)
+		}
+
+		stored, err := db.Users.GetByEmail(context.Background(), "profile@example.com")
+		if err != nil {
+			t.Fatalf("failed to get user: %s", err)
+		}
+		if stored.Name != "Renamed User" || stored.Bio != "Likes fighting games." {
+			t.Errorf("expected the profile to be saved, got %+v", stored)
+		}
+	})
+
+	t.Run("rejects invalid fields", func(t *testing.T) {
+		tests := []map[string]any{
+			{"name": ""},
+			{"country": "Korea"},
+			{"preferred_rules": "ing"},
+			{"avatar_url": "ftp://example.com/avatar.png"},
+		}
+		for _, payload := range tests {
+			if status, _ := patch(t, client, payload); status != http.StatusUnprocessableEntity {
+				t.Errorf("%v: expected status 422, got %d", payload, status)
+			}
+		}
+	})
+
+	t.Run("rejects stale edits", func(t *testing.T) {
+		status, user := patch(t, client, map[string]any{})
+		if status != http.StatusOK || user.Version == 0 {
+			t.Fatalf("expected the version to be returned, got %d: %+v", status, user)
+		}
+		status, updated := patch(t, client, map[string]any{"bio": "newer", "version": user.Version})
+		if status != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", status)
+		}
+		if updated.Version == user.Version {
+			t.Errorf("expected the version to change, got %d", updated.Version)
+		}
+		status, _ = patch(t, client, map[string]any{"bio": "older", "version": user.Version})
+		if status != http.StatusConflict {
+			t.Errorf("expected status 409, got %d", status)
+		}
+
+		stored, err := db.Users.GetByEmail(context.Background(), "profile@example.com")
+		if err != nil {
+			t.Fatalf("failed to get user: %s", err)
+		}
+		if stored.Bio != "newer" {
+			t.Errorf("expected the stale edit to be discarded, got %q", stored.Bio)
+		}
+		stored.Bio, stored.Version = "older", user.Version
+		if err := db.Users.UpdateProfile(context.Background(), stored); !errors.Is(err, data.ErrEditConflict) {
+			t.Errorf("expected ErrEditConflict, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,184 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/hazzardr/baduk-online/internal/clock"
+	"github.com/hazzardr/baduk-online/internal/data"
+	"github.com/hazzardr/baduk-online/internal/game"
+	"github.com/hazzardr/baduk-online/internal/vacation"
+)
+
+// handleUpdateVacation puts the logged in user on vacation, pausing their clocks in games where it is their
+// turn, or brings them back from it.
+func (api *API) handleUpdateVacation(w http.ResponseWriter, r *http.Request) {
+	user, ok := api.authenticatedUser(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Active *bool `json:"active"`
+	}
+	err := api.readJSON(w, r, &input)
+	if err != nil {
+		api.badRequestResponse(w, r, err)
+		return
+	}
+	if input.Active == nil {
+		api.failedValidationResponse(w, r, map[string]string{"active": "must be provided"})
+		return
+	}
+
+	now := api.clock.Now()
+	if *input.Active {
+		err = user.Vacation.Start(now)
+		if errors.Is(err, vacation.ErrNoVacationLeft) {
+			api.failedValidationResponse(w, r, map[string]string{"active": err.Error()})
+			return
+		}
+	} else {
+		user.Vacation.End(now)
+	}
+
+	err = api.db.Users.UpdateVacation(r.Context(), user)
+	if err != nil {
+		if errors.Is(err, data.ErrEditConflict) {
+			api.dataConflictResponse(w, r, err)
+		} else {
+			api.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if user.Vacation.Active {
+		err = api.pauseClocks(r.Context(), user, now)
+	} else {
+		err = api.resumeClocks(r.Context(), user, now)
+	}
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = api.writeJSON(w, http.StatusOK, map[string]any{"vacation": user.Vacation}, nil)
+	if err != nil {
+		api.serverErrorResponse(w, r, err)
+	}
+}
+
+// StartVacationSweeper periodically ends vacations that have used up their budget and pauses the clocks of
+// players who became due to move while away. It runs until Shutdown is called.
+func (api *API) StartVacationSweeper(interval time.Duration) {
+	api.background(func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-api.quit:
+				return
+			case <-ticker.C:
+				err := api.sweepVacations(context.Background())
+				if err != nil {
+					slog.Error("failed to sweep vacations", "err", err)
+				}
+			}
+		}
+	})
+}
+
+// sweepVacations brings every player on vacation up to date.
+func (api *API) sweepVacations(ctx context.Context) error {
+	now := api.clock.Now()
+	users, err := api.db.Users.ListOnVacation(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, user := range users {
+		if !user.Vacation.Settle(now) {
+			// Their opponents may have moved since the clocks were last paused.
+			err := api.pauseClocks(ctx, user, now)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		err := api.db.Users.UpdateVacation(ctx, user)
+		if err != nil {
+			// The user changed their vacation themselves; the next sweep will see it.
+			if errors.Is(err, data.ErrEditConflict) {
+				continue
+			}
+			return err
+		}
+		slog.Info("vacation ran out", "user_id", user.ID)
+		err = api.resumeClocks(ctx, user, now)
+		if err != nil {
+			return err
+		}
+		api.publish(userTopic(user.ID), "vacation_ended", user.Vacation)
+	}
+	return nil
+}
+
+// pauseClocks stops the running clocks in the user's games where it is their turn.
+func (api *API) pauseClocks(ctx context.Context, user *data.User, now time.Time) error {
+	return api.updateClocksToMove(ctx, user, func(s *clock.State) bool {
+		if !s.Running {
+			return false
+		}
+		// A player who already ran out of time is left for the timeout sweeper.
+		return s.Stop(now) == nil
+	})
+}
+
+// resumeClocks restarts the clocks paused by pauseClocks.
+func (api *API) resumeClocks(ctx context.Context, user *data.User, now time.Time) error {
+	return api.updateClocksToMove(ctx, user, func(s *clock.State) bool {
+		if s.Running || s.Flagged != game.Empty || s.UpdatedAt.IsZero() {
+			return false
+		}
+		return s.Start(now, s.ToMove) == nil
+	})
+}
+
+// updateClocksToMove applies change to the clock of every active game in which it is the user's turn, and
+// saves those it reports as changed. A game that was modified concurrently is skipped.
+func (api *API) updateClocksToMove(ctx context.Context, user *data.User, change func(*clock.State) bool) error {
+	games, err := api.db.Games.ListForPlayer(ctx, int64(user.ID))
+	if err != nil {
+		return err
+	}
+
+	for _, g := range games {
+		if g.Status != data.GameStatusActive || g.Clock == nil || playerToMove(g) != int64(user.ID) {
+			continue
+		}
+		if !change(g.Clock) {
+			continue
+		}
+		err := api.db.Games.UpdateClock(ctx, g)
+		if err != nil {
+			if errors.Is(err, data.ErrEditConflict) || errors.Is(err, data.ErrGameFinished) {
+				continue
+			}
+			return err
+		}
+		api.publish(gameTopic(g.ID), "clock", g.Clock)
+	}
+	return nil
+}
+
+// playerToMove returns the ID of the player whose turn it is in a timed game.
+func playerToMove(g *data.Game) int64 {
+	if g.Clock.ToMove == game.White {
+		return g.WhiteID
+	}
+	return g.BlackID
+}
@@ -0,0 +1,152 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hazzardr/baduk-online/internal/clock"
+	"github.com/hazzardr/baduk-online/internal/data"
+	"github.com/hazzardr/baduk-online/internal/game"
+	"github.com/hazzardr/baduk-online/internal/vacation"
+)
+
+func TestCorrespondenceIntegration(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	mailer := &mockMailer{db: db}
+	api := NewAPI("test", "1.0.0", db, mailer)
+	// A fixed start keeps monthly accrual out of the way.
+	start := time.Date(2025, 6, 3, 12, 0, 0, 0, time.UTC)
+	fake := clock.NewFake(start)
+	api.clock = fake
+	server := httptest.NewServer(api.Routes())
+	defer server.Close()
+
+	black := createTestUser(t, db, "Black", "black@example.com", "password123", true)
+	white := createTestUser(t, db, "White", "white@example.com", "password123", true)
+	black.Vacation = vacation.New(start)
+	if err := db.Users.UpdateVacation(ctx, black); err != nil {
+		t.Fatalf("failed to reset vacation: %s", err)
+	}
+
+	g := &data.Game{
+		BlackID:   int64(black.ID),
+		WhiteID:   int64(white.ID),
+		BoardSize: 19,
+		Rules:     "japanese",
+		Komi:      6.5,
+		Clock:     clock.New(clock.TimeControl{Kind: clock.Absolute, MainTime: 3 * vacation.Day}),
+	}
+	if err := g.Clock.Start(start, game.Black); err != nil {
+		t.Fatalf("failed to start clock: %s", err)
+	}
+	if err := db.Games.Insert(ctx, g); err != nil {
+		t.Fatalf("failed to insert game: %s", err)
+	}
+
+	client := newTestClient(t)
+	if status := login(t, client, server.URL, "black@example.com", "password123"); status != http.StatusOK {
+		t.Fatalf("failed to log in: %d", status)
+	}
+	setVacation := func(t *testing.T, active bool) int {
+		t.Helper()
+		body, _ := json.Marshal(map[string]bool{"active": active})
+		req, _ := http.NewRequest(http.MethodPut, server.URL+"/api/v1/user/vacation", bytes.NewBuffer(body))
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("failed to make request: %s", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	remind := func(t *testing.T) int {
+		t.Helper()
+		if err := api.sendMoveReminders(ctx); err != nil {
+			t.Fatalf("failed to send reminders: %s", err)
+		}
+		mailer.mu.Lock()
+		defer mailer.mu.Unlock()
+		return len(mailer.remindersSent)
+	}
+
+	t.Run("no reminder while the deadline is far away", func(t *testing.T) {
+		if sent := remind(t); sent != 0 {
+			t.Errorf("expected no reminders, got %d", sent)
+		}
+	})
+
+	t.Run("reminds once when the move is due", func(t *testing.T) {
+		fake.Advance(2*vacation.Day + time.Hour)
+		if sent := remind(t); sent != 1 {
+			t.Fatalf("expected one reminder, got %d", sent)
+		}
+		// A new API, as after a restart, must not send it again.
+		restarted := NewAPI("test", "1.0.0", db, mailer)
+		restarted.clock = fake
+		if err := restarted.sendMoveReminders(ctx); err != nil {
+			t.Fatalf("failed to send reminders: %s", err)
+		}
+		if sent := remind(t); sent != 1 {
+			t.Errorf("expected the reminder not to be repeated, got %d", sent)
+		}
+	})
+
+	t.Run("vacation pauses the clock", func(t *testing.T) {
+		if status := setVacation(t, true); status != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", status)
+		}
+		got, _ := db.Games.Get(ctx, g.ID)
+		if got.Clock.Running {
+			t.Fatal("expected the clock to be paused")
+		}
+
+		fake.Advance(5 * vacation.Day)
+		if err := api.sweepTimeouts(ctx); err != nil {
+			t.Fatalf("failed to sweep timeouts: %s", err)
+		}
+		if got, _ := db.Games.Get(ctx, g.ID); got.Status != data.GameStatusActive {
+			t.Errorf("expected the game to survive the vacation, got %+v", got)
+		}
+	})
+
+	t.Run("returning restarts the clock and charges the budget", func(t *testing.T) {
+		if status := setVacation(t, false); status != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", status)
+		}
+		got, _ := db.Games.Get(ctx, g.ID)
+		if !got.Clock.Running || got.Clock.Black.Main != 23*time.Hour {
+			t.Errorf("unexpected clock after vacation: %+v", got.Clock)
+		}
+		user, _ := db.Users.GetByID(ctx, int64(black.ID))
+		if user.Vacation.Active || user.Vacation.Remaining != vacation.InitialBudget-5*vacation.Day {
+			t.Errorf("unexpected vacation budget: %+v", user.Vacation)
+		}
+	})
+
+	t.Run("vacation ends when the budget runs out", func(t *testing.T) {
+		if status := setVacation(t, true); status != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", status)
+		}
+		fake.Advance(vacation.InitialBudget)
+		if err := api.sweepVacations(ctx); err != nil {
+			t.Fatalf("failed to sweep vacations: %s", err)
+		}
+		user, _ := db.Users.GetByID(ctx, int64(black.ID))
+		if user.Vacation.Active || user.Vacation.Remaining != 0 {
+			t.Errorf("expected vacation to have ended, got %+v", user.Vacation)
+		}
+		if got, _ := db.Games.Get(ctx, g.ID); !got.Clock.Running {
+			t.Error("expected the clock to be running again")
+		}
+		if status := setVacation(t, true); status != http.StatusUnprocessableEntity {
+			t.Errorf("expected status 422, got %d", status)
+		}
+	})
+}
@@ -0,0 +1,214 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+	"github.com/hazzardr/baduk-online/internal/events"
+)
+
+const (
+	wsPingInterval   = 30 * time.Second
+	wsPingTimeout    = 10 * time.Second
+	wsWriteTimeout   = 10 * time.Second
+	wsReadLimit      = 4096
+	wsMaxTopicLength = 128
+)
+
+// wsCommand is a message sent by the client over the WebSocket.
+type wsCommand struct {
+	Action string `json:"action"`
+	Topic  string `json:"topic"`
+	Since  uint64 `json:"since"`
+}
+
+// wsError is sent to the client when one of its commands could not be carried out.
+type wsError struct {
+	Type  string `json:"type"`
+	Topic string `json:"topic,omitempty"`
+	Error string `json:"error"`
+}
+
+// handleWebSocket upgrades an authenticated request to a WebSocket over which the client can subscribe to
+// event topics. Messages are delivered by a writer goroutine while this handler reads the client's commands.
+func (api *API) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	user, ok := api.authenticatedUser(w, r)
+	if !ok {
+		return
+	}
+
+	client, err := api.hub.Register(int64(user.ID))
+	if err != nil {
+		api.errorResponse(w, r, http.StatusServiceUnavailable, "server is shutting down")
+		return
+	}
+
+	// The server's read and write timeouts would otherwise still apply to the connection once it is hijacked.
+	rc := http.NewResponseController(w)
+	if err := errors.Join(rc.SetReadDeadline(time.Time{}), rc.SetWriteDeadline(time.Time{})); err != nil {
+		client.Done()
+		api.serverErrorResponse(w, r, fmt.Errorf("failed to clear connection deadlines: %w", err))
+		return
+	}
+
+	conn, err := websocket.Accept(w, r, nil)
+	if err != nil {
+		// Accept has already written an error response.
+		client.Done()
+		slog.Warn("failed to accept websocket", "err", err)
+		return
+	}
+	conn.SetReadLimit(wsReadLimit)
+
+	// The connection outlives the request's context, which is cancelled by the server on shutdown before the
+	// hub has had a chance to flush its messages.
+	ctx, cancel := context.WithCancel(context.WithoutCancel(r.Context()))
+	defer cancel()
+
+	written := make(chan struct{})
+	go func() {
+		defer close(written)
+		api.writeEvents(ctx, conn, client)
+	}()
+
+	api.readCommands(ctx, conn, client)
+	client.Close()
+	<-written
+}
+
+// writeEvents delivers the client's messages until the hub disconnects it, then closes the connection with
+// a status that reflects why.
+func (api *API) writeEvents(ctx context.Context, conn *websocket.Conn, client *events.Client) {
+	defer client.Done()
+
+	ticker := time.NewTicker(wsPingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case msg, ok := <-client.Messages():
+			if !ok {
+				switch {
+				case errors.Is(client.Err(), events.ErrSlowConsumer):
+					_ = conn.Close(websocket.StatusTryAgainLater, "too slow to receive messages")
+				default:
+					_ = conn.Close(websocket.StatusGoingAway, "")
+				}
+				return
+			}
+			writeCtx, cancel := context.WithTimeout(ctx, wsWriteTimeout)
+			err := wsjson.Write(writeCtx, conn, msg)
+			cancel()
+			if err != nil {
+				_ = conn.CloseNow()
+				return
+			}
+		case <-ticker.C:
+			pingCtx, cancel := context.WithTimeout(ctx, wsPingTimeout)
+			err := conn.Ping(pingCtx)
+			cancel()
+			if err != nil {
+				_ = conn.CloseNow()
+				return
+			}
+		}
+	}
+}
+
+// readCommands handles subscription commands from the client until the connection is closed.
+func (api *API) readCommands(ctx context.Context, conn *websocket.Conn, client *events.Client) {
+	for {
+		var cmd wsCommand
+		err := wsjson.Read(ctx, conn, &cmd)
+		if err != nil {
+			return
+		}
+
+		if err := api.handleCommand(client, cmd); err != nil {
+			writeCtx, cancel := context.WithTimeout(ctx, wsWriteTimeout)
+			err = wsjson.Write(writeCtx, conn, wsError{Type: "error", Topic: cmd.Topic, Error: err.Error()})
+			cancel()
+			if err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (api *API) handleCommand(client *events.Client, cmd wsCommand) error {
+	if err := validateTopic(client, cmd.Topic); err != nil {
+		return err
+	}
+	switch cmd.Action {
+	case "subscribe":
+		return api.hub.Subscribe(client, cmd.Topic, cmd.Since)
+	case "unsubscribe":
+		api.hub.Unsubscribe(client, cmd.Topic)
+		return nil
+	default:
+		return fmt.Errorf("unknown action %q", cmd.Action)
+	}
+}
+
+// publish sends an event to a topic's subscribers. Failing to publish never fails the request that caused
+// the event; clients that miss it can reload the state they are interested in.
+func (api *API) publish(topic, typ string, data any) {
+	_, err := api.hub.Publish(topic, typ, data)
+	if err != nil && !errors.Is(err, events.ErrHubClosed) {
+		slog.Warn("failed to publish event", "topic", topic, "type", typ, "err", err)
+	}
+}
+
+// StartTopicExpirer launches a background worker that forgets the event topics nobody has used for between
+// one and two intervals, so that the hub doesn't keep every game's messages for the life of the process.
+func (api *API) StartTopicExpirer(interval time.Duration) {
+	api.background(func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-api.quit:
+				return
+			case <-ticker.C:
+				if expired := api.hub.Expire(); expired > 0 {
+					slog.Debug("expired idle event topics", "count", expired)
+				}
+			}
+		}
+	})
+}
+
+// userTopic returns the topic on which messages meant only for the given user are published.
+func userTopic(userID int) string {
+	return "user:" + strconv.Itoa(userID)
+}
+
+// lobbyTopic is the topic on which open seeks are announced.
+const lobbyTopic = "lobby"
+
+// gameTopic returns the topic on which updates to a game are published.
+func gameTopic(gameID int64) string {
+	return "game:" + strconv.FormatInt(gameID, 10)
+}
+
+// validateTopic checks that a topic name is well formed and that the client may subscribe to it. User topics
+// are private to their user; every other topic is public.
+func validateTopic(client *events.Client, topic string) error {
+	switch {
+	case topic == "":
+		return errors.New("topic must be provided")
+	case len(topic) > wsMaxTopicLength:
+		return fmt.Errorf("topic must not be more than %d bytes long", wsMaxTopicLength)
+	case strings.HasPrefix(topic, "user:") && topic != userTopic(int(client.UserID)):
+		return errors.New("not permitted to subscribe to another user's topic")
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+	"github.com/hazzardr/baduk-online/internal/events"
+)
+
+func TestValidateTopic(t *testing.T) {
+	client := &events.Client{UserID: 7}
+	tests := []struct {
+		name    string
+		topic   string
+		wantErr bool
+	}{
+		{name: "Public topic", topic: "game:12", wantErr: false},
+		{name: "Own user topic", topic: "user:7", wantErr: false},
+		{name: "Another user's topic", topic: "user:8", wantErr: true},
+		{name: "Empty topic", topic: "", wantErr: true},
+		{name: "Topic too long", topic: strings.Repeat("a", wsMaxTopicLength+1), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateTopic(client, tt.topic)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateTopic(%q) error = %v, wantErr %v", tt.topic, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWebSocketIntegration(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	mailer := &mockMailer{db: db}
+	api := NewAPI("test", "1.0.0", db, mailer)
+	server := httptest.NewServer(api.Routes())
+	defer server.Close()
+
+	user := createTestUser(t, db, "Socket User", "socket@example.com", "password123", true)
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/api/v1/ws"
+
+	dial := func(t *testing.T, client *http.Client) *websocket.Conn {
+		t.Helper()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		conn, resp, err := websocket.Dial(ctx, wsURL, &websocket.DialOptions{HTTPClient: client})
+		if err != nil {
+			t.Fatalf("failed to dial websocket: %s", err)
+		}
+		if resp.Body != nil {
+			resp.Body.Close()
+		}
+		return conn
+	}
+
+	send := func(t *testing.T, conn *websocket.Conn, topic string, since uint64) {
+		t.Helper()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		err := wsjson.Write(ctx, conn, wsCommand{Action: "subscribe", Topic: topic, Since: since})
+		if err != nil {
+			t.Fatalf("failed to subscribe: %s", err)
+		}
+	}
+
+	read := func(t *testing.T, conn *websocket.Conn) events.Message {
+		t.Helper()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		var msg events.Message
+		if err := wsjson.Read(ctx, conn, &msg); err != nil {
+			t.Fatalf("failed to read message: %s", err)
+		}
+		return msg
+	}
+
+	// subscribe waits for the subscription to be confirmed so that nothing published afterwards is missed.
+	subscribe := func(t *testing.T, conn *websocket.Conn, topic string, since uint64) events.Message {
+		t.Helper()
+		send(t, conn, topic, since)
+		msg := read(t, conn)
+		if msg.Type != events.TypeSubscribed || msg.Topic != topic {
+			t.Fatalf("expected subscription to %s, got %+v", topic, msg)
+		}
+		return msg
+	}
+
+	t.Run("reject unauthenticated connection", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_, resp, err := websocket.Dial(ctx, wsURL, nil)
+		if err == nil {
+			t.Fatal("expected dial to fail")
+		}
+		if resp == nil || resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("expected status 401, got %v", resp)
+		}
+	})
+
+	t.Run("receive published messages and resume", func(t *testing.T) {
+		client := newTestClient(t)
+		if status := login(t, client, server.URL, "socket@example.com", "password123"); status != http.StatusOK {
+			t.Fatalf("failed to log in: %d", status)
+		}
+
+		conn := dial(t, client)
+		subscribe(t, conn, "game:1", 0)
+		if _, err := api.hub.Publish("game:1", "move", map[string]int{"x": 2, "y": 2}); err != nil {
+			t.Fatalf("failed to publish: %s", err)
+		}
+		first := read(t, conn)
+		if first.Type != "move" {
+			t.Fatalf("expected move on game:1, got %+v", first)
+		}
+		conn.CloseNow()
+
+		// Messages published while disconnected are replayed on resubscribing.
+		if _, err := api.hub.Publish("game:1", "move", map[string]int{"x": 3, "y": 3}); err != nil {
+			t.Fatalf("failed to publish: %s", err)
+		}
+		conn = dial(t, client)
+		defer conn.CloseNow()
+		subscribe(t, conn, "game:1", first.Seq)
+		msg := read(t, conn)
+		if msg.Type != "move" || msg.Seq != first.Seq+1 {
+			t.Errorf("expected replayed move with seq %d, got %+v", first.Seq+1, msg)
+		}
+	})
+
+	t.Run("reject another user's topic", func(t *testing.T) {
+		client := newTestClient(t)
+		if status := login(t, client, server.URL, "socket@example.com", "password123"); status != http.StatusOK {
+			t.Fatalf("failed to log in: %d", status)
+		}
+		conn := dial(t, client)
+		defer conn.CloseNow()
+
+		send(t, conn, userTopic(user.ID+1), 0)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		var reply wsError
+		if err := wsjson.Read(ctx, conn, &reply); err != nil {
+			t.Fatalf("failed to read reply: %s", err)
+		}
+		if reply.Type != "error" {
+			t.Errorf("expected error reply, got %+v", reply)
+		}
+	})
+
+	t.Run("flush messages on shutdown", func(t *testing.T) {
+		client := newTestClient(t)
+		if status := login(t, client, server.URL, "socket@example.com", "password123"); status != http.StatusOK {
+			t.Fatalf("failed to log in: %d", status)
+		}
+		conn := dial(t, client)
+		defer conn.CloseNow()
+
+		topic := userTopic(user.ID)
+		subscribe(t, conn, topic, 0)
+
+		if _, err := api.hub.Publish(topic, "notice", nil); err != nil {
+			t.Fatalf("failed to publish: %s", err)
+		}
+		api.Shutdown(true)
+
+		msg := read(t, conn)
+		if msg.Type != "notice" {
+			t.Errorf("expected notice to be flushed, got %+v", msg)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_, _, err := conn.Read(ctx)
+		var closeErr websocket.CloseError
+		if !errors.As(err, &closeErr) || closeErr.Code != websocket.StatusGoingAway {
+			t.Errorf("expected going away close, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,179 @@
+// Package automatch pairs players waiting in the automatch queue. It only decides who should play whom;
+// persisting the queue and creating games is left to the caller.
+package automatch
+
+import (
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/hazzardr/baduk-online/internal/clock"
+	"github.com/hazzardr/baduk-online/internal/ratings"
+	"github.com/hazzardr/baduk-online/internal/scoring"
+	"github.com/hazzardr/baduk-online/internal/validator"
+)
+
+// Speed is a class of time control.
+type Speed string
+
+const (
+	// Blitz games are over in minutes.
+	Blitz Speed = "blitz"
+	// Live games are played in one sitting.
+	Live Speed = "live"
+	// Correspondence games are played over days.
+	Correspondence Speed = "correspondence"
+)
+
+// Speeds lists every speed class.
+var Speeds = []Speed{Blitz, Live, Correspondence}
+
+// BoardSizes lists the board sizes that can be requested.
+var BoardSizes = []int{9, 13, 19}
+
+// TimeControl returns the time control used for automatch games of the given speed.
+func (s Speed) TimeControl() clock.TimeControl {
+	switch s {
+	case Blitz:
+		return clock.TimeControl{Kind: clock.ByoYomi, MainTime: 5 * time.Minute, Periods: 5, PeriodTime: 10 * time.Second}
+	case Correspondence:
+		return clock.TimeControl{
+			Kind:      clock.Fischer,
+			MainTime:  3 * 24 * time.Hour,
+			Increment: 24 * time.Hour,
+			MaxTime:   7 * 24 * time.Hour,
+		}
+	default:
+		return clock.TimeControl{Kind: clock.ByoYomi, MainTime: 10 * time.Minute, Periods: 5, PeriodTime: 30 * time.Second}
+	}
+}
+
+// Entry is a player waiting for a game.
+type Entry struct {
+	UserID     int64        `json:"user_id"`
+	JoinedAt   time.Time    `json:"joined_at"`
+	BoardSizes []int        `json:"board_sizes"`
+	Speed      Speed        `json:"speed"`
+	Rank       ratings.Rank `json:"rank"`
+	// MinRank and MaxRank bound the opponents the player will accept, however long they wait.
+	MinRank ratings.Rank `json:"min_rank"`
+	MaxRank ratings.Rank `json:"max_rank"`
+	// Rules and Rated must be the same for both players of a pairing.
+	Rules scoring.Ruleset `json:"rules"`
+	Rated bool            `json:"rated"`
+	// Version changes whenever the player updates their preferences, so that a pairing made from stale
+	// preferences can be detected.
+	Version int `json:"-"`
+}
+
+// ValidateEntry checks a player's automatch preferences.
+func ValidateEntry(v *validator.Validator, e *Entry) {
+	v.Check(len(e.BoardSizes) > 0, "board_sizes", "must be provided")
+	for _, size := range e.BoardSizes {
+		v.Check(validator.PermittedValue(size, BoardSizes...), "board_sizes", "must only contain 9, 13 or 19")
+	}
+	v.Check(validator.Unique(e.BoardSizes), "board_sizes", "must not contain duplicates")
+	v.Check(validator.PermittedValue(e.Speed, Speeds...), "speed", "must be one of blitz, live or correspondence")
+	scoring.ValidateRuleset(v, e.Rules)
+	v.Check(e.MinRank >= ratings.MinRank && e.MinRank <= ratings.MaxRank, "min_rank", "must be between 30k and 9d")
+	v.Check(e.MaxRank >= ratings.MinRank && e.MaxRank <= ratings.MaxRank, "max_rank", "must be between 30k and 9d")
+	v.Check(e.MinRank <= e.MaxRank, "max_rank", "must not be weaker than min_rank")
+}
+
+// Window is how far apart in rank two players may be matched. It starts narrow so that players get close
+// games, and widens the longer they wait so that everyone eventually gets a game.
+type Window struct {
+	// Initial is the rank difference allowed as soon as a player joins.
+	Initial int
+	// Step is how long a player must wait for the window to widen by one rank.
+	Step time.Duration
+	// Max is the widest the window may become.
+	Max int
+}
+
+// DefaultWindow starts at two ranks and widens by a rank every 30 seconds up to nine, the largest handicap.
+var DefaultWindow = Window{Initial: 2, Step: 30 * time.Second, Max: 9}
+
+// At returns the rank difference allowed after waiting for the given time.
+func (w Window) At(waited time.Duration) int {
+	width := w.Initial
+	if w.Step > 0 && waited > 0 {
+		width += int(waited / w.Step)
+	}
+	return min(width, w.Max)
+}
+
+// Pair is two players who should play each other.
+type Pair struct {
+	A, B      Entry
+	BoardSize int
+}
+
+// Match pairs up as many waiting players as it can at the given time. Players are considered in the order
+// they joined, and each is matched with the closest ranked compatible player, so that those who have waited
+// longest are served first. Entries are not modified.
+func Match(entries []Entry, now time.Time, w Window) []Pair {
+	queue := slices.Clone(entries)
+	sort.SliceStable(queue, func(i, j int) bool {
+		return queue[i].JoinedAt.Before(queue[j].JoinedAt)
+	})
+
+	matched := make([]bool, len(queue))
+	var pairs []Pair
+	for i := range queue {
+		if matched[i] {
+			continue
+		}
+		best, bestSize := -1, 0
+		for j := i + 1; j < len(queue); j++ {
+			if matched[j] {
+				continue
+			}
+			size, ok := compatible(queue[i], queue[j], now, w)
+			if !ok {
+				continue
+			}
+			if best == -1 || rankDistance(queue[i], queue[j]) < rankDistance(queue[i], queue[best]) {
+				best, bestSize = j, size
+			}
+		}
+		if best == -1 {
+			continue
+		}
+		matched[i], matched[best] = true, true
+		pairs = append(pairs, Pair{A: queue[i], B: queue[best], BoardSize: bestSize})
+	}
+	return pairs
+}
+
+// compatible reports whether two players can be matched at the given time, and on which board size. The
+// largest board size both accept is preferred.
+func compatible(a, b Entry, now time.Time, w Window) (int, bool) {
+	if a.UserID == b.UserID || a.Speed != b.Speed || a.Rules != b.Rules || a.Rated != b.Rated {
+		return 0, false
+	}
+	if b.Rank < a.MinRank || b.Rank > a.MaxRank || a.Rank < b.MinRank || a.Rank > b.MaxRank {
+		return 0, false
+	}
+	// Both players must be willing to accept the difference, so the narrower window applies.
+	window := min(w.At(now.Sub(a.JoinedAt)), w.At(now.Sub(b.JoinedAt)))
+	if rankDistance(a, b) > window {
+		return 0, false
+	}
+
+	size := 0
+	for _, s := range a.BoardSizes {
+		if slices.Contains(b.BoardSizes, s) {
+			size = max(size, s)
+		}
+	}
+	return size, size != 0
+}
+
+func rankDistance(a, b Entry) int {
+	d := int(a.Rank - b.Rank)
+	if d < 0 {
+		return -d
+	}
+	return d
+}
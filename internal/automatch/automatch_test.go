@@ -0,0 +1,219 @@
+package automatch
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hazzardr/baduk-online/internal/clock"
+	"github.com/hazzardr/baduk-online/internal/ratings"
+	"github.com/hazzardr/baduk-online/internal/scoring"
+	"github.com/hazzardr/baduk-online/internal/validator"
+)
+
+var now = time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func rank(t *testing.T, s string) ratings.Rank {
+	t.Helper()
+	r, err := ratings.ParseRank(s)
+	if err != nil {
+		t.Fatalf("ParseRank(%q) error = %v", s, err)
+	}
+	return r
+}
+
+// entry returns a player of the given rank who will accept anyone and has waited for the given time.
+func entry(t *testing.T, id int64, r string, waited time.Duration, sizes ...int) Entry {
+	t.Helper()
+	if len(sizes) == 0 {
+		sizes = []int{19}
+	}
+	return Entry{
+		UserID:     id,
+		JoinedAt:   now.Add(-waited),
+		BoardSizes: sizes,
+		Speed:      Live,
+		Rank:       rank(t, r),
+		MinRank:    ratings.MinRank,
+		MaxRank:    ratings.MaxRank,
+		Rules:      scoring.Japanese,
+		Rated:      true,
+	}
+}
+
+func TestWindow(t *testing.T) {
+	tests := []struct {
+		waited time.Duration
+		want   int
+	}{
+		{0, 2},
+		{29 * time.Second, 2},
+		{30 * time.Second, 3},
+		{2 * time.Minute, 6},
+		{time.Hour, 9},
+	}
+	for _, tt := range tests {
+		if got := DefaultWindow.At(tt.waited); got != tt.want {
+			t.Errorf("At(%v) = %d, want %d", tt.waited, got, tt.want)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries func(t *testing.T) []Entry
+		// want lists the user IDs of each expected pair, and the board size they play on.
+		want [][3]int64
+	}{
+		{
+			name: "Close ranks match immediately",
+			entries: func(t *testing.T) []Entry {
+				return []Entry{entry(t, 1, "5k", 0), entry(t, 2, "3k", 0)}
+			},
+			want: [][3]int64{{1, 2, 19}},
+		},
+		{
+			name: "Distant ranks wait",
+			entries: func(t *testing.T) []Entry {
+				return []Entry{entry(t, 1, "10k", 0), entry(t, 2, "3k", 0)}
+			},
+			want: nil,
+		},
+		{
+			name: "Window widens with waiting",
+			entries: func(t *testing.T) []Entry {
+				return []Entry{entry(t, 1, "10k", 3*time.Minute), entry(t, 2, "3k", 3*time.Minute)}
+			},
+			want: [][3]int64{{1, 2, 19}},
+		},
+		{
+			name: "Narrower window applies",
+			entries: func(t *testing.T) []Entry {
+				return []Entry{entry(t, 1, "10k", 3*time.Minute), entry(t, 2, "3k", 0)}
+			},
+			want: nil,
+		},
+		{
+			name: "Longest waiting player is served first with the closest rank",
+			entries: func(t *testing.T) []Entry {
+				return []Entry{
+					entry(t, 1, "5k", time.Second),
+					entry(t, 2, "4k", 0),
+					entry(t, 3, "5k", time.Minute),
+					entry(t, 4, "6k", 2*time.Second),
+				}
+			},
+			want: [][3]int64{{3, 1, 19}, {4, 2, 19}},
+		},
+		{
+			name: "Largest common board size",
+			entries: func(t *testing.T) []Entry {
+				return []Entry{entry(t, 1, "5k", 0, 9, 13, 19), entry(t, 2, "5k", 0, 9, 13)}
+			},
+			want: [][3]int64{{1, 2, 13}},
+		},
+		{
+			name: "No common board size",
+			entries: func(t *testing.T) []Entry {
+				return []Entry{entry(t, 1, "5k", 0, 9), entry(t, 2, "5k", 0, 19)}
+			},
+			want: nil,
+		},
+		{
+			name: "Different speeds",
+			entries: func(t *testing.T) []Entry {
+				a, b := entry(t, 1, "5k", 0), entry(t, 2, "5k", 0)
+				b.Speed = Blitz
+				return []Entry{a, b}
+			},
+			want: nil,
+		},
+		{
+			name: "Different rules",
+			entries: func(t *testing.T) []Entry {
+				a, b := entry(t, 1, "5k", 0), entry(t, 2, "5k", 0)
+				b.Rules = scoring.Chinese
+				return []Entry{a, b}
+			},
+			want: nil,
+		},
+		{
+			name: "Rated and unrated",
+			entries: func(t *testing.T) []Entry {
+				a, b := entry(t, 1, "5k", 0), entry(t, 2, "5k", 0)
+				b.Rated = false
+				return []Entry{a, b}
+			},
+			want: nil,
+		},
+		{
+			name: "Acceptable rank range is never exceeded",
+			entries: func(t *testing.T) []Entry {
+				a, b := entry(t, 1, "5k", time.Hour), entry(t, 2, "3k", time.Hour)
+				a.MaxRank = rank(t, "4k")
+				return []Entry{a, b}
+			},
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pairs := Match(tt.entries(t), now, DefaultWindow)
+			if len(pairs) != len(tt.want) {
+				t.Fatalf("Match() = %d pairs, want %d: %+v", len(pairs), len(tt.want), pairs)
+			}
+			for i, p := range pairs {
+				got := [3]int64{p.A.UserID, p.B.UserID, int64(p.BoardSize)}
+				if got != tt.want[i] {
+					t.Errorf("pair %d = %v, want %v", i, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestValidateEntry(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(e *Entry)
+		wantKey string
+	}{
+		{"Valid", func(*Entry) {}, ""},
+		{"No board sizes", func(e *Entry) { e.BoardSizes = nil }, "board_sizes"},
+		{"Unsupported board size", func(e *Entry) { e.BoardSizes = []int{19, 21} }, "board_sizes"},
+		{"Duplicate board size", func(e *Entry) { e.BoardSizes = []int{9, 9} }, "board_sizes"},
+		{"Unknown speed", func(e *Entry) { e.Speed = "bullet" }, "speed"},
+		{"Unknown rules", func(e *Entry) { e.Rules = "ing" }, "rules"},
+		{"Inverted rank range", func(e *Entry) { e.MinRank, e.MaxRank = e.MaxRank, e.MinRank }, "max_rank"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := entry(t, 1, "5k", 0, 9, 19)
+			e.MinRank, e.MaxRank = rank(t, "10k"), rank(t, "1d")
+			tt.modify(&e)
+			v := validator.New()
+			ValidateEntry(v, &e)
+			if tt.wantKey == "" {
+				if !v.Valid() {
+					t.Errorf("expected no errors, got %v", v.Errors)
+				}
+				return
+			}
+			if _, ok := v.Errors[tt.wantKey]; !ok {
+				t.Errorf("expected error for %q, got %v", tt.wantKey, v.Errors)
+			}
+		})
+	}
+}
+
+func TestSpeedTimeControls(t *testing.T) {
+	for _, s := range Speeds {
+		v := validator.New()
+		clock.ValidateTimeControl(v, s.TimeControl())
+		if !v.Valid() {
+			t.Errorf("time control for %s is invalid: %v", s, v.Errors)
+		}
+	}
+}
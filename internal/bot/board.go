@@ -0,0 +1,172 @@
+package bot
+
+import (
+	"github.com/hazzardr/baduk-online/internal/game"
+)
+
+// border marks the padding around the playable area, so that neighbors never need bounds checks.
+const border game.Color = -1
+
+// noKo is the ko index when no point is forbidden by ko.
+const noKo = -1
+
+// board is a position stored as a flat grid with a one point border on every side. Stones are addressed by
+// index rather than by point, and flood fills reuse a generation stamped mark slice instead of allocating.
+type board struct {
+	size   int
+	stride int
+	cells  []game.Color
+	ko     int
+	marks  []uint32
+	gen    uint32
+}
+
+func newBoard(size int) *board {
+	stride := size + 2
+	b := &board{
+		size:   size,
+		stride: stride,
+		cells:  make([]game.Color, stride*stride),
+		ko:     noKo,
+		marks:  make([]uint32, stride*stride),
+	}
+	for i := range b.cells {
+		x, y := i%stride, i/stride
+		if x == 0 || y == 0 || x == stride-1 || y == stride-1 {
+			b.cells[i] = border
+		}
+	}
+	return b
+}
+
+func (b *board) clone() *board {
+	c := *b
+	c.cells = make([]game.Color, len(b.cells))
+	copy(c.cells, b.cells)
+	c.marks = make([]uint32, len(b.marks))
+	return &c
+}
+
+func (b *board) index(p game.Point) int {
+	return (p.Y+1)*b.stride + p.X + 1
+}
+
+func (b *board) point(i int) game.Point {
+	return game.Point{X: i%b.stride - 1, Y: i/b.stride - 1}
+}
+
+func (b *board) onBoard(p game.Point) bool {
+	return p.X >= 0 && p.X < b.size && p.Y >= 0 && p.Y < b.size
+}
+
+func (b *board) neighbors(i int) [4]int {
+	return [4]int{i - 1, i + 1, i - b.stride, i + b.stride}
+}
+
+func (b *board) diagonals(i int) [4]int {
+	return [4]int{i - b.stride - 1, i - b.stride + 1, i + b.stride - 1, i + b.stride + 1}
+}
+
+// group flood fills from the stone at i, returning the connected stones and their distinct liberties.
+func (b *board) group(i int) ([]int, []int) {
+	b.gen++
+	color := b.cells[i]
+	b.marks[i] = b.gen
+	stack := []int{i}
+	var stones, liberties []int
+
+	for len(stack) > 0 {
+		cur := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		stones = append(stones, cur)
+
+		for _, n := range b.neighbors(cur) {
+			if b.marks[n] == b.gen {
+				continue
+			}
+			switch b.cells[n] {
+			case game.Empty:
+				b.marks[n] = b.gen
+				liberties = append(liberties, n)
+			case color:
+				b.marks[n] = b.gen
+				stack = append(stack, n)
+			}
+		}
+	}
+	return stones, liberties
+}
+
+// play places a stone of color c at i and removes any opposing groups left without liberties. It returns the
+// number of stones captured, and false if the move is illegal, in which case the board is unchanged.
+func (b *board) play(c game.Color, i int) (int, bool) {
+	if b.cells[i] != game.Empty || i == b.ko {
+		return 0, false
+	}
+	b.cells[i] = c
+
+	captured, last := 0, noKo
+	for _, n := range b.neighbors(i) {
+		if b.cells[n] != c.Opponent() {
+			continue
+		}
+		stones, liberties := b.group(n)
+		if len(liberties) > 0 {
+			continue
+		}
+		for _, s := range stones {
+			b.cells[s] = game.Empty
+		}
+		captured += len(stones)
+		last = stones[0]
+	}
+
+	stones, liberties := b.group(i)
+	if len(liberties) == 0 {
+		b.cells[i] = game.Empty
+		return 0, false
+	}
+
+	b.ko = noKo
+	if captured == 1 && len(stones) == 1 && len(liberties) == 1 {
+		b.ko = last
+	}
+	return captured, true
+}
+
+// pass clears any ko.
+func (b *board) pass() {
+	b.ko = noKo
+}
+
+// isEye reports whether the empty point at i is an eye of color c: every neighbor is c or off the board, and
+// the opponent holds too few diagonals to make it false. On the edge a single opposing diagonal is enough.
+func (b *board) isEye(c game.Color, i int) bool {
+	if b.cells[i] != game.Empty {
+		return false
+	}
+	edge := false
+	for _, n := range b.neighbors(i) {
+		switch b.cells[n] {
+		case c:
+		case border:
+			edge = true
+		default:
+			return false
+		}
+	}
+
+	opposing := 0
+	for _, d := range b.diagonals(i) {
+		switch b.cells[d] {
+		case c.Opponent():
+			opposing++
+		case border:
+			edge = true
+		}
+	}
+	if edge {
+		return opposing == 0
+	}
+	return opposing <= 1
+}
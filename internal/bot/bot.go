@@ -0,0 +1,32 @@
+// Package bot implements a computer player that needs no external engine. It picks moves with a handful of
+// tactical heuristics on its own compact board representation.
+package bot
+
+import (
+	"github.com/hazzardr/baduk-online/internal/game"
+	"github.com/hazzardr/baduk-online/internal/scoring"
+)
+
+// Position is the game state a player is asked to move in.
+type Position struct {
+	Size int
+	// Setup holds black's handicap stones, which are placed before the first move.
+	Setup []game.Point
+	Moves []game.Move
+	// ToMove is the color the player is choosing a move for.
+	ToMove game.Color
+	// Rules decides whether moves that repeat an earlier position are forbidden as well as simple ko.
+	Rules scoring.Ruleset
+}
+
+// Move is a player's choice: a point to play on, a pass or a resignation.
+type Move struct {
+	Point  game.Point
+	Pass   bool
+	Resign bool
+}
+
+// Player chooses moves.
+type Player interface {
+	NextMove(position Position) Move
+}
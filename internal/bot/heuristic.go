@@ -0,0 +1,157 @@
+package bot
+
+import (
+	"math/rand/v2"
+
+	"github.com/hazzardr/baduk-online/internal/game"
+	"github.com/hazzardr/baduk-online/internal/superko"
+)
+
+// Weights for the tactical features of a candidate move, per stone involved. Ties are broken at random, so
+// a move without any of these features scores between zero and one.
+const (
+	captureWeight = 100
+	escapeWeight  = 50
+	atariWeight   = 10
+)
+
+// Heuristic is a Player that captures what it can, rescues its own groups from atari and puts opposing groups
+// into atari, choosing at random among the remaining legal moves. It never fills its own eyes, plays into
+// self-atari or, under rules with superko, repeats an earlier position, and passes once no other move is
+// left.
+//
+// A Heuristic is deterministic: the same seed and position always produce the same move. It keeps no state
+// between moves and is safe for concurrent use.
+type Heuristic struct {
+	seed uint64
+}
+
+// NewHeuristic returns a heuristic player whose random choices are derived from seed.
+func NewHeuristic(seed uint64) *Heuristic {
+	return &Heuristic{seed: seed}
+}
+
+// NextMove chooses a move for position.ToMove. A position that can't be reconstructed, because it is off
+// size or contains an illegal move, is answered with a pass.
+func (h *Heuristic) NextMove(position Position) Move {
+	b, history, ok := replay(position)
+	if !ok || (position.ToMove != game.Black && position.ToMove != game.White) {
+		return Move{Pass: true}
+	}
+	// Seeding with the move number keeps each choice independent of which positions were asked about before.
+	rng := rand.New(rand.NewPCG(h.seed, uint64(len(position.Moves)))) //nolint:gosec // bots don't need a secure source
+
+	best, bestScore := noKo, -1.0
+	for y := range b.size {
+		for x := range b.size {
+			i := b.index(game.Point{X: x, Y: y})
+			score, ok := evaluate(b, position.ToMove, i)
+			if !ok || repeats(history, position.ToMove, b.point(i)) {
+				continue
+			}
+			score += rng.Float64()
+			if score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+	}
+	if best == noKo {
+		return Move{Pass: true}
+	}
+	return Move{Point: b.point(best)}
+}
+
+// evaluate scores playing c at i, returning false if the move is illegal or one the player should never make.
+func evaluate(b *board, c game.Color, i int) (float64, bool) {
+	if b.cells[i] != game.Empty || b.isEye(c, i) {
+		return 0, false
+	}
+
+	// Own stones in atari that this move would connect to.
+	endangered := 0
+	for _, n := range b.neighbors(i) {
+		if b.cells[n] != c {
+			continue
+		}
+		stones, liberties := b.group(n)
+		if len(liberties) == 1 {
+			endangered += len(stones)
+		}
+	}
+
+	after := b.clone()
+	captured, ok := after.play(c, i)
+	if !ok {
+		return 0, false
+	}
+	_, liberties := after.group(i)
+	if len(liberties) == 1 && captured == 0 {
+		return 0, false
+	}
+
+	score := float64(captureWeight * captured)
+	if len(liberties) > 1 {
+		score += float64(escapeWeight * endangered)
+	}
+	for _, n := range after.neighbors(i) {
+		if after.cells[n] != c.Opponent() {
+			continue
+		}
+		stones, liberties := after.group(n)
+		if len(liberties) == 1 {
+			// A group touching the move at several points is counted once per point, which only strengthens
+			// a move that is already an atari.
+			score += float64(atariWeight * len(stones))
+		}
+	}
+	return score, true
+}
+
+// repeats reports whether c playing at p recreates an earlier position. It is always false without a history.
+func repeats(history *superko.History, c game.Color, p game.Point) bool {
+	return history != nil && history.Check(superko.Color(c), superko.Point(p)) != nil
+}
+
+// replay builds the board for a position, and the history of earlier positions if its rules have superko. It
+// returns false if the position is invalid.
+func replay(position Position) (*board, *superko.History, bool) {
+	if position.Size < game.MinBoardSize || position.Size > game.MaxBoardSize {
+		return nil, nil, false
+	}
+	b := newBoard(position.Size)
+	var history *superko.History
+	if rule, ok := superko.ForRuleset(position.Rules); ok {
+		history, _ = superko.New(position.Size, rule, false)
+	}
+	for _, p := range position.Setup {
+		if !b.onBoard(p) || b.cells[b.index(p)] != game.Empty {
+			return nil, nil, false
+		}
+		b.cells[b.index(p)] = game.Black
+		if history != nil && history.Setup(superko.Black, superko.Point(p)) != nil {
+			return nil, nil, false
+		}
+	}
+	if history != nil && len(position.Setup) > 0 && history.SetToMove(superko.White) != nil {
+		return nil, nil, false
+	}
+	for _, m := range position.Moves {
+		if m.Pass {
+			b.pass()
+			if history != nil && history.Pass(superko.Color(m.Color)) != nil {
+				return nil, nil, false
+			}
+			continue
+		}
+		if !b.onBoard(m.Point) || (m.Color != game.Black && m.Color != game.White) {
+			return nil, nil, false
+		}
+		if _, ok := b.play(m.Color, b.index(m.Point)); !ok {
+			return nil, nil, false
+		}
+		if history != nil && history.Play(superko.Color(m.Color), superko.Point(m.Point)) != nil {
+			return nil, nil, false
+		}
+	}
+	return b, history, true
+}
@@ -0,0 +1,224 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hazzardr/baduk-online/internal/game"
+	"github.com/hazzardr/baduk-online/internal/scoring"
+)
+
+// positionFromDiagram builds a position from rows of X (black), O (white) and . (empty), playing every stone
+// as a move.
+func positionFromDiagram(diagram string, toMove game.Color) Position {
+	rows := strings.Fields(diagram)
+	position := Position{Size: len(rows), ToMove: toMove}
+	for y, row := range rows {
+		for x, ch := range row {
+			switch ch {
+			case 'X':
+				position.Moves = append(position.Moves, game.Move{Color: game.Black, Point: game.Point{X: x, Y: y}})
+			case 'O':
+				position.Moves = append(position.Moves, game.Move{Color: game.White, Point: game.Point{X: x, Y: y}})
+			}
+		}
+	}
+	return position
+}
+
+func TestHeuristicNextMove(t *testing.T) {
+	tests := []struct {
+		name     string
+		diagram  string
+		toMove   game.Color
+		want     []Move
+		wantPass bool
+	}{
+		{
+			name: "Captures a stone in atari",
+			diagram: `
+				.....
+				..X..
+				.XO..
+				..X..
+				.....`,
+			toMove: game.Black,
+			want:   []Move{{Point: game.Point{X: 3, Y: 2}}},
+		},
+		{
+			name: "Escapes atari",
+			diagram: `
+				.....
+				..O..
+				.OX..
+				..O..
+				.....`,
+			toMove: game.Black,
+			want:   []Move{{Point: game.Point{X: 3, Y: 2}}},
+		},
+		{
+			name: "Avoids self-atari",
+			diagram: `
+				...
+				OOO
+				...`,
+			toMove: game.Black,
+			want:   []Move{{Point: game.Point{X: 1, Y: 0}}, {Point: game.Point{X: 1, Y: 2}}},
+		},
+		{
+			name: "Passes rather than fill its own eyes",
+			diagram: `
+				X.X
+				XXX
+				X.X`,
+			toMove: game.Black,
+			want:   []Move{{Pass: true}},
+		},
+		{
+			name: "Passes without a legal move",
+			diagram: `
+				X.X
+				XXX
+				X.X`,
+			toMove: game.White,
+			want:   []Move{{Pass: true}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			position := positionFromDiagram(tt.diagram, tt.toMove)
+			for seed := range uint64(10) {
+				got := NewHeuristic(seed).NextMove(position)
+				found := false
+				for _, want := range tt.want {
+					if got == want {
+						found = true
+					}
+				}
+				if !found {
+					t.Fatalf("seed %d: expected one of %+v, got %+v", seed, tt.want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestHeuristicSuperko(t *testing.T) {
+	// Black captures a white stone in a ko at (2, 1), and both players pass. Retaking at (1, 1) is the only
+	// capture on the board, and recreates the position before black took the ko.
+	points := []game.Point{
+		{X: 1, Y: 0}, {X: 2, Y: 0}, {X: 0, Y: 1}, {X: 3, Y: 1}, {X: 1, Y: 2}, {X: 2, Y: 2}, {X: 5, Y: 5}, {X: 1, Y: 1},
+		{X: 2, Y: 1},
+	}
+	var moves []game.Move
+	for i, p := range points {
+		moves = append(moves, game.Move{Color: []game.Color{game.Black, game.White}[i%2], Point: p})
+	}
+	moves = append(moves, game.Move{Color: game.White, Pass: true}, game.Move{Color: game.Black, Pass: true})
+	retake := Move{Point: game.Point{X: 1, Y: 1}}
+
+	tests := []struct {
+		rules      scoring.Ruleset
+		wantRetake bool
+	}{
+		{scoring.Japanese, true},
+		{scoring.Korean, true},
+		{scoring.Chinese, false},
+		{scoring.AGA, false},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.rules), func(t *testing.T) {
+			got := NewHeuristic(1).NextMove(Position{Size: 9, Moves: moves, ToMove: game.White, Rules: tt.rules})
+			if (got == retake) != tt.wantRetake {
+				t.Errorf("NextMove() = %+v, want retake %t", got, tt.wantRetake)
+			}
+			if got.Pass {
+				t.Errorf("expected another move to be chosen, got a pass")
+			}
+		})
+	}
+}
+
+func TestHeuristicDeterminism(t *testing.T) {
+	position := Position{Size: 9, ToMove: game.Black}
+
+	seen := make(map[Move]bool)
+	for seed := range uint64(10) {
+		first := NewHeuristic(seed).NextMove(position)
+		if again := NewHeuristic(seed).NextMove(position); again != first {
+			t.Fatalf("seed %d: expected %+v again, got %+v", seed, first, again)
+		}
+		seen[first] = true
+	}
+	if len(seen) < 2 {
+		t.Errorf("expected different seeds to choose different moves, got %v", seen)
+	}
+}
+
+func TestHeuristicPlaysOutGame(t *testing.T) {
+	b, err := game.NewBoard(9)
+	if err != nil {
+		t.Fatalf("failed to create board: %s", err)
+	}
+	black, white := NewHeuristic(1), NewHeuristic(2)
+	position := Position{Size: 9, Setup: []game.Point{{X: 2, Y: 6}, {X: 6, Y: 2}}, ToMove: game.White}
+	for _, p := range position.Setup {
+		if err := b.Setup(game.Black, p); err != nil {
+			t.Fatalf("failed to set up handicap: %s", err)
+		}
+	}
+
+	passes := 0
+	for range 1000 {
+		player := black
+		if position.ToMove == game.White {
+			player = white
+		}
+		move := player.NextMove(position)
+		if move.Resign {
+			t.Fatalf("unexpected resignation")
+		}
+		if move.Pass {
+			passes++
+			if passes == 2 {
+				return
+			}
+			if err := b.Pass(position.ToMove); err != nil {
+				t.Fatalf("failed to pass: %s", err)
+			}
+		} else {
+			passes = 0
+			if _, err := b.Play(position.ToMove, move.Point); err != nil {
+				t.Fatalf("%s played illegal move %+v: %s\n%s", position.ToMove, move.Point, err, b)
+			}
+		}
+		position.Moves = append(position.Moves, game.Move{Color: position.ToMove, Point: move.Point, Pass: move.Pass})
+		position.ToMove = position.ToMove.Opponent()
+	}
+	t.Fatalf("expected the game to end with two passes\n%s", b)
+}
+
+func TestHeuristicInvalidPosition(t *testing.T) {
+	tests := []struct {
+		name     string
+		position Position
+	}{
+		{"Unsupported size", Position{Size: 1, ToMove: game.Black}},
+		{"No color to move", Position{Size: 9}},
+		{"Setup off the board", Position{Size: 9, Setup: []game.Point{{X: 9, Y: 0}}, ToMove: game.Black}},
+		{"Occupied point", Position{Size: 9, ToMove: game.Black, Moves: []game.Move{
+			{Color: game.Black, Point: game.Point{X: 4, Y: 4}},
+			{Color: game.White, Point: game.Point{X: 4, Y: 4}},
+		}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewHeuristic(1).NextMove(tt.position)
+			if !got.Pass {
+				t.Errorf("expected a pass, got %+v", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+// Package clock implements server-authoritative game clocks. Time is only ever advanced by timestamps taken
+// from a Clock on the server, never by clients, so that a slow or dishonest client cannot gain time.
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is a source of the current time.
+type Clock interface {
+	Now() time.Time
+}
+
+// Real is a Clock that reads the system time.
+type Real struct{}
+
+// Now returns the current system time.
+func (Real) Now() time.Time {
+	return time.Now()
+}
+
+// Fake is a Clock that only moves when told to, for deterministic tests. It is safe for concurrent use.
+type Fake struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewFake returns a Fake clock stopped at the given time.
+func NewFake(now time.Time) *Fake {
+	return &Fake{now: now}
+}
+
+// Now returns the fake clock's current time.
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// Advance moves the fake clock forward by d.
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+}
+
+// Set moves the fake clock to the given time.
+func (f *Fake) Set(now time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = now
+}
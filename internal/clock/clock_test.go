@@ -0,0 +1,286 @@
+package clock
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hazzardr/baduk-online/internal/game"
+	"github.com/hazzardr/baduk-online/internal/validator"
+)
+
+var start = time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// step is one press of the clock by the player to move after they have thought for the given time.
+type step struct {
+	think   time.Duration
+	wantErr error
+}
+
+func TestPress(t *testing.T) {
+	tests := []struct {
+		name  string
+		tc    TimeControl
+		steps []step
+		// want is black's time after the last step.
+		want PlayerTime
+	}{
+		{
+			name:  "Absolute deducts time",
+			tc:    TimeControl{Kind: Absolute, MainTime: time.Minute},
+			steps: []step{{think: 10 * time.Second}, {think: 5 * time.Second}, {think: 20 * time.Second}},
+			want:  PlayerTime{Main: 30 * time.Second},
+		},
+		{
+			name:  "Absolute flag falls",
+			tc:    TimeControl{Kind: Absolute, MainTime: time.Minute},
+			steps: []step{{think: time.Minute, wantErr: ErrTimeout}},
+			want:  PlayerTime{},
+		},
+		{
+			name:  "Fischer adds increment",
+			tc:    TimeControl{Kind: Fischer, MainTime: time.Minute, Increment: 10 * time.Second},
+			steps: []step{{think: 5 * time.Second}, {think: time.Second}, {think: 30 * time.Second}},
+			want:  PlayerTime{Main: 45 * time.Second},
+		},
+		{
+			name: "Fischer capped at max time",
+			tc: TimeControl{
+				Kind: Fischer, MainTime: time.Minute, Increment: 10 * time.Second, MaxTime: 65 * time.Second,
+			},
+			steps: []step{{think: time.Second}, {think: time.Second}, {think: time.Second}},
+			want:  PlayerTime{Main: 65 * time.Second},
+		},
+		{
+			name: "Fischer increment not added after flag",
+			tc:   TimeControl{Kind: Fischer, MainTime: time.Minute, Increment: 10 * time.Second},
+			steps: []step{
+				{think: 50 * time.Second}, {think: time.Second}, {think: 20 * time.Second, wantErr: ErrTimeout},
+			},
+			want: PlayerTime{},
+		},
+		{
+			name:  "Byo-yomi period kept when moving in time",
+			tc:    TimeControl{Kind: ByoYomi, MainTime: time.Minute, Periods: 3, PeriodTime: 30 * time.Second},
+			steps: []step{{think: 80 * time.Second}},
+			want:  PlayerTime{Periods: 3, Period: 30 * time.Second},
+		},
+		{
+			name: "Byo-yomi periods used up",
+			tc:   TimeControl{Kind: ByoYomi, MainTime: time.Minute, Periods: 3, PeriodTime: 30 * time.Second},
+			// 60s of main time, then two full periods and 10s into the third.
+			steps: []step{{think: 130 * time.Second}},
+			want:  PlayerTime{Periods: 1, Period: 30 * time.Second},
+		},
+		{
+			name:  "Byo-yomi exactly a period uses it",
+			tc:    TimeControl{Kind: ByoYomi, Periods: 2, PeriodTime: 30 * time.Second},
+			steps: []step{{think: 30 * time.Second}},
+			want:  PlayerTime{Periods: 1, Period: 30 * time.Second},
+		},
+		{
+			name:  "Byo-yomi flag falls on last period",
+			tc:    TimeControl{Kind: ByoYomi, Periods: 2, PeriodTime: 30 * time.Second},
+			steps: []step{{think: 30 * time.Second}, {think: time.Second}, {think: 30 * time.Second, wantErr: ErrTimeout}},
+			want:  PlayerTime{},
+		},
+		{
+			name:  "Canadian counts down stones",
+			tc:    TimeControl{Kind: Canadian, MainTime: time.Minute, PeriodTime: 5 * time.Minute, Stones: 10},
+			steps: []step{{think: 70 * time.Second}, {think: time.Second}, {think: 20 * time.Second}},
+			want:  PlayerTime{Period: 270 * time.Second, Stones: 8},
+		},
+		{
+			name: "Canadian period resets after stones played",
+			tc:   TimeControl{Kind: Canadian, PeriodTime: time.Minute, Stones: 2},
+			steps: []step{
+				{think: 20 * time.Second}, {think: time.Second}, {think: 30 * time.Second},
+				{think: time.Second}, {think: 5 * time.Second},
+			},
+			want: PlayerTime{Period: 55 * time.Second, Stones: 1},
+		},
+		{
+			name:  "Canadian flag falls before stones played",
+			tc:    TimeControl{Kind: Canadian, PeriodTime: time.Minute, Stones: 2},
+			steps: []step{{think: 40 * time.Second}, {think: time.Second}, {think: 20 * time.Second, wantErr: ErrTimeout}},
+			want:  PlayerTime{Stones: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clk := NewFake(start)
+			s := New(tt.tc)
+			if err := s.Start(clk.Now(), game.Black); err != nil {
+				t.Fatalf("Start() error = %v", err)
+			}
+			for i, st := range tt.steps {
+				color := s.ToMove
+				clk.Advance(st.think)
+				err := s.Press(clk.Now(), color)
+				if !errors.Is(err, st.wantErr) {
+					t.Fatalf("step %d: Press() error = %v, want %v", i, err, st.wantErr)
+				}
+			}
+			if s.Black != tt.want {
+				t.Errorf("black time = %+v, want %+v", s.Black, tt.want)
+			}
+		})
+	}
+}
+
+func TestPressErrors(t *testing.T) {
+	s := New(TimeControl{Kind: Absolute, MainTime: time.Minute})
+	if err := s.Press(start, game.Black); !errors.Is(err, ErrNotRunning) {
+		t.Errorf("Press() before start error = %v, want %v", err, ErrNotRunning)
+	}
+	if err := s.Start(start, game.Black); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if err := s.Start(start, game.Black); !errors.Is(err, ErrAlreadyStarted) {
+		t.Errorf("Start() twice error = %v, want %v", err, ErrAlreadyStarted)
+	}
+	if err := s.Press(start, game.White); !errors.Is(err, ErrNotToMove) {
+		t.Errorf("Press() out of turn error = %v, want %v", err, ErrNotToMove)
+	}
+
+	// A clock stepping backwards must not give time back.
+	if err := s.Press(start.Add(-time.Hour), game.Black); err != nil {
+		t.Fatalf("Press() error = %v", err)
+	}
+	if s.Black.Main != time.Minute {
+		t.Errorf("black time = %v, want %v", s.Black.Main, time.Minute)
+	}
+}
+
+func TestDeadlineAndTimeout(t *testing.T) {
+	tests := []struct {
+		name string
+		tc   TimeControl
+		want time.Duration
+	}{
+		{name: "Absolute", tc: TimeControl{Kind: Absolute, MainTime: time.Minute}, want: time.Minute},
+		{
+			name: "Byo-yomi",
+			tc:   TimeControl{Kind: ByoYomi, MainTime: time.Minute, Periods: 3, PeriodTime: 10 * time.Second},
+			want: 90 * time.Second,
+		},
+		{
+			name: "Canadian",
+			tc:   TimeControl{Kind: Canadian, MainTime: time.Minute, PeriodTime: 5 * time.Minute, Stones: 10},
+			want: 6 * time.Minute,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clk := NewFake(start)
+			s := New(tt.tc)
+			if _, ok := s.Deadline(); ok {
+				t.Error("Deadline() of stopped clock ok = true")
+			}
+			_ = s.Start(clk.Now(), game.White)
+
+			deadline, ok := s.Deadline()
+			if !ok || !deadline.Equal(start.Add(tt.want)) {
+				t.Errorf("Deadline() = %v, %v, want %v", deadline, ok, start.Add(tt.want))
+			}
+
+			clk.Advance(tt.want - time.Millisecond)
+			if _, ok := s.CheckTimeout(clk.Now()); ok {
+				t.Fatal("CheckTimeout() before deadline ok = true")
+			}
+			clk.Advance(time.Millisecond)
+			color, ok := s.CheckTimeout(clk.Now())
+			if !ok || color != game.White {
+				t.Errorf("CheckTimeout() = %v, %v, want white, true", color, ok)
+			}
+			if s.Running || s.Flagged != game.White {
+				t.Errorf("clock after timeout running = %v, flagged = %v", s.Running, s.Flagged)
+			}
+		})
+	}
+}
+
+func TestRemainingDoesNotModify(t *testing.T) {
+	s := New(TimeControl{Kind: ByoYomi, MainTime: time.Minute, Periods: 3, PeriodTime: 30 * time.Second})
+	_ = s.Start(start, game.Black)
+
+	got := s.Remaining(start.Add(100*time.Second), game.Black)
+	want := PlayerTime{Periods: 2, Period: 20 * time.Second}
+	if got != want {
+		t.Errorf("Remaining() = %+v, want %+v", got, want)
+	}
+	if white := s.Remaining(start.Add(100*time.Second), game.White); white.Main != time.Minute {
+		t.Errorf("Remaining() of waiting player = %+v", white)
+	}
+	if s.Black.Main != time.Minute {
+		t.Errorf("Remaining() modified the clock: %+v", s.Black)
+	}
+}
+
+func TestStateJSON(t *testing.T) {
+	s := New(TimeControl{Kind: Canadian, MainTime: 10 * time.Minute, PeriodTime: 5 * time.Minute, Stones: 15})
+	_ = s.Start(start, game.Black)
+	_ = s.Press(start.Add(1500*time.Millisecond), game.Black)
+
+	b, err := json.Marshal(s)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	var got State
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if got != *s {
+		t.Errorf("round trip = %+v, want %+v", got, *s)
+	}
+
+	var fields map[string]any
+	_ = json.Unmarshal(b, &fields)
+	if fields["to_move"] != "white" {
+		t.Errorf("to_move = %v, want white", fields["to_move"])
+	}
+	if _, ok := fields["flagged"]; ok {
+		t.Error("flagged should be omitted when no one has run out of time")
+	}
+}
+
+func TestValidateTimeControl(t *testing.T) {
+	tests := []struct {
+		name      string
+		tc        TimeControl
+		wantField string
+	}{
+		{name: "Valid absolute", tc: TimeControl{Kind: Absolute, MainTime: time.Hour}},
+		{name: "Valid byo-yomi without main time", tc: TimeControl{Kind: ByoYomi, Periods: 5, PeriodTime: time.Minute}},
+		{name: "Unknown kind", tc: TimeControl{Kind: "hourglass", MainTime: time.Hour}, wantField: "time_control"},
+		{name: "Absolute without time", tc: TimeControl{Kind: Absolute}, wantField: "main_time"},
+		{name: "Fischer without increment", tc: TimeControl{Kind: Fischer, MainTime: time.Hour}, wantField: "increment"},
+		{
+			name:      "Fischer max below main",
+			tc:        TimeControl{Kind: Fischer, MainTime: time.Hour, Increment: time.Minute, MaxTime: time.Minute},
+			wantField: "max_time",
+		},
+		{name: "Byo-yomi without periods", tc: TimeControl{Kind: ByoYomi, PeriodTime: time.Minute}, wantField: "periods"},
+		{name: "Canadian without stones", tc: TimeControl{Kind: Canadian, PeriodTime: time.Minute}, wantField: "stones"},
+		{name: "Main time too long", tc: TimeControl{Kind: Absolute, MainTime: 8 * 24 * time.Hour}, wantField: "main_time"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			ValidateTimeControl(v, tt.tc)
+			if tt.wantField == "" {
+				if !v.Valid() {
+					t.Errorf("unexpected errors: %v", v.Errors)
+				}
+				return
+			}
+			if _, ok := v.Errors[tt.wantField]; !ok {
+				t.Errorf("expected error for %s, got %v", tt.wantField, v.Errors)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package clock
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/hazzardr/baduk-online/internal/validator"
+)
+
+// Kind is a system of time control.
+type Kind string
+
+const (
+	// Absolute gives each player a fixed amount of time for the whole game.
+	Absolute Kind = "absolute"
+	// Fischer adds an increment to a player's time after each of their moves.
+	Fischer Kind = "fischer"
+	// ByoYomi follows main time with a number of periods. A period is only used up if a move takes longer
+	// than it.
+	ByoYomi Kind = "byoyomi"
+	// Canadian follows main time with periods in which a number of stones must be played.
+	Canadian Kind = "canadian"
+)
+
+// Kinds lists every supported kind of time control.
+var Kinds = []Kind{Absolute, Fischer, ByoYomi, Canadian}
+
+// MaxMainTime is the longest main time that may be given to a player.
+const MaxMainTime = 7 * 24 * time.Hour
+
+// TimeControl describes the time each player is given. Only the fields relevant to Kind are used.
+type TimeControl struct {
+	Kind     Kind
+	MainTime time.Duration
+	// Increment is added after each move under Fischer time.
+	Increment time.Duration
+	// MaxTime caps a player's time under Fischer time. Zero means no cap.
+	MaxTime time.Duration
+	// Periods is the number of byo-yomi periods.
+	Periods int
+	// PeriodTime is the length of a byo-yomi or Canadian period.
+	PeriodTime time.Duration
+	// Stones is the number of moves to be played in each Canadian period.
+	Stones int
+}
+
+// ValidateTimeControl checks that a time control is complete and within sensible limits.
+func ValidateTimeControl(v *validator.Validator, tc TimeControl) {
+	v.Check(validator.PermittedValue(tc.Kind, Kinds...), "time_control",
+		"must be one of absolute, fischer, byoyomi or canadian")
+	v.Check(tc.MainTime >= 0 && tc.MainTime <= MaxMainTime, "main_time", "must be between 0 and 7 days")
+
+	switch tc.Kind {
+	case Absolute:
+		v.Check(tc.MainTime > 0, "main_time", "must be greater than zero")
+	case Fischer:
+		v.Check(tc.MainTime > 0, "main_time", "must be greater than zero")
+		v.Check(tc.Increment > 0, "increment", "must be greater than zero")
+		v.Check(tc.MaxTime == 0 || tc.MaxTime >= tc.MainTime, "max_time", "must not be less than the main time")
+	case ByoYomi:
+		v.Check(tc.Periods > 0 && tc.Periods <= 30, "periods", "must be between 1 and 30")
+		v.Check(tc.PeriodTime > 0, "period_time", "must be greater than zero")
+	case Canadian:
+		v.Check(tc.Stones > 0 && tc.Stones <= 100, "stones", "must be between 1 and 100")
+		v.Check(tc.PeriodTime > 0, "period_time", "must be greater than zero")
+	}
+}
+
+// timeControlJSON is the stored form of a TimeControl, with durations in milliseconds.
+type timeControlJSON struct {
+	Kind       Kind  `json:"kind"`
+	MainTime   int64 `json:"main_time_ms"`
+	Increment  int64 `json:"increment_ms,omitempty"`
+	MaxTime    int64 `json:"max_time_ms,omitempty"`
+	Periods    int   `json:"periods,omitempty"`
+	PeriodTime int64 `json:"period_time_ms,omitempty"`
+	Stones     int   `json:"stones,omitempty"`
+}
+
+// MarshalJSON encodes the time control with durations in milliseconds.
+func (tc TimeControl) MarshalJSON() ([]byte, error) {
+	return json.Marshal(timeControlJSON{
+		Kind:       tc.Kind,
+		MainTime:   tc.MainTime.Milliseconds(),
+		Increment:  tc.Increment.Milliseconds(),
+		MaxTime:    tc.MaxTime.Milliseconds(),
+		Periods:    tc.Periods,
+		PeriodTime: tc.PeriodTime.Milliseconds(),
+		Stones:     tc.Stones,
+	})
+}
+
+// UnmarshalJSON decodes a time control with durations in milliseconds.
+func (tc *TimeControl) UnmarshalJSON(b []byte) error {
+	var j timeControlJSON
+	if err := json.Unmarshal(b, &j); err != nil {
+		return err
+	}
+	*tc = TimeControl{
+		Kind:       j.Kind,
+		MainTime:   time.Duration(j.MainTime) * time.Millisecond,
+		Increment:  time.Duration(j.Increment) * time.Millisecond,
+		MaxTime:    time.Duration(j.MaxTime) * time.Millisecond,
+		Periods:    j.Periods,
+		PeriodTime: time.Duration(j.PeriodTime) * time.Millisecond,
+		Stones:     j.Stones,
+	}
+	return nil
+}
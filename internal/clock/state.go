@@ -0,0 +1,292 @@
+package clock
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/hazzardr/baduk-online/internal/game"
+)
+
+var (
+	// ErrNotRunning is returned when pressing a clock that is stopped.
+	ErrNotRunning = errors.New("clock is not running")
+	// ErrAlreadyStarted is returned when starting a clock that has already been started.
+	ErrAlreadyStarted = errors.New("clock has already been started")
+	// ErrNotToMove is returned when a player presses the clock while it is their opponent's turn.
+	ErrNotToMove = errors.New("it is not this player's turn")
+	// ErrTimeout is returned when a player's time ran out before they pressed the clock.
+	ErrTimeout = errors.New("player ran out of time")
+)
+
+// PlayerTime is the time a player has left.
+type PlayerTime struct {
+	// Main is the remaining main time. Once it reaches zero the player is in overtime, if there is any.
+	Main time.Duration
+	// Periods is the number of byo-yomi periods left, including the current one.
+	Periods int
+	// Period is the time left in the current byo-yomi or Canadian period.
+	Period time.Duration
+	// Stones is the number of moves left to play in the current Canadian period.
+	Stones int
+}
+
+// State is the clock of a single game. Its methods take the current time rather than reading it so that
+// the caller decides which Clock is authoritative.
+type State struct {
+	Control TimeControl
+	Black   PlayerTime
+	White   PlayerTime
+	// ToMove is the player whose time is running, or would be if the clock were running.
+	ToMove  game.Color
+	Running bool
+	// UpdatedAt is when the time of the player to move was last brought up to date.
+	UpdatedAt time.Time
+	// Flagged is the player who ran out of time, if any.
+	Flagged game.Color
+}
+
+// New returns a stopped clock giving both players their full time under the given control.
+func New(tc TimeControl) *State {
+	initial := PlayerTime{Main: tc.MainTime}
+	switch tc.Kind {
+	case ByoYomi:
+		initial.Periods = tc.Periods
+		initial.Period = tc.PeriodTime
+	case Canadian:
+		initial.Period = tc.PeriodTime
+		initial.Stones = tc.Stones
+	}
+	return &State{
+		Control: tc,
+		Black:   initial,
+		White:   initial,
+		ToMove:  game.Black,
+	}
+}
+
+// Start runs the clock of the given player from now. It returns ErrAlreadyStarted if the clock is running or
+// a player has already run out of time.
+func (s *State) Start(now time.Time, toMove game.Color) error {
+	if s.Running || s.Flagged != game.Empty {
+		return ErrAlreadyStarted
+	}
+	if toMove != game.Black && toMove != game.White {
+		return game.ErrInvalidColor
+	}
+	s.ToMove = toMove
+	s.Running = true
+	s.UpdatedAt = now
+	return nil
+}
+
+// Press ends the turn of the given player at now, charging them for the time they took and starting their
+// opponent's clock. If they ran out of time first, the clock is stopped, Flagged is set and ErrTimeout is
+// returned.
+func (s *State) Press(now time.Time, color game.Color) error {
+	if !s.Running {
+		return ErrNotRunning
+	}
+	if color != s.ToMove {
+		return ErrNotToMove
+	}
+
+	p := s.player(color)
+	flagged := s.deduct(p, s.elapsed(now))
+	s.UpdatedAt = now
+	if flagged {
+		s.Running = false
+		s.Flagged = color
+		return ErrTimeout
+	}
+	s.completeMove(p)
+	s.ToMove = color.Opponent()
+	return nil
+}
+
+// Stop pauses the clock at now, charging the player to move for the time they have taken so far. It
+// returns ErrTimeout if they had already run out of time.
+func (s *State) Stop(now time.Time) error {
+	if !s.Running {
+		return ErrNotRunning
+	}
+	flagged := s.deduct(s.player(s.ToMove), s.elapsed(now))
+	s.UpdatedAt = now
+	s.Running = false
+	if flagged {
+		s.Flagged = s.ToMove
+		return ErrTimeout
+	}
+	return nil
+}
+
+// Remaining returns the time the given player would have left at now, without changing the clock.
+func (s *State) Remaining(now time.Time, color game.Color) PlayerTime {
+	p := *s.player(color)
+	if s.Running && color == s.ToMove {
+		s.deduct(&p, s.elapsed(now))
+	}
+	return p
+}
+
+// Deadline returns when the player to move will run out of time if they don't move. It returns false if the
+// clock is not running.
+func (s *State) Deadline() (time.Time, bool) {
+	if !s.Running {
+		return time.Time{}, false
+	}
+	p := s.player(s.ToMove)
+	left := p.Main
+	switch s.Control.Kind {
+	case ByoYomi:
+		if p.Periods > 0 {
+			left += p.Period + time.Duration(p.Periods-1)*s.Control.PeriodTime
+		}
+	case Canadian:
+		left += p.Period
+	}
+	return s.UpdatedAt.Add(left), true
+}
+
+// CheckTimeout stops the clock and returns the player to move if their time has run out at now.
+func (s *State) CheckTimeout(now time.Time) (game.Color, bool) {
+	deadline, ok := s.Deadline()
+	if !ok || now.Before(deadline) {
+		return game.Empty, false
+	}
+	if err := s.Stop(now); !errors.Is(err, ErrTimeout) {
+		return game.Empty, false
+	}
+	return s.Flagged, true
+}
+
+func (s *State) player(color game.Color) *PlayerTime {
+	if color == game.White {
+		return &s.White
+	}
+	return &s.Black
+}
+
+// elapsed returns the time since the clock was last updated. A server clock stepping backwards must never
+// give a player time back.
+func (s *State) elapsed(now time.Time) time.Duration {
+	return max(now.Sub(s.UpdatedAt), 0)
+}
+
+// deduct charges a player for elapsed time, moving them into overtime as needed. It reports whether they
+// ran out of time.
+func (s *State) deduct(p *PlayerTime, elapsed time.Duration) bool {
+	if elapsed < p.Main {
+		p.Main -= elapsed
+		return false
+	}
+	elapsed -= p.Main
+	p.Main = 0
+
+	switch s.Control.Kind {
+	case ByoYomi:
+		for p.Periods > 0 && elapsed >= p.Period {
+			elapsed -= p.Period
+			p.Periods--
+			p.Period = s.Control.PeriodTime
+		}
+		if p.Periods == 0 {
+			p.Period = 0
+			return true
+		}
+		p.Period -= elapsed
+		return false
+	case Canadian:
+		if elapsed >= p.Period {
+			p.Period = 0
+			return true
+		}
+		p.Period -= elapsed
+		return false
+	default:
+		return true
+	}
+}
+
+// completeMove applies the effects of a move made in time: an increment, or the reset of an overtime
+// period.
+func (s *State) completeMove(p *PlayerTime) {
+	switch s.Control.Kind {
+	case Fischer:
+		p.Main += s.Control.Increment
+		if s.Control.MaxTime > 0 {
+			p.Main = min(p.Main, s.Control.MaxTime)
+		}
+	case ByoYomi:
+		if p.Main == 0 {
+			p.Period = s.Control.PeriodTime
+		}
+	case Canadian:
+		if p.Main == 0 {
+			p.Stones--
+			if p.Stones <= 0 {
+				p.Stones = s.Control.Stones
+				p.Period = s.Control.PeriodTime
+			}
+		}
+	}
+}
+
+// playerTimeJSON is the stored form of a PlayerTime, with durations in milliseconds.
+type playerTimeJSON struct {
+	Main    int64 `json:"main_ms"`
+	Periods int   `json:"periods,omitempty"`
+	Period  int64 `json:"period_ms,omitempty"`
+	Stones  int   `json:"stones,omitempty"`
+}
+
+// MarshalJSON encodes the player's time with durations in milliseconds.
+func (p PlayerTime) MarshalJSON() ([]byte, error) {
+	return json.Marshal(playerTimeJSON{
+		Main:    p.Main.Milliseconds(),
+		Periods: p.Periods,
+		Period:  p.Period.Milliseconds(),
+		Stones:  p.Stones,
+	})
+}
+
+// UnmarshalJSON decodes a player's time with durations in milliseconds.
+func (p *PlayerTime) UnmarshalJSON(b []byte) error {
+	var j playerTimeJSON
+	if err := json.Unmarshal(b, &j); err != nil {
+		return err
+	}
+	*p = PlayerTime{
+		Main:    time.Duration(j.Main) * time.Millisecond,
+		Periods: j.Periods,
+		Period:  time.Duration(j.Period) * time.Millisecond,
+		Stones:  j.Stones,
+	}
+	return nil
+}
+
+// stateJSON is the stored form of a State. It is suitable for a jsonb column.
+type stateJSON struct {
+	Control   TimeControl `json:"control"`
+	Black     PlayerTime  `json:"black"`
+	White     PlayerTime  `json:"white"`
+	ToMove    game.Color  `json:"to_move"`
+	Running   bool        `json:"running"`
+	UpdatedAt time.Time   `json:"updated_at"`
+	Flagged   game.Color  `json:"flagged,omitempty"`
+}
+
+// MarshalJSON encodes the clock for storage.
+func (s *State) MarshalJSON() ([]byte, error) {
+	return json.Marshal(stateJSON(*s))
+}
+
+// UnmarshalJSON decodes a stored clock.
+func (s *State) UnmarshalJSON(b []byte) error {
+	var j stateJSON
+	if err := json.Unmarshal(b, &j); err != nil {
+		return err
+	}
+	*s = State(j)
+	return nil
+}
@@ -0,0 +1,187 @@
+package data
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/hazzardr/baduk-online/internal/validator"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	// APIScopeRead allows a personal API token to make requests that don't change anything.
+	APIScopeRead = "read"
+	// APIScopeWrite allows a personal API token to make requests that change things, such as playing moves.
+	APIScopeWrite = "write"
+
+	// MaxAPITokenNameLength is the longest name a user may give an API token, in characters.
+	MaxAPITokenNameLength = 100
+	// MaxAPITokenTTL is the longest an API token may be valid for.
+	MaxAPITokenTTL = 365 * 24 * time.Hour
+)
+
+// APIScopes are the scopes an API token may be given.
+var APIScopes = []string{APIScopeRead, APIScopeWrite}
+
+// APIToken is a long-lived token a user creates to call the API from scripts and bots without a session.
+// Only its hash is stored, so the plaintext is only known when it is created.
+type APIToken struct {
+	ID         int64      `json:"id"`
+	Plaintext  string     `json:"token,omitempty"`
+	Hash       []byte     `json:"-"`
+	UserID     int64      `json:"-"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	Expiry     time.Time  `json:"expiry"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+// HasScope reports whether the token was given a scope.
+func (t *APIToken) HasScope(scope string) bool {
+	return slices.Contains(t.Scopes, scope)
+}
+
+// ValidateAPIToken checks the name, scopes and expiry a user asked for when creating an API token.
+func ValidateAPIToken(v *validator.Validator, t *APIToken, now time.Time) {
+	v.Check(strings.TrimSpace(t.Name) != "", "name", "must be provided")
+	v.Check(utf8.RuneCountInString(t.Name) <= MaxAPITokenNameLength, "name", "must not be more than 100 characters long")
+
+	v.Check(len(t.Scopes) > 0, "scopes", "must contain at least one scope")
+	v.Check(validator.Unique(t.Scopes), "scopes", "must not contain duplicate values")
+	for _, scope := range t.Scopes {
+		v.Check(validator.PermittedValue(scope, APIScopes...), "scopes", "must only contain read or write")
+	}
+
+	v.Check(t.Expiry.After(now), "expiry", "must be in the future")
+	v.Check(!t.Expiry.After(now.Add(MaxAPITokenTTL)), "expiry", "must not be more than a year away")
+}
+
+// ValidateAPITokenPlaintext checks that an API token has the correct length.
+func ValidateAPITokenPlaintext(v *validator.Validator, tokenPlaintext string) {
+	v.Check(len(tokenPlaintext) == 26, "token", "must be exactly 26 bytes")
+}
+
+// apiTokenStore handles database operations for personal API tokens.
+type apiTokenStore struct {
+	db *pgxpool.Pool
+}
+
+// Insert generates the plaintext and hash of a new API token and stores it, populating its ID and CreatedAt
+// fields.
+func (s *apiTokenStore) Insert(ctx context.Context, t *APIToken) error {
+	plaintext, err := generateRandomToken()
+	if err != nil {
+		return err
+	}
+	hash := sha256.Sum256([]byte(plaintext))
+
+	query := `
+		INSERT INTO api_tokens (hash, user_id, name, scopes, expiry)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`
+	c, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	err = s.db.QueryRow(c, query, hash[:], t.UserID, t.Name, t.Scopes, t.Expiry).Scan(&t.ID, &t.CreatedAt)
+	if err != nil {
+		return err
+	}
+	t.Plaintext = plaintext
+	t.Hash = hash[:]
+	return nil
+}
+
+// Use looks up an unexpired API token by its plaintext and records that it was used.
+// Returns ErrNoAPITokenFound if there is no such token.
+func (s *apiTokenStore) Use(ctx context.Context, plaintext string, now time.Time) (*APIToken, error) {
+	query := `
+		UPDATE api_tokens
+		SET last_used_at = $2
+		WHERE hash = $1 AND expiry > $2
+		RETURNING id, hash, user_id, name, scopes, expiry, created_at, last_used_at
+	`
+	hash := sha256.Sum256([]byte(plaintext))
+
+	c, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	t, err := scanAPIToken(s.db.QueryRow(c, query, hash[:], now))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoAPITokenFound
+		}
+		return nil, err
+	}
+	return t, nil
+}
+
+// ListForUser returns a user's API tokens, including expired ones, oldest first.
+func (s *apiTokenStore) ListForUser(ctx context.Context, userID int64) ([]*APIToken, error) {
+	query := `
+		SELECT id, hash, user_id, name, scopes, expiry, created_at, last_used_at
+		FROM api_tokens
+		WHERE user_id = $1
+		ORDER BY id
+	`
+	c, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := s.db.Query(c, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []*APIToken{}
+	for rows.Next() {
+		t, err := scanAPIToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, t)
+	}
+	return tokens, rows.Err()
+}
+
+// Delete revokes one of a user's API tokens.
+// Returns ErrNoAPITokenFound if the user has no token with that ID.
+func (s *apiTokenStore) Delete(ctx context.Context, userID, id int64) error {
+	c, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	result, err := s.db.Exec(c, `DELETE FROM api_tokens WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNoAPITokenFound
+	}
+	return nil
+}
+
+func scanAPIToken(row pgx.Row) (*APIToken, error) {
+	var t APIToken
+	err := row.Scan(
+		&t.ID,
+		&t.Hash,
+		&t.UserID,
+		&t.Name,
+		&t.Scopes,
+		&t.Expiry,
+		&t.CreatedAt,
+		&t.LastUsedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
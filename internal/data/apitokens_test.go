@@ -0,0 +1,113 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hazzardr/baduk-online/internal/validator"
+)
+
+func TestValidateAPIToken(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	valid := func() *APIToken {
+		return &APIToken{Name: "Bot", Scopes: []string{APIScopeRead}, Expiry: now.Add(24 * time.Hour)}
+	}
+	tests := []struct {
+		name    string
+		modify  func(*APIToken)
+		wantErr string
+	}{
+		{"Valid token", func(*APIToken) {}, ""},
+		{"Both scopes", func(t *APIToken) { t.Scopes = APIScopes }, ""},
+		{"Empty name", func(t *APIToken) { t.Name = " " }, "name"},
+		{"Name too long", func(t *APIToken) { t.Name = strings.Repeat("a", MaxAPITokenNameLength+1) }, "name"},
+		{"No scopes", func(t *APIToken) { t.Scopes = nil }, "scopes"},
+		{"Unknown scope", func(t *APIToken) { t.Scopes = []string{"admin"} }, "scopes"},
+		{"Duplicate scope", func(t *APIToken) { t.Scopes = []string{APIScopeRead, APIScopeRead} }, "scopes"},
+		{"No expiry", func(t *APIToken) { t.Expiry = time.Time{} }, "expiry"},
+		{"Already expired", func(t *APIToken) { t.Expiry = now }, "expiry"},
+		{"Longest expiry", func(t *APIToken) { t.Expiry = now.Add(MaxAPITokenTTL) }, ""},
+		{"Expiry too far away", func(t *APIToken) { t.Expiry = now.Add(MaxAPITokenTTL + time.Second) }, "expiry"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := valid()
+			tt.modify(token)
+			v := validator.New()
+			ValidateAPIToken(v, token, now)
+			if tt.wantErr == "" && !v.Valid() {
+				t.Errorf("expected no errors, got %v", v.Errors)
+			}
+			if _, ok := v.Errors[tt.wantErr]; tt.wantErr != "" && !ok {
+				t.Errorf("expected an error for %s, got %v", tt.wantErr, v.Errors)
+			}
+		})
+	}
+}
+
+func TestAPITokensIntegration(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	user := insertTestUser(t, db, "tokens@example.com")
+	other := insertTestUser(t, db, "other@example.com")
+	now := time.Now()
+
+	token := &APIToken{
+		UserID: int64(user.ID),
+		Name:   "Bot",
+		Scopes: []string{APIScopeRead, APIScopeWrite},
+		Expiry: now.Add(time.Hour),
+	}
+	if err := db.APITokens.Insert(ctx, token); err != nil {
+		t.Fatalf("failed to insert token: %s", err)
+	}
+	if token.ID == 0 || len(token.Plaintext) != 26 || token.CreatedAt.IsZero() {
+		t.Errorf("expected ID, Plaintext and CreatedAt to be set, got %+v", token)
+	}
+
+	t.Run("using a token records when", func(t *testing.T) {
+		got, err := db.APITokens.Use(ctx, token.Plaintext, now)
+		if err != nil {
+			t.Fatalf("failed to use token: %s", err)
+		}
+		if got.ID != token.ID || got.UserID != int64(user.ID) || got.LastUsedAt == nil {
+			t.Errorf("unexpected token %+v", got)
+		}
+		if !got.HasScope(APIScopeWrite) || got.Plaintext != "" {
+			t.Errorf("unexpected token %+v", got)
+		}
+		if _, err := db.APITokens.Use(ctx, "ABCDEFGHIJKLMNOPQRSTUVWXYZ", now); !errors.Is(err, ErrNoAPITokenFound) {
+			t.Errorf("expected ErrNoAPITokenFound, got %v", err)
+		}
+	})
+
+	t.Run("expired tokens can't be used", func(t *testing.T) {
+		if _, err := db.APITokens.Use(ctx, token.Plaintext, now.Add(2*time.Hour)); !errors.Is(err, ErrNoAPITokenFound) {
+			t.Errorf("expected ErrNoAPITokenFound, got %v", err)
+		}
+	})
+
+	t.Run("only the owner can delete a token", func(t *testing.T) {
+		if err := db.APITokens.Delete(ctx, int64(other.ID), token.ID); !errors.Is(err, ErrNoAPITokenFound) {
+			t.Errorf("expected ErrNoAPITokenFound, got %v", err)
+		}
+		tokens, err := db.APITokens.ListForUser(ctx, int64(user.ID))
+		if err != nil {
+			t.Fatalf("failed to list tokens: %s", err)
+		}
+		if len(tokens) != 1 {
+			t.Fatalf("expected 1 token, got %d", len(tokens))
+		}
+		if err := db.APITokens.Delete(ctx, int64(user.ID), token.ID); err != nil {
+			t.Fatalf("failed to delete token: %s", err)
+		}
+		if _, err := db.APITokens.Use(ctx, token.Plaintext, now); !errors.Is(err, ErrNoAPITokenFound) {
+			t.Errorf("expected a deleted token not to be usable, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,192 @@
+package data
+
+import (
+	"context"
+	"time"
+
+	"github.com/hazzardr/baduk-online/internal/automatch"
+	"github.com/hazzardr/baduk-online/internal/ratings"
+	"github.com/hazzardr/baduk-online/internal/scoring"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// automatchStore handles database operations for the automatch queue.
+type automatchStore struct {
+	db *pgxpool.Pool
+}
+
+// Join adds a user to the automatch queue, or updates their preferences if they are already waiting. A user
+// who updates their preferences keeps their place in the queue. The entry's JoinedAt, Rank and Version
+// fields are populated.
+func (s *automatchStore) Join(ctx context.Context, entry *automatch.Entry) error {
+	query := `
+		WITH upserted AS (
+			INSERT INTO automatch_queue (user_id, board_sizes, speed, rules, rated, min_rank, max_rank)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			ON CONFLICT (user_id) DO UPDATE
+			SET
+				board_sizes = EXCLUDED.board_sizes,
+				speed = EXCLUDED.speed,
+				rules = EXCLUDED.rules,
+				rated = EXCLUDED.rated,
+				min_rank = EXCLUDED.min_rank,
+				max_rank = EXCLUDED.max_rank,
+				version = automatch_queue.version + 1
+			RETURNING joined_at, version
+		)
+		SELECT upserted.joined_at, upserted.version, u.rating
+		FROM upserted, users u
+		WHERE u.id = $1
+	`
+	c, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	var rating float64
+	err := s.db.QueryRow(
+		c,
+		query,
+		entry.UserID,
+		entry.BoardSizes,
+		string(entry.Speed),
+		string(entry.Rules),
+		entry.Rated,
+		int(entry.MinRank),
+		int(entry.MaxRank),
+	).Scan(&entry.JoinedAt, &entry.Version, &rating)
+	if err != nil {
+		return err
+	}
+	entry.Rank = ratings.RankFor(rating)
+	return nil
+}
+
+// Leave removes a user from the automatch queue.
+// Returns ErrNotQueued if they were not waiting.
+func (s *automatchStore) Leave(ctx context.Context, userID int64) error {
+	c, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	result, err := s.db.Exec(c, `DELETE FROM automatch_queue WHERE user_id = $1`, userID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotQueued
+	}
+	return nil
+}
+
+// Get returns a user's place in the automatch queue.
+// Returns ErrNotQueued if they are not waiting.
+func (s *automatchStore) Get(ctx context.Context, userID int64) (*automatch.Entry, error) {
+	entries, err := s.list(ctx, `WHERE q.user_id = $1`, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, ErrNotQueued
+	}
+	return &entries[0], nil
+}
+
+// List returns everyone waiting in the automatch queue, longest waiting first. Ranks are taken from the
+// players' current ratings.
+func (s *automatchStore) List(ctx context.Context) ([]automatch.Entry, error) {
+	return s.list(ctx, "")
+}
+
+func (s *automatchStore) list(ctx context.Context, where string, args ...any) ([]automatch.Entry, error) {
+	query := `
+		SELECT
+			q.user_id, q.joined_at, q.board_sizes, q.speed, q.rules, q.rated, q.min_rank, q.max_rank, q.version,
+			u.rating
+		FROM automatch_queue q
+		INNER JOIN users u ON u.id = q.user_id
+		` + where + `
+		ORDER BY q.joined_at, q.user_id
+	`
+	c, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := s.db.Query(c, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []automatch.Entry{}
+	for rows.Next() {
+		var e automatch.Entry
+		var speed, rules string
+		var minRank, maxRank int
+		var rating float64
+		err := rows.Scan(
+			&e.UserID,
+			&e.JoinedAt,
+			&e.BoardSizes,
+			&speed,
+			&rules,
+			&e.Rated,
+			&minRank,
+			&maxRank,
+			&e.Version,
+			&rating,
+		)
+		if err != nil {
+			return nil, err
+		}
+		e.Speed = automatch.Speed(speed)
+		e.Rules = scoring.Ruleset(rules)
+		e.MinRank, e.MaxRank = ratings.Rank(minRank), ratings.Rank(maxRank)
+		e.Rank = ratings.RankFor(rating)
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// Pair removes both players of a pairing from the queue, creates their game and records the pairing in a
+// single transaction. The game must already have its players, settings and clock filled in. Returns
+// ErrQueueChanged, without creating the game, if either player left the queue or changed their preferences
+// since the pairing was made.
+func (s *automatchStore) Pair(ctx context.Context, pair automatch.Pair, game *Game) error {
+	c, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	tx, err := s.db.Begin(c)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(c) //nolint:errcheck // rollback after commit is a no-op
+
+	// Deleting a row locks it, so a concurrent leave, update or pairing of the same player either happens
+	// first, and the versions no longer match, or waits and then finds nothing to change.
+	remove := `
+		DELETE FROM automatch_queue
+		WHERE (user_id = $1 AND version = $2) OR (user_id = $3 AND version = $4)
+	`
+	result, err := tx.Exec(c, remove, pair.A.UserID, pair.A.Version, pair.B.UserID, pair.B.Version)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() != 2 {
+		return ErrQueueChanged
+	}
+
+	err = insertGame(c, tx, game)
+	if err != nil {
+		return err
+	}
+
+	record := `
+		INSERT INTO automatch_pairings (
+			first_user_id, second_user_id, game_id, first_waited, second_waited
+		)
+		VALUES ($1, $2, $3, NOW() - $4::timestamptz, NOW() - $5::timestamptz)
+	`
+	_, err = tx.Exec(c, record, pair.A.UserID, pair.B.UserID, game.ID, pair.A.JoinedAt, pair.B.JoinedAt)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(c)
+}
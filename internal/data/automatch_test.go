@@ -0,0 +1,192 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hazzardr/baduk-online/internal/automatch"
+	"github.com/hazzardr/baduk-online/internal/ratings"
+	"github.com/hazzardr/baduk-online/internal/scoring"
+)
+
+func TestAutomatchStoreIntegration(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	users := make([]*User, 6)
+	for i := range users {
+		users[i] = insertTestUser(t, db, fmt.Sprintf("player%d@example.com", i))
+	}
+
+	join := func(t *testing.T, user *User, sizes ...int) *automatch.Entry {
+		t.Helper()
+		entry := &automatch.Entry{
+			UserID:     int64(user.ID),
+			BoardSizes: sizes,
+			Speed:      automatch.Live,
+			MinRank:    ratings.MinRank,
+			MaxRank:    ratings.MaxRank,
+			Rules:      scoring.Japanese,
+			Rated:      true,
+		}
+		if err := db.Automatch.Join(ctx, entry); err != nil {
+			t.Fatalf("failed to join queue: %s", err)
+		}
+		return entry
+	}
+
+	gameFor := func(pair automatch.Pair) *Game {
+		return &Game{
+			BlackID:   pair.A.UserID,
+			WhiteID:   pair.B.UserID,
+			BoardSize: pair.BoardSize,
+			Rules:     "japanese",
+			Komi:      6.5,
+			Rated:     true,
+		}
+	}
+
+	t.Run("join, update and leave", func(t *testing.T) {
+		first := join(t, users[0], 19)
+		if first.Rank != ratings.RankFor(ratings.DefaultRating) {
+			t.Errorf("expected rank from rating, got %v", first.Rank)
+		}
+
+		second := join(t, users[0], 9, 13)
+		if !second.JoinedAt.Equal(first.JoinedAt) || second.Version != first.Version+1 {
+			t.Errorf("expected update to keep place and bump version, got %+v then %+v", first, second)
+		}
+
+		got, err := db.Automatch.Get(ctx, int64(users[0].ID))
+		if err != nil {
+			t.Fatalf("failed to get entry: %s", err)
+		}
+		if len(got.BoardSizes) != 2 || got.Speed != automatch.Live {
+			t.Errorf("unexpected entry: %+v", got)
+		}
+
+		if err := db.Automatch.Leave(ctx, int64(users[0].ID)); err != nil {
+			t.Fatalf("failed to leave: %s", err)
+		}
+		if err := db.Automatch.Leave(ctx, int64(users[0].ID)); !errors.Is(err, ErrNotQueued) {
+			t.Errorf("expected ErrNotQueued, got %v", err)
+		}
+		if _, err := db.Automatch.Get(ctx, int64(users[0].ID)); !errors.Is(err, ErrNotQueued) {
+			t.Errorf("expected ErrNotQueued, got %v", err)
+		}
+	})
+
+	t.Run("pair removes both players and creates game", func(t *testing.T) {
+		join(t, users[0], 19)
+		join(t, users[1], 19)
+
+		entries, err := db.Automatch.List(ctx)
+		if err != nil {
+			t.Fatalf("failed to list queue: %s", err)
+		}
+		pairs := automatch.Match(entries, time.Now(), automatch.DefaultWindow)
+		if len(pairs) != 1 {
+			t.Fatalf("expected one pair, got %d", len(pairs))
+		}
+
+		g := gameFor(pairs[0])
+		if err := db.Automatch.Pair(ctx, pairs[0], g); err != nil {
+			t.Fatalf("failed to pair: %s", err)
+		}
+		if g.ID == 0 {
+			t.Error("expected game to be created")
+		}
+		entries, _ = db.Automatch.List(ctx)
+		if len(entries) != 0 {
+			t.Errorf("expected queue to be empty, got %+v", entries)
+		}
+
+		// Pairing the same players again must fail, as they have left the queue.
+		if err := db.Automatch.Pair(ctx, pairs[0], gameFor(pairs[0])); !errors.Is(err, ErrQueueChanged) {
+			t.Errorf("expected ErrQueueChanged, got %v", err)
+		}
+	})
+
+	t.Run("pair fails if preferences changed", func(t *testing.T) {
+		join(t, users[2], 19)
+		join(t, users[3], 19)
+		entries, _ := db.Automatch.List(ctx)
+		pairs := automatch.Match(entries, time.Now(), automatch.DefaultWindow)
+		if len(pairs) != 1 {
+			t.Fatalf("expected one pair, got %d", len(pairs))
+		}
+
+		join(t, users[3], 9)
+		if err := db.Automatch.Pair(ctx, pairs[0], gameFor(pairs[0])); !errors.Is(err, ErrQueueChanged) {
+			t.Errorf("expected ErrQueueChanged, got %v", err)
+		}
+		entries, _ = db.Automatch.List(ctx)
+		if len(entries) != 2 {
+			t.Errorf("expected both players to remain queued, got %d", len(entries))
+		}
+		_ = db.Automatch.Leave(ctx, int64(users[2].ID))
+		_ = db.Automatch.Leave(ctx, int64(users[3].ID))
+	})
+
+	t.Run("concurrent pairing, joining and leaving", func(t *testing.T) {
+		for _, u := range users[2:] {
+			join(t, u, 19)
+		}
+		entries, _ := db.Automatch.List(ctx)
+		pairs := automatch.Match(entries, time.Now(), automatch.DefaultWindow)
+		if len(pairs) != 2 {
+			t.Fatalf("expected two pairs, got %d", len(pairs))
+		}
+
+		var wg sync.WaitGroup
+		results := make([]error, 0, 8)
+		var mu sync.Mutex
+		record := func(err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			results = append(results, err)
+		}
+		// Two matchmakers race to make the same pairings while one player leaves.
+		for range 2 {
+			for _, p := range pairs {
+				wg.Go(func() { record(db.Automatch.Pair(ctx, p, gameFor(p))) })
+			}
+		}
+		wg.Go(func() {
+			err := db.Automatch.Leave(ctx, pairs[1].B.UserID)
+			if err != nil && !errors.Is(err, ErrNotQueued) {
+				record(err)
+			}
+		})
+		wg.Wait()
+
+		for _, err := range results {
+			if err != nil && !errors.Is(err, ErrQueueChanged) {
+				t.Errorf("unexpected error: %s", err)
+			}
+		}
+
+		// The first pairing is untouched by the leave, so exactly one of the racing attempts made its game.
+		for _, id := range []int64{pairs[0].A.UserID, pairs[0].B.UserID} {
+			if _, err := db.Automatch.Get(ctx, id); !errors.Is(err, ErrNotQueued) {
+				t.Errorf("paired player %d still queued", id)
+			}
+			games, _ := db.Games.ListForPlayer(ctx, id)
+			if len(games) != 1 {
+				t.Errorf("player %d was given %d games, want 1", id, len(games))
+			}
+		}
+		// The second pairing either won the race against the leave or never happened.
+		for _, id := range []int64{pairs[1].A.UserID, pairs[1].B.UserID} {
+			games, _ := db.Games.ListForPlayer(ctx, id)
+			if len(games) > 1 {
+				t.Errorf("player %d was given %d games", id, len(games))
+			}
+		}
+	})
+}
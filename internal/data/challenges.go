@@ -0,0 +1,309 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/hazzardr/baduk-online/internal/clock"
+	"github.com/hazzardr/baduk-online/internal/handicap"
+	"github.com/hazzardr/baduk-online/internal/scoring"
+	"github.com/hazzardr/baduk-online/internal/validator"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	// ChallengeStatusOpen is the status of a challenge waiting for a response.
+	ChallengeStatusOpen = "open"
+	// ChallengeStatusAccepted is the status of a challenge that became a game.
+	ChallengeStatusAccepted = "accepted"
+	// ChallengeStatusDeclined is the status of a direct challenge its opponent turned down.
+	ChallengeStatusDeclined = "declined"
+	// ChallengeStatusCancelled is the status of a challenge withdrawn by its challenger.
+	ChallengeStatusCancelled = "cancelled"
+
+	// ColorRandom lets the colors of a challenge be decided when it is accepted.
+	ColorRandom = "random"
+)
+
+// Challenge is a proposal to play a game. A challenge without an OpponentID is an open seek that any other
+// user may accept.
+type Challenge struct {
+	ID           int64     `json:"id"`
+	CreatedAt    time.Time `json:"created_at"`
+	ChallengerID int64     `json:"challenger_id"`
+	OpponentID   *int64    `json:"opponent_id,omitempty"`
+	BoardSize    int       `json:"board_size"`
+	Rules        string    `json:"rules"`
+	Komi         float64   `json:"komi"`
+	Handicap     int       `json:"handicap"`
+	// Color is the color the challenger will play.
+	Color string `json:"color"`
+	// TimeControl is nil for untimed games.
+	TimeControl *clock.TimeControl `json:"time_control,omitempty"`
+	Rated       bool               `json:"rated"`
+	Status      string             `json:"status"`
+	GameID      *int64             `json:"game_id,omitempty"`
+	Version     int                `json:"-"`
+}
+
+// ValidateChallenge checks the settings of a new challenge.
+func ValidateChallenge(v *validator.Validator, challenge *Challenge) {
+	v.Check(challenge.ChallengerID != 0, "challenger_id", "must be provided")
+	if challenge.OpponentID != nil {
+		v.Check(*challenge.OpponentID != challenge.ChallengerID, "opponent_id", "must not be yourself")
+	}
+	v.Check(challenge.BoardSize >= 2 && challenge.BoardSize <= 25, "board_size", "must be between 2 and 25")
+	scoring.ValidateRuleset(v, scoring.Ruleset(challenge.Rules))
+	scoring.ValidateKomi(v, challenge.Komi)
+	v.Check(challenge.Handicap >= 0 && challenge.Handicap <= 9, "handicap", "must be between 0 and 9")
+	if challenge.Handicap >= handicap.MinStones {
+		v.Check(
+			validator.PermittedValue(challenge.BoardSize, handicap.SupportedSizes...),
+			"handicap",
+			"stones can only be placed on 9x9, 13x13 and 19x19 boards",
+		)
+	}
+	v.Check(
+		validator.PermittedValue(challenge.Color, ColorBlack, ColorWhite, ColorRandom),
+		"color",
+		"must be black, white or random",
+	)
+	if challenge.TimeControl != nil {
+		clock.ValidateTimeControl(v, *challenge.TimeControl)
+	}
+}
+
+// challengeStore handles database operations for challenges.
+type challengeStore struct {
+	db *pgxpool.Pool
+}
+
+// Insert creates a new challenge and populates its ID, CreatedAt, Status and Version fields.
+func (s *challengeStore) Insert(ctx context.Context, challenge *Challenge) error {
+	query := `
+		INSERT INTO challenges (
+			challenger_id, opponent_id, board_size, rules, komi, handicap, color, time_control, rated
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, created_at, status, version`
+	c, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	return s.db.QueryRow(
+		c,
+		query,
+		challenge.ChallengerID,
+		challenge.OpponentID,
+		challenge.BoardSize,
+		challenge.Rules,
+		challenge.Komi,
+		challenge.Handicap,
+		challenge.Color,
+		challenge.TimeControl,
+		challenge.Rated,
+	).Scan(&challenge.ID, &challenge.CreatedAt, &challenge.Status, &challenge.Version)
+}
+
+// Get retrieves a challenge by its ID.
+// Returns ErrNoChallengeFound if no challenge exists with the given ID.
+func (s *challengeStore) Get(ctx context.Context, id int64) (*Challenge, error) {
+	query := `
+		SELECT ` + challengeColumns + `
+		FROM challenges
+		WHERE id = $1
+	`
+	c, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	challenge, err := scanChallenge(s.db.QueryRow(c, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoChallengeFound
+		}
+		return nil, err
+	}
+	return challenge, nil
+}
+
+// ListOpenSeeks returns the open seeks that any user may accept, oldest first.
+func (s *challengeStore) ListOpenSeeks(ctx context.Context) ([]*Challenge, error) {
+	query := `
+		SELECT ` + challengeColumns + `
+		FROM challenges
+		WHERE status = $1 AND opponent_id IS NULL
+		ORDER BY created_at, id
+	`
+	return s.list(ctx, query, ChallengeStatusOpen)
+}
+
+// ListOpenForUser returns the open direct challenges a user has sent or received, oldest first.
+func (s *challengeStore) ListOpenForUser(ctx context.Context, userID int64) ([]*Challenge, error) {
+	query := `
+		SELECT ` + challengeColumns + `
+		FROM challenges
+		WHERE status = $1 AND (challenger_id = $2 OR opponent_id = $2)
+		ORDER BY created_at, id
+	`
+	return s.list(ctx, query, ChallengeStatusOpen, userID)
+}
+
+// ListForUser returns every challenge a user has sent or received, whatever its status, oldest first.
+func (s *challengeStore) ListForUser(ctx context.Context, userID int64) ([]*Challenge, error) {
+	query := `
+		SELECT ` + challengeColumns + `
+		FROM challenges
+		WHERE challenger_id = $1 OR opponent_id = $1
+		ORDER BY created_at, id
+	`
+	return s.list(ctx, query, userID)
+}
+
+func (s *challengeStore) list(ctx context.Context, query string, args ...any) ([]*Challenge, error) {
+	c, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := s.db.Query(c, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	challenges := []*Challenge{}
+	for rows.Next() {
+		challenge, err := scanChallenge(rows)
+		if err != nil {
+			return nil, err
+		}
+		challenges = append(challenges, challenge)
+	}
+	return challenges, rows.Err()
+}
+
+// Accept creates the game for a challenge and marks the challenge as accepted by the given user in a single
+// transaction. The game must already have its players, settings and clock filled in. If two users race to
+// accept the same seek, the row lock taken by the update makes the loser wait and then see that the challenge
+// is no longer open, so only one game is created. Returns ErrChallengeClosed if the challenge was accepted,
+// declined or cancelled first, or is a direct challenge to someone else.
+func (s *challengeStore) Accept(ctx context.Context, challenge *Challenge, acceptorID int64, game *Game) error {
+	c, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	tx, err := s.db.Begin(c)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(c) //nolint:errcheck // rollback after commit is a no-op
+
+	err = insertGame(c, tx, game)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE challenges
+		SET
+			status = $1,
+			opponent_id = $2,
+			game_id = $3,
+			version = version + 1
+		WHERE
+			id = $4
+		AND
+			status = $5
+		AND
+			challenger_id <> $2
+		AND
+			(opponent_id IS NULL OR opponent_id = $2)
+		RETURNING
+			status, opponent_id, game_id, version
+	`
+	err = tx.QueryRow(
+		c,
+		query,
+		ChallengeStatusAccepted,
+		acceptorID,
+		game.ID,
+		challenge.ID,
+		ChallengeStatusOpen,
+	).Scan(&challenge.Status, &challenge.OpponentID, &challenge.GameID, &challenge.Version)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrChallengeClosed
+		}
+		return err
+	}
+
+	return tx.Commit(c)
+}
+
+// Decline marks an open challenge as turned down by its opponent.
+// Returns ErrChallengeClosed if it is no longer open.
+func (s *challengeStore) Decline(ctx context.Context, challenge *Challenge) error {
+	return s.close(ctx, challenge, ChallengeStatusDeclined)
+}
+
+// Cancel marks an open challenge as withdrawn by its challenger.
+// Returns ErrChallengeClosed if it is no longer open.
+func (s *challengeStore) Cancel(ctx context.Context, challenge *Challenge) error {
+	return s.close(ctx, challenge, ChallengeStatusCancelled)
+}
+
+func (s *challengeStore) close(ctx context.Context, challenge *Challenge, status string) error {
+	query := `
+		UPDATE challenges
+		SET
+			status = $1,
+			version = version + 1
+		WHERE
+			id = $2
+		AND
+			status = $3
+		RETURNING
+			status, version
+	`
+	c, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	err := s.db.QueryRow(c, query, status, challenge.ID, ChallengeStatusOpen).Scan(
+		&challenge.Status,
+		&challenge.Version,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrChallengeClosed
+		}
+		return err
+	}
+	return nil
+}
+
+// challengeColumns are the columns read by scanChallenge.
+const challengeColumns = `
+	id, created_at, challenger_id, opponent_id, board_size, rules, komi, handicap,
+	color, time_control, rated, status, game_id, version`
+
+func scanChallenge(row pgx.Row) (*Challenge, error) {
+	var challenge Challenge
+	err := row.Scan(
+		&challenge.ID,
+		&challenge.CreatedAt,
+		&challenge.ChallengerID,
+		&challenge.OpponentID,
+		&challenge.BoardSize,
+		&challenge.Rules,
+		&challenge.Komi,
+		&challenge.Handicap,
+		&challenge.Color,
+		&challenge.TimeControl,
+		&challenge.Rated,
+		&challenge.Status,
+		&challenge.GameID,
+		&challenge.Version,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &challenge, nil
+}
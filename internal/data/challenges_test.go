@@ -0,0 +1,246 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hazzardr/baduk-online/internal/clock"
+	"github.com/hazzardr/baduk-online/internal/validator"
+)
+
+func TestValidateChallenge(t *testing.T) {
+	self := int64(1)
+	other := int64(2)
+	valid := Challenge{ChallengerID: 1, BoardSize: 19, Rules: "japanese", Komi: 6.5, Color: ColorRandom}
+
+	tests := []struct {
+		name    string
+		modify  func(c *Challenge)
+		wantKey string
+	}{
+		{"Valid open seek", func(*Challenge) {}, ""},
+		{"Valid direct challenge", func(c *Challenge) { c.OpponentID = &other }, ""},
+		{"Challenge yourself", func(c *Challenge) { c.OpponentID = &self }, "opponent_id"},
+		{"Unknown color", func(c *Challenge) { c.Color = "nigiri" }, "color"},
+		{"Board too small", func(c *Challenge) { c.BoardSize = 1 }, "board_size"},
+		{"Unknown rules", func(c *Challenge) { c.Rules = "ing" }, "rules"},
+		{"Fractional komi", func(c *Challenge) { c.Komi = 6.2 }, "komi"},
+		{"Too many handicap stones", func(c *Challenge) { c.Handicap = 12 }, "handicap"},
+		{"Handicap on an unusual board", func(c *Challenge) { c.BoardSize, c.Handicap = 7, 4 }, "handicap"},
+		{"No komi on an unusual board", func(c *Challenge) { c.BoardSize, c.Handicap = 7, 1 }, ""},
+		{
+			"Invalid time control",
+			func(c *Challenge) { c.TimeControl = &clock.TimeControl{Kind: clock.ByoYomi, MainTime: time.Hour} },
+			"periods",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := valid
+			tt.modify(&c)
+			v := validator.New()
+			ValidateChallenge(v, &c)
+			if tt.wantKey == "" {
+				if !v.Valid() {
+					t.Errorf("expected no errors, got %v", v.Errors)
+				}
+				return
+			}
+			if _, ok := v.Errors[tt.wantKey]; !ok {
+				t.Errorf("expected error for %q, got %v", tt.wantKey, v.Errors)
+			}
+		})
+	}
+}
+
+func TestChallengeStoreIntegration(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	challenger := insertTestUser(t, db, "challenger@example.com")
+	opponent := insertTestUser(t, db, "opponent@example.com")
+	other := insertTestUser(t, db, "other@example.com")
+
+	newChallenge := func(t *testing.T, opponentID *int64) *Challenge {
+		t.Helper()
+		challenge := &Challenge{
+			ChallengerID: int64(challenger.ID),
+			OpponentID:   opponentID,
+			BoardSize:    19,
+			Rules:        "japanese",
+			Komi:         6.5,
+			Color:        ColorBlack,
+			TimeControl:  &clock.TimeControl{Kind: clock.Fischer, MainTime: 10 * time.Minute, Increment: 5 * time.Second},
+			Rated:        true,
+		}
+		if err := db.Challenges.Insert(ctx, challenge); err != nil {
+			t.Fatalf("failed to insert challenge: %s", err)
+		}
+		return challenge
+	}
+
+	gameFor := func(c *Challenge, acceptorID int64) *Game {
+		return &Game{
+			BlackID:   c.ChallengerID,
+			WhiteID:   acceptorID,
+			BoardSize: c.BoardSize,
+			Rules:     c.Rules,
+			Komi:      c.Komi,
+			Rated:     c.Rated,
+		}
+	}
+
+	t.Run("create and get", func(t *testing.T) {
+		created := newChallenge(t, nil)
+		if created.Status != ChallengeStatusOpen {
+			t.Errorf("expected open status, got %q", created.Status)
+		}
+		got, err := db.Challenges.Get(ctx, created.ID)
+		if err != nil {
+			t.Fatalf("failed to get challenge: %s", err)
+		}
+		if got.TimeControl == nil || got.TimeControl.Increment != 5*time.Second || !got.Rated {
+			t.Errorf("unexpected challenge: %+v", got)
+		}
+		if _, err := db.Challenges.Get(ctx, created.ID+1000); !errors.Is(err, ErrNoChallengeFound) {
+			t.Errorf("expected ErrNoChallengeFound, got %v", err)
+		}
+	})
+
+	t.Run("open seeks exclude direct challenges", func(t *testing.T) {
+		opponentID := int64(opponent.ID)
+		seek := newChallenge(t, nil)
+		direct := newChallenge(t, &opponentID)
+
+		seeks, err := db.Challenges.ListOpenSeeks(ctx)
+		if err != nil {
+			t.Fatalf("failed to list seeks: %s", err)
+		}
+		for _, c := range seeks {
+			if c.ID == direct.ID {
+				t.Error("direct challenge listed as open seek")
+			}
+		}
+
+		mine, err := db.Challenges.ListOpenForUser(ctx, opponentID)
+		if err != nil {
+			t.Fatalf("failed to list challenges for user: %s", err)
+		}
+		if len(mine) != 1 || mine[0].ID != direct.ID {
+			t.Errorf("expected only the direct challenge, got %+v", mine)
+		}
+		_ = db.Challenges.Cancel(ctx, seek)
+		_ = db.Challenges.Cancel(ctx, direct)
+	})
+
+	t.Run("accept creates game", func(t *testing.T) {
+		c := newChallenge(t, nil)
+		g := gameFor(c, int64(opponent.ID))
+		if err := db.Challenges.Accept(ctx, c, int64(opponent.ID), g); err != nil {
+			t.Fatalf("failed to accept: %s", err)
+		}
+		if c.Status != ChallengeStatusAccepted || c.GameID == nil || *c.GameID != g.ID {
+			t.Errorf("unexpected challenge after accept: %+v", c)
+		}
+		got, err := db.Games.Get(ctx, g.ID)
+		if err != nil {
+			t.Fatalf("failed to get game: %s", err)
+		}
+		if !got.Rated || got.WhiteID != int64(opponent.ID) {
+			t.Errorf("unexpected game: %+v", got)
+		}
+	})
+
+	t.Run("concurrent accepts create one game", func(t *testing.T) {
+		c := newChallenge(t, nil)
+		acceptors := []int64{int64(opponent.ID), int64(other.ID)}
+		before, _ := db.Games.ListForPlayer(ctx, int64(challenger.ID))
+
+		var wg sync.WaitGroup
+		errs := make([]error, len(acceptors))
+		for i, id := range acceptors {
+			wg.Go(func() {
+				copied := *c
+				errs[i] = db.Challenges.Accept(ctx, &copied, id, gameFor(&copied, id))
+			})
+		}
+		wg.Wait()
+
+		succeeded := 0
+		for _, err := range errs {
+			switch {
+			case err == nil:
+				succeeded++
+			case !errors.Is(err, ErrChallengeClosed):
+				t.Errorf("unexpected error: %s", err)
+			}
+		}
+		if succeeded != 1 {
+			t.Errorf("expected exactly one accept to succeed, got %d", succeeded)
+		}
+
+		after, _ := db.Games.ListForPlayer(ctx, int64(challenger.ID))
+		if len(after) != len(before)+1 {
+			t.Errorf("expected one game to be created, got %d", len(after)-len(before))
+		}
+		got, _ := db.Challenges.Get(ctx, c.ID)
+		if got.GameID == nil {
+			t.Fatal("expected challenge to record its game")
+		}
+	})
+
+	t.Run("direct challenge only accepted by opponent", func(t *testing.T) {
+		opponentID := int64(opponent.ID)
+		c := newChallenge(t, &opponentID)
+		err := db.Challenges.Accept(ctx, c, int64(other.ID), gameFor(c, int64(other.ID)))
+		if !errors.Is(err, ErrChallengeClosed) {
+			t.Errorf("expected ErrChallengeClosed, got %v", err)
+		}
+	})
+
+	t.Run("decline and cancel close challenge", func(t *testing.T) {
+		opponentID := int64(opponent.ID)
+		declined := newChallenge(t, &opponentID)
+		if err := db.Challenges.Decline(ctx, declined); err != nil {
+			t.Fatalf("failed to decline: %s", err)
+		}
+		if declined.Status != ChallengeStatusDeclined {
+			t.Errorf("expected declined status, got %q", declined.Status)
+		}
+		if err := db.Challenges.Cancel(ctx, declined); !errors.Is(err, ErrChallengeClosed) {
+			t.Errorf("expected ErrChallengeClosed, got %v", err)
+		}
+
+		cancelled := newChallenge(t, nil)
+		if err := db.Challenges.Cancel(ctx, cancelled); err != nil {
+			t.Fatalf("failed to cancel: %s", err)
+		}
+		err := db.Challenges.Accept(ctx, cancelled, opponentID, gameFor(cancelled, opponentID))
+		if !errors.Is(err, ErrChallengeClosed) {
+			t.Errorf("expected ErrChallengeClosed, got %v", err)
+		}
+	})
+
+	t.Run("closed challenges are listed for their players", func(t *testing.T) {
+		all, err := db.Challenges.ListForUser(ctx, int64(opponent.ID))
+		if err != nil {
+			t.Fatalf("failed to list challenges: %s", err)
+		}
+		open, err := db.Challenges.ListOpenForUser(ctx, int64(opponent.ID))
+		if err != nil {
+			t.Fatalf("failed to list open challenges: %s", err)
+		}
+		statuses := make(map[string]bool)
+		for _, c := range all {
+			statuses[c.Status] = true
+		}
+		if len(all) <= len(open) || !statuses[ChallengeStatusDeclined] || !statuses[ChallengeStatusAccepted] {
+			t.Errorf("expected accepted and declined challenges to be listed, got %+v", all)
+		}
+	})
+}
@@ -0,0 +1,208 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/hazzardr/baduk-online/internal/validator"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	// ChatChannelPlayers is the channel only the two players of a game can read and write.
+	ChatChannelPlayers = "players"
+	// ChatChannelPublic is the channel anyone can read and any logged in user can write.
+	ChatChannelPublic = "public"
+
+	// MaxChatMessageLength is the longest chat message allowed, in characters.
+	MaxChatMessageLength = 500
+	// MaxChatPageSize is the most chat messages returned at once.
+	MaxChatPageSize = 100
+)
+
+// ChatMessage is a message posted in one of a game's chat channels.
+type ChatMessage struct {
+	ID        int64     `json:"id"`
+	GameID    int64     `json:"game_id"`
+	UserID    int64     `json:"user_id"`
+	UserName  string    `json:"user_name"`
+	Channel   string    `json:"channel"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ValidateChatChannel checks that a chat channel is one of the known channels.
+func ValidateChatChannel(v *validator.Validator, channel string) {
+	v.Check(
+		validator.PermittedValue(channel, ChatChannelPlayers, ChatChannelPublic),
+		"channel",
+		"must be players or public",
+	)
+}
+
+// ValidateChatMessage checks a new chat message before it is posted.
+func ValidateChatMessage(v *validator.Validator, msg *ChatMessage) {
+	ValidateChatChannel(v, msg.Channel)
+	v.Check(strings.TrimSpace(msg.Body) != "", "body", "must be provided")
+	v.Check(utf8.RuneCountInString(msg.Body) <= MaxChatMessageLength, "body", "must not be more than 500 characters long")
+	v.Check(utf8.ValidString(msg.Body), "body", "must be valid UTF-8")
+}
+
+// chatStore handles database operations for game chat.
+type chatStore struct {
+	db *pgxpool.Pool
+}
+
+// Insert posts a chat message and populates its ID, CreatedAt and UserName fields. CreatedAt is always the
+// server's time.
+func (s *chatStore) Insert(ctx context.Context, msg *ChatMessage) error {
+	query := `
+		WITH m AS (
+			INSERT INTO game_chat (game_id, user_id, channel, body)
+			VALUES ($1, $2, $3, $4)
+			RETURNING id, created_at, user_id
+		)
+		SELECT m.id, m.created_at, u.name
+		FROM m
+		INNER JOIN users u ON u.id = m.user_id
+	`
+	c, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	return s.db.QueryRow(c, query, msg.GameID, msg.UserID, msg.Channel, msg.Body).Scan(
+		&msg.ID,
+		&msg.CreatedAt,
+		&msg.UserName,
+	)
+}
+
+// Get retrieves a chat message that has not been deleted.
+// Returns ErrNoChatMessageFound if there is no such message.
+func (s *chatStore) Get(ctx context.Context, id int64) (*ChatMessage, error) {
+	query := `
+		SELECT m.id, m.game_id, m.user_id, u.name, m.channel, m.body, m.created_at
+		FROM game_chat m
+		INNER JOIN users u ON u.id = m.user_id
+		WHERE m.id = $1 AND m.deleted_at IS NULL
+	`
+	c, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	msg, err := scanChatMessage(s.db.QueryRow(c, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoChatMessageFound
+		}
+		return nil, err
+	}
+	return msg, nil
+}
+
+// List returns up to limit messages from a game's chat channel, newest first. Only messages older than
+// before are returned if it is positive, so passing the ID of the last message received fetches the next
+// page. Deleted messages are left out.
+func (s *chatStore) List(
+	ctx context.Context, gameID int64, channel string, before int64, limit int,
+) ([]*ChatMessage, error) {
+	query := `
+		SELECT m.id, m.game_id, m.user_id, u.name, m.channel, m.body, m.created_at
+		FROM game_chat m
+		INNER JOIN users u ON u.id = m.user_id
+		WHERE m.game_id = $1
+		AND m.channel = $2
+		AND m.deleted_at IS NULL
+		AND ($3 <= 0 OR m.id < $3)
+		ORDER BY m.id DESC
+		LIMIT $4
+	`
+	c, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := s.db.Query(c, query, gameID, channel, before, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := []*ChatMessage{}
+	for rows.Next() {
+		msg, err := scanChatMessage(rows)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, msg)
+	}
+	return messages, rows.Err()
+}
+
+// ListForUser returns every message a user has written, oldest first. Messages hidden by a moderator are
+// included.
+func (s *chatStore) ListForUser(ctx context.Context, userID int64) ([]*ChatMessage, error) {
+	query := `
+		SELECT m.id, m.game_id, m.user_id, u.name, m.channel, m.body, m.created_at
+		FROM game_chat m
+		INNER JOIN users u ON u.id = m.user_id
+		WHERE m.user_id = $1
+		ORDER BY m.id
+	`
+	c, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := s.db.Query(c, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := []*ChatMessage{}
+	for rows.Next() {
+		msg, err := scanChatMessage(rows)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, msg)
+	}
+	return messages, rows.Err()
+}
+
+// Delete hides a chat message from everyone, recording the moderator who removed it.
+// Returns ErrNoChatMessageFound if the message does not exist or was already deleted.
+func (s *chatStore) Delete(ctx context.Context, id, moderatorID int64) error {
+	query := `
+		UPDATE game_chat
+		SET deleted_at = NOW(), deleted_by = $2
+		WHERE id = $1 AND deleted_at IS NULL
+	`
+	c, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	result, err := s.db.Exec(c, query, id, moderatorID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNoChatMessageFound
+	}
+	return nil
+}
+
+func scanChatMessage(row pgx.Row) (*ChatMessage, error) {
+	var msg ChatMessage
+	err := row.Scan(
+		&msg.ID,
+		&msg.GameID,
+		&msg.UserID,
+		&msg.UserName,
+		&msg.Channel,
+		&msg.Body,
+		&msg.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
@@ -0,0 +1,122 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/hazzardr/baduk-online/internal/validator"
+)
+
+func TestValidateChatMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		msg     ChatMessage
+		wantKey string
+	}{
+		{"Valid message", ChatMessage{Channel: ChatChannelPublic, Body: "good game"}, ""},
+		{"Longest message", ChatMessage{Channel: ChatChannelPlayers, Body: strings.Repeat("碁", 500)}, ""},
+		{"Unknown channel", ChatMessage{Channel: "moderators", Body: "hello"}, "channel"},
+		{"Empty body", ChatMessage{Channel: ChatChannelPublic, Body: ""}, "body"},
+		{"Blank body", ChatMessage{Channel: ChatChannelPublic, Body: " \n\t"}, "body"},
+		{"Too long", ChatMessage{Channel: ChatChannelPublic, Body: strings.Repeat("a", 501)}, "body"},
+		{"Invalid UTF-8", ChatMessage{Channel: ChatChannelPublic, Body: "\xff"}, "body"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			ValidateChatMessage(v, &tt.msg)
+			if tt.wantKey == "" {
+				if !v.Valid() {
+					t.Errorf("expected no errors, got %v", v.Errors)
+				}
+				return
+			}
+			if _, ok := v.Errors[tt.wantKey]; !ok {
+				t.Errorf("expected error for %q, got %v", tt.wantKey, v.Errors)
+			}
+		})
+	}
+}
+
+func TestChatStoreIntegration(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	black := insertTestUser(t, db, "black@example.com")
+	white := insertTestUser(t, db, "white@example.com")
+	game := &Game{BlackID: int64(black.ID), WhiteID: int64(white.ID), BoardSize: 19, Rules: "japanese", Komi: 6.5}
+	if err := db.Games.Insert(ctx, game); err != nil {
+		t.Fatalf("failed to insert game: %s", err)
+	}
+
+	post := func(t *testing.T, userID int, channel, body string) *ChatMessage {
+		t.Helper()
+		msg := &ChatMessage{GameID: game.ID, UserID: int64(userID), Channel: channel, Body: body}
+		if err := db.Chat.Insert(ctx, msg); err != nil {
+			t.Fatalf("failed to insert chat message: %s", err)
+		}
+		return msg
+	}
+
+	t.Run("insert fills in server fields", func(t *testing.T) {
+		msg := post(t, black.ID, ChatChannelPublic, "hello")
+		if msg.ID == 0 || msg.CreatedAt.IsZero() || msg.UserName != black.Name {
+			t.Errorf("unexpected message: %+v", msg)
+		}
+	})
+
+	t.Run("channels are kept apart and paged newest first", func(t *testing.T) {
+		var ids []int64
+		for _, body := range []string{"one", "two", "three"} {
+			ids = append(ids, post(t, white.ID, ChatChannelPlayers, body).ID)
+		}
+
+		page, err := db.Chat.List(ctx, game.ID, ChatChannelPlayers, 0, 2)
+		if err != nil {
+			t.Fatalf("failed to list chat: %s", err)
+		}
+		if len(page) != 2 || page[0].ID != ids[2] || page[1].ID != ids[1] {
+			t.Fatalf("unexpected first page: %+v", page)
+		}
+		page, err = db.Chat.List(ctx, game.ID, ChatChannelPlayers, page[1].ID, 2)
+		if err != nil {
+			t.Fatalf("failed to list chat: %s", err)
+		}
+		if len(page) != 1 || page[0].Body != "one" {
+			t.Fatalf("unexpected second page: %+v", page)
+		}
+
+		public, err := db.Chat.List(ctx, game.ID, ChatChannelPublic, 0, MaxChatPageSize)
+		if err != nil {
+			t.Fatalf("failed to list chat: %s", err)
+		}
+		for _, m := range public {
+			if m.Channel != ChatChannelPublic {
+				t.Errorf("players message in public channel: %+v", m)
+			}
+		}
+	})
+
+	t.Run("deleted messages are hidden", func(t *testing.T) {
+		msg := post(t, black.ID, ChatChannelPublic, "rude")
+		if err := db.Chat.Delete(ctx, msg.ID, int64(white.ID)); err != nil {
+			t.Fatalf("failed to delete message: %s", err)
+		}
+		if _, err := db.Chat.Get(ctx, msg.ID); !errors.Is(err, ErrNoChatMessageFound) {
+			t.Errorf("expected ErrNoChatMessageFound, got %v", err)
+		}
+		if err := db.Chat.Delete(ctx, msg.ID, int64(white.ID)); !errors.Is(err, ErrNoChatMessageFound) {
+			t.Errorf("expected deleting twice to fail, got %v", err)
+		}
+		public, _ := db.Chat.List(ctx, game.ID, ChatChannelPublic, 0, MaxChatPageSize)
+		for _, m := range public {
+			if m.ID == msg.ID {
+				t.Error("deleted message was listed")
+			}
+		}
+	})
+}
@@ -12,6 +12,18 @@ type Database struct {
 	Pool         *pgxpool.Pool
 	Users        *userStore
 	Registration *registrationStore
+	Tokens       *tokenStore
+	Games        *gameStore
+	Challenges   *challengeStore
+	Ratings      *ratingStore
+	Automatch    *automatchStore
+	Chat         *chatStore
+	Reminders    *reminderStore
+	TwoFactor    *twoFactorStore
+	Passkeys     *passkeyStore
+	Identities   *identityStore
+	APITokens    *apiTokenStore
+	UserSessions *userSessionStore
 }
 
 // userStore handles database operations for users.
@@ -35,6 +47,18 @@ func New(dsn string) (*Database, error) {
 		pool,
 		&userStore{db: pool},
 		&registrationStore{db: pool},
+		&tokenStore{db: pool},
+		&gameStore{db: pool},
+		&challengeStore{db: pool},
+		&ratingStore{db: pool},
+		&automatchStore{db: pool},
+		&chatStore{db: pool},
+		&reminderStore{db: pool},
+		&twoFactorStore{db: pool},
+		&passkeyStore{db: pool},
+		&identityStore{db: pool},
+		&apiTokenStore{db: pool},
+		&userSessionStore{db: pool},
 	}, nil
 }
 
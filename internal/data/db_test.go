@@ -0,0 +1,74 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/pressly/goose/v3"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+func setupTestDB(t *testing.T) (*Database, func()) {
+	ctx := context.Background()
+
+	pgContainer, err := postgres.Run(ctx,
+		"postgres:17.5",
+		postgres.WithDatabase("testdb"),
+		postgres.WithUsername("postgres"),
+		postgres.WithPassword("postgres"),
+		testcontainers.WithWaitStrategy(
+			wait.ForLog("database system is ready to accept connections").
+				WithOccurrence(2).
+				WithStartupTimeout(60*time.Second)),
+	)
+	if err != nil {
+		t.Fatalf("failed to start postgres container: %s", err)
+	}
+
+	connStr, err := pgContainer.ConnectionString(ctx, "sslmode=disable")
+	if err != nil {
+		t.Fatalf("failed to get connection string: %s", err)
+	}
+
+	sqlDB, err := sql.Open("pgx", connStr)
+	if err != nil {
+		t.Fatalf("failed to open database connection for migrations: %s", err)
+	}
+	defer sqlDB.Close()
+
+	if err := goose.Up(sqlDB, "../../migrations"); err != nil {
+		t.Fatalf("failed to run migrations: %s", err)
+	}
+
+	db, err := New(connStr)
+	if err != nil {
+		t.Fatalf("failed to connect to test database: %s", err)
+	}
+
+	cleanup := func() {
+		db.Close()
+		if err := pgContainer.Terminate(ctx); err != nil {
+			t.Logf("failed to terminate container: %s", err)
+		}
+	}
+
+	return db, cleanup
+}
+
+// insertTestUser creates a validated user with the given email.
+func insertTestUser(t *testing.T, db *Database, email string) *User {
+	t.Helper()
+	user := &User{Name: "Test User", Email: email, Validated: true}
+	if err := user.Password.Set("password123"); err != nil {
+		t.Fatalf("failed to set password: %s", err)
+	}
+	if err := db.Users.Insert(context.Background(), user); err != nil {
+		t.Fatalf("failed to insert user: %s", err)
+	}
+	return user
+}
@@ -0,0 +1,519 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/hazzardr/baduk-online/internal/clock"
+	"github.com/hazzardr/baduk-online/internal/scoring"
+	"github.com/hazzardr/baduk-online/internal/validator"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	// GameStatusActive is the status of a game that is still being played.
+	GameStatusActive = "active"
+	// GameStatusFinished is the status of a game that has a result.
+	GameStatusFinished = "finished"
+
+	// ColorBlack is the color of the first player.
+	ColorBlack = "black"
+	// ColorWhite is the color of the second player.
+	ColorWhite = "white"
+)
+
+// Game represents a game between two users. A player's ID is 0 once their account has been purged, so that
+// their opponent keeps the game.
+type Game struct {
+	ID        int64      `json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	BlackID   int64      `json:"black_id"`
+	WhiteID   int64      `json:"white_id"`
+	BoardSize int        `json:"board_size"`
+	Rules     string     `json:"rules"`
+	Komi      float64    `json:"komi"`
+	Handicap  int        `json:"handicap"`
+	Rated     bool       `json:"rated"`
+	Status    string     `json:"status"`
+	Result    string     `json:"result"`
+	MoveCount int        `json:"move_count"`
+	EndedAt   *time.Time `json:"ended_at,omitempty"`
+	// Clock is nil for untimed games.
+	Clock   *clock.State `json:"clock,omitempty"`
+	Version int          `json:"-"`
+}
+
+// Move is a single move in a game. Sequence numbers start at 1 and PlayedAt is assigned by the database so
+// that games can be replayed exactly.
+type Move struct {
+	GameID   int64     `json:"-"`
+	Seq      int       `json:"seq"`
+	Color    string    `json:"color"`
+	X        int       `json:"x"`
+	Y        int       `json:"y"`
+	Pass     bool      `json:"pass"`
+	PlayedAt time.Time `json:"played_at"`
+}
+
+// ValidateGame checks the settings of a new game.
+func ValidateGame(v *validator.Validator, game *Game) {
+	v.Check(game.BlackID != 0, "black_id", "must be provided")
+	v.Check(game.WhiteID != 0, "white_id", "must be provided")
+	v.Check(game.BlackID != game.WhiteID, "white_id", "must not be the same player as black")
+	v.Check(game.BoardSize >= 2 && game.BoardSize <= 25, "board_size", "must be between 2 and 25")
+	scoring.ValidateRuleset(v, scoring.Ruleset(game.Rules))
+	scoring.ValidateKomi(v, game.Komi)
+	v.Check(game.Handicap >= 0 && game.Handicap <= 9, "handicap", "must be between 0 and 9")
+}
+
+// ValidateMove checks that a move is made by a valid color and, unless it is a pass, lies on the board.
+func ValidateMove(v *validator.Validator, game *Game, move *Move) {
+	v.Check(validator.PermittedValue(move.Color, ColorBlack, ColorWhite), "color", "must be black or white")
+	if !move.Pass {
+		v.Check(move.X >= 0 && move.X < game.BoardSize, "x", "must be on the board")
+		v.Check(move.Y >= 0 && move.Y < game.BoardSize, "y", "must be on the board")
+	}
+}
+
+// ToMove returns the color of the player whose turn it is. Black moves first unless white does because black
+// has handicap stones, and passes count as moves.
+func (game *Game) ToMove() string {
+	moves := game.MoveCount
+	if game.Handicap > 1 {
+		moves++
+	}
+	if moves%2 == 0 {
+		return ColorBlack
+	}
+	return ColorWhite
+}
+
+// queryRower is implemented by both pgxpool.Pool and pgx.Tx, so that an insert can take part in a larger
+// transaction.
+type queryRower interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// gameStore handles database operations for games and their moves.
+type gameStore struct {
+	db *pgxpool.Pool
+}
+
+// Insert creates a new game and populates its ID, CreatedAt, Status and Version fields.
+func (g *gameStore) Insert(ctx context.Context, game *Game) error {
+	c, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	return insertGame(c, g.db, game)
+}
+
+// insertGame inserts a game using either the pool or a transaction.
+func insertGame(ctx context.Context, q queryRower, game *Game) error {
+	query := `
+		INSERT INTO games (black_id, white_id, board_size, rules, komi, handicap, rated, clock, clock_deadline)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, created_at, status, version`
+	return q.QueryRow(
+		ctx,
+		query,
+		game.BlackID,
+		game.WhiteID,
+		game.BoardSize,
+		game.Rules,
+		game.Komi,
+		game.Handicap,
+		game.Rated,
+		game.Clock,
+		clockDeadline(game),
+	).Scan(&game.ID, &game.CreatedAt, &game.Status, &game.Version)
+}
+
+// Get retrieves a game by its ID.
+// Returns ErrNoGameFound if no game exists with the given ID.
+func (g *gameStore) Get(ctx context.Context, id int64) (*Game, error) {
+	query := `
+		SELECT ` + gameColumns + `
+		FROM games
+		WHERE id = $1
+	`
+	c, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	game, err := scanGame(g.db.QueryRow(c, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoGameFound
+		}
+		return nil, err
+	}
+	return game, nil
+}
+
+// ListForPlayer returns the games a user has played as either color, most recent first.
+func (g *gameStore) ListForPlayer(ctx context.Context, userID int64) ([]*Game, error) {
+	query := `
+		SELECT ` + gameColumns + `
+		FROM games
+		WHERE black_id = $1 OR white_id = $1
+		ORDER BY created_at DESC, id DESC
+	`
+	c, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := g.db.Query(c, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	games := []*Game{}
+	for rows.Next() {
+		game, err := scanGame(rows)
+		if err != nil {
+			return nil, err
+		}
+		games = append(games, game)
+	}
+	return games, rows.Err()
+}
+
+// AppendMove records the next move of an active game. The move's sequence number and timestamp are assigned
+// here, and the game's MoveCount and Version are updated. The game's clock is saved as it is, so the caller
+// should press it first. Returns ErrGameFinished if the game is over and
+// ErrEditConflict if the game was modified since it was read.
+func (g *gameStore) AppendMove(ctx context.Context, game *Game, move *Move) error {
+	if game.Status != GameStatusActive {
+		return ErrGameFinished
+	}
+
+	c, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	tx, err := g.db.Begin(c)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(c) //nolint:errcheck // rollback after commit is a no-op
+
+	update := `
+		UPDATE games
+		SET
+			move_count = move_count + 1,
+			clock = $1,
+			clock_deadline = $2,
+			version = version + 1
+		WHERE
+			id = $3
+		AND
+			version = $4
+		AND
+			status = $5
+		RETURNING
+			move_count, version
+	`
+	var moveCount, version int
+	err = tx.QueryRow(
+		c,
+		update,
+		game.Clock,
+		clockDeadline(game),
+		game.ID,
+		game.Version,
+		GameStatusActive,
+	).Scan(&moveCount, &version)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrEditConflict
+		}
+		return err
+	}
+
+	insert := `
+		INSERT INTO moves (game_id, seq, color, x, y, pass)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING played_at
+	`
+	var playedAt time.Time
+	err = tx.QueryRow(c, insert, game.ID, moveCount, move.Color, move.X, move.Y, move.Pass).Scan(&playedAt)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit(c)
+	if err != nil {
+		return err
+	}
+
+	move.GameID = game.ID
+	move.Seq = moveCount
+	move.PlayedAt = playedAt
+	game.MoveCount = moveCount
+	game.Version = version
+	return nil
+}
+
+// Moves returns every move of a game in the order they were played.
+func (g *gameStore) Moves(ctx context.Context, gameID int64) ([]*Move, error) {
+	query := `
+		SELECT game_id, seq, color, x, y, pass, played_at
+		FROM moves
+		WHERE game_id = $1
+		ORDER BY seq
+	`
+	c, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := g.db.Query(c, query, gameID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	moves := []*Move{}
+	for rows.Next() {
+		var move Move
+		err := rows.Scan(&move.GameID, &move.Seq, &move.Color, &move.X, &move.Y, &move.Pass, &move.PlayedAt)
+		if err != nil {
+			return nil, err
+		}
+		moves = append(moves, &move)
+	}
+	return moves, rows.Err()
+}
+
+// Resign ends an active game with a win for the opponent of the given color.
+func (g *gameStore) Resign(ctx context.Context, game *Game, color string) error {
+	result := "W+R"
+	if color == ColorWhite {
+		result = "B+R"
+	}
+	return g.Finalize(ctx, game, result)
+}
+
+// Finalize ends an active game with the given result, e.g. "B+3.5", "W+T" or "0" for a draw. The game's
+// clock is saved as it is, so the caller should stop it first. Returns ErrGameFinished if the game is
+// already over and ErrEditConflict if it was modified since it was read.
+func (g *gameStore) Finalize(ctx context.Context, game *Game, result string) error {
+	if game.Status != GameStatusActive {
+		return ErrGameFinished
+	}
+
+	query := `
+		UPDATE games
+		SET
+			status = $1,
+			result = $2,
+			ended_at = NOW(),
+			clock = $3,
+			clock_deadline = NULL,
+			version = version + 1
+		WHERE
+			id = $4
+		AND
+			version = $5
+		AND
+			status = $6
+		RETURNING
+			status, result, ended_at, version
+	`
+	c, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	err := g.db.QueryRow(
+		c,
+		query,
+		GameStatusFinished,
+		result,
+		game.Clock,
+		game.ID,
+		game.Version,
+		GameStatusActive,
+	).Scan(&game.Status, &game.Result, &game.EndedAt, &game.Version)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrEditConflict
+		}
+		return err
+	}
+	return nil
+}
+
+// ListExpiredClocks returns the active games whose player to move ran out of time at or before now.
+func (g *gameStore) ListExpiredClocks(ctx context.Context, now time.Time) ([]*Game, error) {
+	query := `
+		SELECT ` + gameColumns + `
+		FROM games
+		WHERE status = $1 AND clock_deadline <= $2
+		ORDER BY clock_deadline
+	`
+	c, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := g.db.Query(c, query, GameStatusActive, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	games := []*Game{}
+	for rows.Next() {
+		game, err := scanGame(rows)
+		if err != nil {
+			return nil, err
+		}
+		games = append(games, game)
+	}
+	return games, rows.Err()
+}
+
+// UpdateClock saves the clock of an active game, for instance after pausing it. Returns ErrGameFinished if
+// the game is over and ErrEditConflict if it was modified since it was read.
+func (g *gameStore) UpdateClock(ctx context.Context, game *Game) error {
+	if game.Status != GameStatusActive {
+		return ErrGameFinished
+	}
+
+	query := `
+		UPDATE games
+		SET
+			clock = $1,
+			clock_deadline = $2,
+			version = version + 1
+		WHERE
+			id = $3
+		AND
+			version = $4
+		AND
+			status = $5
+		RETURNING
+			version
+	`
+	c, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	err := g.db.QueryRow(
+		c,
+		query,
+		game.Clock,
+		clockDeadline(game),
+		game.ID,
+		game.Version,
+		GameStatusActive,
+	).Scan(&game.Version)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrEditConflict
+		}
+		return err
+	}
+	return nil
+}
+
+// ListClocksDueBy returns the active games whose player to move has time left at now but will run out of
+// it by the given time, soonest first.
+func (g *gameStore) ListClocksDueBy(ctx context.Context, now, by time.Time) ([]*Game, error) {
+	query := `
+		SELECT ` + gameColumns + `
+		FROM games
+		WHERE status = $1 AND clock_deadline > $2 AND clock_deadline <= $3
+		ORDER BY clock_deadline
+	`
+	c, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := g.db.Query(c, query, GameStatusActive, now, by)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	games := []*Game{}
+	for rows.Next() {
+		game, err := scanGame(rows)
+		if err != nil {
+			return nil, err
+		}
+		games = append(games, game)
+	}
+	return games, rows.Err()
+}
+
+// ListAwaitingBot returns the active games in which it is a bot's turn, oldest first. The turn is worked out
+// the same way as by Game.ToMove.
+func (g *gameStore) ListAwaitingBot(ctx context.Context) ([]*Game, error) {
+	query := `
+		SELECT ` + gameColumns + `
+		FROM games
+		INNER JOIN users u
+		ON u.id = CASE
+			WHEN (games.move_count + CASE WHEN games.handicap > 1 THEN 1 ELSE 0 END) % 2 = 0 THEN games.black_id
+			ELSE games.white_id
+		END
+		WHERE games.status = $1 AND u.bot
+		ORDER BY games.id
+	`
+	c, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := g.db.Query(c, query, GameStatusActive)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	games := []*Game{}
+	for rows.Next() {
+		game, err := scanGame(rows)
+		if err != nil {
+			return nil, err
+		}
+		games = append(games, game)
+	}
+	return games, rows.Err()
+}
+
+// gameColumns are the columns read by scanGame. They are qualified so that queries can join other tables.
+const gameColumns = `
+	games.id, games.created_at, COALESCE(games.black_id, 0), COALESCE(games.white_id, 0), games.board_size,
+	games.rules, games.komi, games.handicap, games.rated, games.status, games.result, games.move_count,
+	games.ended_at, games.clock, games.version`
+
+func scanGame(row pgx.Row) (*Game, error) {
+	var game Game
+	err := row.Scan(
+		&game.ID,
+		&game.CreatedAt,
+		&game.BlackID,
+		&game.WhiteID,
+		&game.BoardSize,
+		&game.Rules,
+		&game.Komi,
+		&game.Handicap,
+		&game.Rated,
+		&game.Status,
+		&game.Result,
+		&game.MoveCount,
+		&game.EndedAt,
+		&game.Clock,
+		&game.Version,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &game, nil
+}
+
+// clockDeadline returns when the player to move in a game runs out of time, or nil if the game is untimed
+// or its clock is stopped. It is stored alongside the clock so that expired games can be found by index.
+func clockDeadline(game *Game) *time.Time {
+	if game.Clock == nil {
+		return nil
+	}
+	deadline, ok := game.Clock.Deadline()
+	if !ok {
+		return nil
+	}
+	return &deadline
+}
@@ -0,0 +1,281 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hazzardr/baduk-online/internal/clock"
+	"github.com/hazzardr/baduk-online/internal/game"
+	"github.com/hazzardr/baduk-online/internal/validator"
+)
+
+func TestValidateGame(t *testing.T) {
+	tests := []struct {
+		name    string
+		game    Game
+		wantKey string
+	}{
+		{"Valid game", Game{BlackID: 1, WhiteID: 2, BoardSize: 19, Rules: "japanese", Komi: 6.5}, ""},
+		{"Same player", Game{BlackID: 1, WhiteID: 1, BoardSize: 19, Rules: "japanese", Komi: 6.5}, "white_id"},
+		{"Board too large", Game{BlackID: 1, WhiteID: 2, BoardSize: 30, Rules: "chinese", Komi: 7.5}, "board_size"},
+		{"Unknown rules", Game{BlackID: 1, WhiteID: 2, BoardSize: 9, Rules: "ing", Komi: 7.5}, "rules"},
+		{"Fractional komi", Game{BlackID: 1, WhiteID: 2, BoardSize: 9, Rules: "aga", Komi: 7.2}, "komi"},
+		{"Too many handicap stones", Game{BlackID: 1, WhiteID: 2, BoardSize: 19, Rules: "aga", Handicap: 10}, "handicap"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			ValidateGame(v, &tt.game)
+			if tt.wantKey == "" {
+				if !v.Valid() {
+					t.Errorf("expected no errors, got %v", v.Errors)
+				}
+				return
+			}
+			if _, ok := v.Errors[tt.wantKey]; !ok {
+				t.Errorf("expected error for %q, got %v", tt.wantKey, v.Errors)
+			}
+		})
+	}
+}
+
+func TestGameToMove(t *testing.T) {
+	tests := []struct {
+		name string
+		game Game
+		want string
+	}{
+		{"First move", Game{}, ColorBlack},
+		{"Second move", Game{MoveCount: 1}, ColorWhite},
+		{"Third move", Game{MoveCount: 2}, ColorBlack},
+		{"White starts handicap games", Game{Handicap: 2}, ColorWhite},
+		{"Black replies in handicap games", Game{Handicap: 4, MoveCount: 1}, ColorBlack},
+		{"One stone handicap has black first", Game{Handicap: 1}, ColorBlack},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.game.ToMove(); got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestGameStoreIntegration(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	black := insertTestUser(t, db, "black@example.com")
+	white := insertTestUser(t, db, "white@example.com")
+	other := insertTestUser(t, db, "other@example.com")
+
+	newGame := func(t *testing.T) *Game {
+		t.Helper()
+		game := &Game{
+			BlackID:   int64(black.ID),
+			WhiteID:   int64(white.ID),
+			BoardSize: 19,
+			Rules:     "japanese",
+			Komi:      6.5,
+		}
+		if err := db.Games.Insert(ctx, game); err != nil {
+			t.Fatalf("failed to insert game: %s", err)
+		}
+		return game
+	}
+
+	t.Run("create and get", func(t *testing.T) {
+		game := newGame(t)
+		if game.Status != GameStatusActive {
+			t.Errorf("expected status %q, got %q", GameStatusActive, game.Status)
+		}
+		got, err := db.Games.Get(ctx, game.ID)
+		if err != nil {
+			t.Fatalf("failed to get game: %s", err)
+		}
+		if got.BlackID != game.BlackID || got.Komi != 6.5 || got.Version != 1 {
+			t.Errorf("unexpected game: %+v", got)
+		}
+	})
+
+	t.Run("get missing game", func(t *testing.T) {
+		_, err := db.Games.Get(ctx, 999999)
+		if !errors.Is(err, ErrNoGameFound) {
+			t.Errorf("expected ErrNoGameFound, got %v", err)
+		}
+	})
+
+	t.Run("list by player", func(t *testing.T) {
+		games, err := db.Games.ListForPlayer(ctx, int64(white.ID))
+		if err != nil {
+			t.Fatalf("failed to list games: %s", err)
+		}
+		if len(games) == 0 {
+			t.Error("expected games for white")
+		}
+		games, err = db.Games.ListForPlayer(ctx, int64(other.ID))
+		if err != nil {
+			t.Fatalf("failed to list games: %s", err)
+		}
+		if len(games) != 0 {
+			t.Errorf("expected no games for other player, got %d", len(games))
+		}
+	})
+
+	t.Run("append moves in sequence", func(t *testing.T) {
+		game := newGame(t)
+		moves := []*Move{
+			{Color: ColorBlack, X: 3, Y: 3},
+			{Color: ColorWhite, X: 15, Y: 15},
+			{Color: ColorBlack, Pass: true},
+		}
+		for i, m := range moves {
+			if err := db.Games.AppendMove(ctx, game, m); err != nil {
+				t.Fatalf("failed to append move %d: %s", i, err)
+			}
+			if m.Seq != i+1 {
+				t.Errorf("expected seq %d, got %d", i+1, m.Seq)
+			}
+		}
+		if game.MoveCount != 3 || game.Version != 4 {
+			t.Errorf("expected move count 3 and version 4, got %d and %d", game.MoveCount, game.Version)
+		}
+
+		stored, err := db.Games.Moves(ctx, game.ID)
+		if err != nil {
+			t.Fatalf("failed to list moves: %s", err)
+		}
+		if len(stored) != 3 {
+			t.Fatalf("expected 3 moves, got %d", len(stored))
+		}
+		for i := 1; i < len(stored); i++ {
+			if stored[i].PlayedAt.Before(stored[i-1].PlayedAt) {
+				t.Error("expected move timestamps to be non-decreasing")
+			}
+		}
+		if !stored[2].Pass || stored[1].X != 15 {
+			t.Errorf("unexpected stored moves: %+v %+v", stored[1], stored[2])
+		}
+	})
+
+	t.Run("stale append conflicts", func(t *testing.T) {
+		game := newGame(t)
+		stale := *game
+		if err := db.Games.AppendMove(ctx, game, &Move{Color: ColorBlack, X: 3, Y: 3}); err != nil {
+			t.Fatalf("failed to append move: %s", err)
+		}
+		err := db.Games.AppendMove(ctx, &stale, &Move{Color: ColorBlack, X: 4, Y: 4})
+		if !errors.Is(err, ErrEditConflict) {
+			t.Errorf("expected ErrEditConflict, got %v", err)
+		}
+		stored, _ := db.Games.Moves(ctx, game.ID)
+		if len(stored) != 1 {
+			t.Errorf("expected conflicting move to be discarded, got %d moves", len(stored))
+		}
+	})
+
+	t.Run("resign", func(t *testing.T) {
+		game := newGame(t)
+		if err := db.Games.Resign(ctx, game, ColorBlack); err != nil {
+			t.Fatalf("failed to resign: %s", err)
+		}
+		if game.Status != GameStatusFinished || game.Result != "W+R" || game.EndedAt == nil {
+			t.Errorf("unexpected game after resignation: %+v", game)
+		}
+		err := db.Games.AppendMove(ctx, game, &Move{Color: ColorWhite, X: 3, Y: 3})
+		if !errors.Is(err, ErrGameFinished) {
+			t.Errorf("expected ErrGameFinished, got %v", err)
+		}
+	})
+
+	t.Run("finalize", func(t *testing.T) {
+		game := newGame(t)
+		stale := *game
+		if err := db.Games.Finalize(ctx, game, "B+3.5"); err != nil {
+			t.Fatalf("failed to finalize: %s", err)
+		}
+		got, err := db.Games.Get(ctx, game.ID)
+		if err != nil {
+			t.Fatalf("failed to get game: %s", err)
+		}
+		if got.Result != "B+3.5" || got.Status != GameStatusFinished {
+			t.Errorf("unexpected game after finalize: %+v", got)
+		}
+		if err := db.Games.Finalize(ctx, &stale, "W+R"); !errors.Is(err, ErrEditConflict) {
+			t.Errorf("expected ErrEditConflict, got %v", err)
+		}
+	})
+
+	t.Run("clock is stored and expired games are listed", func(t *testing.T) {
+		start := time.Now().Truncate(time.Millisecond)
+		g := &Game{
+			BlackID:   int64(black.ID),
+			WhiteID:   int64(white.ID),
+			BoardSize: 9,
+			Rules:     "chinese",
+			Komi:      7.5,
+			Clock:     clock.New(clock.TimeControl{Kind: clock.Fischer, MainTime: time.Minute, Increment: time.Second}),
+		}
+		if err := g.Clock.Start(start, game.Black); err != nil {
+			t.Fatalf("failed to start clock: %s", err)
+		}
+		if err := db.Games.Insert(ctx, g); err != nil {
+			t.Fatalf("failed to insert game: %s", err)
+		}
+
+		if err := g.Clock.Press(start.Add(10*time.Second), game.Black); err != nil {
+			t.Fatalf("failed to press clock: %s", err)
+		}
+		if err := db.Games.AppendMove(ctx, g, &Move{Color: ColorBlack, X: 4, Y: 4}); err != nil {
+			t.Fatalf("failed to append move: %s", err)
+		}
+
+		got, err := db.Games.Get(ctx, g.ID)
+		if err != nil {
+			t.Fatalf("failed to get game: %s", err)
+		}
+		if got.Clock == nil || got.Clock.Black.Main != 51*time.Second || got.Clock.ToMove != game.White {
+			t.Errorf("unexpected stored clock: %+v", got.Clock)
+		}
+
+		// White's minute runs out 70 seconds after the start.
+		expired, err := db.Games.ListExpiredClocks(ctx, start.Add(69*time.Second))
+		if err != nil {
+			t.Fatalf("failed to list expired clocks: %s", err)
+		}
+		for _, e := range expired {
+			if e.ID == g.ID {
+				t.Error("game listed as expired before its deadline")
+			}
+		}
+		expired, err = db.Games.ListExpiredClocks(ctx, start.Add(70*time.Second))
+		if err != nil {
+			t.Fatalf("failed to list expired clocks: %s", err)
+		}
+		found := false
+		for _, e := range expired {
+			found = found || e.ID == g.ID
+		}
+		if !found {
+			t.Fatal("expected game to be listed as expired")
+		}
+
+		if _, ok := g.Clock.CheckTimeout(start.Add(70 * time.Second)); !ok {
+			t.Fatal("expected white to have run out of time")
+		}
+		if err := db.Games.Finalize(ctx, g, "B+T"); err != nil {
+			t.Fatalf("failed to finalize: %s", err)
+		}
+		expired, _ = db.Games.ListExpiredClocks(ctx, start.Add(time.Hour))
+		for _, e := range expired {
+			if e.ID == g.ID {
+				t.Error("finished game listed as expired")
+			}
+		}
+	})
+}
@@ -11,4 +11,35 @@ var (
 	ErrNoUserFound = errors.New("no user found")
 	// ErrEditConflict is returned when an edit is performed on stale data.
 	ErrEditConflict = errors.New("record modified in flight")
+	// ErrNoGameFound is returned when a game query returns no results.
+	ErrNoGameFound = errors.New("no game found")
+	// ErrGameFinished is returned when attempting to change a game that already has a result.
+	ErrGameFinished = errors.New("game is already finished")
+	// ErrNoChallengeFound is returned when a challenge query returns no results.
+	ErrNoChallengeFound = errors.New("no challenge found")
+	// ErrChallengeClosed is returned when responding to a challenge that is no longer open, including one that
+	// another user accepted first.
+	ErrChallengeClosed = errors.New("challenge is no longer open")
+	// ErrNotQueued is returned when a user is not waiting in the automatch queue.
+	ErrNotQueued = errors.New("user is not in the automatch queue")
+	// ErrQueueChanged is returned when pairing players whose automatch queue entries have changed.
+	ErrQueueChanged = errors.New("automatch queue changed")
+	// ErrNoChatMessageFound is returned when a chat message query returns no results.
+	ErrNoChatMessageFound = errors.New("no chat message found")
+	// ErrNoTwoFactor is returned when a user has not enrolled in two-factor authentication.
+	ErrNoTwoFactor = errors.New("two-factor authentication is not set up")
+	// ErrTwoFactorEnabled is returned when enrolling a user who already has two-factor authentication.
+	ErrTwoFactorEnabled = errors.New("two-factor authentication is already enabled")
+	// ErrNoPasskeyFound is returned when a passkey query returns no results.
+	ErrNoPasskeyFound = errors.New("no passkey found")
+	// ErrDuplicatePasskey is returned when registering a credential that is already registered.
+	ErrDuplicatePasskey = errors.New("duplicate passkey")
+	// ErrNoIdentityFound is returned when no user is linked to an external identity.
+	ErrNoIdentityFound = errors.New("no identity found")
+	// ErrDuplicateIdentity is returned when linking an external identity that is already linked.
+	ErrDuplicateIdentity = errors.New("duplicate identity")
+	// ErrNoAPITokenFound is returned when an API token query returns no results.
+	ErrNoAPITokenFound = errors.New("no API token found")
+	// ErrNoUserSessionFound is returned when a user session query returns no results.
+	ErrNoUserSessionFound = errors.New("no user session found")
 )
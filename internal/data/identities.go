@@ -0,0 +1,113 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Identity links a user to an account with an OpenID Connect provider, so that they can log in with it.
+type Identity struct {
+	Issuer    string    `json:"issuer"`
+	Subject   string    `json:"-"`
+	UserID    int64     `json:"-"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// identityStore handles database operations for external identities.
+type identityStore struct {
+	db *pgxpool.Pool
+}
+
+// Insert links an identity to its user and populates its CreatedAt field.
+// Returns ErrDuplicateIdentity if the identity is already linked.
+func (s *identityStore) Insert(ctx context.Context, identity *Identity) error {
+	query := `
+		INSERT INTO identities (issuer, subject, user_id, email)
+		VALUES ($1, $2, $3, $4)
+		RETURNING created_at
+	`
+	c, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	err := s.db.QueryRow(c, query, identity.Issuer, identity.Subject, identity.UserID, identity.Email).Scan(
+		&identity.CreatedAt,
+	)
+	if err != nil {
+		var e *pgconn.PgError
+		if errors.As(err, &e) && e.Code == pgerrcode.UniqueViolation {
+			return ErrDuplicateIdentity
+		}
+		return err
+	}
+	return nil
+}
+
+// Get retrieves the identity with the given issuer and subject.
+// Returns ErrNoIdentityFound if it isn't linked to any user.
+func (s *identityStore) Get(ctx context.Context, issuer, subject string) (*Identity, error) {
+	query := `
+		SELECT issuer, subject, user_id, email, created_at
+		FROM identities
+		WHERE issuer = $1 AND subject = $2
+	`
+	c, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	var identity Identity
+	err := s.db.QueryRow(c, query, issuer, subject).Scan(
+		&identity.Issuer,
+		&identity.Subject,
+		&identity.UserID,
+		&identity.Email,
+		&identity.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoIdentityFound
+		}
+		return nil, err
+	}
+	return &identity, nil
+}
+
+// ListForUser returns the identities linked to a user, oldest first.
+func (s *identityStore) ListForUser(ctx context.Context, userID int64) ([]*Identity, error) {
+	query := `
+		SELECT issuer, subject, user_id, email, created_at
+		FROM identities
+		WHERE user_id = $1
+		ORDER BY created_at, issuer
+	`
+	c, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := s.db.Query(c, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	identities := []*Identity{}
+	for rows.Next() {
+		var identity Identity
+		err := rows.Scan(
+			&identity.Issuer,
+			&identity.Subject,
+			&identity.UserID,
+			&identity.Email,
+			&identity.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		identities = append(identities, &identity)
+	}
+	return identities, rows.Err()
+}
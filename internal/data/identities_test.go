@@ -0,0 +1,74 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestIdentitiesIntegration(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	user := insertTestUser(t, db, "identities@example.com")
+	other := insertTestUser(t, db, "other@example.com")
+
+	identity := &Identity{
+		Issuer:  "https://accounts.example.com",
+		Subject: "12345",
+		UserID:  int64(user.ID),
+		Email:   "identities@example.com",
+	}
+	if err := db.Identities.Insert(ctx, identity); err != nil {
+		t.Fatalf("failed to insert identity: %s", err)
+	}
+	if identity.CreatedAt.IsZero() {
+		t.Error("expected CreatedAt to be set")
+	}
+
+	got, err := db.Identities.Get(ctx, "https://accounts.example.com", "12345")
+	if err != nil {
+		t.Fatalf("failed to get identity: %s", err)
+	}
+	if got.UserID != int64(user.ID) || got.Email != "identities@example.com" {
+		t.Errorf("unexpected identity %+v", got)
+	}
+
+	t.Run("a user's identities are listed", func(t *testing.T) {
+		identities, err := db.Identities.ListForUser(ctx, int64(user.ID))
+		if err != nil {
+			t.Fatalf("failed to list identities: %s", err)
+		}
+		if len(identities) != 1 || identities[0].Subject != "12345" {
+			t.Errorf("unexpected identities %+v", identities)
+		}
+	})
+
+	t.Run("an identity can only be linked once", func(t *testing.T) {
+		duplicate := &Identity{
+			Issuer:  "https://accounts.example.com",
+			Subject: "12345",
+			UserID:  int64(other.ID),
+			Email:   "other@example.com",
+		}
+		if err := db.Identities.Insert(ctx, duplicate); !errors.Is(err, ErrDuplicateIdentity) {
+			t.Errorf("expected ErrDuplicateIdentity, got %v", err)
+		}
+	})
+
+	t.Run("subjects are scoped to their issuer", func(t *testing.T) {
+		if _, err := db.Identities.Get(ctx, "https://other.example.com", "12345"); !errors.Is(err, ErrNoIdentityFound) {
+			t.Errorf("expected ErrNoIdentityFound, got %v", err)
+		}
+		elsewhere := &Identity{
+			Issuer:  "https://other.example.com",
+			Subject: "12345",
+			UserID:  int64(other.ID),
+			Email:   "other@example.com",
+		}
+		if err := db.Identities.Insert(ctx, elsewhere); err != nil {
+			t.Errorf("failed to insert identity from another issuer: %s", err)
+		}
+	})
+}
@@ -0,0 +1,175 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/hazzardr/baduk-online/internal/validator"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// MaxPasskeyNameLength is the longest name a user may give a passkey, in characters.
+const MaxPasskeyNameLength = 100
+
+// Passkey is a WebAuthn credential a user can log in with instead of their password.
+type Passkey struct {
+	ID           int64      `json:"id"`
+	UserID       int64      `json:"-"`
+	CredentialID []byte     `json:"-"`
+	PublicKey    []byte     `json:"-"`
+	SignCount    uint32     `json:"-"`
+	Name         string     `json:"name"`
+	CreatedAt    time.Time  `json:"created_at"`
+	LastUsedAt   *time.Time `json:"last_used_at"`
+}
+
+// ValidatePasskeyName checks the name a user gave a passkey to tell it apart from their others.
+func ValidatePasskeyName(v *validator.Validator, name string) {
+	v.Check(strings.TrimSpace(name) != "", "name", "must be provided")
+	v.Check(utf8.RuneCountInString(name) <= MaxPasskeyNameLength, "name", "must not be more than 100 characters long")
+}
+
+// passkeyStore handles database operations for passkeys.
+type passkeyStore struct {
+	db *pgxpool.Pool
+}
+
+// Insert stores a newly registered passkey and populates its ID and CreatedAt fields.
+// Returns ErrDuplicatePasskey if the credential is already registered.
+func (s *passkeyStore) Insert(ctx context.Context, p *Passkey) error {
+	query := `
+		INSERT INTO passkeys (user_id, credential_id, public_key, sign_count, name)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`
+	c, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	err := s.db.QueryRow(c, query, p.UserID, p.CredentialID, p.PublicKey, p.SignCount, p.Name).Scan(
+		&p.ID,
+		&p.CreatedAt,
+	)
+	if err != nil {
+		var e *pgconn.PgError
+		if errors.As(err, &e) && e.Code == pgerrcode.UniqueViolation {
+			return ErrDuplicatePasskey
+		}
+		return err
+	}
+	return nil
+}
+
+// GetByCredentialID retrieves the passkey with the given WebAuthn credential ID.
+// Returns ErrNoPasskeyFound if it is not registered.
+func (s *passkeyStore) GetByCredentialID(ctx context.Context, credentialID []byte) (*Passkey, error) {
+	query := `
+		SELECT id, user_id, credential_id, public_key, sign_count, name, created_at, last_used_at
+		FROM passkeys
+		WHERE credential_id = $1
+	`
+	c, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	p, err := scanPasskey(s.db.QueryRow(c, query, credentialID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoPasskeyFound
+		}
+		return nil, err
+	}
+	return p, nil
+}
+
+// ListForUser returns a user's passkeys, oldest first.
+func (s *passkeyStore) ListForUser(ctx context.Context, userID int64) ([]*Passkey, error) {
+	query := `
+		SELECT id, user_id, credential_id, public_key, sign_count, name, created_at, last_used_at
+		FROM passkeys
+		WHERE user_id = $1
+		ORDER BY id
+	`
+	c, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := s.db.Query(c, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	passkeys := []*Passkey{}
+	for rows.Next() {
+		p, err := scanPasskey(rows)
+		if err != nil {
+			return nil, err
+		}
+		passkeys = append(passkeys, p)
+	}
+	return passkeys, rows.Err()
+}
+
+// RecordUse stores the signature count of a successful login with a passkey. The count is only updated if
+// it hasn't changed since the passkey was read, so that two logins racing with the same count can't both
+// succeed.
+// Returns ErrEditConflict if it has.
+func (s *passkeyStore) RecordUse(ctx context.Context, p *Passkey, signCount uint32, usedAt time.Time) error {
+	query := `
+		UPDATE passkeys
+		SET sign_count = $1, last_used_at = $2
+		WHERE id = $3 AND sign_count = $4
+	`
+	c, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	result, err := s.db.Exec(c, query, signCount, usedAt, p.ID, p.SignCount)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrEditConflict
+	}
+	p.SignCount = signCount
+	p.LastUsedAt = &usedAt
+	return nil
+}
+
+// Delete removes one of a user's passkeys.
+// Returns ErrNoPasskeyFound if the user has no passkey with that ID.
+func (s *passkeyStore) Delete(ctx context.Context, userID, id int64) error {
+	c, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	result, err := s.db.Exec(c, `DELETE FROM passkeys WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNoPasskeyFound
+	}
+	return nil
+}
+
+func scanPasskey(row pgx.Row) (*Passkey, error) {
+	var p Passkey
+	err := row.Scan(
+		&p.ID,
+		&p.UserID,
+		&p.CredentialID,
+		&p.PublicKey,
+		&p.SignCount,
+		&p.Name,
+		&p.CreatedAt,
+		&p.LastUsedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
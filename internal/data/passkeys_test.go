@@ -0,0 +1,121 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hazzardr/baduk-online/internal/validator"
+)
+
+func TestValidatePasskeyName(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr bool
+	}{
+		{"Valid name", "Laptop", false},
+		{"Empty name", "", true},
+		{"Blank name", "   ", true},
+		{"Longest name", strings.Repeat("鍵", MaxPasskeyNameLength), false},
+		{"Too long", strings.Repeat("a", MaxPasskeyNameLength+1), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			ValidatePasskeyName(v, tt.input)
+			if v.Valid() == tt.wantErr {
+				t.Errorf("expected error: %t, got errors %v", tt.wantErr, v.Errors)
+			}
+		})
+	}
+}
+
+func TestPasskeysIntegration(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	user := insertTestUser(t, db, "passkeys@example.com")
+	other := insertTestUser(t, db, "other@example.com")
+
+	passkey := &Passkey{
+		UserID:       int64(user.ID),
+		CredentialID: []byte("credential-1"),
+		PublicKey:    []byte("key"),
+		Name:         "Laptop",
+	}
+	if err := db.Passkeys.Insert(ctx, passkey); err != nil {
+		t.Fatalf("failed to insert passkey: %s", err)
+	}
+	if passkey.ID == 0 || passkey.CreatedAt.IsZero() {
+		t.Errorf("expected ID and CreatedAt to be set, got %+v", passkey)
+	}
+
+	duplicate := &Passkey{
+		UserID:       int64(other.ID),
+		CredentialID: []byte("credential-1"),
+		PublicKey:    []byte("key"),
+		Name:         "Copy",
+	}
+	if err := db.Passkeys.Insert(ctx, duplicate); !errors.Is(err, ErrDuplicatePasskey) {
+		t.Errorf("expected ErrDuplicatePasskey, got %v", err)
+	}
+
+	got, err := db.Passkeys.GetByCredentialID(ctx, []byte("credential-1"))
+	if err != nil {
+		t.Fatalf("failed to get passkey: %s", err)
+	}
+	if got.ID != passkey.ID || got.UserID != int64(user.ID) || got.LastUsedAt != nil {
+		t.Errorf("unexpected passkey %+v", got)
+	}
+	if _, err := db.Passkeys.GetByCredentialID(ctx, []byte("missing")); !errors.Is(err, ErrNoPasskeyFound) {
+		t.Errorf("expected ErrNoPasskeyFound, got %v", err)
+	}
+
+	t.Run("recording a use checks the sign count", func(t *testing.T) {
+		stale, err := db.Passkeys.GetByCredentialID(ctx, []byte("credential-1"))
+		if err != nil {
+			t.Fatalf("failed to get passkey: %s", err)
+		}
+		now := time.Now()
+		if err := db.Passkeys.RecordUse(ctx, got, 5, now); err != nil {
+			t.Fatalf("failed to record use: %s", err)
+		}
+		if err := db.Passkeys.RecordUse(ctx, stale, 5, now); !errors.Is(err, ErrEditConflict) {
+			t.Errorf("expected ErrEditConflict, got %v", err)
+		}
+		updated, err := db.Passkeys.GetByCredentialID(ctx, []byte("credential-1"))
+		if err != nil {
+			t.Fatalf("failed to get passkey: %s", err)
+		}
+		if updated.SignCount != 5 || updated.LastUsedAt == nil {
+			t.Errorf("expected the use to be recorded, got %+v", updated)
+		}
+	})
+
+	t.Run("only the owner can delete a passkey", func(t *testing.T) {
+		if err := db.Passkeys.Delete(ctx, int64(other.ID), passkey.ID); !errors.Is(err, ErrNoPasskeyFound) {
+			t.Errorf("expected ErrNoPasskeyFound, got %v", err)
+		}
+		passkeys, err := db.Passkeys.ListForUser(ctx, int64(user.ID))
+		if err != nil {
+			t.Fatalf("failed to list passkeys: %s", err)
+		}
+		if len(passkeys) != 1 {
+			t.Fatalf("expected 1 passkey, got %d", len(passkeys))
+		}
+		if err := db.Passkeys.Delete(ctx, int64(user.ID), passkey.ID); err != nil {
+			t.Fatalf("failed to delete passkey: %s", err)
+		}
+		passkeys, err = db.Passkeys.ListForUser(ctx, int64(user.ID))
+		if err != nil {
+			t.Fatalf("failed to list passkeys: %s", err)
+		}
+		if len(passkeys) != 0 {
+			t.Errorf("expected no passkeys, got %d", len(passkeys))
+		}
+	})
+}
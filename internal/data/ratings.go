@@ -0,0 +1,185 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/hazzardr/baduk-online/internal/ratings"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// RatedResult is the outcome of a rated game, ready to be applied to both players' ratings.
+type RatedResult struct {
+	// ID identifies the result, such as "game:42". A result is only ever applied once per ID.
+	ID       string
+	GameID   *int64
+	BlackID  int64
+	WhiteID  int64
+	Handicap int
+	// Winner is ColorBlack, ColorWhite, or empty for a draw.
+	Winner string
+}
+
+// RatingPoint is a user's rating after a single rated result.
+type RatingPoint struct {
+	ResultID   string       `json:"result_id"`
+	GameID     *int64       `json:"game_id,omitempty"`
+	Rating     float64      `json:"rating"`
+	Deviation  float64      `json:"deviation"`
+	Volatility float64      `json:"volatility"`
+	Rank       ratings.Rank `json:"rank"`
+	RecordedAt time.Time    `json:"recorded_at"`
+}
+
+// ratingStore handles database operations for player ratings.
+type ratingStore struct {
+	db *pgxpool.Pool
+}
+
+// Apply updates both players' ratings for a result and records the new ratings in their history. It returns
+// false without changing anything if the result has already been applied, so a retried submission is never
+// counted twice. Returns ErrNoUserFound if either player no longer exists.
+func (s *ratingStore) Apply(ctx context.Context, result RatedResult) (bool, error) {
+	if result.BlackID == result.WhiteID {
+		return false, errors.New("black and white must be different players")
+	}
+
+	c, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	tx, err := s.db.Begin(c)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(c) //nolint:errcheck // rollback after commit is a no-op
+
+	// Locking both players in a fixed order serializes concurrent submissions of the same result, and of
+	// different results for the same players, without deadlocking.
+	lock := `
+		SELECT id, rating, rating_deviation, rating_volatility
+		FROM users
+		WHERE id = $1 OR id = $2
+		ORDER BY id
+		FOR UPDATE
+	`
+	rows, err := tx.Query(c, lock, result.BlackID, result.WhiteID)
+	if err != nil {
+		return false, err
+	}
+	current := make(map[int64]ratings.Rating, 2)
+	for rows.Next() {
+		var id int64
+		var r ratings.Rating
+		if err := rows.Scan(&id, &r.Rating, &r.Deviation, &r.Volatility); err != nil {
+			rows.Close()
+			return false, err
+		}
+		current[id] = r
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return false, err
+	}
+	black, okBlack := current[result.BlackID]
+	white, okWhite := current[result.WhiteID]
+	if !okBlack || !okWhite {
+		return false, ErrNoUserFound
+	}
+
+	var applied bool
+	err = tx.QueryRow(
+		c,
+		`SELECT EXISTS (SELECT 1 FROM rating_history WHERE result_id = $1 AND user_id IN ($2, $3))`,
+		result.ID,
+		result.BlackID,
+		result.WhiteID,
+	).Scan(&applied)
+	if err != nil {
+		return false, err
+	}
+	if applied {
+		return false, nil
+	}
+
+	blackScore := 0.5
+	switch result.Winner {
+	case ColorBlack:
+		blackScore = 1
+	case ColorWhite:
+		blackScore = 0
+	}
+	advantage := ratings.HandicapAdvantage(result.Handicap)
+	newBlack, err := ratings.Update(black, []ratings.Result{
+		{Opponent: white, Score: blackScore, Advantage: advantage},
+	})
+	if err != nil {
+		return false, err
+	}
+	newWhite, err := ratings.Update(white, []ratings.Result{
+		{Opponent: black, Score: 1 - blackScore, Advantage: -advantage},
+	})
+	if err != nil {
+		return false, err
+	}
+
+	update := `
+		UPDATE users
+		SET
+			rating = $1,
+			rating_deviation = $2,
+			rating_volatility = $3
+		WHERE
+			id = $4
+	`
+	insert := `
+		INSERT INTO rating_history (user_id, result_id, game_id, rating, deviation, volatility)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	for id, r := range map[int64]ratings.Rating{result.BlackID: newBlack, result.WhiteID: newWhite} {
+		_, err = tx.Exec(c, update, r.Rating, r.Deviation, r.Volatility, id)
+		if err != nil {
+			return false, err
+		}
+		_, err = tx.Exec(c, insert, id, result.ID, result.GameID, r.Rating, r.Deviation, r.Volatility)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	err = tx.Commit(c)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// History returns a user's rating after each of their rated results, oldest first.
+func (s *ratingStore) History(ctx context.Context, userID int64) ([]*RatingPoint, error) {
+	query := `
+		SELECT result_id, game_id, rating, deviation, volatility, recorded_at
+		FROM rating_history
+		WHERE user_id = $1
+		ORDER BY recorded_at, id
+	`
+	c, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := s.db.Query(c, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := []*RatingPoint{}
+	for rows.Next() {
+		var p RatingPoint
+		err := rows.Scan(&p.ResultID, &p.GameID, &p.Rating, &p.Deviation, &p.Volatility, &p.RecordedAt)
+		if err != nil {
+			return nil, err
+		}
+		p.Rank = ratings.RankFor(p.Rating)
+		history = append(history, &p)
+	}
+	return history, rows.Err()
+}
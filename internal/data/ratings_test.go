@@ -0,0 +1,107 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/hazzardr/baduk-online/internal/ratings"
+)
+
+func TestRatingStoreIntegration(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	black := insertTestUser(t, db, "black@example.com")
+	white := insertTestUser(t, db, "white@example.com")
+
+	if black.Rating != ratings.Default() {
+		t.Errorf("expected new user to have the default rating, got %+v", black.Rating)
+	}
+
+	result := RatedResult{
+		ID:      "game:1",
+		BlackID: int64(black.ID),
+		WhiteID: int64(white.ID),
+		Winner:  ColorBlack,
+	}
+
+	t.Run("apply updates both players", func(t *testing.T) {
+		applied, err := db.Ratings.Apply(ctx, result)
+		if err != nil {
+			t.Fatalf("failed to apply result: %s", err)
+		}
+		if !applied {
+			t.Fatal("expected result to be applied")
+		}
+
+		b, _ := db.Users.GetByID(ctx, int64(black.ID))
+		w, _ := db.Users.GetByID(ctx, int64(white.ID))
+		if b.Rating.Rating <= ratings.DefaultRating || w.Rating.Rating >= ratings.DefaultRating {
+			t.Errorf("expected winner up and loser down, got black %+v white %+v", b.Rating, w.Rating)
+		}
+
+		history, err := db.Ratings.History(ctx, int64(black.ID))
+		if err != nil {
+			t.Fatalf("failed to get history: %s", err)
+		}
+		if len(history) != 1 || history[0].ResultID != "game:1" || history[0].Rating != b.Rating.Rating {
+			t.Errorf("unexpected history: %+v", history)
+		}
+	})
+
+	t.Run("retried result is not counted twice", func(t *testing.T) {
+		before, _ := db.Users.GetByID(ctx, int64(black.ID))
+
+		var wg sync.WaitGroup
+		for range 3 {
+			wg.Go(func() {
+				applied, err := db.Ratings.Apply(ctx, result)
+				if err != nil || applied {
+					t.Errorf("expected retry to be ignored, got applied=%v err=%v", applied, err)
+				}
+			})
+		}
+		wg.Wait()
+
+		after, _ := db.Users.GetByID(ctx, int64(black.ID))
+		if after.Rating != before.Rating {
+			t.Errorf("rating changed on retry: %+v -> %+v", before.Rating, after.Rating)
+		}
+		history, _ := db.Ratings.History(ctx, int64(white.ID))
+		if len(history) != 1 {
+			t.Errorf("expected one history entry, got %d", len(history))
+		}
+	})
+
+	t.Run("concurrent distinct results are all applied", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for _, id := range []string{"game:2", "game:3", "game:4"} {
+			wg.Go(func() {
+				r := result
+				r.ID = id
+				r.Winner = ColorWhite
+				if _, err := db.Ratings.Apply(ctx, r); err != nil {
+					t.Errorf("failed to apply %s: %s", id, err)
+				}
+			})
+		}
+		wg.Wait()
+
+		history, _ := db.Ratings.History(ctx, int64(black.ID))
+		if len(history) != 4 {
+			t.Errorf("expected four history entries, got %d", len(history))
+		}
+	})
+
+	t.Run("unknown player", func(t *testing.T) {
+		r := result
+		r.ID = "game:5"
+		r.WhiteID = 999999
+		if _, err := db.Ratings.Apply(ctx, r); !errors.Is(err, ErrNoUserFound) {
+			t.Errorf("expected ErrNoUserFound, got %v", err)
+		}
+	})
+}
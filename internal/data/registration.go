@@ -102,14 +102,7 @@ func (r *registrationStore) RevokeTokensForUser(ctx context.Context, userID int6
 // GetUserFromToken retrieves any user associated with a valid, non-expired token.
 func (r *registrationStore) GetUserFromToken(ctx context.Context, plaintextToken string) (*User, error) {
 	query := `
-		SELECT
-			u.id,
-			u.created_at,
-			u.name,
-			u.email,
-			u.password_hash,
-			u.validated,
-			u.version
+		SELECT ` + userColumns + `
 		FROM
 			users u
 		INNER JOIN
@@ -130,16 +123,7 @@ func (r *registrationStore) GetUserFromToken(ctx context.Context, plaintextToken
 	c, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
-	var user User
-	err := r.db.QueryRow(c, query, args...).Scan(
-		&user.ID,
-		&user.CreatedAt,
-		&user.Name,
-		&user.Email,
-		&user.Password.hash,
-		&user.Validated,
-		&user.Version,
-	)
+	user, err := scanUser(r.db.QueryRow(c, query, args...))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrNoUserFound
@@ -147,5 +131,5 @@ func (r *registrationStore) GetUserFromToken(ctx context.Context, plaintextToken
 		return nil, err
 	}
 
-	return &user, nil
+	return user, nil
 }
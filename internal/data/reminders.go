@@ -0,0 +1,44 @@
+package data
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// reminderStore records the move reminders that have been sent, so that none is sent twice.
+type reminderStore struct {
+	db *pgxpool.Pool
+}
+
+// Claim records that the reminder for the given move of a game is being sent to a user. It reports false
+// if the reminder was already claimed, in which case it must not be sent again.
+func (s *reminderStore) Claim(ctx context.Context, gameID int64, moveNumber int, userID int64) (bool, error) {
+	query := `
+		INSERT INTO move_reminders (game_id, move_number, user_id)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (game_id, move_number) DO NOTHING
+	`
+	c, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	result, err := s.db.Exec(c, query, gameID, moveNumber, userID)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() == 1, nil
+}
+
+// Release forgets a claimed reminder that could not be sent, so that it may be tried again.
+func (s *reminderStore) Release(ctx context.Context, gameID int64, moveNumber int) error {
+	query := `
+		DELETE FROM move_reminders
+		WHERE game_id = $1 AND move_number = $2
+	`
+	c, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	_, err := s.db.Exec(c, query, gameID, moveNumber)
+	return err
+}
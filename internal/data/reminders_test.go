@@ -0,0 +1,119 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hazzardr/baduk-online/internal/clock"
+	"github.com/hazzardr/baduk-online/internal/game"
+	"github.com/hazzardr/baduk-online/internal/vacation"
+)
+
+func TestCorrespondenceStoreIntegration(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	black := insertTestUser(t, db, "black@example.com")
+	white := insertTestUser(t, db, "white@example.com")
+
+	start := time.Now().Truncate(time.Millisecond)
+	g := &Game{
+		BlackID:   int64(black.ID),
+		WhiteID:   int64(white.ID),
+		BoardSize: 19,
+		Rules:     "japanese",
+		Komi:      6.5,
+		Clock:     clock.New(clock.TimeControl{Kind: clock.Absolute, MainTime: 3 * vacation.Day}),
+	}
+	if err := g.Clock.Start(start, game.Black); err != nil {
+		t.Fatalf("failed to start clock: %s", err)
+	}
+	if err := db.Games.Insert(ctx, g); err != nil {
+		t.Fatalf("failed to insert game: %s", err)
+	}
+
+	t.Run("new users get a vacation budget", func(t *testing.T) {
+		if black.Vacation.Remaining != vacation.InitialBudget || black.Vacation.Active {
+			t.Errorf("unexpected vacation budget: %+v", black.Vacation)
+		}
+	})
+
+	t.Run("lists clocks due within a window", func(t *testing.T) {
+		due, err := db.Games.ListClocksDueBy(ctx, start, start.Add(2*vacation.Day))
+		if err != nil {
+			t.Fatalf("failed to list clocks: %s", err)
+		}
+		if len(due) != 0 {
+			t.Errorf("expected no clocks due, got %d", len(due))
+		}
+		due, err = db.Games.ListClocksDueBy(ctx, start.Add(2*vacation.Day), start.Add(3*vacation.Day))
+		if err != nil {
+			t.Fatalf("failed to list clocks: %s", err)
+		}
+		if len(due) != 1 || due[0].ID != g.ID {
+			t.Errorf("expected the game to be due, got %+v", due)
+		}
+	})
+
+	t.Run("reminders are claimed once", func(t *testing.T) {
+		claimed, err := db.Reminders.Claim(ctx, g.ID, g.MoveCount, int64(black.ID))
+		if err != nil || !claimed {
+			t.Fatalf("expected to claim reminder, got %v %v", claimed, err)
+		}
+		claimed, err = db.Reminders.Claim(ctx, g.ID, g.MoveCount, int64(black.ID))
+		if err != nil || claimed {
+			t.Fatalf("expected reminder to be claimed already, got %v %v", claimed, err)
+		}
+		if err := db.Reminders.Release(ctx, g.ID, g.MoveCount); err != nil {
+			t.Fatalf("failed to release reminder: %s", err)
+		}
+		claimed, err = db.Reminders.Claim(ctx, g.ID, g.MoveCount, int64(black.ID))
+		if err != nil || !claimed {
+			t.Fatalf("expected to claim released reminder, got %v %v", claimed, err)
+		}
+	})
+
+	t.Run("paused clocks have no deadline", func(t *testing.T) {
+		stale := *g
+		if err := g.Clock.Stop(start.Add(vacation.Day)); err != nil {
+			t.Fatalf("failed to stop clock: %s", err)
+		}
+		if err := db.Games.UpdateClock(ctx, g); err != nil {
+			t.Fatalf("failed to update clock: %s", err)
+		}
+		due, _ := db.Games.ListClocksDueBy(ctx, start, start.Add(10*vacation.Day))
+		for _, d := range due {
+			if d.ID == g.ID {
+				t.Error("paused clock listed as due")
+			}
+		}
+		if err := db.Games.UpdateClock(ctx, &stale); !errors.Is(err, ErrEditConflict) {
+			t.Errorf("expected ErrEditConflict, got %v", err)
+		}
+	})
+
+	t.Run("vacation is saved and listed", func(t *testing.T) {
+		if err := white.Vacation.Start(start); err != nil {
+			t.Fatalf("failed to start vacation: %s", err)
+		}
+		if err := db.Users.UpdateVacation(ctx, white); err != nil {
+			t.Fatalf("failed to update vacation: %s", err)
+		}
+		away, err := db.Users.ListOnVacation(ctx)
+		if err != nil {
+			t.Fatalf("failed to list users on vacation: %s", err)
+		}
+		if len(away) != 1 || away[0].ID != white.ID || !away[0].Vacation.Since.Equal(start) {
+			t.Errorf("unexpected users on vacation: %+v", away)
+		}
+
+		stale := *black
+		stale.Version--
+		if err := db.Users.UpdateVacation(ctx, &stale); !errors.Is(err, ErrEditConflict) {
+			t.Errorf("expected ErrEditConflict, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,132 @@
+package data
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/hazzardr/baduk-online/internal/validator"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	// ScopePasswordReset is the scope for tokens used to reset a forgotten password.
+	ScopePasswordReset = "password-reset"
+
+	// ScopeEmailChange is the scope for tokens used to confirm a new email address.
+	ScopeEmailChange = "email-change"
+)
+
+// Token represents a time-limited, single-use token issued to a user for a specific purpose.
+type Token struct {
+	Plaintext string
+	Hash      []byte
+	UserID    int64
+	Expiry    time.Time
+	Scope     string
+}
+
+// ValidateTokenPlaintext checks that a token is provided and has the correct length.
+func ValidateTokenPlaintext(v *validator.Validator, tokenPlaintext string) {
+	v.Check(tokenPlaintext != "", "token", "must be provided")
+	v.Check(len(tokenPlaintext) == 26, "token", "must be exactly 26 bytes")
+}
+
+// generateToken creates a new scoped token with a SHA256 hash and expiry time.
+func generateToken(userID int64, ttl time.Duration, scope string) (*Token, error) {
+	plaintext, err := generateRandomToken()
+	if err != nil {
+		return nil, err
+	}
+	t := &Token{
+		Plaintext: plaintext,
+		UserID:    userID,
+		Expiry:    time.Now().Add(ttl),
+		Scope:     scope,
+	}
+	hash := sha256.Sum256([]byte(t.Plaintext))
+	t.Hash = hash[:]
+	return t, nil
+}
+
+// tokenStore handles database operations for scoped tokens.
+type tokenStore struct {
+	db *pgxpool.Pool
+}
+
+// Insert stores a token in the database.
+func (s *tokenStore) Insert(ctx context.Context, token *Token) error {
+	query := `
+		INSERT INTO tokens (hash, user_id, expiry, scope)
+		VALUES ($1, $2, $3, $4)
+	`
+	c, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	_, err := s.db.Exec(c, query, token.Hash, token.UserID, token.Expiry, token.Scope)
+	return err
+}
+
+// New creates a token for the given scope and inserts it into the database, returning the plaintext token.
+func (s *tokenStore) New(ctx context.Context, userID int64, ttl time.Duration, scope string) (*Token, error) {
+	t, err := generateToken(userID, ttl, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.Insert(ctx, t)
+	return t, err
+}
+
+// DeleteAllForUser removes all tokens in the given scope associated with a user.
+func (s *tokenStore) DeleteAllForUser(ctx context.Context, scope string, userID int64) error {
+	query := `
+		DELETE FROM tokens
+		WHERE scope = $1
+		AND user_id = $2
+	`
+
+	c, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	_, err := s.db.Exec(c, query, scope, userID)
+	return err
+}
+
+// GetUserForToken retrieves the user associated with a valid, non-expired token in the given scope.
+// Returns ErrNoUserFound if no such token exists.
+func (s *tokenStore) GetUserForToken(ctx context.Context, scope, plaintextToken string) (*User, error) {
+	query := `
+		SELECT ` + userColumns + `
+		FROM
+			users u
+		INNER JOIN
+			tokens t
+		ON
+			u.id = t.user_id
+		WHERE
+			t.hash = $1
+		AND
+			t.scope = $2
+		AND
+			t.expiry > $3
+	`
+
+	tokenHash := sha256.Sum256([]byte(plaintextToken))
+	args := []any{tokenHash[:], scope, time.Now()}
+
+	c, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	user, err := scanUser(s.db.QueryRow(c, query, args...))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoUserFound
+		}
+		return nil, err
+	}
+
+	return user, nil
+}
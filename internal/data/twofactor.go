@@ -0,0 +1,223 @@
+package data
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base32"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// RecoveryCodeCount is how many recovery codes a user is given at a time.
+const RecoveryCodeCount = 10
+
+// TwoFactor holds a user's TOTP enrollment. The secret is stored encrypted, and is only in use once the
+// enrollment has been confirmed with a first code.
+type TwoFactor struct {
+	UserID      int64
+	Secret      []byte
+	Enabled     bool
+	LastCounter int64
+	CreatedAt   time.Time
+}
+
+// twoFactorStore handles database operations for two-factor authentication.
+type twoFactorStore struct {
+	db *pgxpool.Pool
+}
+
+// Enroll starts a new TOTP enrollment for a user, replacing any enrollment they have not yet confirmed.
+// Returns ErrTwoFactorEnabled if the user already has two-factor authentication turned on.
+func (s *twoFactorStore) Enroll(ctx context.Context, tf *TwoFactor) error {
+	query := `
+		INSERT INTO two_factor (user_id, secret)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret = EXCLUDED.secret, last_counter = 0, created_at = NOW()
+		WHERE NOT two_factor.enabled
+		RETURNING enabled, last_counter, created_at
+	`
+	c, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	err := s.db.QueryRow(c, query, tf.UserID, tf.Secret).Scan(&tf.Enabled, &tf.LastCounter, &tf.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrTwoFactorEnabled
+		}
+		return err
+	}
+	return nil
+}
+
+// Get returns a user's TOTP enrollment.
+// Returns ErrNoTwoFactor if the user has never enrolled.
+func (s *twoFactorStore) Get(ctx context.Context, userID int64) (*TwoFactor, error) {
+	query := `
+		SELECT user_id, secret, enabled, last_counter, created_at
+		FROM two_factor
+		WHERE user_id = $1
+	`
+	c, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	var tf TwoFactor
+	err := s.db.QueryRow(c, query, userID).Scan(&tf.UserID, &tf.Secret, &tf.Enabled, &tf.LastCounter, &tf.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoTwoFactor
+		}
+		return nil, err
+	}
+	return &tf, nil
+}
+
+// Enabled reports whether a user has confirmed two-factor authentication.
+func (s *twoFactorStore) Enabled(ctx context.Context, userID int64) (bool, error) {
+	tf, err := s.Get(ctx, userID)
+	if err != nil {
+		if errors.Is(err, ErrNoTwoFactor) {
+			return false, nil
+		}
+		return false, err
+	}
+	return tf.Enabled, nil
+}
+
+// Use records that the code for counter has been used, and turns two-factor authentication on if this
+// confirms the enrollment. It reports false if a code for the same or a later period was already used, so
+// that a code can't be replayed.
+func (s *twoFactorStore) Use(ctx context.Context, tf *TwoFactor, counter int64) (bool, error) {
+	query := `
+		UPDATE two_factor
+		SET last_counter = $1, enabled = true
+		WHERE user_id = $2
+		AND last_counter < $1
+	`
+	c, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	result, err := s.db.Exec(c, query, counter, tf.UserID)
+	if err != nil {
+		return false, err
+	}
+	if result.RowsAffected() == 0 {
+		return false, nil
+	}
+	tf.LastCounter = counter
+	tf.Enabled = true
+	return true, nil
+}
+
+// Delete turns off two-factor authentication for a user and discards their recovery codes.
+func (s *twoFactorStore) Delete(ctx context.Context, userID int64) error {
+	c, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	tx, err := s.db.Begin(c)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(c) //nolint:errcheck // rollback after commit is a no-op
+
+	_, err = tx.Exec(c, `DELETE FROM recovery_codes WHERE user_id = $1`, userID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(c, `DELETE FROM two_factor WHERE user_id = $1`, userID)
+	if err != nil {
+		return err
+	}
+	return tx.Commit(c)
+}
+
+// NewRecoveryCodes replaces a user's recovery codes with RecoveryCodeCount new ones, returning them in
+// plaintext. Only their hashes are stored, so they can't be shown again.
+func (s *twoFactorStore) NewRecoveryCodes(ctx context.Context, userID int64) ([]string, error) {
+	codes := make([]string, RecoveryCodeCount)
+	for i := range codes {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes[i] = code
+	}
+
+	c, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	tx, err := s.db.Begin(c)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(c) //nolint:errcheck // rollback after commit is a no-op
+
+	_, err = tx.Exec(c, `DELETE FROM recovery_codes WHERE user_id = $1`, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, code := range codes {
+		_, err = tx.Exec(c, `INSERT INTO recovery_codes (hash, user_id) VALUES ($1, $2)`, hashRecoveryCode(code), userID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return codes, tx.Commit(c)
+}
+
+// UseRecoveryCode consumes one of a user's recovery codes. It reports false if the code is not one of theirs
+// or was already used.
+func (s *twoFactorStore) UseRecoveryCode(ctx context.Context, userID int64, code string) (bool, error) {
+	query := `
+		DELETE FROM recovery_codes
+		WHERE hash = $1
+		AND user_id = $2
+	`
+	c, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	result, err := s.db.Exec(c, query, hashRecoveryCode(code), userID)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() == 1, nil
+}
+
+// CountRecoveryCodes returns how many unused recovery codes a user has left.
+func (s *twoFactorStore) CountRecoveryCodes(ctx context.Context, userID int64) (int, error) {
+	c, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	var count int
+	err := s.db.QueryRow(c, `SELECT count(*) FROM recovery_codes WHERE user_id = $1`, userID).Scan(&count)
+	return count, err
+}
+
+// generateRecoveryCode returns a random code of two groups of five base32 characters, such as
+// "ABCDE-FGHIJ".
+func generateRecoveryCode() (string, error) {
+	randomBytes := make([]byte, 5)
+	_, err := rand.Read(randomBytes)
+	if err != nil {
+		return "", err
+	}
+	code := base32.StdEncoding.EncodeToString(randomBytes)
+	return code[:5] + "-" + code[5:], nil
+}
+
+// hashRecoveryCode hashes a recovery code as the user may have typed it, ignoring case, spaces and dashes.
+func hashRecoveryCode(code string) []byte {
+	normalized := strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToUpper(code))
+	hash := sha256.Sum256([]byte(normalized))
+	return hash[:]
+}
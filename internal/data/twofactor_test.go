@@ -0,0 +1,118 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestTwoFactorIntegration(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	user := insertTestUser(t, db, "twofactor@example.com")
+	userID := int64(user.ID)
+
+	if _, err := db.TwoFactor.Get(ctx, userID); !errors.Is(err, ErrNoTwoFactor) {
+		t.Fatalf("expected ErrNoTwoFactor, got %v", err)
+	}
+
+	t.Run("enrollment is confirmed by the first code", func(t *testing.T) {
+		tf := &TwoFactor{UserID: userID, Secret: []byte("first")}
+		if err := db.TwoFactor.Enroll(ctx, tf); err != nil {
+			t.Fatalf("failed to enroll: %s", err)
+		}
+		// Enrolling again before confirming replaces the secret.
+		tf = &TwoFactor{UserID: userID, Secret: []byte("second")}
+		if err := db.TwoFactor.Enroll(ctx, tf); err != nil {
+			t.Fatalf("failed to enroll again: %s", err)
+		}
+		if enabled, err := db.TwoFactor.Enabled(ctx, userID); err != nil || enabled {
+			t.Fatalf("expected two-factor to be off until confirmed, got %t, %v", enabled, err)
+		}
+
+		used, err := db.TwoFactor.Use(ctx, tf, 100)
+		if err != nil || !used {
+			t.Fatalf("expected the first code to be accepted, got %t, %v", used, err)
+		}
+		got, err := db.TwoFactor.Get(ctx, userID)
+		if err != nil {
+			t.Fatalf("failed to get two-factor: %s", err)
+		}
+		if !got.Enabled || got.LastCounter != 100 || string(got.Secret) != "second" {
+			t.Errorf("unexpected two-factor: %+v", got)
+		}
+
+		err = db.TwoFactor.Enroll(ctx, &TwoFactor{UserID: userID, Secret: []byte("third")})
+		if !errors.Is(err, ErrTwoFactorEnabled) {
+			t.Errorf("expected ErrTwoFactorEnabled, got %v", err)
+		}
+	})
+
+	t.Run("codes can't be replayed", func(t *testing.T) {
+		tf, err := db.TwoFactor.Get(ctx, userID)
+		if err != nil {
+			t.Fatalf("failed to get two-factor: %s", err)
+		}
+		for _, counter := range []int64{100, 99} {
+			if used, err := db.TwoFactor.Use(ctx, tf, counter); err != nil || used {
+				t.Errorf("expected counter %d to be rejected, got %t, %v", counter, used, err)
+			}
+		}
+		if used, err := db.TwoFactor.Use(ctx, tf, 101); err != nil || !used {
+			t.Errorf("expected a later counter to be accepted, got %t, %v", used, err)
+		}
+	})
+
+	t.Run("recovery codes are single use", func(t *testing.T) {
+		codes, err := db.TwoFactor.NewRecoveryCodes(ctx, userID)
+		if err != nil {
+			t.Fatalf("failed to create recovery codes: %s", err)
+		}
+		if len(codes) != RecoveryCodeCount {
+			t.Fatalf("expected %d codes, got %d", RecoveryCodeCount, len(codes))
+		}
+
+		// Codes are accepted however the user types them.
+		typed := strings.ToLower(strings.ReplaceAll(codes[0], "-", " "))
+		if used, err := db.TwoFactor.UseRecoveryCode(ctx, userID, typed); err != nil || !used {
+			t.Fatalf("expected the code to be accepted, got %t, %v", used, err)
+		}
+		if used, err := db.TwoFactor.UseRecoveryCode(ctx, userID, codes[0]); err != nil || used {
+			t.Errorf("expected the code to be rejected the second time, got %t, %v", used, err)
+		}
+		if n, err := db.TwoFactor.CountRecoveryCodes(ctx, userID); err != nil || n != RecoveryCodeCount-1 {
+			t.Errorf("expected %d codes left, got %d, %v", RecoveryCodeCount-1, n, err)
+		}
+
+		other := insertTestUser(t, db, "other@example.com")
+		if used, err := db.TwoFactor.UseRecoveryCode(ctx, int64(other.ID), codes[1]); err != nil || used {
+			t.Errorf("expected another user's code to be rejected, got %t, %v", used, err)
+		}
+
+		regenerated, err := db.TwoFactor.NewRecoveryCodes(ctx, userID)
+		if err != nil {
+			t.Fatalf("failed to regenerate recovery codes: %s", err)
+		}
+		if used, err := db.TwoFactor.UseRecoveryCode(ctx, userID, codes[1]); err != nil || used {
+			t.Errorf("expected old codes to be discarded, got %t, %v", used, err)
+		}
+		if used, err := db.TwoFactor.UseRecoveryCode(ctx, userID, regenerated[0]); err != nil || !used {
+			t.Errorf("expected a new code to be accepted, got %t, %v", used, err)
+		}
+	})
+
+	t.Run("deleting turns two-factor off", func(t *testing.T) {
+		if err := db.TwoFactor.Delete(ctx, userID); err != nil {
+			t.Fatalf("failed to delete two-factor: %s", err)
+		}
+		if enabled, err := db.TwoFactor.Enabled(ctx, userID); err != nil || enabled {
+			t.Errorf("expected two-factor to be off, got %t, %v", enabled, err)
+		}
+		if n, err := db.TwoFactor.CountRecoveryCodes(ctx, userID); err != nil || n != 0 {
+			t.Errorf("expected recovery codes to be removed, got %d, %v", n, err)
+		}
+	})
+}
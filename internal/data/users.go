@@ -4,25 +4,52 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
 	"time"
+	"unicode/utf8"
 
+	"github.com/hazzardr/baduk-online/internal/ratings"
+	"github.com/hazzardr/baduk-online/internal/scoring"
+	"github.com/hazzardr/baduk-online/internal/vacation"
 	"github.com/hazzardr/baduk-online/internal/validator"
 	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"golang.org/x/crypto/bcrypt"
 )
 
 // User represents a user account in the system.
 type User struct {
-	ID        int       `json:"-"`
-	CreatedAt time.Time `json:"created_at" db:"created_at"`
-	Name      string    `json:"name"`
-	Email     string    `json:"email"`
-	Password  password  `json:"-" db:"password_hash"`
-	Validated bool      `json:"validated"`
-	Version   int       `json:"-"`
+	ID             int             `json:"-"`
+	CreatedAt      time.Time       `json:"created_at" db:"created_at"`
+	Name           string          `json:"name"`
+	Email          string          `json:"email"`
+	Password       password        `json:"-" db:"password_hash"`
+	Validated      bool            `json:"validated"`
+	Rating         ratings.Rating  `json:"rating"`
+	Moderator      bool            `json:"moderator"`
+	Bot            bool            `json:"bot"`
+	Country        string          `json:"country"`
+	Bio            string          `json:"bio"`
+	PreferredRules string          `json:"preferred_rules"`
+	AvatarURL      string          `json:"avatar_url"`
+	PurgeAt        *time.Time      `json:"purge_at,omitempty"`
+	Vacation       vacation.Budget `json:"vacation"`
+	Version        int             `json:"version"`
 }
 
+const (
+	// MaxBioLength is the longest bio a user may write, in characters.
+	MaxBioLength = 1000
+	// MaxAvatarURLLength is the longest avatar URL a user may link to.
+	MaxAvatarURLLength = 2048
+)
+
+// countryRX matches an ISO 3166-1 alpha-2 country code.
+var countryRX = regexp.MustCompile("^[A-Z]{2}$")
+
 // password holds both plaintext and bcrypt-hashed password values.
 type password struct {
 	plaintext *string
@@ -67,10 +94,38 @@ func ValidatePasswordPlaintext(v *validator.Validator, password string) {
 	v.Check(len(password) <= 72, "password", "must not be more than 72 characters long")
 }
 
+// ValidateName checks that a display name is provided and not too long.
+func ValidateName(v *validator.Validator, name string) {
+	v.Check(name != "", "name", "must be provided")
+	v.Check(len(name) <= 50, "name", "must not be more than 50 characters long")
+}
+
+// ValidateProfile checks the fields of a user that they can edit on their profile. The country and avatar
+// are optional.
+func ValidateProfile(v *validator.Validator, user *User) {
+	ValidateName(v, user.Name)
+
+	if user.Country != "" {
+		v.Check(validator.Matches(user.Country, countryRX), "country", "must be a two letter ISO 3166 country code")
+	}
+
+	v.Check(utf8.RuneCountInString(user.Bio) <= MaxBioLength, "bio",
+		fmt.Sprintf("must not be more than %d characters long", MaxBioLength))
+
+	v.Check(validator.PermittedValue(scoring.Ruleset(user.PreferredRules), scoring.Rulesets...), "preferred_rules",
+		"must be one of japanese, korean, chinese or aga")
+
+	if user.AvatarURL != "" {
+		v.Check(len(user.AvatarURL) <= MaxAvatarURLLength, "avatar_url",
+			fmt.Sprintf("must not be more than %d bytes long", MaxAvatarURLLength))
+		u, err := url.Parse(user.AvatarURL)
+		v.Check(err == nil && u.Scheme == "https" && u.Host != "", "avatar_url", "must be an https URL")
+	}
+}
+
 // ValidateUser performs validation checks on a User struct, including name, email, and password.
 func ValidateUser(v *validator.Validator, user *User) {
-	v.Check(user.Name != "", "name", "must be provided")
-	v.Check(len(user.Name) <= 50, "name", "must not be more than 50 characters long")
+	ValidateName(v, user.Name)
 
 	ValidateEmail(v, user.Email)
 
@@ -83,20 +138,17 @@ func ValidateUser(v *validator.Validator, user *User) {
 	}
 }
 
-// Insert creates a new user in the database and populates the user's ID, CreatedAt, and Version fields.
+// Insert creates a new user in the database and populates the fields that have defaults, such as ID,
+// CreatedAt and Version.
 // Returns ErrDuplicateEmail if a user with the same email already exists.
 func (u *userStore) Insert(ctx context.Context, user *User) error {
 	query := `
-		INSERT INTO users (name, email, password_hash, validated)
+		INSERT INTO users AS u (name, email, password_hash, validated)
 		VALUES ($1, $2, $3, $4)
-        RETURNING id, created_at, version`
+		RETURNING ` + userColumns
 	c, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
-	err := u.db.QueryRow(c, query, user.Name, user.Email, user.Password.hash, user.Validated).Scan(
-		&user.ID,
-		&user.CreatedAt,
-		&user.Version,
-	)
+	err := u.db.QueryRow(c, query, user.Name, user.Email, user.Password.hash, user.Validated).Scan(userFields(user)...)
 	if err != nil {
 		var e *pgconn.PgError
 		if errors.As(err, &e) {
@@ -114,53 +166,59 @@ func (u *userStore) Insert(ctx context.Context, user *User) error {
 // Returns ErrNoUserFound if no user exists with the given email.
 func (u *userStore) GetByEmail(ctx context.Context, email string) (*User, error) {
 	query := `
-		SELECT
-			u.id,
-			u.created_at,
-			u.name,
-			u.email,
-			u.password_hash,
-			u.validated,
-			u.version
+		SELECT ` + userColumns + `
 		FROM users u
 		WHERE
 			u.email = $1
 		;
 	`
-	var user User
 	c, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
-	err := u.db.QueryRow(c, query, email).Scan(
-		&user.ID,
-		&user.CreatedAt,
-		&user.Name,
-		&user.Email,
-		&user.Password.hash,
-		&user.Validated,
-		&user.Version,
-	)
+	user, err := scanUser(u.db.QueryRow(c, query, email))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrNoUserFound
 		}
 		return nil, err
 	}
-	return &user, nil
+	return user, nil
 }
 
-// Delete removes a user from the database by their email address.
-// Returns ErrNoUserFound if no user exists with the given email.
+// GetByID retrieves a user by their ID.
+// Returns ErrNoUserFound if no user exists with the given ID.
+func (u *userStore) GetByID(ctx context.Context, id int64) (*User, error) {
+	query := `
+		SELECT ` + userColumns + `
+		FROM users u
+		WHERE
+			u.id = $1
+		;
+	`
+	c, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	user, err := scanUser(u.db.QueryRow(c, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoUserFound
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+// Delete permanently removes a user from the database, along with everything that references them.
+// Returns ErrNoUserFound if the user does not exist.
 func (u *userStore) Delete(ctx context.Context, user *User) error {
 	query := `
 		DELETE
 		FROM users
 		WHERE
-			email = $1
+			id = $1
 		;
 	`
 	c, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
-	result, err := u.db.Exec(c, query, user.Email)
+	result, err := u.db.Exec(c, query, user.ID)
 	if err != nil {
 		return err
 	}
@@ -173,6 +231,64 @@ func (u *userStore) Delete(ctx context.Context, user *User) error {
 	return nil
 }
 
+// ScheduleDeletion marks the user for deletion at purgeAt. Until then the account is kept, so the deletion can
+// be cancelled.
+// Returns ErrEditConflict if the user was modified since they were read.
+func (u *userStore) ScheduleDeletion(ctx context.Context, user *User, purgeAt time.Time) error {
+	return u.setPurgeAt(ctx, user, &purgeAt)
+}
+
+// CancelDeletion clears a scheduled deletion.
+// Returns ErrEditConflict if the user was modified since they were read.
+func (u *userStore) CancelDeletion(ctx context.Context, user *User) error {
+	return u.setPurgeAt(ctx, user, nil)
+}
+
+func (u *userStore) setPurgeAt(ctx context.Context, user *User, purgeAt *time.Time) error {
+	query := `
+		UPDATE users
+		SET
+			purge_at = $1,
+			version = version + 1
+		WHERE
+			id = $2
+		AND
+			version = $3
+		RETURNING
+			purge_at, version
+		;
+	`
+	c, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	err := u.db.QueryRow(c, query, purgeAt, user.ID, user.Version).Scan(&user.PurgeAt, &user.Version)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrEditConflict
+		}
+		return err
+	}
+	return nil
+}
+
+// PurgeDeleted permanently removes every user whose scheduled deletion is due by now, returning how many
+// were removed.
+func (u *userStore) PurgeDeleted(ctx context.Context, now time.Time) (int64, error) {
+	query := `
+		DELETE
+		FROM users
+		WHERE
+			purge_at <= $1
+		;
+	`
+	c, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	result, err := u.db.Exec(c, query, now)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 // Update will update the given user.
 func (u *userStore) Update(ctx context.Context, user *User) error {
 	query := `
@@ -212,3 +328,222 @@ func (u *userStore) Update(ctx context.Context, user *User) error {
 
 	return nil
 }
+
+// UpdateProfile saves the fields of a user that they can edit on their profile.
+// Returns ErrEditConflict if the user was modified since they were read.
+func (u *userStore) UpdateProfile(ctx context.Context, user *User) error {
+	query := `
+		UPDATE users
+		SET
+			name = $1,
+			country = $2,
+			bio = $3,
+			preferred_rules = $4,
+			avatar_url = $5,
+			version = version + 1
+		WHERE
+			id = $6
+		AND
+			version = $7
+		RETURNING
+			version
+		;
+	`
+	c, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	err := u.db.QueryRow(
+		c,
+		query,
+		user.Name,
+		user.Country,
+		user.Bio,
+		user.PreferredRules,
+		user.AvatarURL,
+		user.ID,
+		user.Version,
+	).Scan(&user.Version)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrEditConflict
+		}
+		return err
+	}
+	return nil
+}
+
+// SetPendingEmail records the address the user wants to change their email to. It only takes effect once
+// ConfirmPendingEmail is called.
+// Returns ErrEditConflict if the user was modified since they were read.
+func (u *userStore) SetPendingEmail(ctx context.Context, user *User, email string) error {
+	query := `
+		UPDATE users
+		SET
+			pending_email = $1,
+			version = version + 1
+		WHERE
+			id = $2
+		AND
+			version = $3
+		RETURNING
+			version
+		;
+	`
+	c, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	err := u.db.QueryRow(c, query, email, user.ID, user.Version).Scan(&user.Version)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrEditConflict
+		}
+		return err
+	}
+	return nil
+}
+
+// ConfirmPendingEmail replaces the user's email with their pending email and updates user.Email to match.
+// Returns ErrDuplicateEmail if another user has taken the address in the meantime, and ErrEditConflict if the
+// user was modified since they were read.
+func (u *userStore) ConfirmPendingEmail(ctx context.Context, user *User) error {
+	query := `
+		UPDATE users
+		SET
+			email = COALESCE(pending_email, email),
+			pending_email = NULL,
+			version = version + 1
+		WHERE
+			id = $1
+		AND
+			version = $2
+		RETURNING
+			email, version
+		;
+	`
+	c, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	err := u.db.QueryRow(c, query, user.ID, user.Version).Scan(&user.Email, &user.Version)
+	if err != nil {
+		var e *pgconn.PgError
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		case errors.As(err, &e) && e.Code == pgerrcode.UniqueViolation:
+			return ErrDuplicateEmail
+		}
+		return err
+	}
+	return nil
+}
+
+// UpdateVacation saves the user's vacation budget.
+// Returns ErrEditConflict if the user was modified since they were read.
+func (u *userStore) UpdateVacation(ctx context.Context, user *User) error {
+	query := `
+		UPDATE users
+		SET
+			vacation_remaining = $1,
+			on_vacation = $2,
+			vacation_since = $3,
+			vacation_accrued_at = $4,
+			version = version + 1
+		WHERE
+			id = $5
+		AND
+			version = $6
+		RETURNING
+			version
+		;
+	`
+	c, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	err := u.db.QueryRow(
+		c,
+		query,
+		user.Vacation.Remaining,
+		user.Vacation.Active,
+		user.Vacation.Since,
+		user.Vacation.AccruedAt,
+		user.ID,
+		user.Version,
+	).Scan(&user.Version)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrEditConflict
+		}
+		return err
+	}
+	return nil
+}
+
+// ListOnVacation returns every user who is currently on vacation.
+func (u *userStore) ListOnVacation(ctx context.Context) ([]*User, error) {
+	query := `
+		SELECT ` + userColumns + `
+		FROM users u
+		WHERE
+			u.on_vacation
+		ORDER BY u.id
+		;
+	`
+	c, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := u.db.Query(c, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []*User{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+// userColumns are the columns of the users table, aliased as u, that scanUser reads. Every query that returns
+// whole users selects them, so that adding a column only means changing these and userFields.
+const userColumns = `
+	u.id, u.created_at, u.name, u.email, u.password_hash, u.validated,
+	u.rating, u.rating_deviation, u.rating_volatility, u.moderator, u.bot,
+	u.country, u.bio, u.preferred_rules, u.avatar_url, u.purge_at,
+	u.vacation_remaining, u.on_vacation, u.vacation_since, u.vacation_accrued_at, u.version`
+
+// userFields returns the fields of a user to scan userColumns into, in the same order.
+func userFields(user *User) []any {
+	return []any{
+		&user.ID,
+		&user.CreatedAt,
+		&user.Name,
+		&user.Email,
+		&user.Password.hash,
+		&user.Validated,
+		&user.Rating.Rating,
+		&user.Rating.Deviation,
+		&user.Rating.Volatility,
+		&user.Moderator,
+		&user.Bot,
+		&user.Country,
+		&user.Bio,
+		&user.PreferredRules,
+		&user.AvatarURL,
+		&user.PurgeAt,
+		&user.Vacation.Remaining,
+		&user.Vacation.Active,
+		&user.Vacation.Since,
+		&user.Vacation.AccruedAt,
+		&user.Version,
+	}
+}
+
+func scanUser(row pgx.Row) (*User, error) {
+	var user User
+	err := row.Scan(userFields(&user)...)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
@@ -0,0 +1,171 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hazzardr/baduk-online/internal/validator"
+)
+
+func TestValidateProfile(t *testing.T) {
+	valid := func(edit func(*User)) User {
+		user := User{Name: "Honinbo Shusaku", PreferredRules: "japanese"}
+		edit(&user)
+		return user
+	}
+
+	tests := []struct {
+		name    string
+		user    User
+		wantKey string
+	}{
+		{"Defaults", valid(func(*User) {}), ""},
+		{"Full profile", valid(func(u *User) {
+			u.Country, u.Bio, u.PreferredRules = "JP", "Played the ear-reddening move.", "chinese"
+			u.AvatarURL = "https://example.com/avatar.png"
+		}), ""},
+		{"Longest bio", valid(func(u *User) { u.Bio = strings.Repeat("碁", MaxBioLength) }), ""},
+		{"Missing name", valid(func(u *User) { u.Name = "" }), "name"},
+		{"Long name", valid(func(u *User) { u.Name = strings.Repeat("a", 51) }), "name"},
+		{"Lowercase country", valid(func(u *User) { u.Country = "jp" }), "country"},
+		{"Country name", valid(func(u *User) { u.Country = "Japan" }), "country"},
+		{"Long bio", valid(func(u *User) { u.Bio = strings.Repeat("a", MaxBioLength+1) }), "bio"},
+		{"Unknown rules", valid(func(u *User) { u.PreferredRules = "ing" }), "preferred_rules"},
+		{"Missing rules", valid(func(u *User) { u.PreferredRules = "" }), "preferred_rules"},
+		{"Insecure avatar", valid(func(u *User) { u.AvatarURL = "http://example.com/a.png" }), "avatar_url"},
+		{"Relative avatar", valid(func(u *User) { u.AvatarURL = "/avatar.png" }), "avatar_url"},
+		{"Script avatar", valid(func(u *User) { u.AvatarURL = "javascript:alert(1)" }), "avatar_url"},
+		{"Long avatar", valid(func(u *User) {
+			u.AvatarURL = "https://example.com/" + strings.Repeat("a", MaxAvatarURLLength)
+		}), "avatar_url"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			ValidateProfile(v, &tt.user)
+			if tt.wantKey == "" {
+				if !v.Valid() {
+					t.Errorf("expected no errors, got %v", v.Errors)
+				}
+				return
+			}
+			if _, ok := v.Errors[tt.wantKey]; !ok {
+				t.Errorf("expected error for %q, got %v", tt.wantKey, v.Errors)
+			}
+		})
+	}
+}
+
+func TestUserDeletionIntegration(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	leaving := insertTestUser(t, db, "leaving@example.com")
+	staying := insertTestUser(t, db, "staying@example.com")
+	for _, user := range []*User{leaving, staying} {
+		if _, err := db.Registration.NewToken(ctx, int64(user.ID), time.Hour); err != nil {
+			t.Fatalf("failed to create registration token: %s", err)
+		}
+		if _, err := db.Tokens.New(ctx, int64(user.ID), time.Hour, ScopePasswordReset); err != nil {
+			t.Fatalf("failed to create token: %s", err)
+		}
+	}
+	count := func(t *testing.T, table string, user *User) int {
+		t.Helper()
+		var n int
+		err := db.Pool.QueryRow(ctx, "SELECT count(*) FROM "+table+" WHERE user_id = $1", user.ID).Scan(&n)
+		if err != nil {
+			t.Fatalf("failed to count %s: %s", table, err)
+		}
+		return n
+	}
+
+	game := &Game{
+		BlackID:   int64(leaving.ID),
+		WhiteID:   int64(staying.ID),
+		BoardSize: 9,
+		Rules:     "japanese",
+		Komi:      6.5,
+	}
+	if err := db.Games.Insert(ctx, game); err != nil {
+		t.Fatalf("failed to insert game: %s", err)
+	}
+	if err := db.Games.AppendMove(ctx, game, &Move{Color: ColorBlack, X: 2, Y: 2}); err != nil {
+		t.Fatalf("failed to append move: %s", err)
+	}
+
+	now := time.Now()
+	if err := db.Users.ScheduleDeletion(ctx, leaving, now.Add(time.Hour)); err != nil {
+		t.Fatalf("failed to schedule deletion: %s", err)
+	}
+	if leaving.PurgeAt == nil {
+		t.Fatalf("expected PurgeAt to be set")
+	}
+
+	stale := *staying
+	if err := db.Users.CancelDeletion(ctx, staying); err != nil {
+		t.Fatalf("failed to cancel deletion: %s", err)
+	}
+	if err := db.Users.ScheduleDeletion(ctx, &stale, now); !errors.Is(err, ErrEditConflict) {
+		t.Errorf("expected ErrEditConflict, got %v", err)
+	}
+
+	purged, err := db.Users.PurgeDeleted(ctx, now)
+	if err != nil {
+		t.Fatalf("failed to purge: %s", err)
+	}
+	if purged != 0 {
+		t.Errorf("expected nothing to be purged during the grace period, got %d", purged)
+	}
+
+	purged, err = db.Users.PurgeDeleted(ctx, now.Add(2*time.Hour))
+	if err != nil {
+		t.Fatalf("failed to purge: %s", err)
+	}
+	if purged != 1 {
+		t.Fatalf("expected 1 user to be purged, got %d", purged)
+	}
+
+	if _, err := db.Users.GetByID(ctx, int64(leaving.ID)); !errors.Is(err, ErrNoUserFound) {
+		t.Errorf("expected ErrNoUserFound, got %v", err)
+	}
+	if n := count(t, "registration", leaving); n != 0 {
+		t.Errorf("expected registration tokens to be removed, got %d", n)
+	}
+	if n := count(t, "tokens", leaving); n != 0 {
+		t.Errorf("expected tokens to be removed, got %d", n)
+	}
+	if n := count(t, "registration", staying); n != 1 {
+		t.Errorf("expected other users' registration tokens to be kept, got %d", n)
+	}
+
+	games, err := db.Games.ListForPlayer(ctx, int64(staying.ID))
+	if err != nil {
+		t.Fatalf("failed to list games: %s", err)
+	}
+	if len(games) != 1 || games[0].ID != game.ID || games[0].BlackID != 0 || games[0].WhiteID != int64(staying.ID) {
+		t.Fatalf("expected the opponent to keep the game without the purged player, got %+v", games)
+	}
+	moves, err := db.Games.Moves(ctx, game.ID)
+	if err != nil {
+		t.Fatalf("failed to list moves: %s", err)
+	}
+	if len(moves) != 1 {
+		t.Errorf("expected the game's moves to be kept, got %d", len(moves))
+	}
+
+	if err := db.Users.Delete(ctx, staying); err != nil {
+		t.Fatalf("failed to delete user: %s", err)
+	}
+	if err := db.Users.Delete(ctx, staying); !errors.Is(err, ErrNoUserFound) {
+		t.Errorf("expected ErrNoUserFound, got %v", err)
+	}
+	if n := count(t, "registration", staying); n != 0 {
+		t.Errorf("expected registration tokens to be removed, got %d", n)
+	}
+}
@@ -0,0 +1,233 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	// MaxUserAgentLength is how much of a session's user agent is kept, in bytes.
+	MaxUserAgentLength = 512
+	// sessionTouchInterval is the least time between recording activity on a session, so that a busy client
+	// doesn't cause a write for every request.
+	sessionTouchInterval = time.Minute
+)
+
+// UserSession indexes a logged in session by its user, so that they can see where they are logged in and
+// log out of other sessions. The session's data is kept by the session manager under the same token.
+type UserSession struct {
+	ID         int64     `json:"id"`
+	Token      string    `json:"-"`
+	UserID     int64     `json:"-"`
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"user_agent"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	Expiry     time.Time `json:"expiry"`
+	// Current is set for the session a request was made with, and isn't stored.
+	Current bool `json:"current"`
+}
+
+// userSessionStore handles database operations for the index of logged in sessions.
+type userSessionStore struct {
+	db *pgxpool.Pool
+}
+
+// Insert adds a newly logged in session to the index and populates its ID field.
+func (s *userSessionStore) Insert(ctx context.Context, us *UserSession) error {
+	query := `
+		INSERT INTO user_sessions (token, user_id, ip, user_agent, created_at, last_seen_at, expiry)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id
+	`
+	args := []any{us.Token, us.UserID, us.IP, truncateUserAgent(us.UserAgent), us.CreatedAt, us.LastSeenAt, us.Expiry}
+
+	c, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	return s.db.QueryRow(c, query, args...).Scan(&us.ID)
+}
+
+// Touch records activity on a session from the given IP and user agent. Activity within a minute of the
+// last recorded activity is ignored.
+func (s *userSessionStore) Touch(ctx context.Context, token, ip, userAgent string, seenAt time.Time) error {
+	query := `
+		UPDATE user_sessions
+		SET last_seen_at = $2, ip = $3, user_agent = $4
+		WHERE token = $1 AND last_seen_at <= $5
+	`
+	args := []any{token, seenAt, ip, truncateUserAgent(userAgent), seenAt.Add(-sessionTouchInterval)}
+
+	c, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	_, err := s.db.Exec(c, query, args...)
+	return err
+}
+
+// UpdateToken moves a session's entry over to the new token it was given.
+func (s *userSessionStore) UpdateToken(ctx context.Context, oldToken, newToken string) error {
+	c, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	_, err := s.db.Exec(c, `UPDATE user_sessions SET token = $2 WHERE token = $1`, oldToken, newToken)
+	return err
+}
+
+// ListForUser returns a user's sessions that haven't expired, most recently active first.
+func (s *userSessionStore) ListForUser(ctx context.Context, userID int64, now time.Time) ([]*UserSession, error) {
+	query := `
+		SELECT id, token, user_id, ip, user_agent, created_at, last_seen_at, expiry
+		FROM user_sessions
+		WHERE user_id = $1 AND expiry > $2
+		ORDER BY last_seen_at DESC, id DESC
+	`
+	c, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := s.db.Query(c, query, userID, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []*UserSession{}
+	for rows.Next() {
+		us, err := scanUserSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, us)
+	}
+	return sessions, rows.Err()
+}
+
+// Delete removes one of a user's sessions from the index, returning it so that the session itself can be
+// destroyed.
+// Returns ErrNoUserSessionFound if the user has no session with that ID.
+func (s *userSessionStore) Delete(ctx context.Context, userID, id int64) (*UserSession, error) {
+	query := `
+		DELETE FROM user_sessions
+		WHERE id = $1 AND user_id = $2
+		RETURNING id, token, user_id, ip, user_agent, created_at, last_seen_at, expiry
+	`
+	c, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	us, err := scanUserSession(s.db.QueryRow(c, query, id, userID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoUserSessionFound
+		}
+		return nil, err
+	}
+	return us, nil
+}
+
+// DeleteByToken removes the session with the given token from the index, if it is there.
+func (s *userSessionStore) DeleteByToken(ctx context.Context, token string) error {
+	c, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	_, err := s.db.Exec(c, `DELETE FROM user_sessions WHERE token = $1`, token)
+	return err
+}
+
+// DeleteOthersForUser removes all of a user's sessions from the index except for the one with the token
+// keep, returning their tokens so that the sessions themselves can be destroyed. An empty keep removes them
+// all.
+func (s *userSessionStore) DeleteOthersForUser(ctx context.Context, userID int64, keep string) ([]string, error) {
+	query := `
+		DELETE FROM user_sessions
+		WHERE user_id = $1 AND token <> $2
+		RETURNING token
+	`
+	c, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := s.db.Query(c, query, userID, keep)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []string{}
+	for rows.Next() {
+		var token string
+		err := rows.Scan(&token)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, rows.Err()
+}
+
+// HasUnindexed reports whether the session manager holds unexpired sessions that are missing from the index
+// and began before the oldest session in it, as those logged in before the index was added did. Sessions
+// last for lifetime, so none are left a lifetime after the index is first used.
+func (s *userSessionStore) HasUnindexed(ctx context.Context, now time.Time, lifetime time.Duration) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM sessions s
+			WHERE s.expiry > $1
+				AND s.expiry - make_interval(secs => $2) < COALESCE((SELECT min(created_at) FROM user_sessions), $1)
+				AND NOT EXISTS (SELECT 1 FROM user_sessions us WHERE us.token = s.token)
+		)
+	`
+	c, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	var exists bool
+	err := s.db.QueryRow(c, query, now, lifetime.Seconds()).Scan(&exists)
+	return exists, err
+}
+
+// DeleteExpired removes the sessions that expired before now from the index, returning how many there were.
+func (s *userSessionStore) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	c, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	result, err := s.db.Exec(c, `DELETE FROM user_sessions WHERE expiry <= $1`, now)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+// truncateUserAgent shortens a user agent to MaxUserAgentLength bytes, without splitting a character.
+func truncateUserAgent(userAgent string) string {
+	if len(userAgent) <= MaxUserAgentLength {
+		return userAgent
+	}
+	end := MaxUserAgentLength
+	for end > 0 && !utf8.RuneStart(userAgent[end]) {
+		end--
+	}
+	return userAgent[:end]
+}
+
+func scanUserSession(row pgx.Row) (*UserSession, error) {
+	var us UserSession
+	err := row.Scan(
+		&us.ID,
+		&us.Token,
+		&us.UserID,
+		&us.IP,
+		&us.UserAgent,
+		&us.CreatedAt,
+		&us.LastSeenAt,
+		&us.Expiry,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &us, nil
+}
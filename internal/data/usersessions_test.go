@@ -0,0 +1,177 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestTruncateUserAgent(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  int
+	}{
+		{"Short", "Mozilla/5.0", len("Mozilla/5.0")},
+		{"Longest", strings.Repeat("a", MaxUserAgentLength), MaxUserAgentLength},
+		{"Too long", strings.Repeat("a", MaxUserAgentLength+1), MaxUserAgentLength},
+		{"Multibyte boundary", "a" + strings.Repeat("碁", MaxUserAgentLength), MaxUserAgentLength - 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := truncateUserAgent(tt.input)
+			if len(got) != tt.want || !utf8.ValidString(got) {
+				t.Errorf("expected %d valid bytes, got %d", tt.want, len(got))
+			}
+		})
+	}
+}
+
+func TestUserSessionsIntegration(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	user := insertTestUser(t, db, "sessions@example.com")
+	other := insertTestUser(t, db, "other@example.com")
+	now := time.Now().Truncate(time.Second)
+
+	insert := func(t *testing.T, token string, userID int64, expiry time.Time) *UserSession {
+		t.Helper()
+		us := &UserSession{
+			Token:      token,
+			UserID:     userID,
+			IP:         "192.0.2.1",
+			UserAgent:  "Laptop",
+			CreatedAt:  now,
+			LastSeenAt: now,
+			Expiry:     expiry,
+		}
+		if err := db.UserSessions.Insert(ctx, us); err != nil {
+			t.Fatalf("failed to insert session: %s", err)
+		}
+		return us
+	}
+	list := func(t *testing.T, userID int64) []*UserSession {
+		t.Helper()
+		sessions, err := db.UserSessions.ListForUser(ctx, userID, now)
+		if err != nil {
+			t.Fatalf("failed to list sessions: %s", err)
+		}
+		return sessions
+	}
+
+	first := insert(t, "first", int64(user.ID), now.Add(time.Hour))
+	insert(t, "second", int64(user.ID), now.Add(time.Hour))
+	insert(t, "expired", int64(user.ID), now.Add(-time.Hour))
+	insert(t, "other", int64(other.ID), now.Add(time.Hour))
+
+	if sessions := list(t, int64(user.ID)); len(sessions) != 2 {
+		t.Fatalf("expected 2 unexpired sessions, got %d", len(sessions))
+	}
+
+	t.Run("activity is recorded at most once a minute", func(t *testing.T) {
+		if err := db.UserSessions.Touch(ctx, "first", "192.0.2.2", "Phone", now.Add(30*time.Second)); err != nil {
+			t.Fatalf("failed to touch session: %s", err)
+		}
+		if s := list(t, int64(user.ID))[0]; s.UserAgent != "Laptop" {
+			t.Errorf("expected activity within a minute to be ignored, got %+v", s)
+		}
+		if err := db.UserSessions.Touch(ctx, "first", "192.0.2.2", "Phone", now.Add(time.Minute)); err != nil {
+			t.Fatalf("failed to touch session: %s", err)
+		}
+		s := list(t, int64(user.ID))[0]
+		if s.ID != first.ID || s.IP != "192.0.2.2" || s.UserAgent != "Phone" {
+			t.Errorf("expected the activity to be recorded, got %+v", s)
+		}
+	})
+
+	t.Run("renewed tokens keep their entry", func(t *testing.T) {
+		if err := db.UserSessions.UpdateToken(ctx, "first", "renewed"); err != nil {
+			t.Fatalf("failed to update token: %s", err)
+		}
+		if s := list(t, int64(user.ID))[0]; s.ID != first.ID || s.Token != "renewed" {
+			t.Errorf("expected the token to be updated, got %+v", s)
+		}
+	})
+
+	t.Run("only the owner can delete a session", func(t *testing.T) {
+		if _, err := db.UserSessions.Delete(ctx, int64(other.ID), first.ID); !errors.Is(err, ErrNoUserSessionFound) {
+			t.Errorf("expected ErrNoUserSessionFound, got %v", err)
+		}
+		deleted, err := db.UserSessions.Delete(ctx, int64(user.ID), first.ID)
+		if err != nil {
+			t.Fatalf("failed to delete session: %s", err)
+		}
+		if deleted.Token != "renewed" {
+			t.Errorf("expected the deleted session's token, got %q", deleted.Token)
+		}
+	})
+
+	t.Run("deleting other sessions keeps one", func(t *testing.T) {
+		insert(t, "third", int64(user.ID), now.Add(time.Hour))
+		tokens, err := db.UserSessions.DeleteOthersForUser(ctx, int64(user.ID), "third")
+		if err != nil {
+			t.Fatalf("failed to delete sessions: %s", err)
+		}
+		if len(tokens) != 2 {
+			t.Errorf("expected the tokens of the 2 deleted sessions, got %v", tokens)
+		}
+		sessions := list(t, int64(user.ID))
+		if len(sessions) != 1 || sessions[0].Token != "third" {
+			t.Errorf("expected only the kept session, got %+v", sessions)
+		}
+		if len(list(t, int64(other.ID))) != 1 {
+			t.Error("expected other users' sessions to be kept")
+		}
+		if err := db.UserSessions.DeleteByToken(ctx, "third"); err != nil {
+			t.Fatalf("failed to delete session: %s", err)
+		}
+		if sessions := list(t, int64(user.ID)); len(sessions) != 0 {
+			t.Errorf("expected no sessions, got %+v", sessions)
+		}
+	})
+
+	t.Run("expired sessions are removed", func(t *testing.T) {
+		insert(t, "stale", int64(user.ID), now.Add(-time.Hour))
+		removed, err := db.UserSessions.DeleteExpired(ctx, now)
+		if err != nil {
+			t.Fatalf("failed to delete expired sessions: %s", err)
+		}
+		if removed != 1 {
+			t.Errorf("expected 1 expired session to be removed, got %d", removed)
+		}
+	})
+
+	t.Run("sessions from before the index are found", func(t *testing.T) {
+		unindexed := func(t *testing.T) bool {
+			t.Helper()
+			found, err := db.UserSessions.HasUnindexed(ctx, now, 24*time.Hour)
+			if err != nil {
+				t.Fatalf("failed to look for unindexed sessions: %s", err)
+			}
+			return found
+		}
+		insert(t, "indexed", int64(user.ID), now.Add(24*time.Hour))
+		store := func(t *testing.T, token string, expiry time.Time) {
+			t.Helper()
+			_, err := db.Pool.Exec(ctx, `INSERT INTO sessions (token, data, expiry) VALUES ($1, '', $2)`, token, expiry)
+			if err != nil {
+				t.Fatalf("failed to store session: %s", err)
+			}
+		}
+
+		store(t, "indexed", now.Add(24*time.Hour))
+		store(t, "anonymous", now.Add(24*time.Hour))
+		if unindexed(t) {
+			t.Error("expected sessions begun since the index to be ignored")
+		}
+		store(t, "legacy", now.Add(time.Hour))
+		if !unindexed(t) {
+			t.Error("expected the session begun before the index to be found")
+		}
+	})
+}
@@ -0,0 +1,50 @@
+package events
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrSlowConsumer is the reason given when a client is disconnected for not keeping up with its messages.
+var ErrSlowConsumer = errors.New("client could not keep up with messages")
+
+// Client is a single subscriber, typically one WebSocket connection.
+type Client struct {
+	UserID int64
+
+	hub  *Hub
+	send chan Message
+	once sync.Once
+
+	// The following are guarded by hub.mu.
+	topics map[string]struct{}
+	closed bool
+	err    error
+}
+
+// Messages returns the channel on which the client's messages are delivered. It is closed when the client
+// is disconnected, after any buffered messages.
+func (c *Client) Messages() <-chan Message {
+	return c.send
+}
+
+// Err returns why the hub disconnected the client, or nil if it hasn't or was shut down cleanly.
+func (c *Client) Err() error {
+	c.hub.mu.Lock()
+	defer c.hub.mu.Unlock()
+	return c.err
+}
+
+// Close disconnects the client from the hub.
+func (c *Client) Close() {
+	c.hub.mu.Lock()
+	defer c.hub.mu.Unlock()
+	c.hub.disconnect(c, nil)
+}
+
+// Done tells the hub that the client's messages have been delivered, or abandoned, so that Shutdown can
+// return. It closes the client if it hasn't been already. Calling Done more than once has no effect.
+func (c *Client) Done() {
+	c.Close()
+	c.once.Do(c.hub.drained.Done)
+}
@@ -0,0 +1,250 @@
+// Package events fans out real-time messages to subscribers grouped by topic. It knows nothing about the
+// transport; callers register a Client per connection and deliver whatever arrives on Client.Messages.
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+)
+
+const (
+	// TypeSubscribed confirms a subscription. Its Seq is that of the latest message published on the topic.
+	TypeSubscribed = "subscribed"
+	// TypeReset tells a client that messages it asked to resume from are no longer available and it should
+	// reload the state of the topic.
+	TypeReset = "reset"
+)
+
+var (
+	// ErrHubClosed is returned when using a hub that has been shut down.
+	ErrHubClosed = errors.New("event hub is shut down")
+	// ErrClientClosed is returned when subscribing a client that has been disconnected.
+	ErrClientClosed = errors.New("client is closed")
+)
+
+// Message is a single event published to a topic. Seq increases by one for each message on a topic so that
+// clients can detect gaps and resume after reconnecting.
+type Message struct {
+	Topic string          `json:"topic"`
+	Seq   uint64          `json:"seq"`
+	Type  string          `json:"type"`
+	Data  json.RawMessage `json:"data,omitempty"`
+}
+
+// Hub routes published messages to the clients subscribed to each topic.
+type Hub struct {
+	historySize int
+	bufferSize  int
+
+	mu     sync.Mutex
+	topics map[string]*topic
+	// firstSeq is where the sequence numbers of a new topic start. It is raised past the last message of
+	// every expired topic, so that a client resuming from before a topic expired is sent TypeReset rather
+	// than mistaking a later topic of the same name for the one it saw.
+	firstSeq uint64
+	clients  map[*Client]struct{}
+	closed   bool
+	drained  sync.WaitGroup
+}
+
+type topic struct {
+	seq         uint64
+	history     []Message
+	subscribers map[*Client]struct{}
+	// idle is set by Expire and cleared by any activity on the topic.
+	idle bool
+}
+
+// NewHub returns a hub that remembers the last historySize messages of each topic for resumption and
+// buffers up to bufferSize undelivered messages per client.
+func NewHub(historySize, bufferSize int) *Hub {
+	return &Hub{
+		historySize: historySize,
+		bufferSize:  bufferSize,
+		topics:      make(map[string]*topic),
+		clients:     make(map[*Client]struct{}),
+	}
+}
+
+// Register adds a client for the given user. The caller must call Done once it has finished delivering
+// the client's messages.
+func (h *Hub) Register(userID int64) (*Client, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return nil, ErrHubClosed
+	}
+	c := &Client{
+		UserID: userID,
+		hub:    h,
+		send:   make(chan Message, h.bufferSize),
+		topics: make(map[string]struct{}),
+	}
+	h.clients[c] = struct{}{}
+	h.drained.Add(1)
+	return c, nil
+}
+
+// Subscribe starts delivering messages on a topic to the client, beginning with a TypeSubscribed message. If
+// since is non-zero, any retained messages after that sequence number are delivered next. If some of them are
+// no longer retained, a TypeReset message is delivered instead.
+func (h *Hub) Subscribe(c *Client, name string, since uint64) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return ErrHubClosed
+	}
+	if c.closed {
+		return ErrClientClosed
+	}
+
+	t := h.topic(name)
+	t.idle = false
+	t.subscribers[c] = struct{}{}
+	c.topics[name] = struct{}{}
+	h.deliver(c, Message{Topic: name, Seq: t.seq, Type: TypeSubscribed})
+
+	if since == 0 || since == t.seq {
+		return nil
+	}
+	oldest := t.seq - uint64(len(t.history)) + 1
+	if since > t.seq || since+1 < oldest {
+		h.deliver(c, Message{Topic: name, Seq: t.seq, Type: TypeReset})
+		return nil
+	}
+	for _, m := range t.history {
+		if m.Seq > since {
+			h.deliver(c, m)
+		}
+	}
+	return nil
+}
+
+// Unsubscribe stops delivering messages on a topic to the client.
+func (h *Hub) Unsubscribe(c *Client, name string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.unsubscribe(c, name)
+}
+
+// Publish assigns the next sequence number on a topic to a message and delivers it to every subscriber.
+// Subscribers whose buffers are full are disconnected rather than allowed to hold up the others; they can
+// reconnect and resume from the last sequence number they saw.
+func (h *Hub) Publish(name, typ string, data any) (Message, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return Message{}, err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return Message{}, ErrHubClosed
+	}
+
+	t := h.topic(name)
+	t.idle = false
+	t.seq++
+	m := Message{Topic: name, Seq: t.seq, Type: typ, Data: raw}
+	t.history = append(t.history, m)
+	if len(t.history) > h.historySize {
+		t.history = t.history[len(t.history)-h.historySize:]
+	}
+	for c := range t.subscribers {
+		h.deliver(c, m)
+	}
+	return m, nil
+}
+
+// Expire forgets the topics, and the messages retained for them, that have had no subscribers and no messages
+// published since the previous call, returning how many there were. Calling it periodically keeps topics that
+// are no longer used from building up, while leaving time for clients to reconnect and resume.
+func (h *Hub) Expire() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	expired := 0
+	for name, t := range h.topics {
+		if len(t.subscribers) > 0 {
+			continue
+		}
+		if !t.idle {
+			t.idle = true
+			continue
+		}
+		h.firstSeq = max(h.firstSeq, t.seq)
+		delete(h.topics, name)
+		expired++
+	}
+	return expired
+}
+
+// Shutdown stops accepting clients and messages, then closes every client's message channel so that
+// buffered messages are flushed. It waits until every client has called Done or ctx expires.
+func (h *Hub) Shutdown(ctx context.Context) error {
+	h.mu.Lock()
+	if !h.closed {
+		h.closed = true
+		for c := range h.clients {
+			h.disconnect(c, nil)
+		}
+	}
+	h.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		h.drained.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// deliver queues a message for a client, disconnecting it if its buffer is full. h.mu must be held.
+func (h *Hub) deliver(c *Client, m Message) {
+	if c.closed {
+		return
+	}
+	select {
+	case c.send <- m:
+	default:
+		h.disconnect(c, ErrSlowConsumer)
+	}
+}
+
+// disconnect removes a client from every topic and closes its message channel. h.mu must be held.
+func (h *Hub) disconnect(c *Client, reason error) {
+	if c.closed {
+		return
+	}
+	for name := range c.topics {
+		h.unsubscribe(c, name)
+	}
+	delete(h.clients, c)
+	c.closed = true
+	c.err = reason
+	close(c.send)
+}
+
+func (h *Hub) unsubscribe(c *Client, name string) {
+	t, ok := h.topics[name]
+	if !ok {
+		return
+	}
+	delete(t.subscribers, c)
+	delete(c.topics, name)
+}
+
+func (h *Hub) topic(name string) *topic {
+	t, ok := h.topics[name]
+	if !ok {
+		t = &topic{seq: h.firstSeq, subscribers: make(map[*Client]struct{})}
+		h.topics[name] = t
+	}
+	return t
+}
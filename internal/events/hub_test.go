@@ -0,0 +1,271 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// drain returns every message currently buffered for the client.
+func drain(c *Client) []Message {
+	var msgs []Message
+	for {
+		select {
+		case m, ok := <-c.Messages():
+			if !ok {
+				return msgs
+			}
+			msgs = append(msgs, m)
+		default:
+			return msgs
+		}
+	}
+}
+
+func seqs(msgs []Message) []uint64 {
+	out := make([]uint64, 0, len(msgs))
+	for _, m := range msgs {
+		out = append(out, m.Seq)
+	}
+	return out
+}
+
+func equalSeqs(a, b []uint64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestPublishSubscribe(t *testing.T) {
+	h := NewHub(8, 8)
+	a, err := h.Register(1)
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	b, err := h.Register(2)
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	if err := h.Subscribe(a, "game:1", 0); err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	if err := h.Subscribe(b, "game:2", 0); err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	drain(a)
+	drain(b)
+
+	m, err := h.Publish("game:1", "move", map[string]int{"x": 3})
+	if err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	if m.Seq != 1 || string(m.Data) != `{"x":3}` {
+		t.Errorf("Publish() = %+v", m)
+	}
+
+	if got := drain(a); len(got) != 1 || got[0].Type != "move" {
+		t.Errorf("subscriber received %+v, want one move", got)
+	}
+	if got := drain(b); len(got) != 0 {
+		t.Errorf("non-subscriber received %+v", got)
+	}
+
+	h.Unsubscribe(a, "game:1")
+	if _, err := h.Publish("game:1", "move", nil); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	if got := drain(a); len(got) != 0 {
+		t.Errorf("unsubscribed client received %+v", got)
+	}
+}
+
+func TestSubscribeResume(t *testing.T) {
+	tests := []struct {
+		name      string
+		published int
+		since     uint64
+		wantSeqs  []uint64
+		wantReset bool
+	}{
+		{name: "From start", published: 3, since: 0, wantSeqs: nil},
+		{name: "Up to date", published: 3, since: 3, wantSeqs: nil},
+		{name: "Missed some", published: 3, since: 1, wantSeqs: []uint64{2, 3}},
+		{name: "Oldest retained", published: 6, since: 2, wantSeqs: []uint64{3, 4, 5, 6}},
+		{name: "Gap", published: 6, since: 1, wantSeqs: []uint64{6}, wantReset: true},
+		{name: "Ahead of topic", published: 2, since: 5, wantSeqs: []uint64{2}, wantReset: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHub(4, 8)
+			for range tt.published {
+				if _, err := h.Publish("game:1", "move", nil); err != nil {
+					t.Fatalf("Publish() error = %v", err)
+				}
+			}
+			c, _ := h.Register(1)
+			if err := h.Subscribe(c, "game:1", tt.since); err != nil {
+				t.Fatalf("Subscribe() error = %v", err)
+			}
+
+			got := drain(c)
+			if len(got) == 0 || got[0].Type != TypeSubscribed || got[0].Seq != uint64(tt.published) {
+				t.Fatalf("first message = %+v, want subscription at seq %d", got, tt.published)
+			}
+			got = got[1:]
+			if !equalSeqs(seqs(got), tt.wantSeqs) {
+				t.Errorf("replayed seqs = %v, want %v", seqs(got), tt.wantSeqs)
+			}
+			if tt.wantReset && (len(got) != 1 || got[0].Type != TypeReset) {
+				t.Errorf("replayed %+v, want a reset", got)
+			}
+		})
+	}
+}
+
+func TestExpire(t *testing.T) {
+	h := NewHub(4, 8)
+	for range 3 {
+		if _, err := h.Publish("game:1", "move", nil); err != nil {
+			t.Fatalf("Publish() error = %v", err)
+		}
+	}
+	watcher, _ := h.Register(1)
+	if err := h.Subscribe(watcher, "game:2", 0); err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	if _, err := h.Publish("game:3", "move", nil); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+
+	if n := h.Expire(); n != 0 {
+		t.Errorf("first Expire() = %d, want 0", n)
+	}
+	if _, err := h.Publish("game:3", "move", nil); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	if n := h.Expire(); n != 1 {
+		t.Errorf("second Expire() = %d, want 1", n)
+	}
+	if _, ok := h.topics["game:1"]; ok || len(h.topics) != 2 {
+		t.Errorf("expected only the idle topic to be forgotten, have %d topics", len(h.topics))
+	}
+
+	h.Unsubscribe(watcher, "game:2")
+	h.Expire()
+	h.Expire()
+	if len(h.topics) != 0 {
+		t.Errorf("expected every topic to be forgotten once unused, have %d topics", len(h.topics))
+	}
+
+	// Resuming a forgotten topic resets the client, and the topic continues from beyond every sequence
+	// number it had before.
+	c, _ := h.Register(2)
+	if err := h.Subscribe(c, "game:1", 2); err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	m, err := h.Publish("game:1", "move", nil)
+	if err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	got := drain(c)
+	if len(got) != 3 || got[0].Type != TypeSubscribed || got[1].Type != TypeReset || got[2].Seq != m.Seq {
+		t.Fatalf("messages = %+v, want subscription, reset and the new message", got)
+	}
+	if m.Seq <= 3 {
+		t.Errorf("new message seq = %d, want it after the forgotten messages", m.Seq)
+	}
+}
+
+func TestSlowConsumerDisconnected(t *testing.T) {
+	h := NewHub(8, 2)
+	slow, _ := h.Register(1)
+	fast, _ := h.Register(2)
+	_ = h.Subscribe(slow, "lobby", 0)
+	_ = h.Subscribe(fast, "lobby", 0)
+	drain(fast)
+
+	var received int
+	for range 3 {
+		if _, err := h.Publish("lobby", "seek", nil); err != nil {
+			t.Fatalf("Publish() error = %v", err)
+		}
+		received += len(drain(fast))
+	}
+	if received != 3 {
+		t.Errorf("fast consumer received %d messages, want 3", received)
+	}
+
+	got := 0
+	for range slow.Messages() {
+		got++
+	}
+	if got != 2 {
+		t.Errorf("slow consumer received %d messages before disconnect, want its subscription and 1 more", got)
+	}
+	if !errors.Is(slow.Err(), ErrSlowConsumer) {
+		t.Errorf("Err() = %v, want %v", slow.Err(), ErrSlowConsumer)
+	}
+	if err := h.Subscribe(slow, "lobby", 0); !errors.Is(err, ErrClientClosed) {
+		t.Errorf("Subscribe() after disconnect error = %v, want %v", err, ErrClientClosed)
+	}
+}
+
+func TestShutdownDrains(t *testing.T) {
+	h := NewHub(8, 8)
+	c, _ := h.Register(1)
+	_ = h.Subscribe(c, "lobby", 0)
+	drain(c)
+	for range 3 {
+		_, _ = h.Publish("lobby", "seek", nil)
+	}
+
+	received := make(chan int)
+	go func() {
+		n := 0
+		for range c.Messages() {
+			n++
+		}
+		c.Done()
+		received <- n
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := h.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	if n := <-received; n != 3 {
+		t.Errorf("received %d messages before close, want 3", n)
+	}
+	if c.Err() != nil {
+		t.Errorf("Err() = %v, want nil", c.Err())
+	}
+
+	if _, err := h.Register(2); !errors.Is(err, ErrHubClosed) {
+		t.Errorf("Register() after shutdown error = %v, want %v", err, ErrHubClosed)
+	}
+	if _, err := h.Publish("lobby", "seek", nil); !errors.Is(err, ErrHubClosed) {
+		t.Errorf("Publish() after shutdown error = %v, want %v", err, ErrHubClosed)
+	}
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	h := NewHub(8, 8)
+	c, _ := h.Register(1)
+	defer c.Done()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := h.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Shutdown() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
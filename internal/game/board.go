@@ -0,0 +1,326 @@
+// Package game implements the rules of Go: stone placement, liberties, captures, suicide and simple ko.
+package game
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	// MinBoardSize is the smallest supported board.
+	MinBoardSize = 2
+	// MaxBoardSize is the largest supported board.
+	MaxBoardSize = 25
+)
+
+var (
+	// ErrInvalidBoardSize is returned when a board is created outside of the supported size range.
+	ErrInvalidBoardSize = fmt.Errorf("board size must be between %d and %d", MinBoardSize, MaxBoardSize)
+	// ErrInvalidColor is returned when a move is made by neither black nor white.
+	ErrInvalidColor = errors.New("stone color must be black or white")
+	// ErrOutOfBounds is returned when a move is made off the board.
+	ErrOutOfBounds = errors.New("point is not on the board")
+	// ErrOccupied is returned when a move is made on a point that already has a stone.
+	ErrOccupied = errors.New("point is already occupied")
+	// ErrSuicide is returned when a move would leave its own group without liberties.
+	ErrSuicide = errors.New("move is suicide")
+	// ErrKo is returned when a move immediately retakes a ko.
+	ErrKo = errors.New("move retakes ko")
+)
+
+// Color is the occupant of a point on the board.
+type Color int8
+
+const (
+	Empty Color = iota
+	Black
+	White
+)
+
+// Opponent returns the opposing color. Empty has no opponent and is returned unchanged.
+func (c Color) Opponent() Color {
+	switch c {
+	case Black:
+		return White
+	case White:
+		return Black
+	default:
+		return Empty
+	}
+}
+
+func (c Color) String() string {
+	switch c {
+	case Black:
+		return "black"
+	case White:
+		return "white"
+	default:
+		return "empty"
+	}
+}
+
+// MarshalText encodes the color by name.
+func (c Color) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+// UnmarshalText decodes a color from its name.
+func (c *Color) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "black":
+		*c = Black
+	case "white":
+		*c = White
+	case "empty", "":
+		*c = Empty
+	default:
+		return fmt.Errorf("unknown color %q", text)
+	}
+	return nil
+}
+
+// Point is a zero-indexed intersection on the board, with (0, 0) in the top left corner.
+type Point struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+// Move is a single entry in a board's history.
+type Move struct {
+	Color    Color   `json:"color"`
+	Point    Point   `json:"point"`
+	Pass     bool    `json:"pass"`
+	Captured []Point `json:"captured,omitempty"`
+}
+
+// Board holds a position and the moves that produced it.
+type Board struct {
+	size     int
+	grid     []Color
+	ko       *Point
+	captures map[Color]int
+	history  []Move
+}
+
+// NewBoard returns an empty board of the given size. The standard sizes are 9, 13 and 19.
+func NewBoard(size int) (*Board, error) {
+	if size < MinBoardSize || size > MaxBoardSize {
+		return nil, ErrInvalidBoardSize
+	}
+	return &Board{
+		size:     size,
+		grid:     make([]Color, size*size),
+		captures: make(map[Color]int),
+	}, nil
+}
+
+// Size returns the length of one side of the board.
+func (b *Board) Size() int {
+	return b.size
+}
+
+// OnBoard reports whether the point lies on the board.
+func (b *Board) OnBoard(p Point) bool {
+	return p.X >= 0 && p.X < b.size && p.Y >= 0 && p.Y < b.size
+}
+
+// At returns the color of the stone at p, or Empty if the point is vacant or off the board.
+func (b *Board) At(p Point) Color {
+	if !b.OnBoard(p) {
+		return Empty
+	}
+	return b.grid[b.index(p)]
+}
+
+// Captures returns the number of stones the given color has captured.
+func (b *Board) Captures(c Color) int {
+	return b.captures[c]
+}
+
+// Ko returns the point that may not be played on the next move because of simple ko, if there is one.
+func (b *Board) Ko() (Point, bool) {
+	if b.ko == nil {
+		return Point{}, false
+	}
+	return *b.ko, true
+}
+
+// History returns a copy of every move played on the board, in order.
+func (b *Board) History() []Move {
+	history := make([]Move, len(b.history))
+	copy(history, b.history)
+	return history
+}
+
+// Setup places a stone without treating it as a move, for handicap and position setup. No captures are
+// resolved and the stone is not added to the history.
+func (b *Board) Setup(c Color, p Point) error {
+	if c != Black && c != White {
+		return ErrInvalidColor
+	}
+	if !b.OnBoard(p) {
+		return ErrOutOfBounds
+	}
+	if b.At(p) != Empty {
+		return ErrOccupied
+	}
+	b.grid[b.index(p)] = c
+	return nil
+}
+
+// Legal reports whether c may play at p, returning the reason if not.
+func (b *Board) Legal(c Color, p Point) error {
+	_, err := b.Clone().Play(c, p)
+	return err
+}
+
+// Play places a stone of color c at p, removing any opposing groups left without liberties. It returns the
+// captured points. The board is unchanged if the move is illegal.
+func (b *Board) Play(c Color, p Point) ([]Point, error) {
+	if c != Black && c != White {
+		return nil, ErrInvalidColor
+	}
+	if !b.OnBoard(p) {
+		return nil, ErrOutOfBounds
+	}
+	if b.At(p) != Empty {
+		return nil, ErrOccupied
+	}
+	if b.ko != nil && *b.ko == p {
+		return nil, ErrKo
+	}
+
+	b.grid[b.index(p)] = c
+
+	var captured []Point
+	for _, n := range b.neighbors(p) {
+		if b.At(n) != c.Opponent() {
+			continue
+		}
+		group, liberties := b.group(n)
+		if liberties == 0 {
+			for _, s := range group {
+				b.grid[b.index(s)] = Empty
+			}
+			captured = append(captured, group...)
+		}
+	}
+
+	group, liberties := b.group(p)
+	if liberties == 0 {
+		b.grid[b.index(p)] = Empty
+		return nil, ErrSuicide
+	}
+
+	b.ko = nil
+	if len(captured) == 1 && len(group) == 1 && liberties == 1 {
+		ko := captured[0]
+		b.ko = &ko
+	}
+
+	b.captures[c] += len(captured)
+	b.history = append(b.history, Move{Color: c, Point: p, Captured: captured})
+	return captured, nil
+}
+
+// Pass records a pass for color c, clearing any ko.
+func (b *Board) Pass(c Color) error {
+	if c != Black && c != White {
+		return ErrInvalidColor
+	}
+	b.ko = nil
+	b.history = append(b.history, Move{Color: c, Pass: true})
+	return nil
+}
+
+// Group returns the stones connected to p and the number of liberties they share. Both are empty if p is
+// vacant.
+func (b *Board) Group(p Point) ([]Point, int) {
+	if b.At(p) == Empty {
+		return nil, 0
+	}
+	return b.group(p)
+}
+
+// Clone returns a deep copy of the board.
+func (b *Board) Clone() *Board {
+	c := &Board{
+		size:     b.size,
+		grid:     make([]Color, len(b.grid)),
+		captures: make(map[Color]int, len(b.captures)),
+		history:  b.History(),
+	}
+	copy(c.grid, b.grid)
+	for k, v := range b.captures {
+		c.captures[k] = v
+	}
+	if b.ko != nil {
+		ko := *b.ko
+		c.ko = &ko
+	}
+	return c
+}
+
+// String renders the board as text, one row per line, using X for black, O for white and . for empty.
+func (b *Board) String() string {
+	var sb strings.Builder
+	for y := range b.size {
+		for x := range b.size {
+			switch b.At(Point{x, y}) {
+			case Black:
+				sb.WriteByte('X')
+			case White:
+				sb.WriteByte('O')
+			default:
+				sb.WriteByte('.')
+			}
+		}
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+func (b *Board) index(p Point) int {
+	return p.Y*b.size + p.X
+}
+
+func (b *Board) neighbors(p Point) []Point {
+	candidates := [4]Point{{p.X - 1, p.Y}, {p.X + 1, p.Y}, {p.X, p.Y - 1}, {p.X, p.Y + 1}}
+	neighbors := make([]Point, 0, 4)
+	for _, n := range candidates {
+		if b.OnBoard(n) {
+			neighbors = append(neighbors, n)
+		}
+	}
+	return neighbors
+}
+
+// group flood fills from p, returning the connected stones and their distinct liberties.
+func (b *Board) group(p Point) ([]Point, int) {
+	color := b.At(p)
+	visited := map[Point]bool{p: true}
+	liberties := make(map[Point]bool)
+	stack := []Point{p}
+	var stones []Point
+
+	for len(stack) > 0 {
+		cur := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		stones = append(stones, cur)
+
+		for _, n := range b.neighbors(cur) {
+			switch b.At(n) {
+			case Empty:
+				liberties[n] = true
+			case color:
+				if !visited[n] {
+					visited[n] = true
+					stack = append(stack, n)
+				}
+			}
+		}
+	}
+	return stones, len(liberties)
+}
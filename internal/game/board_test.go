@@ -0,0 +1,346 @@
+package game
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// boardFromDiagram builds a board from rows of X (black), O (white) and . (empty).
+func boardFromDiagram(t *testing.T, diagram string) *Board {
+	t.Helper()
+	rows := strings.Fields(diagram)
+	b, err := NewBoard(len(rows))
+	if err != nil {
+		t.Fatalf("failed to create board: %s", err)
+	}
+	for y, row := range rows {
+		for x, ch := range row {
+			var c Color
+			switch ch {
+			case 'X':
+				c = Black
+			case 'O':
+				c = White
+			default:
+				continue
+			}
+			if err := b.Setup(c, Point{x, y}); err != nil {
+				t.Fatalf("failed to set up stone at (%d, %d): %s", x, y, err)
+			}
+		}
+	}
+	return b
+}
+
+func TestNewBoard(t *testing.T) {
+	tests := []struct {
+		name    string
+		size    int
+		wantErr error
+	}{
+		{"9x9", 9, nil},
+		{"13x13", 13, nil},
+		{"19x19", 19, nil},
+		{"Maximum size", 25, nil},
+		{"Minimum size", 2, nil},
+		{"Too small", 1, ErrInvalidBoardSize},
+		{"Too large", 26, ErrInvalidBoardSize},
+		{"Zero", 0, ErrInvalidBoardSize},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := NewBoard(tt.size)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("NewBoard() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && b.Size() != tt.size {
+				t.Errorf("Size() = %d, want %d", b.Size(), tt.size)
+			}
+		})
+	}
+}
+
+func TestPlay(t *testing.T) {
+	tests := []struct {
+		name         string
+		diagram      string
+		color        Color
+		point        Point
+		wantErr      error
+		wantCaptured int
+		want         string
+	}{
+		{
+			name:    "Place on empty board",
+			diagram: "... ... ...",
+			color:   Black,
+			point:   Point{1, 1},
+			want:    "... .X. ...",
+		},
+		{
+			name:    "Out of bounds",
+			diagram: "... ... ...",
+			color:   Black,
+			point:   Point{3, 0},
+			wantErr: ErrOutOfBounds,
+		},
+		{
+			name:    "Negative coordinate",
+			diagram: "... ... ...",
+			color:   White,
+			point:   Point{0, -1},
+			wantErr: ErrOutOfBounds,
+		},
+		{
+			name:    "Occupied point",
+			diagram: "... .O. ...",
+			color:   Black,
+			point:   Point{1, 1},
+			wantErr: ErrOccupied,
+		},
+		{
+			name:    "Invalid color",
+			diagram: "... ... ...",
+			color:   Empty,
+			point:   Point{1, 1},
+			wantErr: ErrInvalidColor,
+		},
+		{
+			name:         "Capture single stone in center",
+			diagram:      "..... ..X.. .XO.. ..X.. .....",
+			color:        Black,
+			point:        Point{3, 2},
+			wantCaptured: 1,
+			want:         "..... ..X.. .X.X. ..X.. .....",
+		},
+		{
+			name:         "Capture in corner",
+			diagram:      "OX. ... ...",
+			color:        Black,
+			point:        Point{0, 1},
+			wantCaptured: 1,
+			want:         ".X. X.. ...",
+		},
+		{
+			name:         "Capture group on edge",
+			diagram:      "XOO.. .XX.. ..... ..... .....",
+			color:        Black,
+			point:        Point{3, 0},
+			wantCaptured: 2,
+			want:         "X..X. .XX.. ..... ..... .....",
+		},
+		{
+			name:         "Capture two groups at once",
+			diagram:      ".XOX. XO.OX .XOX. ..X.. .....",
+			color:        Black,
+			point:        Point{2, 1},
+			wantCaptured: 4,
+			want:         ".X.X. X.X.X .X.X. ..X.. .....",
+		},
+		{
+			name:    "Single stone suicide",
+			diagram: ".X. X.. ...",
+			color:   White,
+			point:   Point{0, 0},
+			wantErr: ErrSuicide,
+		},
+		{
+			name:    "Group suicide",
+			diagram: "O.X.. XXX.. ..... ..... .....",
+			color:   White,
+			point:   Point{1, 0},
+			wantErr: ErrSuicide,
+		},
+		{
+			name:         "Capture is not suicide",
+			diagram:      ".XO.. XO... O.... ..... .....",
+			color:        White,
+			point:        Point{0, 0},
+			wantCaptured: 2,
+			want:         "O.O.. .O... O.... ..... .....",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := boardFromDiagram(t, tt.diagram)
+			before := b.String()
+
+			captured, err := b.Play(tt.color, tt.point)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Play() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if b.String() != before {
+					t.Errorf("board changed after illegal move:\n%s", b.String())
+				}
+				if len(b.History()) != 0 {
+					t.Errorf("illegal move was added to history")
+				}
+				return
+			}
+			if len(captured) != tt.wantCaptured {
+				t.Errorf("captured %d stones, want %d", len(captured), tt.wantCaptured)
+			}
+			if b.Captures(tt.color) != tt.wantCaptured {
+				t.Errorf("Captures() = %d, want %d", b.Captures(tt.color), tt.wantCaptured)
+			}
+			want := boardFromDiagram(t, tt.want).String()
+			if b.String() != want {
+				t.Errorf("board =\n%s\nwant\n%s", b.String(), want)
+			}
+		})
+	}
+}
+
+func TestKo(t *testing.T) {
+	// Black captures at (2, 1), creating a ko at (1, 1).
+	diagram := ".XO.. XO.O. .XO.. ..... ....."
+
+	t.Run("Immediate retake is forbidden", func(t *testing.T) {
+		b := boardFromDiagram(t, diagram)
+		if _, err := b.Play(Black, Point{2, 1}); err != nil {
+			t.Fatalf("failed to take ko: %s", err)
+		}
+		ko, ok := b.Ko()
+		if !ok || ko != (Point{1, 1}) {
+			t.Fatalf("Ko() = %v, %v, want (1, 1), true", ko, ok)
+		}
+		if _, err := b.Play(White, Point{1, 1}); !errors.Is(err, ErrKo) {
+			t.Errorf("Play() error = %v, want %v", err, ErrKo)
+		}
+	})
+
+	t.Run("Retake allowed after ko threat", func(t *testing.T) {
+		b := boardFromDiagram(t, diagram)
+		if _, err := b.Play(Black, Point{2, 1}); err != nil {
+			t.Fatalf("failed to take ko: %s", err)
+		}
+		if _, err := b.Play(White, Point{4, 4}); err != nil {
+			t.Fatalf("failed to play ko threat: %s", err)
+		}
+		if _, err := b.Play(Black, Point{4, 3}); err != nil {
+			t.Fatalf("failed to answer ko threat: %s", err)
+		}
+		if _, err := b.Play(White, Point{1, 1}); err != nil {
+			t.Errorf("expected retake to be legal, got %s", err)
+		}
+	})
+
+	t.Run("Pass clears ko", func(t *testing.T) {
+		b := boardFromDiagram(t, diagram)
+		if _, err := b.Play(Black, Point{2, 1}); err != nil {
+			t.Fatalf("failed to take ko: %s", err)
+		}
+		if err := b.Pass(White); err != nil {
+			t.Fatalf("failed to pass: %s", err)
+		}
+		if _, ok := b.Ko(); ok {
+			t.Error("expected ko to be cleared by pass")
+		}
+	})
+
+	t.Run("Capturing two stones is not ko", func(t *testing.T) {
+		b := boardFromDiagram(t, ".XX.. XOO.. .XX.. ..... .....")
+		captured, err := b.Play(Black, Point{3, 1})
+		if err != nil {
+			t.Fatalf("failed to capture: %s", err)
+		}
+		if len(captured) != 2 {
+			t.Fatalf("captured %d stones, want 2", len(captured))
+		}
+		if _, ok := b.Ko(); ok {
+			t.Error("expected no ko after multi-stone capture")
+		}
+	})
+}
+
+func TestGroup(t *testing.T) {
+	tests := []struct {
+		name          string
+		diagram       string
+		point         Point
+		wantStones    int
+		wantLiberties int
+	}{
+		{"Empty point", "... ... ...", Point{1, 1}, 0, 0},
+		{"Single stone in center", "... .X. ...", Point{1, 1}, 1, 4},
+		{"Single stone in corner", "X.. ... ...", Point{0, 0}, 1, 2},
+		{"Connected group", "XX. .X. ...", Point{0, 0}, 3, 4},
+		{"Shared liberties counted once", "X.X ... ...", Point{0, 0}, 1, 2},
+		{"Group in atari", "XO. O.. ...", Point{0, 0}, 1, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := boardFromDiagram(t, tt.diagram)
+			stones, liberties := b.Group(tt.point)
+			if len(stones) != tt.wantStones {
+				t.Errorf("Group() stones = %d, want %d", len(stones), tt.wantStones)
+			}
+			if liberties != tt.wantLiberties {
+				t.Errorf("Group() liberties = %d, want %d", liberties, tt.wantLiberties)
+			}
+		})
+	}
+}
+
+func TestHistory(t *testing.T) {
+	b, err := NewBoard(9)
+	if err != nil {
+		t.Fatalf("failed to create board: %s", err)
+	}
+	moves := []Move{
+		{Color: Black, Point: Point{2, 2}},
+		{Color: White, Point: Point{6, 6}},
+		{Color: Black, Pass: true},
+		{Color: White, Point: Point{2, 6}},
+	}
+	for _, m := range moves {
+		if m.Pass {
+			err = b.Pass(m.Color)
+		} else {
+			_, err = b.Play(m.Color, m.Point)
+		}
+		if err != nil {
+			t.Fatalf("failed to play move %+v: %s", m, err)
+		}
+	}
+
+	history := b.History()
+	if len(history) != len(moves) {
+		t.Fatalf("History() has %d moves, want %d", len(history), len(moves))
+	}
+	for i, m := range moves {
+		if history[i].Color != m.Color || history[i].Point != m.Point || history[i].Pass != m.Pass {
+			t.Errorf("History()[%d] = %+v, want %+v", i, history[i], m)
+		}
+	}
+
+	history[0].Point = Point{0, 0}
+	if b.History()[0].Point != (Point{2, 2}) {
+		t.Error("modifying returned history should not affect the board")
+	}
+}
+
+func TestClone(t *testing.T) {
+	b := boardFromDiagram(t, ".XO.. XO.O. .XO.. ..... .....")
+	if _, err := b.Play(Black, Point{2, 1}); err != nil {
+		t.Fatalf("failed to play: %s", err)
+	}
+	c := b.Clone()
+	if _, err := c.Play(White, Point{4, 4}); err != nil {
+		t.Fatalf("failed to play on clone: %s", err)
+	}
+	if b.At(Point{4, 4}) != Empty {
+		t.Error("playing on a clone should not affect the original")
+	}
+	if _, ok := b.Ko(); !ok {
+		t.Error("original should retain its ko")
+	}
+	if b.Captures(Black) != 1 || c.Captures(Black) != 1 {
+		t.Error("expected captures to be copied")
+	}
+}
@@ -0,0 +1,158 @@
+package gtp
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hazzardr/baduk-online/internal/game"
+)
+
+// columns are the GTP column letters, which skip I.
+const columns = "ABCDEFGHJKLMNOPQRSTUVWXYZ"
+
+// Move is a move generated by an engine.
+type Move struct {
+	Point  game.Point
+	Pass   bool
+	Resign bool
+}
+
+// FormatVertex writes a point as a GTP vertex, e.g. "D4", on a board of the given size. GTP counts rows from
+// the bottom of the board, while points count them from the top.
+func FormatVertex(p game.Point, size int) (string, error) {
+	if p.X < 0 || p.X >= size || p.Y < 0 || p.Y >= size || size > len(columns) {
+		return "", game.ErrOutOfBounds
+	}
+	return fmt.Sprintf("%c%d", columns[p.X], size-p.Y), nil
+}
+
+// ParseVertex reads a GTP vertex on a board of the given size. Pass is reported separately since it is not
+// a point.
+func ParseVertex(s string, size int) (game.Point, bool, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if s == "PASS" {
+		return game.Point{}, true, nil
+	}
+	if len(s) < 2 {
+		return game.Point{}, false, fmt.Errorf("invalid vertex %q", s)
+	}
+	x := strings.IndexByte(columns, s[0])
+	row, err := strconv.Atoi(s[1:])
+	if x < 0 || err != nil {
+		return game.Point{}, false, fmt.Errorf("invalid vertex %q", s)
+	}
+	p := game.Point{X: x, Y: size - row}
+	if p.X >= size || p.Y < 0 || p.Y >= size {
+		return game.Point{}, false, game.ErrOutOfBounds
+	}
+	return p, false, nil
+}
+
+// BoardSize changes the size of the board. The position is undefined until ClearBoard is called.
+func (c *Client) BoardSize(ctx context.Context, size int) error {
+	if size < game.MinBoardSize || size > len(columns) {
+		return game.ErrInvalidBoardSize
+	}
+	_, err := c.Run(ctx, "boardsize", strconv.Itoa(size))
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.size = size
+	return nil
+}
+
+// Size returns the size of the engine's board.
+func (c *Client) Size() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.size
+}
+
+// ClearBoard empties the board.
+func (c *Client) ClearBoard(ctx context.Context) error {
+	_, err := c.Run(ctx, "clear_board")
+	return err
+}
+
+// Komi sets the komi.
+func (c *Client) Komi(ctx context.Context, komi float64) error {
+	_, err := c.Run(ctx, "komi", strconv.FormatFloat(komi, 'f', -1, 64))
+	return err
+}
+
+// SetFreeHandicap places black handicap stones on the given points of an empty board.
+func (c *Client) SetFreeHandicap(ctx context.Context, points []game.Point) error {
+	size := c.Size()
+	vertices := make([]string, len(points))
+	for i, p := range points {
+		v, err := FormatVertex(p, size)
+		if err != nil {
+			return err
+		}
+		vertices[i] = v
+	}
+	_, err := c.Run(ctx, "set_free_handicap", vertices...)
+	return err
+}
+
+// Play plays a stone of the given color.
+func (c *Client) Play(ctx context.Context, color game.Color, p game.Point) error {
+	vertex, err := FormatVertex(p, c.Size())
+	if err != nil {
+		return err
+	}
+	_, err = c.Run(ctx, "play", color.String(), vertex)
+	return err
+}
+
+// Pass plays a pass for the given color.
+func (c *Client) Pass(ctx context.Context, color game.Color) error {
+	_, err := c.Run(ctx, "play", color.String(), "pass")
+	return err
+}
+
+// GenMove asks the engine for a move for color, and plays it on the engine's board.
+func (c *Client) GenMove(ctx context.Context, color game.Color) (Move, error) {
+	if color != game.Black && color != game.White {
+		return Move{}, game.ErrInvalidColor
+	}
+	resp, err := c.Run(ctx, "genmove", color.String())
+	if err != nil {
+		return Move{}, err
+	}
+	if strings.EqualFold(resp, "resign") {
+		return Move{Resign: true}, nil
+	}
+	p, pass, err := ParseVertex(resp, c.Size())
+	if err != nil {
+		return Move{}, err
+	}
+	return Move{Point: p, Pass: pass}, nil
+}
+
+// FinalScore returns the engine's estimate of the result, e.g. "B+3.5", "W+12" or "0".
+func (c *Client) FinalScore(ctx context.Context) (string, error) {
+	return c.Run(ctx, "final_score")
+}
+
+// FinalStatusList returns the points of stones with the given status, which is "alive", "dead" or "seki".
+func (c *Client) FinalStatusList(ctx context.Context, status string) ([]game.Point, error) {
+	resp, err := c.Run(ctx, "final_status_list", status)
+	if err != nil {
+		return nil, err
+	}
+	size := c.Size()
+	points := []game.Point{}
+	for _, v := range strings.Fields(resp) {
+		p, _, err := ParseVertex(v, size)
+		if err != nil {
+			return nil, err
+		}
+		points = append(points, p)
+	}
+	return points, nil
+}
@@ -0,0 +1,324 @@
+// Package gtp runs a local Go engine, such as GNU Go or KataGo, as a subprocess and talks to it using the
+// Go Text Protocol version 2.
+//
+// A Client remembers the commands that set up the engine's current position. If the engine crashes or stops
+// responding, it is killed and, on the next command, started again with that position restored.
+package gtp
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultTimeout bounds how long a command may take when neither the Config nor the context say otherwise.
+const DefaultTimeout = 30 * time.Second
+
+var (
+	// ErrClosed is returned when using a client after Close.
+	ErrClosed = errors.New("gtp client is closed")
+	// ErrTimeout is returned when the engine doesn't answer a command in time. The engine is killed, and
+	// restarted by the next command.
+	ErrTimeout = errors.New("gtp engine did not respond in time")
+	// ErrCrashed is returned when the engine exits or breaks the protocol while answering a command.
+	ErrCrashed = errors.New("gtp engine crashed")
+)
+
+// CommandError is a failure reported by the engine, such as an illegal move.
+type CommandError struct {
+	Command string
+	Message string
+}
+
+func (e *CommandError) Error() string {
+	return fmt.Sprintf("gtp command %q failed: %s", e.Command, e.Message)
+}
+
+// Config describes how to start an engine.
+type Config struct {
+	// Path is the engine executable.
+	Path string
+	// Args are the arguments that put the engine into GTP mode, e.g. "--mode gtp" for GNU Go.
+	Args []string
+	// Timeout bounds each command. Zero means DefaultTimeout.
+	Timeout time.Duration
+}
+
+// Client is a connection to an engine subprocess. It is safe for concurrent use, though commands are
+// answered one at a time.
+type Client struct {
+	config Config
+
+	mu     sync.Mutex
+	proc   *process
+	nextID int
+	closed bool
+	// setup holds the state changing commands since the engine's position was last cleared, so that a
+	// restarted engine can be put back where it was.
+	setup    []string
+	size     int
+	restarts int
+}
+
+// Start launches the engine and checks that it speaks GTP version 2.
+func Start(config Config) (*Client, error) {
+	if config.Timeout == 0 {
+		config.Timeout = DefaultTimeout
+	}
+	// GTP engines start with a 19x19 board.
+	c := &Client{config: config, size: 19}
+
+	ctx, cancel := context.WithTimeout(context.Background(), config.Timeout)
+	defer cancel()
+	version, err := c.Run(ctx, "protocol_version")
+	if err != nil {
+		c.Close() //nolint:errcheck // the engine is unusable either way
+		return nil, err
+	}
+	if version != "2" {
+		c.Close() //nolint:errcheck // the engine is unusable either way
+		return nil, fmt.Errorf("gtp engine speaks protocol version %s, not 2", version)
+	}
+	return c, nil
+}
+
+// Restarts returns how many times the engine has been restarted after crashing or timing out.
+func (c *Client) Restarts() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.restarts
+}
+
+// Run sends a command to the engine and returns its response. If the engine is not running it is started,
+// and the position it had is restored, first. A command that crashes the engine is tried once more on a
+// fresh engine.
+func (c *Client) Run(ctx context.Context, command string, args ...string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	line := strings.Join(append([]string{command}, args...), " ")
+	resp, err := c.run(ctx, line)
+	if errors.Is(err, ErrCrashed) {
+		resp, err = c.run(ctx, line)
+	}
+	if err != nil {
+		return "", err
+	}
+	c.record(command, line, resp)
+	return resp, nil
+}
+
+// Close stops the engine.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	if c.proc == nil {
+		return nil
+	}
+	err := c.proc.quit(c.config.Timeout)
+	c.proc = nil
+	return err
+}
+
+// run sends a single line, making sure an engine is running first.
+func (c *Client) run(ctx context.Context, line string) (string, error) {
+	if c.closed {
+		return "", ErrClosed
+	}
+	if c.proc == nil {
+		err := c.restore(ctx)
+		if err != nil {
+			return "", err
+		}
+	}
+	resp, err := c.send(ctx, line)
+	if errors.Is(err, ErrCrashed) || errors.Is(err, ErrTimeout) {
+		c.proc.kill()
+		c.proc = nil
+	}
+	return resp, err
+}
+
+// restore starts a new engine and replays the setup of the previous one.
+func (c *Client) restore(ctx context.Context) error {
+	proc, err := startProcess(c.config)
+	if err != nil {
+		return err
+	}
+	if c.nextID > 0 {
+		c.restarts++
+	}
+	c.proc = proc
+	for _, line := range c.setup {
+		_, err := c.send(ctx, line)
+		if err != nil {
+			c.proc.kill()
+			c.proc = nil
+			return fmt.Errorf("failed to restore gtp engine: %w", err)
+		}
+	}
+	return nil
+}
+
+// send writes a command to the running engine and waits for its response.
+func (c *Client) send(ctx context.Context, line string) (string, error) {
+	c.nextID++
+	id := c.nextID
+
+	ctx, cancel := context.WithTimeout(ctx, c.config.Timeout)
+	defer cancel()
+
+	type result struct {
+		resp string
+		err  error
+	}
+	proc := c.proc
+	done := make(chan result, 1)
+	go func() {
+		resp, err := proc.exchange(id, line)
+		done <- result{resp, err}
+	}()
+
+	select {
+	case r := <-done:
+		var cmdErr *CommandError
+		if errors.As(r.err, &cmdErr) {
+			cmdErr.Command = line
+		}
+		return r.resp, r.err
+	case <-ctx.Done():
+		// Killing the engine unblocks the exchange, which is then abandoned.
+		proc.kill()
+		return "", ErrTimeout
+	}
+}
+
+// record remembers a successful state changing command for restoring the engine.
+func (c *Client) record(command, line, resp string) {
+	switch command {
+	case "komi":
+		// Only the latest komi matters.
+		c.setup = slices.DeleteFunc(c.setup, func(l string) bool { return strings.HasPrefix(l, "komi ") })
+		c.setup = append(c.setup, line)
+	case "boardsize", "clear_board":
+		// Both empty the board, so only the komi, and the board size when clearing, still matter.
+		c.setup = slices.DeleteFunc(c.setup, func(l string) bool {
+			name, _, _ := strings.Cut(l, " ")
+			return name != "komi" && (name != "boardsize" || command == "boardsize")
+		})
+		c.setup = append(c.setup, line)
+	case "play", "set_free_handicap", "fixed_handicap":
+		c.setup = append(c.setup, line)
+	case "genmove":
+		if !strings.EqualFold(resp, "resign") {
+			color := strings.TrimPrefix(line, "genmove ")
+			c.setup = append(c.setup, "play "+color+" "+resp)
+		}
+	}
+}
+
+// process is a running engine.
+type process struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout *bufio.Reader
+	exited chan struct{}
+}
+
+func startProcess(config Config) (*process, error) {
+	cmd := exec.Command(config.Path, config.Args...) //nolint:gosec // the engine is chosen by the operator
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	err = cmd.Start()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start gtp engine: %w", err)
+	}
+
+	p := &process{
+		cmd:    cmd,
+		stdin:  stdin,
+		stdout: bufio.NewReader(stdout),
+		exited: make(chan struct{}),
+	}
+	go func() {
+		_ = cmd.Wait()
+		close(p.exited)
+	}()
+	return p, nil
+}
+
+// exchange writes a numbered command and reads the engine's response to it.
+func (p *process) exchange(id int, line string) (string, error) {
+	_, err := fmt.Fprintf(p.stdin, "%d %s\n", id, line)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrCrashed, err)
+	}
+
+	var lines []string
+	for {
+		l, err := p.stdout.ReadString('\n')
+		if err != nil {
+			return "", fmt.Errorf("%w: %w", ErrCrashed, err)
+		}
+		l = strings.TrimRight(l, "\r\n")
+		if l == "" {
+			if len(lines) == 0 {
+				continue
+			}
+			break
+		}
+		lines = append(lines, l)
+	}
+
+	status, rest, _ := strings.Cut(lines[0], " ")
+	if len(status) < 1 || (status[0] != '=' && status[0] != '?') {
+		return "", fmt.Errorf("%w: malformed response %q", ErrCrashed, lines[0])
+	}
+	if respID, err := strconv.Atoi(status[1:]); err != nil || respID != id {
+		return "", fmt.Errorf("%w: response %q does not answer command %d", ErrCrashed, lines[0], id)
+	}
+	lines[0] = rest
+	resp := strings.TrimSpace(strings.Join(lines, "\n"))
+	if status[0] == '?' {
+		return "", &CommandError{Message: resp}
+	}
+	return resp, nil
+}
+
+// quit asks the engine to exit, killing it if it doesn't within the timeout.
+func (p *process) quit(timeout time.Duration) error {
+	_, _ = fmt.Fprintln(p.stdin, "quit")
+	_ = p.stdin.Close()
+	select {
+	case <-p.exited:
+		return nil
+	case <-time.After(timeout):
+		p.kill()
+		return ErrTimeout
+	}
+}
+
+// kill stops the engine immediately and waits for it to exit.
+func (p *process) kill() {
+	_ = p.cmd.Process.Kill()
+	<-p.exited
+}
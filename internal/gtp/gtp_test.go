@@ -0,0 +1,250 @@
+package gtp
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/hazzardr/baduk-online/internal/game"
+)
+
+// fakeEngine is the path of the test engine built from testdata/fakeengine.
+var fakeEngine string
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "gtp")
+	if err != nil {
+		panic(err)
+	}
+	fakeEngine = filepath.Join(dir, "fakeengine")
+	build := exec.Command("go", "build", "-o", fakeEngine, "./testdata/fakeengine")
+	build.Stderr = os.Stderr
+	if err := build.Run(); err != nil {
+		panic(err)
+	}
+
+	code := m.Run()
+	_ = os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func startFake(t *testing.T, args ...string) *Client {
+	t.Helper()
+	c, err := Start(Config{Path: fakeEngine, Args: args, Timeout: 2 * time.Second})
+	if err != nil {
+		t.Fatalf("failed to start engine: %s", err)
+	}
+	t.Cleanup(func() {
+		if err := c.Close(); err != nil {
+			t.Errorf("failed to close engine: %s", err)
+		}
+	})
+	return c
+}
+
+func TestVertex(t *testing.T) {
+	tests := []struct {
+		vertex string
+		size   int
+		point  game.Point
+	}{
+		{"A19", 19, game.Point{X: 0, Y: 0}},
+		{"T1", 19, game.Point{X: 18, Y: 18}},
+		{"D4", 19, game.Point{X: 3, Y: 15}},
+		{"J9", 9, game.Point{X: 8, Y: 0}},
+		{"H8", 9, game.Point{X: 7, Y: 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.vertex, func(t *testing.T) {
+			p, pass, err := ParseVertex(tt.vertex, tt.size)
+			if err != nil || pass || p != tt.point {
+				t.Errorf("ParseVertex = %v, %v, %v, want %v", p, pass, err, tt.point)
+			}
+			v, err := FormatVertex(tt.point, tt.size)
+			if err != nil || v != tt.vertex {
+				t.Errorf("FormatVertex = %q, %v, want %q", v, err, tt.vertex)
+			}
+		})
+	}
+
+	t.Run("pass", func(t *testing.T) {
+		if _, pass, err := ParseVertex("PASS", 19); err != nil || !pass {
+			t.Errorf("expected pass, got %v %v", pass, err)
+		}
+	})
+
+	t.Run("invalid vertices", func(t *testing.T) {
+		for _, v := range []string{"", "I5", "A0", "A20", "T5x", "Z1"} {
+			if _, _, err := ParseVertex(v, 19); err == nil {
+				t.Errorf("expected %q to be rejected", v)
+			}
+		}
+		if _, err := FormatVertex(game.Point{X: 9, Y: 0}, 9); !errors.Is(err, game.ErrOutOfBounds) {
+			t.Errorf("expected ErrOutOfBounds, got %v", err)
+		}
+	})
+}
+
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("plays a game", func(t *testing.T) {
+		c := startFake(t)
+		if err := c.BoardSize(ctx, 9); err != nil {
+			t.Fatalf("boardsize: %s", err)
+		}
+		if err := c.ClearBoard(ctx); err != nil {
+			t.Fatalf("clear_board: %s", err)
+		}
+		if err := c.Komi(ctx, 0.5); err != nil {
+			t.Fatalf("komi: %s", err)
+		}
+		if err := c.Play(ctx, game.Black, game.Point{X: 0, Y: 0}); err != nil {
+			t.Fatalf("play: %s", err)
+		}
+		if err := c.Pass(ctx, game.Black); err != nil {
+			t.Fatalf("pass: %s", err)
+		}
+
+		move, err := c.GenMove(ctx, game.White)
+		if err != nil {
+			t.Fatalf("genmove: %s", err)
+		}
+		if move.Pass || move.Resign || move.Point != (game.Point{X: 1, Y: 0}) {
+			t.Errorf("unexpected move: %+v", move)
+		}
+
+		score, err := c.FinalScore(ctx)
+		if err != nil || score != "W+0.5" {
+			t.Errorf("final_score = %q, %v", score, err)
+		}
+		alive, err := c.FinalStatusList(ctx, "alive")
+		if err != nil {
+			t.Fatalf("final_status_list: %s", err)
+		}
+		if !slices.Equal(alive, []game.Point{{X: 0, Y: 0}, {X: 1, Y: 0}}) {
+			t.Errorf("unexpected alive stones: %v", alive)
+		}
+	})
+
+	t.Run("reports engine errors", func(t *testing.T) {
+		c := startFake(t)
+		if err := c.Play(ctx, game.Black, game.Point{X: 3, Y: 3}); err != nil {
+			t.Fatalf("play: %s", err)
+		}
+		err := c.Play(ctx, game.White, game.Point{X: 3, Y: 3})
+		var cmdErr *CommandError
+		if !errors.As(err, &cmdErr) || cmdErr.Message != "illegal move" || cmdErr.Command != "play white D16" {
+			t.Errorf("expected illegal move, got %v", err)
+		}
+		if _, err := c.Run(ctx, "bogus"); !errors.As(err, &cmdErr) {
+			t.Errorf("expected unknown command error, got %v", err)
+		}
+		if c.Restarts() != 0 {
+			t.Errorf("expected errors not to restart the engine, got %d restarts", c.Restarts())
+		}
+	})
+
+	t.Run("restarts after a timeout with the position restored", func(t *testing.T) {
+		c := startFake(t)
+		if err := c.BoardSize(ctx, 5); err != nil {
+			t.Fatalf("boardsize: %s", err)
+		}
+		if err := c.Play(ctx, game.Black, game.Point{X: 2, Y: 2}); err != nil {
+			t.Fatalf("play: %s", err)
+		}
+
+		started := time.Now()
+		if _, err := c.Run(ctx, "hang"); !errors.Is(err, ErrTimeout) {
+			t.Fatalf("expected ErrTimeout, got %v", err)
+		}
+		if time.Since(started) > 5*time.Second {
+			t.Error("timeout took too long")
+		}
+
+		alive, err := c.FinalStatusList(ctx, "alive")
+		if err != nil {
+			t.Fatalf("final_status_list: %s", err)
+		}
+		if !slices.Equal(alive, []game.Point{{X: 2, Y: 2}}) {
+			t.Errorf("expected the position to be restored, got %v", alive)
+		}
+		if c.Restarts() != 1 {
+			t.Errorf("expected 1 restart, got %d", c.Restarts())
+		}
+	})
+
+	t.Run("context deadline bounds a command", func(t *testing.T) {
+		c := startFake(t)
+		short, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		if _, err := c.Run(short, "hang"); !errors.Is(err, ErrTimeout) {
+			t.Errorf("expected ErrTimeout, got %v", err)
+		}
+	})
+
+	t.Run("retries a command that crashed the engine", func(t *testing.T) {
+		marker := filepath.Join(t.TempDir(), "crashed")
+		c := startFake(t, "-crash-marker", marker)
+		if err := c.Play(ctx, game.Black, game.Point{X: 0, Y: 0}); err != nil {
+			t.Fatalf("play: %s", err)
+		}
+		move, err := c.GenMove(ctx, game.White)
+		if err != nil {
+			t.Fatalf("genmove: %s", err)
+		}
+		if move.Point != (game.Point{X: 1, Y: 0}) {
+			t.Errorf("expected the restored engine to avoid the played stone, got %+v", move)
+		}
+		if c.Restarts() != 1 {
+			t.Errorf("expected 1 restart, got %d", c.Restarts())
+		}
+
+		// The generated move is part of the position restored after the next crash.
+		if _, err := c.Run(ctx, "crash"); !errors.Is(err, ErrCrashed) {
+			t.Fatalf("expected ErrCrashed, got %v", err)
+		}
+		alive, err := c.FinalStatusList(ctx, "alive")
+		if err != nil {
+			t.Fatalf("final_status_list: %s", err)
+		}
+		if len(alive) != 2 {
+			t.Errorf("expected both stones after restarting, got %v", alive)
+		}
+	})
+
+	t.Run("protocol violations count as crashes", func(t *testing.T) {
+		c := startFake(t)
+		if _, err := c.Run(ctx, "garble"); !errors.Is(err, ErrCrashed) {
+			t.Errorf("expected ErrCrashed, got %v", err)
+		}
+		if _, err := c.Run(ctx, "name"); err != nil {
+			t.Errorf("expected the engine to recover, got %v", err)
+		}
+	})
+
+	t.Run("closed client", func(t *testing.T) {
+		c, err := Start(Config{Path: fakeEngine})
+		if err != nil {
+			t.Fatalf("failed to start engine: %s", err)
+		}
+		if err := c.Close(); err != nil {
+			t.Fatalf("failed to close: %s", err)
+		}
+		if _, err := c.Run(ctx, "name"); !errors.Is(err, ErrClosed) {
+			t.Errorf("expected ErrClosed, got %v", err)
+		}
+	})
+
+	t.Run("missing engine", func(t *testing.T) {
+		if _, err := Start(Config{Path: filepath.Join(t.TempDir(), "missing")}); err == nil {
+			t.Error("expected an error starting a missing engine")
+		}
+	})
+}
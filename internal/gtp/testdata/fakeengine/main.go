@@ -0,0 +1,188 @@
+// Command fakeengine is a minimal GTP engine for testing the gtp package. It plays on the first empty point
+// it finds, and understands a few extra commands to misbehave on demand.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const columns = "ABCDEFGHJKLMNOPQRSTUVWXYZ"
+
+type engine struct {
+	size  int
+	komi  float64
+	board map[string]string
+}
+
+func main() {
+	crashMarker := flag.String("crash-marker", "", "crash on the first genmove if this file does not exist yet")
+	flag.Parse()
+
+	e := &engine{size: 19, board: map[string]string{}}
+	in := bufio.NewScanner(os.Stdin)
+	for in.Scan() {
+		fields := strings.Fields(in.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		id := ""
+		if _, err := strconv.Atoi(fields[0]); err == nil {
+			id, fields = fields[0], fields[1:]
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		command, args := fields[0], fields[1:]
+
+		switch command {
+		case "quit":
+			fmt.Printf("=%s\n\n", id)
+			return
+		case "crash":
+			os.Exit(1)
+		case "hang":
+			time.Sleep(time.Hour)
+		case "garble":
+			fmt.Print("nonsense\n\n")
+			continue
+		case "genmove":
+			if *crashMarker != "" {
+				if _, err := os.Stat(*crashMarker); os.IsNotExist(err) {
+					_ = os.WriteFile(*crashMarker, nil, 0o600)
+					os.Exit(1)
+				}
+			}
+		}
+
+		resp, err := e.handle(command, args)
+		if err != nil {
+			fmt.Printf("?%s %s\n\n", id, err)
+			continue
+		}
+		fmt.Printf("=%s %s\n\n", id, resp)
+	}
+}
+
+func (e *engine) handle(command string, args []string) (string, error) {
+	switch command {
+	case "protocol_version":
+		return "2", nil
+	case "name":
+		return "fakeengine", nil
+	case "boardsize":
+		size, err := strconv.Atoi(arg(args, 0))
+		if err != nil || size < 2 || size > len(columns) {
+			return "", fmt.Errorf("unacceptable size")
+		}
+		e.size = size
+		e.board = map[string]string{}
+		return "", nil
+	case "clear_board":
+		e.board = map[string]string{}
+		return "", nil
+	case "komi":
+		komi, err := strconv.ParseFloat(arg(args, 0), 64)
+		if err != nil {
+			return "", fmt.Errorf("syntax error")
+		}
+		e.komi = komi
+		return "", nil
+	case "play":
+		return "", e.play(color(arg(args, 0)), strings.ToUpper(arg(args, 1)))
+	case "set_free_handicap":
+		for _, v := range args {
+			if err := e.play("B", strings.ToUpper(v)); err != nil {
+				return "", err
+			}
+		}
+		return "", nil
+	case "genmove":
+		c := color(arg(args, 0))
+		for row := e.size; row >= 1; row-- {
+			for x := range e.size {
+				v := fmt.Sprintf("%c%d", columns[x], row)
+				if _, ok := e.board[v]; !ok {
+					e.board[v] = c
+					return v, nil
+				}
+			}
+		}
+		return "pass", nil
+	case "final_score":
+		var score float64
+		for _, c := range e.board {
+			if c == "B" {
+				score++
+			} else {
+				score--
+			}
+		}
+		score -= e.komi
+		switch {
+		case score > 0:
+			return "B+" + strconv.FormatFloat(score, 'f', -1, 64), nil
+		case score < 0:
+			return "W+" + strconv.FormatFloat(-score, 'f', -1, 64), nil
+		default:
+			return "0", nil
+		}
+	case "final_status_list":
+		if arg(args, 0) != "alive" {
+			return "", nil
+		}
+		var vertices []string
+		for row := e.size; row >= 1; row-- {
+			for x := range e.size {
+				v := fmt.Sprintf("%c%d", columns[x], row)
+				if _, ok := e.board[v]; ok {
+					vertices = append(vertices, v)
+				}
+			}
+		}
+		return strings.Join(vertices, "\n"), nil
+	default:
+		return "", fmt.Errorf("unknown command")
+	}
+}
+
+func (e *engine) play(c, vertex string) error {
+	if vertex == "PASS" {
+		return nil
+	}
+	if c == "" || len(vertex) < 2 || !strings.Contains(columns[:e.size], vertex[:1]) {
+		return fmt.Errorf("illegal move")
+	}
+	row, err := strconv.Atoi(vertex[1:])
+	if err != nil || row < 1 || row > e.size {
+		return fmt.Errorf("illegal move")
+	}
+	if _, ok := e.board[vertex]; ok {
+		return fmt.Errorf("illegal move")
+	}
+	e.board[vertex] = c
+	return nil
+}
+
+func color(s string) string {
+	switch strings.ToLower(s) {
+	case "b", "black":
+		return "B"
+	case "w", "white":
+		return "W"
+	default:
+		return ""
+	}
+}
+
+func arg(args []string, i int) string {
+	if i < len(args) {
+		return args[i]
+	}
+	return ""
+}
@@ -0,0 +1,163 @@
+// Package handicap places handicap stones and recommends handicap and komi for players of different
+// strengths.
+package handicap
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/hazzardr/baduk-online/internal/game"
+	"github.com/hazzardr/baduk-online/internal/ratings"
+	"github.com/hazzardr/baduk-online/internal/scoring"
+	"github.com/hazzardr/baduk-online/internal/validator"
+)
+
+const (
+	// MinStones is the smallest handicap that is placed as stones. A handicap of one stone means black
+	// simply plays first without komi.
+	MinStones = 2
+	// MaxStones is the largest handicap.
+	MaxStones = 9
+)
+
+var (
+	// ErrUnsupportedSize is returned when asking for fixed placements on a board without standard star
+	// points.
+	ErrUnsupportedSize = errors.New("fixed handicap placement is only defined for 9x9, 13x13 and 19x19")
+	// ErrInvalidStones is returned when asking for a handicap outside MinStones to MaxStones.
+	ErrInvalidStones = fmt.Errorf("handicap must be between %d and %d stones", MinStones, MaxStones)
+)
+
+// SupportedSizes lists the board sizes with fixed handicap placements.
+var SupportedSizes = []int{9, 13, 19}
+
+// Placement returns the traditional fixed placement of handicap stones on a board, with y increasing
+// downwards. The first two stones go in the upper right and lower left corners, then the lower right, the
+// upper left, and the center for odd numbers of stones, and from six stones on, the sides.
+func Placement(size, stones int) ([]game.Point, error) {
+	var edge int
+	switch size {
+	case 9:
+		edge = 2
+	case 13, 19:
+		edge = 3
+	default:
+		return nil, ErrUnsupportedSize
+	}
+	if stones < MinStones || stones > MaxStones {
+		return nil, ErrInvalidStones
+	}
+
+	near, mid, far := edge, size/2, size-1-edge
+	upperRight := game.Point{X: far, Y: near}
+	lowerLeft := game.Point{X: near, Y: far}
+	lowerRight := game.Point{X: far, Y: far}
+	upperLeft := game.Point{X: near, Y: near}
+	center := game.Point{X: mid, Y: mid}
+	left := game.Point{X: near, Y: mid}
+	right := game.Point{X: far, Y: mid}
+	top := game.Point{X: mid, Y: near}
+	bottom := game.Point{X: mid, Y: far}
+
+	points := []game.Point{upperRight, lowerLeft}
+	if stones >= 3 {
+		points = append(points, lowerRight)
+	}
+	if stones >= 4 {
+		points = append(points, upperLeft)
+	}
+	if stones >= 6 {
+		points = append(points, left, right)
+	}
+	if stones >= 8 {
+		points = append(points, top, bottom)
+	}
+	if stones%2 == 1 && stones >= 5 {
+		points = append(points, center)
+	}
+	return points, nil
+}
+
+// ValidateFreePlacement checks handicap stones placed by black where they like: there must be exactly the
+// agreed number, each on the board and on a different point.
+func ValidateFreePlacement(v *validator.Validator, size, stones int, points []game.Point) {
+	v.Check(stones >= MinStones && stones <= MaxStones, "handicap", ErrInvalidStones.Error())
+	v.Check(len(points) == stones, "stones", fmt.Sprintf("must contain exactly %d points", stones))
+	for _, p := range points {
+		v.Check(p.X >= 0 && p.X < size && p.Y >= 0 && p.Y < size, "stones", "must all be on the board")
+	}
+	v.Check(validator.Unique(points), "stones", "must not contain the same point twice")
+}
+
+// Recommendation is the handicap and komi suggested for a game.
+type Recommendation struct {
+	// SwapColors is true if the player who asked to take black is the stronger one and should take white.
+	SwapColors bool `json:"swap_colors"`
+	// Handicap is the number of handicap stones. Zero is an even game, or a game without komi if Komi is
+	// 0.5.
+	Handicap int `json:"handicap"`
+	// Komi includes any compensation white receives for handicap stones under area scoring.
+	Komi float64 `json:"komi"`
+}
+
+// ranksPerStone is roughly how many ranks one handicap stone is worth on each board size. Stones are worth
+// more on smaller boards.
+var ranksPerStone = map[int]float64{9: 4, 13: 2, 19: 1}
+
+// Recommend suggests the handicap and komi for a game between players of the given ranks under a ruleset.
+// Each rank of difference is worth a stone on 19x19, with smaller boards needing fewer stones. One rank of
+// difference is met by black playing without komi, and the handicap never exceeds nine stones.
+func Recommend(black, white ratings.Rank, size int, rules scoring.Ruleset) (Recommendation, error) {
+	perStone, ok := ranksPerStone[size]
+	if !ok {
+		return Recommendation{}, ErrUnsupportedSize
+	}
+	if !validator.PermittedValue(rules, scoring.Rulesets...) {
+		return Recommendation{}, scoring.ErrUnknownRuleset
+	}
+
+	var rec Recommendation
+	diff := int(white - black)
+	if diff < 0 {
+		rec.SwapColors = true
+		diff = -diff
+	}
+
+	stones := int(math.Round(float64(diff) / perStone))
+	switch {
+	case stones == 0:
+		rec.Komi = EvenKomi(rules)
+	case stones == 1:
+		rec.Komi = 0.5
+	default:
+		rec.Handicap = min(stones, MaxStones)
+		rec.Komi = 0.5 + Compensation(rec.Handicap, rules)
+	}
+	return rec, nil
+}
+
+// EvenKomi returns the usual komi for an even game under a ruleset.
+func EvenKomi(rules scoring.Ruleset) float64 {
+	if rules.AreaScoring() {
+		return 7.5
+	}
+	return 6.5
+}
+
+// Compensation returns the points white receives for black's handicap stones. Under territory scoring the
+// stones cost black nothing extra. Under area scoring each stone would otherwise count for black, so
+// Chinese rules give white a point per stone and AGA rules a point for every stone after the first.
+func Compensation(stones int, rules scoring.Ruleset) float64 {
+	if stones < MinStones {
+		return 0
+	}
+	switch rules {
+	case scoring.Chinese:
+		return float64(stones)
+	case scoring.AGA:
+		return float64(stones - 1)
+	default:
+		return 0
+	}
+}
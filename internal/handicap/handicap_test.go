@@ -0,0 +1,159 @@
+package handicap
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/hazzardr/baduk-online/internal/game"
+	"github.com/hazzardr/baduk-online/internal/ratings"
+	"github.com/hazzardr/baduk-online/internal/scoring"
+	"github.com/hazzardr/baduk-online/internal/validator"
+)
+
+func TestPlacement(t *testing.T) {
+	tests := []struct {
+		name   string
+		size   int
+		stones int
+		want   []game.Point
+	}{
+		{"Two stones on 19x19", 19, 2, []game.Point{{X: 15, Y: 3}, {X: 3, Y: 15}}},
+		{"Three stones on 19x19", 19, 3, []game.Point{{X: 15, Y: 3}, {X: 3, Y: 15}, {X: 15, Y: 15}}},
+		{"Five stones on 13x13", 13, 5, []game.Point{{X: 9, Y: 3}, {X: 3, Y: 9}, {X: 9, Y: 9}, {X: 3, Y: 3}, {X: 6, Y: 6}}},
+		{"Six stones on 19x19", 19, 6, []game.Point{
+			{X: 15, Y: 3}, {X: 3, Y: 15}, {X: 15, Y: 15}, {X: 3, Y: 3}, {X: 3, Y: 9}, {X: 15, Y: 9},
+		}},
+		{"Nine stones on 9x9", 9, 9, []game.Point{
+			{X: 6, Y: 2}, {X: 2, Y: 6}, {X: 6, Y: 6}, {X: 2, Y: 2}, {X: 2, Y: 4}, {X: 6, Y: 4},
+			{X: 4, Y: 2}, {X: 4, Y: 6}, {X: 4, Y: 4},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Placement(tt.size, tt.stones)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	t.Run("Every placement is on star points without duplicates", func(t *testing.T) {
+		for _, size := range SupportedSizes {
+			for stones := MinStones; stones <= MaxStones; stones++ {
+				points, err := Placement(size, stones)
+				if err != nil {
+					t.Fatalf("%dx%d with %d stones: %s", size, size, stones, err)
+				}
+				v := validator.New()
+				ValidateFreePlacement(v, size, stones, points)
+				if !v.Valid() {
+					t.Errorf("%dx%d with %d stones: %v", size, size, stones, v.Errors)
+				}
+			}
+		}
+	})
+
+	t.Run("Rejects unsupported input", func(t *testing.T) {
+		if _, err := Placement(15, 4); !errors.Is(err, ErrUnsupportedSize) {
+			t.Errorf("expected ErrUnsupportedSize, got %v", err)
+		}
+		if _, err := Placement(19, 1); !errors.Is(err, ErrInvalidStones) {
+			t.Errorf("expected ErrInvalidStones, got %v", err)
+		}
+		if _, err := Placement(19, 10); !errors.Is(err, ErrInvalidStones) {
+			t.Errorf("expected ErrInvalidStones, got %v", err)
+		}
+	})
+}
+
+func TestValidateFreePlacement(t *testing.T) {
+	tests := []struct {
+		name    string
+		size    int
+		stones  int
+		points  []game.Point
+		wantKey string
+	}{
+		{"Valid placement", 19, 3, []game.Point{{X: 0, Y: 0}, {X: 10, Y: 4}, {X: 18, Y: 18}}, ""},
+		{"Too few points", 19, 3, []game.Point{{X: 0, Y: 0}, {X: 10, Y: 4}}, "stones"},
+		{"Off the board", 9, 2, []game.Point{{X: 0, Y: 0}, {X: 9, Y: 4}}, "stones"},
+		{"Negative coordinate", 9, 2, []game.Point{{X: -1, Y: 0}, {X: 4, Y: 4}}, "stones"},
+		{"Duplicate point", 13, 2, []game.Point{{X: 3, Y: 3}, {X: 3, Y: 3}}, "stones"},
+		{"Too many stones", 19, 10, make([]game.Point, 10), "handicap"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			ValidateFreePlacement(v, tt.size, tt.stones, tt.points)
+			if tt.wantKey == "" {
+				if !v.Valid() {
+					t.Errorf("expected no errors, got %v", v.Errors)
+				}
+				return
+			}
+			if _, ok := v.Errors[tt.wantKey]; !ok {
+				t.Errorf("expected error for %q, got %v", tt.wantKey, v.Errors)
+			}
+		})
+	}
+}
+
+func TestRecommend(t *testing.T) {
+	rank := func(s string) ratings.Rank {
+		r, err := ratings.ParseRank(s)
+		if err != nil {
+			t.Fatalf("failed to parse rank %q: %s", s, err)
+		}
+		return r
+	}
+
+	tests := []struct {
+		name  string
+		black string
+		white string
+		size  int
+		rules scoring.Ruleset
+		want  Recommendation
+	}{
+		{"Even game japanese", "5k", "5k", 19, scoring.Japanese, Recommendation{Komi: 6.5}},
+		{"Even game chinese", "2d", "2d", 19, scoring.Chinese, Recommendation{Komi: 7.5}},
+		{"One rank apart", "6k", "5k", 19, scoring.Japanese, Recommendation{Komi: 0.5}},
+		{"Four stones japanese", "5k", "1k", 19, scoring.Japanese, Recommendation{Handicap: 4, Komi: 0.5}},
+		{"Four stones chinese", "5k", "1k", 19, scoring.Chinese, Recommendation{Handicap: 4, Komi: 4.5}},
+		{"Four stones aga", "5k", "1k", 19, scoring.AGA, Recommendation{Handicap: 4, Komi: 3.5}},
+		{"Capped at nine stones", "20k", "1d", 19, scoring.Japanese, Recommendation{Handicap: 9, Komi: 0.5}},
+		{"Stronger player asked for black", "1d", "3k", 19, scoring.Japanese, Recommendation{
+			SwapColors: true, Handicap: 3, Komi: 0.5,
+		}},
+		{"Fewer stones on 13x13", "5k", "1k", 13, scoring.Japanese, Recommendation{Handicap: 2, Komi: 0.5}},
+		{"Small gap is even on 9x9", "5k", "4k", 9, scoring.AGA, Recommendation{Komi: 7.5}},
+		{"Eight ranks on 9x9", "9k", "1k", 9, scoring.Chinese, Recommendation{Handicap: 2, Komi: 2.5}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Recommend(rank(tt.black), rank(tt.white), tt.size, tt.rules)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+
+	t.Run("Rejects unsupported input", func(t *testing.T) {
+		if _, err := Recommend(rank("5k"), rank("1k"), 15, scoring.Japanese); !errors.Is(err, ErrUnsupportedSize) {
+			t.Errorf("expected ErrUnsupportedSize, got %v", err)
+		}
+		if _, err := Recommend(rank("5k"), rank("1k"), 19, "ing"); !errors.Is(err, scoring.ErrUnknownRuleset) {
+			t.Errorf("expected ErrUnknownRuleset, got %v", err)
+		}
+	})
+}
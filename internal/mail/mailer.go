@@ -22,6 +22,12 @@ const (
 	// RegistrationTokenTTL is the amount of time a registration token is valid for.
 	RegistrationTokenTTL time.Duration = 15 * time.Minute
 
+	// PasswordResetTokenTTL is the amount of time a password reset token is valid for.
+	PasswordResetTokenTTL time.Duration = 45 * time.Minute
+
+	// EmailChangeTokenTTL is the amount of time an email change token is valid for.
+	EmailChangeTokenTTL time.Duration = time.Hour
+
 	// SendEmailTimeout is the amount of time we give to our email sending process.
 	SendEmailTimeout time.Duration = 10 * time.Second
 )
@@ -34,6 +40,9 @@ var templateFS embed.FS
 // Mailer defines the interface for sending transactional emails.
 type Mailer interface {
 	SendRegistrationEmail(ctx context.Context, user *data.User) error
+	SendPasswordResetEmail(ctx context.Context, user *data.User) error
+	SendMoveReminderEmail(ctx context.Context, user *data.User, game *data.Game, deadline time.Time) error
+	SendEmailChangeEmail(ctx context.Context, user *data.User, newEmail string) error
 }
 
 // SESMailer implements the Mailer interface using AWS SES.
@@ -73,7 +82,6 @@ func (m *SESMailer) SendRegistrationEmail(parentCtx context.Context, user *data.
 	ctx, cancel := context.WithTimeout(parentCtx, SendEmailTimeout)
 	defer cancel()
 	subject := "Please verify your baduk.online account"
-	fromEmail := "no-reply@baduk.online"
 	bodyTmpl, err := template.New("registration.tmpl").ParseFS(templateFS, "templates/registration.tmpl")
 	if err != nil {
 		return err
@@ -100,11 +108,177 @@ func (m *SESMailer) SendRegistrationEmail(parentCtx context.Context, user *data.
 	if err != nil {
 		return errors.Join(errors.New("failed to render email template"), err)
 	}
-	body := htmlBody.String()
 
+	messageID, err := m.send(ctx, user.Email, subject, htmlBody.String())
+	if err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "sent registration email", "messageID", messageID, "destination", user.Email)
+	return nil
+}
+
+// PasswordResetEmailData holds the template data for password reset emails.
+type PasswordResetEmailData struct {
+	Name     string
+	Email    string
+	ResetURL string
+	Token    string
+}
+
+// SendPasswordResetEmail sends an email with a single-use token the user can exchange for a new password.
+// Any previously issued password reset tokens for the user are revoked.
+func (m *SESMailer) SendPasswordResetEmail(parentCtx context.Context, user *data.User) error {
+	ctx, cancel := context.WithTimeout(parentCtx, SendEmailTimeout)
+	defer cancel()
+	subject := "Reset your baduk.online password"
+	bodyTmpl, err := template.New("password_reset.tmpl").ParseFS(templateFS, "templates/password_reset.tmpl")
+	if err != nil {
+		return err
+	}
+
+	err = m.db.Tokens.DeleteAllForUser(ctx, data.ScopePasswordReset, int64(user.ID))
+	if err != nil {
+		return errors.Join(errors.New("failed to delete existing password reset tokens for user"), err)
+	}
+	token, err := m.db.Tokens.New(ctx, int64(user.ID), PasswordResetTokenTTL, data.ScopePasswordReset)
+	if err != nil {
+		return err
+	}
+
+	resetData := &PasswordResetEmailData{
+		Name:     user.Name,
+		Email:    user.Email,
+		Token:    token.Plaintext,
+		ResetURL: fmt.Sprintf("https://play.baduk.online/reset-password?token=%s", token.Plaintext),
+	}
+
+	htmlBody := new(bytes.Buffer)
+	err = bodyTmpl.ExecuteTemplate(htmlBody, "password_reset.tmpl", resetData)
+	if err != nil {
+		return errors.Join(errors.New("failed to render email template"), err)
+	}
+
+	messageID, err := m.send(ctx, user.Email, subject, htmlBody.String())
+	if err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "sent password reset email", "messageID", messageID, "destination", user.Email)
+	return nil
+}
+
+// MoveReminderEmailData holds the template data for move reminder emails.
+type MoveReminderEmailData struct {
+	Name     string
+	Email    string
+	GameID   int64
+	GameURL  string
+	Deadline string
+}
+
+// SendMoveReminderEmail warns a user that they will run out of time in a game unless they move before the
+// deadline.
+func (m *SESMailer) SendMoveReminderEmail(
+	parentCtx context.Context, user *data.User, game *data.Game, deadline time.Time,
+) error {
+	ctx, cancel := context.WithTimeout(parentCtx, SendEmailTimeout)
+	defer cancel()
+	subject := fmt.Sprintf("Your move is due soon in game %d", game.ID)
+	bodyTmpl, err := template.New("move_reminder.tmpl").ParseFS(templateFS, "templates/move_reminder.tmpl")
+	if err != nil {
+		return err
+	}
+
+	reminderData := &MoveReminderEmailData{
+		Name:     user.Name,
+		Email:    user.Email,
+		GameID:   game.ID,
+		GameURL:  fmt.Sprintf("https://play.baduk.online/games/%d", game.ID),
+		Deadline: deadline.UTC().Format("Monday 2 January 15:04 MST"),
+	}
+
+	htmlBody := new(bytes.Buffer)
+	err = bodyTmpl.ExecuteTemplate(htmlBody, "move_reminder.tmpl", reminderData)
+	if err != nil {
+		return errors.Join(errors.New("failed to render email template"), err)
+	}
+
+	messageID, err := m.send(ctx, user.Email, subject, htmlBody.String())
+	if err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "sent move reminder email", "messageID", messageID, "destination", user.Email)
+	return nil
+}
+
+// EmailChangeEmailData holds the template data for email change emails.
+type EmailChangeEmailData struct {
+	Name       string
+	Email      string
+	NewEmail   string
+	ConfirmURL string
+	Token      string
+}
+
+// SendEmailChangeEmail sends a single-use token to the new address that confirms the change, and lets the
+// current address know that a change was requested. Any previously issued email change tokens for the user
+// are revoked.
+func (m *SESMailer) SendEmailChangeEmail(parentCtx context.Context, user *data.User, newEmail string) error {
+	ctx, cancel := context.WithTimeout(parentCtx, SendEmailTimeout)
+	defer cancel()
+	bodyTmpl, err := template.New("email_change.tmpl").ParseFS(
+		templateFS, "templates/email_change.tmpl", "templates/email_change_notice.tmpl",
+	)
+	if err != nil {
+		return err
+	}
+
+	err = m.db.Tokens.DeleteAllForUser(ctx, data.ScopeEmailChange, int64(user.ID))
+	if err != nil {
+		return errors.Join(errors.New("failed to delete existing email change tokens for user"), err)
+	}
+	token, err := m.db.Tokens.New(ctx, int64(user.ID), EmailChangeTokenTTL, data.ScopeEmailChange)
+	if err != nil {
+		return err
+	}
+
+	changeData := &EmailChangeEmailData{
+		Name:       user.Name,
+		Email:      user.Email,
+		NewEmail:   newEmail,
+		Token:      token.Plaintext,
+		ConfirmURL: fmt.Sprintf("https://play.baduk.online/confirm-email?token=%s", token.Plaintext),
+	}
+
+	htmlBody := new(bytes.Buffer)
+	err = bodyTmpl.ExecuteTemplate(htmlBody, "email_change.tmpl", changeData)
+	if err != nil {
+		return errors.Join(errors.New("failed to render email template"), err)
+	}
+	messageID, err := m.send(ctx, newEmail, "Confirm your new baduk.online email address", htmlBody.String())
+	if err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "sent email change email", "messageID", messageID, "destination", newEmail)
+
+	htmlBody.Reset()
+	err = bodyTmpl.ExecuteTemplate(htmlBody, "email_change_notice.tmpl", changeData)
+	if err != nil {
+		return errors.Join(errors.New("failed to render email template"), err)
+	}
+	messageID, err = m.send(ctx, user.Email, "Your baduk.online email address is changing", htmlBody.String())
+	if err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "sent email change notice", "messageID", messageID, "destination", user.Email)
+	return nil
+}
+
+// send delivers an HTML email to a single recipient and returns the SES message ID.
+func (m *SESMailer) send(ctx context.Context, to, subject, body string) (*string, error) {
+	fromEmail := "no-reply@baduk.online"
 	res, err := m.client.SendEmail(ctx, &ses.SendEmailInput{
 		Destination: &sesTypes.Destination{
-			ToAddresses: []string{user.Email},
+			ToAddresses: []string{to},
 		},
 		Content: &sesTypes.EmailContent{
 			Simple: &sesTypes.Message{
@@ -122,8 +296,7 @@ func (m *SESMailer) SendRegistrationEmail(parentCtx context.Context, user *data.
 		FromEmailAddress: &fromEmail,
 	})
 	if err != nil {
-		return err
+		return nil, err
 	}
-	slog.InfoContext(ctx, "sent registration email", "messageID", res.MessageId, "destination", user.Email)
-	return nil
+	return res.MessageId, nil
 }
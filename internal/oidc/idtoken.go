@@ -0,0 +1,283 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Leeway is how far the provider's clock may be from ours when checking token times.
+	Leeway = time.Minute
+	// keyCacheTTL is how long the provider's signing keys are cached for.
+	keyCacheTTL = time.Hour
+	// keyRefreshInterval is the least time between fetching the keys again because a token was signed with
+	// one we don't know, so that forged tokens can't make us hammer the provider.
+	keyRefreshInterval = time.Minute
+)
+
+var (
+	// ErrInvalidToken is returned for ID tokens that are malformed, badly signed, or not meant for us.
+	ErrInvalidToken = errors.New("invalid ID token")
+	// ErrUnknownKey is returned when an ID token is signed with a key the provider doesn't publish.
+	ErrUnknownKey = errors.New("unknown signing key")
+)
+
+// invalidToken wraps ErrInvalidToken with the reason a token was rejected.
+func invalidToken(reason string) error {
+	return fmt.Errorf("%w: %s", ErrInvalidToken, reason)
+}
+
+// Claims are the claims of a verified ID token that we use.
+type Claims struct {
+	Issuer          string   `json:"iss"`
+	Subject         string   `json:"sub"`
+	Audience        []string `json:"aud"`
+	AuthorizedParty string   `json:"azp"`
+	Expiry          int64    `json:"exp"`
+	IssuedAt        int64    `json:"iat"`
+	Nonce           string   `json:"nonce"`
+	Email           string   `json:"email"`
+	EmailVerified   bool     `json:"email_verified"`
+	Name            string   `json:"name"`
+}
+
+// UnmarshalJSON decodes claims, allowing for the audience to be a single string and for providers that send
+// email_verified as a string.
+func (c *Claims) UnmarshalJSON(data []byte) error {
+	type plainClaims Claims
+	var raw struct {
+		plainClaims
+		Audience      json.RawMessage `json:"aud"`
+		EmailVerified json.RawMessage `json:"email_verified"`
+	}
+	err := json.Unmarshal(data, &raw)
+	if err != nil {
+		return err
+	}
+	*c = Claims(raw.plainClaims)
+
+	if len(raw.Audience) > 0 {
+		var single string
+		if json.Unmarshal(raw.Audience, &single) == nil {
+			c.Audience = []string{single}
+		} else {
+			err = json.Unmarshal(raw.Audience, &c.Audience)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	switch string(raw.EmailVerified) {
+	case "true", `"true"`:
+		c.EmailVerified = true
+	case "", "false", `"false"`, "null":
+		c.EmailVerified = false
+	default:
+		return fmt.Errorf("invalid email_verified %s", raw.EmailVerified)
+	}
+	return nil
+}
+
+// VerifyIDToken checks an ID token's signature and claims, returning the claims if it was issued to us for
+// the login started with nonce.
+func (p *Provider) VerifyIDToken(ctx context.Context, raw, nonce string, now time.Time) (*Claims, error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return nil, invalidToken("malformed token")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	err := decodeSegment(parts[0], &header)
+	if err != nil {
+		return nil, invalidToken("malformed header")
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, invalidToken("malformed signature")
+	}
+
+	key, err := p.keys.key(ctx, header.Kid, now)
+	if err != nil {
+		return nil, err
+	}
+	if !verifySignature(header.Alg, key, []byte(parts[0]+"."+parts[1]), signature) {
+		return nil, invalidToken("invalid signature")
+	}
+
+	var claims Claims
+	err = decodeSegment(parts[1], &claims)
+	if err != nil {
+		return nil, invalidToken("malformed claims")
+	}
+	if claims.Issuer != p.config.Issuer {
+		return nil, invalidToken("wrong issuer")
+	}
+	if !slices.Contains(claims.Audience, p.config.ClientID) {
+		return nil, invalidToken("wrong audience")
+	}
+	if len(claims.Audience) > 1 && claims.AuthorizedParty != p.config.ClientID {
+		return nil, invalidToken("wrong authorized party")
+	}
+	if claims.Subject == "" {
+		return nil, invalidToken("missing subject")
+	}
+	if now.After(time.Unix(claims.Expiry, 0).Add(Leeway)) {
+		return nil, invalidToken("expired")
+	}
+	if time.Unix(claims.IssuedAt, 0).After(now.Add(Leeway)) {
+		return nil, invalidToken("issued in the future")
+	}
+	if nonce == "" || subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(nonce)) != 1 {
+		return nil, invalidToken("wrong nonce")
+	}
+	return &claims, nil
+}
+
+func decodeSegment(segment string, dst any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, dst)
+}
+
+// verifySignature checks a JWS signature. Only the asymmetric algorithms providers sign ID tokens with are
+// accepted, and the key must be of the algorithm's type.
+func verifySignature(alg string, key crypto.PublicKey, signed, signature []byte) bool {
+	digest := sha256.Sum256(signed)
+	switch alg {
+	case "RS256":
+		rsaKey, ok := key.(*rsa.PublicKey)
+		return ok && rsa.VerifyPKCS1v15(rsaKey, crypto.SHA256, digest[:], signature) == nil
+	case "ES256":
+		ecKey, ok := key.(*ecdsa.PublicKey)
+		if !ok || len(signature) != 64 {
+			return false
+		}
+		r, s := new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])
+		return ecdsa.Verify(ecKey, digest[:], r, s)
+	}
+	return false
+}
+
+// jwk is a JSON Web Key, as published in the provider's key set.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// publicKey returns the key, or nil if it isn't a signing key of a type we support.
+func (k *jwk) publicKey() crypto.PublicKey {
+	if k.Use != "" && k.Use != "sig" {
+		return nil
+	}
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil || len(n) < 256 {
+			return nil
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return nil
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	case "EC":
+		x, errX := base64.RawURLEncoding.DecodeString(k.X)
+		y, errY := base64.RawURLEncoding.DecodeString(k.Y)
+		if k.Crv != "P-256" || errX != nil || errY != nil || len(x) != 32 || len(y) != 32 {
+			return nil
+		}
+		key, err := ecdsa.ParseUncompressedPublicKey(elliptic.P256(), append(append([]byte{4}, x...), y...))
+		if err != nil {
+			return nil
+		}
+		return key
+	}
+	return nil
+}
+
+// keySet caches the provider's signing keys, fetching them again when they expire or a token names a key
+// we haven't seen.
+type keySet struct {
+	client *http.Client
+	url    string
+
+	mu        sync.Mutex
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+}
+
+func newKeySet(client *http.Client, url string) *keySet {
+	return &keySet{client: client, url: url}
+}
+
+// key returns the key with the given ID. Tokens without a key ID can only be checked if the provider
+// publishes a single key.
+func (s *keySet) key(ctx context.Context, kid string, now time.Time) (crypto.PublicKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fresh := !s.fetchedAt.IsZero() && now.Sub(s.fetchedAt) < keyCacheTTL
+	if fresh {
+		if key := s.lookup(kid); key != nil {
+			return key, nil
+		}
+		if now.Sub(s.fetchedAt) < keyRefreshInterval {
+			return nil, ErrUnknownKey
+		}
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	err := getJSON(ctx, s.client, s.url, &set)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch signing keys: %w", err)
+	}
+	s.keys = make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if key := k.publicKey(); key != nil {
+			s.keys[k.Kid] = key
+		}
+	}
+	s.fetchedAt = now
+
+	if key := s.lookup(kid); key != nil {
+		return key, nil
+	}
+	return nil, ErrUnknownKey
+}
+
+func (s *keySet) lookup(kid string) crypto.PublicKey {
+	if kid == "" && len(s.keys) == 1 {
+		for _, key := range s.keys {
+			return key
+		}
+	}
+	return s.keys[kid]
+}
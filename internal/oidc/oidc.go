@@ -0,0 +1,186 @@
+// Package oidc implements logging in with an OpenID Connect provider using the authorization code flow with
+// PKCE, as described by OpenID Connect Core 1.0 and RFC 7636.
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultTimeout bounds each request made to the provider.
+const DefaultTimeout = 10 * time.Second
+
+var (
+	// ErrDiscovery is returned when a provider's configuration can't be fetched or is incomplete.
+	ErrDiscovery = errors.New("oidc discovery failed")
+	// ErrExchange is returned when the provider refuses to exchange an authorization code for tokens.
+	ErrExchange = errors.New("oidc code exchange failed")
+)
+
+// Config configures the client we are registered as with a provider.
+type Config struct {
+	// Issuer is the provider's issuer URL, from which its configuration is discovered.
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	// RedirectURL is where the provider sends the user back to with an authorization code.
+	RedirectURL string
+	// Scopes are requested in addition to openid.
+	Scopes []string
+	// HTTPClient is used for requests to the provider. If nil, a client with DefaultTimeout is used.
+	HTTPClient *http.Client
+}
+
+// metadata is the subset of the provider's discovery document that we use.
+type metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Provider is an OpenID Connect provider whose configuration has been discovered.
+type Provider struct {
+	config   Config
+	client   *http.Client
+	metadata metadata
+	keys     *keySet
+}
+
+// Discover fetches the provider's configuration from its well-known discovery document.
+func Discover(ctx context.Context, config Config) (*Provider, error) {
+	client := config.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: DefaultTimeout}
+	}
+
+	wellKnown := strings.TrimSuffix(config.Issuer, "/") + "/.well-known/openid-configuration"
+	var m metadata
+	err := getJSON(ctx, client, wellKnown, &m)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDiscovery, err)
+	}
+	if m.Issuer != config.Issuer {
+		return nil, fmt.Errorf("%w: issuer %q does not match %q", ErrDiscovery, m.Issuer, config.Issuer)
+	}
+	if m.AuthorizationEndpoint == "" || m.TokenEndpoint == "" || m.JWKSURI == "" {
+		return nil, fmt.Errorf("%w: missing endpoints", ErrDiscovery)
+	}
+
+	return &Provider{
+		config:   config,
+		client:   client,
+		metadata: m,
+		keys:     newKeySet(client, m.JWKSURI),
+	}, nil
+}
+
+// getJSON fetches and decodes a JSON document.
+func getJSON(ctx context.Context, client *http.Client, u string, dst any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d from %s", resp.StatusCode, u)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(dst)
+}
+
+// NewRandom returns a random URL safe string, for use as a state, nonce or PKCE code verifier.
+func NewRandom() (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// CodeChallenge returns the S256 PKCE code challenge for a code verifier.
+func CodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// AuthCodeURL returns the URL to send the user to for logging in. The state, nonce and code verifier must be
+// kept until the user comes back, to check the response against.
+func (p *Provider) AuthCodeURL(state, nonce, verifier string) string {
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", p.config.ClientID)
+	query.Set("redirect_uri", p.config.RedirectURL)
+	query.Set("scope", strings.Join(append([]string{"openid"}, p.config.Scopes...), " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", CodeChallenge(verifier))
+	query.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(p.metadata.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return p.metadata.AuthorizationEndpoint + separator + query.Encode()
+}
+
+// tokenResponse is the provider's response to a code exchange.
+type tokenResponse struct {
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// Exchange trades an authorization code for the user's ID token, and verifies it. The nonce and verifier
+// must be those the authorization URL was made with.
+func (p *Provider) Exchange(ctx context.Context, code, nonce, verifier string, now time.Time) (*Claims, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.config.RedirectURL)
+	form.Set("code_verifier", verifier)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.metadata.TokenEndpoint,
+		strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var token tokenResponse
+	err = json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&token)
+	if err != nil && resp.StatusCode == http.StatusOK {
+		return nil, fmt.Errorf("%w: %w", ErrExchange, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: status %d: %s %s", ErrExchange, resp.StatusCode, token.Error,
+			token.ErrorDescription)
+	}
+	if token.IDToken == "" {
+		return nil, fmt.Errorf("%w: no ID token", ErrExchange)
+	}
+
+	return p.VerifyIDToken(ctx, token.IDToken, nonce, now)
+}
@@ -0,0 +1,48 @@
+package oidc
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestCodeChallenge(t *testing.T) {
+	// From RFC 7636 appendix B.
+	got := CodeChallenge("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk")
+	if want := "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"; got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+}
+
+func TestClaims(t *testing.T) {
+	tests := []struct {
+		name         string
+		payload      string
+		wantAudience []string
+		wantVerified bool
+	}{
+		{"Single audience", `{"aud": "client", "email_verified": true}`, []string{"client"}, true},
+		{"Audience list", `{"aud": ["client", "other"], "email_verified": false}`, []string{"client", "other"}, false},
+		{"Verified as a string", `{"aud": "client", "email_verified": "true"}`, []string{"client"}, true},
+		{"Verified missing", `{"aud": "client"}`, []string{"client"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var claims Claims
+			if err := json.Unmarshal([]byte(tt.payload), &claims); err != nil {
+				t.Fatalf("failed to decode claims: %s", err)
+			}
+			if !slices.Equal(claims.Audience, tt.wantAudience) {
+				t.Errorf("expected audience %v, got %v", tt.wantAudience, claims.Audience)
+			}
+			if claims.EmailVerified != tt.wantVerified {
+				t.Errorf("expected email_verified %t, got %t", tt.wantVerified, claims.EmailVerified)
+			}
+		})
+	}
+
+	var claims Claims
+	if err := json.Unmarshal([]byte(`{"email_verified": "yes"}`), &claims); err == nil {
+		t.Errorf("expected an invalid boolean to be rejected")
+	}
+}
@@ -0,0 +1,164 @@
+package oidctest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/hazzardr/baduk-online/internal/oidc"
+)
+
+func TestLogin(t *testing.T) {
+	ctx := context.Background()
+	fake := NewProvider("client", "secret")
+	defer fake.Close()
+	fake.SetUser(User{Subject: "user-1", Email: "player@example.com", EmailVerified: true, Name: "Player"})
+
+	config := oidc.Config{
+		Issuer:       fake.Issuer(),
+		ClientID:     "client",
+		ClientSecret: "secret",
+		RedirectURL:  "https://baduk.online/login/callback",
+		Scopes:       []string{"email", "profile"},
+	}
+	provider, err := oidc.Discover(ctx, config)
+	if err != nil {
+		t.Fatalf("failed to discover provider: %s", err)
+	}
+	noRedirects := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+
+	// authorize follows the authorization URL and returns the code and state the provider redirects with.
+	authorize := func(t *testing.T, state, nonce, verifier string) (string, string) {
+		t.Helper()
+		resp, err := noRedirects.Get(provider.AuthCodeURL(state, nonce, verifier))
+		if err != nil {
+			t.Fatalf("failed to authorize: %s", err)
+		}
+		resp.Body.Close()
+		location, err := url.Parse(resp.Header.Get("Location"))
+		if err != nil || resp.StatusCode != http.StatusFound {
+			t.Fatalf("expected a redirect, got status %d", resp.StatusCode)
+		}
+		return location.Query().Get("code"), location.Query().Get("state")
+	}
+
+	t.Run("Exchanging a code", func(t *testing.T) {
+		code, state := authorize(t, "state", "nonce", "verifier")
+		if state != "state" {
+			t.Errorf("expected the state to be returned, got %q", state)
+		}
+		claims, err := provider.Exchange(ctx, code, "nonce", "verifier", time.Now())
+		if err != nil {
+			t.Fatalf("failed to exchange code: %s", err)
+		}
+		if claims.Subject != "user-1" || claims.Email != "player@example.com" || !claims.EmailVerified {
+			t.Errorf("unexpected claims %+v", claims)
+		}
+
+		if _, err := provider.Exchange(ctx, code, "nonce", "verifier", time.Now()); !errors.Is(err, oidc.ErrExchange) {
+			t.Errorf("expected a used code to be rejected, got %v", err)
+		}
+	})
+
+	t.Run("PKCE and the nonce are checked", func(t *testing.T) {
+		code, _ := authorize(t, "state", "nonce", "verifier")
+		if _, err := provider.Exchange(ctx, code, "nonce", "other", time.Now()); !errors.Is(err, oidc.ErrExchange) {
+			t.Errorf("expected the wrong verifier to be rejected, got %v", err)
+		}
+		code, _ = authorize(t, "state", "nonce", "verifier")
+		if _, err := provider.Exchange(ctx, code, "other", "verifier", time.Now()); !errors.Is(err, oidc.ErrInvalidToken) {
+			t.Errorf("expected the wrong nonce to be rejected, got %v", err)
+		}
+	})
+
+	t.Run("ID token claims are checked", func(t *testing.T) {
+		now := time.Now()
+		valid := func() map[string]any {
+			return map[string]any{
+				"iss":   fake.Issuer(),
+				"sub":   "user-1",
+				"aud":   "client",
+				"exp":   now.Add(time.Hour).Unix(),
+				"iat":   now.Unix(),
+				"nonce": "nonce",
+			}
+		}
+		if _, err := provider.VerifyIDToken(ctx, fake.IDToken(valid()), "nonce", now); err != nil {
+			t.Fatalf("expected a valid token to be accepted, got %v", err)
+		}
+
+		tests := []struct {
+			name   string
+			change func(map[string]any)
+		}{
+			{"Wrong issuer", func(c map[string]any) { c["iss"] = "https://evil.example" }},
+			{"Wrong audience", func(c map[string]any) { c["aud"] = "other" }},
+			{"Shared audience", func(c map[string]any) { c["aud"] = []string{"client", "other"} }},
+			{"Expired", func(c map[string]any) { c["exp"] = now.Add(-2 * oidc.Leeway).Unix() }},
+			{"Issued in the future", func(c map[string]any) { c["iat"] = now.Add(2 * oidc.Leeway).Unix() }},
+			{"Missing subject", func(c map[string]any) { delete(c, "sub") }},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				claims := valid()
+				tt.change(claims)
+				_, err := provider.VerifyIDToken(ctx, fake.IDToken(claims), "nonce", now)
+				if !errors.Is(err, oidc.ErrInvalidToken) {
+					t.Errorf("expected ErrInvalidToken, got %v", err)
+				}
+			})
+		}
+
+		token := fake.IDToken(valid())
+		tampered := token[:len(token)-4] + "AAAA"
+		if _, err := provider.VerifyIDToken(ctx, tampered, "nonce", now); !errors.Is(err, oidc.ErrInvalidToken) {
+			t.Errorf("expected a tampered token to be rejected, got %v", err)
+		}
+		// {"alg":"none","kid":"1"}.{}
+		unsigned := "eyJhbGciOiJub25lIiwia2lkIjoiMSJ9.e30."
+		if _, err := provider.VerifyIDToken(ctx, unsigned, "nonce", now); err == nil {
+			t.Errorf("expected an unsigned token to be rejected")
+		}
+	})
+
+	t.Run("Signing keys are cached", func(t *testing.T) {
+		start := fake.JWKSRequests()
+		now := time.Now()
+		for range 2 {
+			code, _ := authorize(t, "state", "nonce", "verifier")
+			if _, err := provider.Exchange(ctx, code, "nonce", "verifier", now); err != nil {
+				t.Fatalf("failed to exchange code: %s", err)
+			}
+		}
+		if got := fake.JWKSRequests() - start; got != 0 {
+			t.Errorf("expected cached keys to be used, got %d fetches", got)
+		}
+
+		// A token signed with a new key is only checked against fresh keys once the refresh interval passes.
+		fake.RotateKey()
+		code, _ := authorize(t, "state", "nonce", "verifier")
+		if _, err := provider.Exchange(ctx, code, "nonce", "verifier", now); !errors.Is(err, oidc.ErrUnknownKey) {
+			t.Errorf("expected ErrUnknownKey, got %v", err)
+		}
+		code, _ = authorize(t, "state", "nonce", "verifier")
+		if _, err := provider.Exchange(ctx, code, "nonce", "verifier", now.Add(2*time.Minute)); err != nil {
+			t.Fatalf("expected the new key to be fetched, got %v", err)
+		}
+		if got := fake.JWKSRequests() - start; got != 1 {
+			t.Errorf("expected the keys to be fetched once more, got %d", got)
+		}
+	})
+
+	t.Run("Discovery checks the issuer", func(t *testing.T) {
+		wrong := config
+		wrong.Issuer = fake.Issuer() + "/"
+		if _, err := oidc.Discover(ctx, wrong); !errors.Is(err, oidc.ErrDiscovery) {
+			t.Errorf("expected ErrDiscovery, got %v", err)
+		}
+	})
+}
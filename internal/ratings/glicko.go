@@ -0,0 +1,155 @@
+// Package ratings implements the Glicko-2 rating system, adjusted for handicap games, and maps ratings onto
+// the traditional kyu and dan ranks.
+package ratings
+
+import (
+	"errors"
+	"math"
+)
+
+const (
+	// DefaultRating is the rating of a new player.
+	DefaultRating = 1500
+	// DefaultDeviation is the rating deviation of a new player, i.e. nothing is known about them.
+	DefaultDeviation = 350
+	// DefaultVolatility is the volatility of a new player.
+	DefaultVolatility = 0.06
+	// MinDeviation keeps very active players' ratings from becoming so certain that they stop moving.
+	MinDeviation = 30
+
+	// tau constrains how quickly volatility may change. Glickman suggests values between 0.3 and 1.2.
+	tau = 0.5
+	// scale converts between the Glicko and Glicko-2 scales.
+	scale = 173.7178
+	// epsilon is the convergence tolerance of the volatility iteration.
+	epsilon = 0.000001
+)
+
+// ErrInvalidScore is returned when a result's score is not between 0 and 1.
+var ErrInvalidScore = errors.New("score must be between 0 and 1")
+
+// Rating is a player's strength estimate on the Glicko scale.
+type Rating struct {
+	Rating     float64 `json:"rating"`
+	Deviation  float64 `json:"deviation"`
+	Volatility float64 `json:"volatility"`
+}
+
+// Default returns the rating of a new player.
+func Default() Rating {
+	return Rating{Rating: DefaultRating, Deviation: DefaultDeviation, Volatility: DefaultVolatility}
+}
+
+// Rank returns the kyu or dan rank for the rating.
+func (r Rating) Rank() Rank {
+	return RankFor(r.Rating)
+}
+
+// Result is the outcome of one game from a player's point of view.
+type Result struct {
+	Opponent Rating
+	// Score is 1 for a win, 0.5 for a draw and 0 for a loss.
+	Score float64
+	// Advantage is the rating points the player's position was worth over an even game, such as
+	// HandicapAdvantage for the player who received handicap stones. It is negative for the player who gave
+	// them.
+	Advantage float64
+}
+
+// ExpectedScore returns the probability that a player with rating r beats an opponent, given the player's
+// advantage in rating points.
+func ExpectedScore(r, opponent Rating, advantage float64) float64 {
+	mu := toMu(r.Rating + advantage)
+	return expected(mu, toMu(opponent.Rating), toPhi(opponent.Deviation))
+}
+
+// Update returns the player's rating after a rating period in which they played the given games. With no
+// games, only the deviation grows to reflect the time since they last played.
+func Update(r Rating, results []Result) (Rating, error) {
+	mu := toMu(r.Rating)
+	phi := toPhi(r.Deviation)
+	sigma := r.Volatility
+
+	if len(results) == 0 {
+		phi = math.Sqrt(phi*phi + sigma*sigma)
+		return clamp(Rating{Rating: r.Rating, Deviation: phi * scale, Volatility: sigma}), nil
+	}
+
+	var invV, sum float64
+	for _, res := range results {
+		if res.Score < 0 || res.Score > 1 {
+			return Rating{}, ErrInvalidScore
+		}
+		phiJ := toPhi(res.Opponent.Deviation)
+		gJ := g(phiJ)
+		e := expected(mu+res.Advantage/scale, toMu(res.Opponent.Rating), phiJ)
+		invV += gJ * gJ * e * (1 - e)
+		sum += gJ * (res.Score - e)
+	}
+	v := 1 / invV
+	delta := v * sum
+
+	sigma = newVolatility(sigma, phi, v, delta)
+	phiStar := math.Sqrt(phi*phi + sigma*sigma)
+	phi = 1 / math.Sqrt(1/(phiStar*phiStar)+1/v)
+	mu += phi * phi * sum
+
+	return clamp(Rating{Rating: mu*scale + DefaultRating, Deviation: phi * scale, Volatility: sigma}), nil
+}
+
+// newVolatility finds the new volatility using the Illinois algorithm, as in step 5 of Glickman's "Example
+// of the Glicko-2 system".
+func newVolatility(sigma, phi, v, delta float64) float64 {
+	a := math.Log(sigma * sigma)
+	f := func(x float64) float64 {
+		ex := math.Exp(x)
+		d := phi*phi + v + ex
+		return ex*(delta*delta-phi*phi-v-ex)/(2*d*d) - (x-a)/(tau*tau)
+	}
+
+	A := a
+	var B float64
+	if delta*delta > phi*phi+v {
+		B = math.Log(delta*delta - phi*phi - v)
+	} else {
+		k := 1.0
+		for f(a-k*tau) < 0 {
+			k++
+		}
+		B = a - k*tau
+	}
+
+	fA, fB := f(A), f(B)
+	for math.Abs(B-A) > epsilon {
+		C := A + (A-B)*fA/(fB-fA)
+		fC := f(C)
+		if fC*fB <= 0 {
+			A, fA = B, fB
+		} else {
+			fA /= 2
+		}
+		B, fB = C, fC
+	}
+	return math.Exp(A / 2)
+}
+
+func g(phi float64) float64 {
+	return 1 / math.Sqrt(1+3*phi*phi/(math.Pi*math.Pi))
+}
+
+func expected(mu, muJ, phiJ float64) float64 {
+	return 1 / (1 + math.Exp(-g(phiJ)*(mu-muJ)))
+}
+
+func toMu(rating float64) float64 {
+	return (rating - DefaultRating) / scale
+}
+
+func toPhi(deviation float64) float64 {
+	return deviation / scale
+}
+
+func clamp(r Rating) Rating {
+	r.Deviation = max(MinDeviation, min(DefaultDeviation, r.Deviation))
+	return r
+}
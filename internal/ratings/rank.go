@@ -0,0 +1,101 @@
+package ratings
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	// RankWidth is the number of rating points between adjacent ranks. One rank is roughly worth one
+	// handicap stone.
+	RankWidth = 60
+	// MinRank is 30 kyu, the weakest rank.
+	MinRank Rank = 0
+	// FirstDan is 1 dan.
+	FirstDan Rank = 30
+	// MaxRank is 9 dan, the strongest rank.
+	MaxRank Rank = 38
+
+	// minRankRating is the lowest rating of 30 kyu, chosen so that 1 dan starts at 2000.
+	minRankRating = 2000 - int(FirstDan)*RankWidth
+)
+
+// Rank is a kyu or dan rank, counted upwards from 30 kyu.
+type Rank int
+
+// RankFor returns the rank for a rating. Ratings beyond the ends of the scale are given 30 kyu or 9 dan.
+func RankFor(rating float64) Rank {
+	r := Rank(math.Floor((rating - float64(minRankRating)) / RankWidth))
+	return max(MinRank, min(MaxRank, r))
+}
+
+// Rating returns the rating at the middle of the rank.
+func (r Rank) Rating() float64 {
+	return float64(minRankRating) + (float64(r)+0.5)*RankWidth
+}
+
+// String returns the rank as it is usually written, e.g. "12k" or "3d".
+func (r Rank) String() string {
+	if r >= FirstDan {
+		return strconv.Itoa(int(r-FirstDan)+1) + "d"
+	}
+	return strconv.Itoa(int(FirstDan-r)) + "k"
+}
+
+// MarshalText encodes the rank as it is usually written.
+func (r Rank) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// UnmarshalText decodes a rank such as "12k" or "3d".
+func (r *Rank) UnmarshalText(text []byte) error {
+	rank, err := ParseRank(string(text))
+	if err != nil {
+		return err
+	}
+	*r = rank
+	return nil
+}
+
+// ParseRank parses a rank such as "12k" or "3d". Upper case and the long forms "kyu" and "dan" are accepted.
+func ParseRank(s string) (Rank, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	var num, suffix string
+	for i, c := range s {
+		if c < '0' || c > '9' {
+			num, suffix = s[:i], strings.TrimSpace(s[i:])
+			break
+		}
+	}
+	n, err := strconv.Atoi(num)
+	if err != nil {
+		return 0, fmt.Errorf("invalid rank %q", s)
+	}
+
+	switch suffix {
+	case "k", "kyu":
+		if n < 1 || n > int(FirstDan) {
+			return 0, fmt.Errorf("invalid rank %q: kyu must be between 1 and 30", s)
+		}
+		return FirstDan - Rank(n), nil
+	case "d", "dan":
+		if n < 1 || n > int(MaxRank-FirstDan)+1 {
+			return 0, fmt.Errorf("invalid rank %q: dan must be between 1 and 9", s)
+		}
+		return FirstDan + Rank(n-1), nil
+	default:
+		return 0, fmt.Errorf("invalid rank %q", s)
+	}
+}
+
+// HandicapAdvantage returns the rating points that the given number of handicap stones are worth to the
+// player who receives them. A single stone means taking black without komi, which is worth half a stone, and
+// each further stone is worth a full rank.
+func HandicapAdvantage(stones int) float64 {
+	if stones <= 0 {
+		return 0
+	}
+	return (float64(stones) - 0.5) * RankWidth
+}
@@ -0,0 +1,198 @@
+package ratings
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func approx(a, b, tolerance float64) bool {
+	return math.Abs(a-b) <= tolerance
+}
+
+// TestUpdateGlickmanExample checks the worked example from Glickman's "Example of the Glicko-2 system".
+func TestUpdateGlickmanExample(t *testing.T) {
+	player := Rating{Rating: 1500, Deviation: 200, Volatility: 0.06}
+	results := []Result{
+		{Opponent: Rating{Rating: 1400, Deviation: 30, Volatility: 0.06}, Score: 1},
+		{Opponent: Rating{Rating: 1550, Deviation: 100, Volatility: 0.06}, Score: 0},
+		{Opponent: Rating{Rating: 1700, Deviation: 300, Volatility: 0.06}, Score: 0},
+	}
+
+	got, err := Update(player, results)
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if !approx(got.Rating, 1464.06, 0.01) || !approx(got.Deviation, 151.52, 0.01) ||
+		!approx(got.Volatility, 0.05999, 0.00001) {
+		t.Errorf("Update() = %+v, want 1464.06, 151.52, 0.05999", got)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	even := Default()
+	tests := []struct {
+		name   string
+		player Rating
+		result Result
+		check  func(t *testing.T, before, after Rating)
+	}{
+		{
+			name:   "Win raises rating",
+			player: even,
+			result: Result{Opponent: even, Score: 1},
+			check: func(t *testing.T, before, after Rating) {
+				if after.Rating <= before.Rating || after.Deviation >= before.Deviation {
+					t.Errorf("rating %+v after win from %+v", after, before)
+				}
+			},
+		},
+		{
+			name:   "Draw between equals changes nothing but deviation",
+			player: even,
+			result: Result{Opponent: even, Score: 0.5},
+			check: func(t *testing.T, before, after Rating) {
+				if !approx(after.Rating, before.Rating, 0.001) {
+					t.Errorf("rating %v after even draw, want %v", after.Rating, before.Rating)
+				}
+			},
+		},
+		{
+			name:   "Expected handicap win gains less",
+			player: even,
+			result: Result{Opponent: even, Score: 1, Advantage: HandicapAdvantage(5)},
+			check: func(t *testing.T, before, after Rating) {
+				evenWin, _ := Update(before, []Result{{Opponent: even, Score: 1}})
+				if after.Rating <= before.Rating || after.Rating >= evenWin.Rating {
+					t.Errorf("handicap win gave %v, want between %v and %v", after.Rating, before.Rating, evenWin.Rating)
+				}
+			},
+		},
+		{
+			name:   "Deviation does not fall below minimum",
+			player: Rating{Rating: 1800, Deviation: MinDeviation, Volatility: 0.03},
+			result: Result{Opponent: Rating{Rating: 1800, Deviation: MinDeviation, Volatility: 0.03}, Score: 1},
+			check: func(t *testing.T, _, after Rating) {
+				if after.Deviation < MinDeviation {
+					t.Errorf("deviation %v below minimum", after.Deviation)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Update(tt.player, []Result{tt.result})
+			if err != nil {
+				t.Fatalf("Update() error = %v", err)
+			}
+			tt.check(t, tt.player, got)
+		})
+	}
+}
+
+func TestUpdateWithoutGames(t *testing.T) {
+	r := Rating{Rating: 1700, Deviation: 100, Volatility: 0.06}
+	got, err := Update(r, nil)
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if got.Rating != r.Rating || got.Deviation <= r.Deviation {
+		t.Errorf("Update() with no games = %+v", got)
+	}
+}
+
+func TestUpdateInvalidScore(t *testing.T) {
+	_, err := Update(Default(), []Result{{Opponent: Default(), Score: 2}})
+	if !errors.Is(err, ErrInvalidScore) {
+		t.Errorf("Update() error = %v, want %v", err, ErrInvalidScore)
+	}
+}
+
+func TestExpectedScore(t *testing.T) {
+	r := Default()
+	if got := ExpectedScore(r, r, 0); !approx(got, 0.5, 1e-9) {
+		t.Errorf("ExpectedScore() between equals = %v, want 0.5", got)
+	}
+	stronger := Rating{Rating: r.Rating + 3*RankWidth, Deviation: 50, Volatility: 0.06}
+	weak := Rating{Rating: r.Rating, Deviation: 50, Volatility: 0.06}
+	withoutHandicap := ExpectedScore(weak, stronger, 0)
+	withHandicap := ExpectedScore(weak, stronger, HandicapAdvantage(3)+RankWidth/2)
+	if withoutHandicap >= 0.5 || !approx(withHandicap, 0.5, 1e-9) {
+		t.Errorf("ExpectedScore() = %v without handicap, %v with, want < 0.5 and 0.5", withoutHandicap, withHandicap)
+	}
+}
+
+func TestRanks(t *testing.T) {
+	tests := []struct {
+		rating float64
+		want   string
+	}{
+		{-500, "30k"},
+		{200, "30k"},
+		{259.9, "30k"},
+		{260, "29k"},
+		{1500, "9k"},
+		{1999, "1k"},
+		{2000, "1d"},
+		{2480, "9d"},
+		{5000, "9d"},
+	}
+	for _, tt := range tests {
+		if got := RankFor(tt.rating).String(); got != tt.want {
+			t.Errorf("RankFor(%v) = %s, want %s", tt.rating, got, tt.want)
+		}
+	}
+
+	for r := MinRank; r <= MaxRank; r++ {
+		parsed, err := ParseRank(r.String())
+		if err != nil || parsed != r {
+			t.Errorf("ParseRank(%q) = %v, %v, want %v", r.String(), parsed, err, r)
+		}
+		if RankFor(r.Rating()) != r {
+			t.Errorf("RankFor(%v.Rating()) = %v", r, RankFor(r.Rating()))
+		}
+	}
+}
+
+func TestParseRank(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Rank
+		wantErr bool
+	}{
+		{in: "30k", want: MinRank},
+		{in: "1 Kyu", want: FirstDan - 1},
+		{in: "1D", want: FirstDan},
+		{in: "9 dan", want: MaxRank},
+		{in: "31k", wantErr: true},
+		{in: "10d", wantErr: true},
+		{in: "0k", wantErr: true},
+		{in: "5p", wantErr: true},
+		{in: "dan", wantErr: true},
+		{in: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseRank(tt.in)
+		if (err != nil) != tt.wantErr || (!tt.wantErr && got != tt.want) {
+			t.Errorf("ParseRank(%q) = %v, %v, want %v, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestHandicapAdvantage(t *testing.T) {
+	tests := []struct {
+		stones int
+		want   float64
+	}{
+		{0, 0},
+		{1, RankWidth / 2},
+		{2, 1.5 * RankWidth},
+		{9, 8.5 * RankWidth},
+	}
+	for _, tt := range tests {
+		if got := HandicapAdvantage(tt.stones); got != tt.want {
+			t.Errorf("HandicapAdvantage(%d) = %v, want %v", tt.stones, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package scoring
+
+import (
+	"errors"
+	"slices"
+
+	"github.com/hazzardr/baduk-online/internal/game"
+)
+
+// AgreementState is the stage of a dead stone negotiation.
+type AgreementState string
+
+const (
+	// Marking means players are still marking dead stones.
+	Marking AgreementState = "marking"
+	// Agreed means both players accepted the same set of dead stones. It is final.
+	Agreed AgreementState = "agreed"
+	// Reopened means a player rejected counting and play should resume. It is final.
+	Reopened AgreementState = "reopened"
+)
+
+var (
+	// ErrAgreementClosed is returned when changing an agreement that has already been settled or reopened.
+	ErrAgreementClosed = errors.New("dead stone agreement is closed")
+	// ErrNoStone is returned when toggling an empty point.
+	ErrNoStone = errors.New("there is no stone at that point")
+	// ErrNotPlayer is returned when someone other than black or white acts on an agreement.
+	ErrNotPlayer = errors.New("only black or white may take part in the agreement")
+)
+
+// Agreement is the negotiation between two players over which stones are dead once play has ended. Any
+// change to the marked stones withdraws both players' acceptance, so the final set is always one both saw.
+type Agreement struct {
+	board    *game.Board
+	dead     map[game.Point]bool
+	accepted map[game.Color]bool
+	state    AgreementState
+}
+
+// NewAgreement starts a negotiation over the given final position with no stones marked dead.
+func NewAgreement(b *game.Board) *Agreement {
+	return &Agreement{
+		board:    b.Clone(),
+		dead:     make(map[game.Point]bool),
+		accepted: make(map[game.Color]bool),
+		state:    Marking,
+	}
+}
+
+// State returns the current stage of the negotiation.
+func (a *Agreement) State() AgreementState {
+	return a.state
+}
+
+// Dead returns the stones currently marked dead, in board order.
+func (a *Agreement) Dead() []game.Point {
+	dead := make([]game.Point, 0, len(a.dead))
+	for p := range a.dead {
+		dead = append(dead, p)
+	}
+	slices.SortFunc(dead, func(p, q game.Point) int {
+		if p.Y != q.Y {
+			return p.Y - q.Y
+		}
+		return p.X - q.X
+	})
+	return dead
+}
+
+// Accepted reports whether the given player has accepted the current marks.
+func (a *Agreement) Accepted(by game.Color) bool {
+	return a.accepted[by]
+}
+
+// ToggleGroup marks the whole group at p dead, or alive again if it was already dead. Either player may
+// toggle either color. Both players' acceptance is withdrawn.
+func (a *Agreement) ToggleGroup(by game.Color, p game.Point) error {
+	if err := a.checkOpen(by); err != nil {
+		return err
+	}
+	group, _ := a.board.Group(p)
+	if len(group) == 0 {
+		return ErrNoStone
+	}
+	markDead := !a.dead[p]
+	for _, s := range group {
+		if markDead {
+			a.dead[s] = true
+		} else {
+			delete(a.dead, s)
+		}
+	}
+	clear(a.accepted)
+	return nil
+}
+
+// Accept records that a player agrees with the current marks. Once both players have accepted, the
+// agreement is settled and true is returned.
+func (a *Agreement) Accept(by game.Color) (bool, error) {
+	if err := a.checkOpen(by); err != nil {
+		return false, err
+	}
+	a.accepted[by] = true
+	if a.accepted[game.Black] && a.accepted[game.White] {
+		a.state = Agreed
+		return true, nil
+	}
+	return false, nil
+}
+
+// Reopen abandons counting so that play can resume. All marks are discarded.
+func (a *Agreement) Reopen(by game.Color) error {
+	if err := a.checkOpen(by); err != nil {
+		return err
+	}
+	clear(a.dead)
+	clear(a.accepted)
+	a.state = Reopened
+	return nil
+}
+
+// Score counts the position with the currently marked dead stones.
+func (a *Agreement) Score(rules Ruleset, komi float64, blackCaptures, whiteCaptures int) (*Result, error) {
+	return Score(Position{
+		Board:         a.board,
+		Dead:          a.Dead(),
+		BlackCaptures: blackCaptures,
+		WhiteCaptures: whiteCaptures,
+	}, rules, komi)
+}
+
+func (a *Agreement) checkOpen(by game.Color) error {
+	if by != game.Black && by != game.White {
+		return ErrNotPlayer
+	}
+	if a.state != Marking {
+		return ErrAgreementClosed
+	}
+	return nil
+}
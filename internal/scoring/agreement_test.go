@@ -0,0 +1,105 @@
+package scoring
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hazzardr/baduk-online/internal/game"
+)
+
+func TestAgreement(t *testing.T) {
+	board := boardFromDiagram(t, ".XO.X .XO.X .XO.. .XO.. .XO..")
+	deadGroup := game.Point{X: 4, Y: 0}
+
+	t.Run("Toggle marks the whole group", func(t *testing.T) {
+		a := NewAgreement(board)
+		if err := a.ToggleGroup(game.White, deadGroup); err != nil {
+			t.Fatalf("ToggleGroup() returned error: %s", err)
+		}
+		if got := len(a.Dead()); got != 2 {
+			t.Errorf("Dead() has %d stones, want 2", got)
+		}
+		if err := a.ToggleGroup(game.Black, game.Point{X: 4, Y: 1}); err != nil {
+			t.Fatalf("ToggleGroup() returned error: %s", err)
+		}
+		if got := len(a.Dead()); got != 0 {
+			t.Errorf("Dead() has %d stones after toggling back, want 0", got)
+		}
+	})
+
+	t.Run("Both players accepting settles the agreement", func(t *testing.T) {
+		a := NewAgreement(board)
+		if err := a.ToggleGroup(game.White, deadGroup); err != nil {
+			t.Fatalf("ToggleGroup() returned error: %s", err)
+		}
+		done, err := a.Accept(game.White)
+		if err != nil || done {
+			t.Fatalf("Accept(white) = %v, %v, want false, nil", done, err)
+		}
+		done, err = a.Accept(game.Black)
+		if err != nil || !done {
+			t.Fatalf("Accept(black) = %v, %v, want true, nil", done, err)
+		}
+		if a.State() != Agreed {
+			t.Errorf("State() = %s, want %s", a.State(), Agreed)
+		}
+
+		res, err := a.Score(Japanese, 0.5, 0, 0)
+		if err != nil {
+			t.Fatalf("Score() returned error: %s", err)
+		}
+		if res.WhitePrisoners != 2 || res.WhiteScore != 12.5 {
+			t.Errorf("WhitePrisoners = %d, WhiteScore = %v, want 2, 12.5", res.WhitePrisoners, res.WhiteScore)
+		}
+
+		if err := a.ToggleGroup(game.Black, deadGroup); !errors.Is(err, ErrAgreementClosed) {
+			t.Errorf("ToggleGroup() after agreement error = %v, want %v", err, ErrAgreementClosed)
+		}
+	})
+
+	t.Run("Changing marks withdraws acceptance", func(t *testing.T) {
+		a := NewAgreement(board)
+		if _, err := a.Accept(game.Black); err != nil {
+			t.Fatalf("Accept() returned error: %s", err)
+		}
+		if err := a.ToggleGroup(game.White, deadGroup); err != nil {
+			t.Fatalf("ToggleGroup() returned error: %s", err)
+		}
+		if a.Accepted(game.Black) {
+			t.Error("expected black's acceptance to be withdrawn")
+		}
+		done, err := a.Accept(game.White)
+		if err != nil || done {
+			t.Errorf("Accept(white) = %v, %v, want false, nil", done, err)
+		}
+	})
+
+	t.Run("Reopen resumes play", func(t *testing.T) {
+		a := NewAgreement(board)
+		if err := a.ToggleGroup(game.White, deadGroup); err != nil {
+			t.Fatalf("ToggleGroup() returned error: %s", err)
+		}
+		if err := a.Reopen(game.Black); err != nil {
+			t.Fatalf("Reopen() returned error: %s", err)
+		}
+		if a.State() != Reopened {
+			t.Errorf("State() = %s, want %s", a.State(), Reopened)
+		}
+		if len(a.Dead()) != 0 {
+			t.Error("expected marks to be discarded on reopen")
+		}
+		if _, err := a.Accept(game.White); !errors.Is(err, ErrAgreementClosed) {
+			t.Errorf("Accept() after reopen error = %v, want %v", err, ErrAgreementClosed)
+		}
+	})
+
+	t.Run("Invalid actions", func(t *testing.T) {
+		a := NewAgreement(board)
+		if err := a.ToggleGroup(game.Black, game.Point{X: 0, Y: 0}); !errors.Is(err, ErrNoStone) {
+			t.Errorf("ToggleGroup() on empty point error = %v, want %v", err, ErrNoStone)
+		}
+		if _, err := a.Accept(game.Empty); !errors.Is(err, ErrNotPlayer) {
+			t.Errorf("Accept() by non player error = %v, want %v", err, ErrNotPlayer)
+		}
+	})
+}
@@ -0,0 +1,231 @@
+// Package scoring computes the result of a finished game of Go under territory and area rules.
+package scoring
+
+import (
+	"errors"
+	"math"
+
+	"github.com/hazzardr/baduk-online/internal/game"
+	"github.com/hazzardr/baduk-online/internal/validator"
+)
+
+// Ruleset identifies the conventions used to count a game.
+type Ruleset string
+
+const (
+	Japanese Ruleset = "japanese"
+	Korean   Ruleset = "korean"
+	Chinese  Ruleset = "chinese"
+	AGA      Ruleset = "aga"
+)
+
+// Rulesets lists every supported ruleset.
+var Rulesets = []Ruleset{Japanese, Korean, Chinese, AGA}
+
+// MaxKomi is the largest komi, in either direction, that will be accepted.
+const MaxKomi = 150
+
+var (
+	// ErrUnknownRuleset is returned when scoring under a ruleset that isn't supported.
+	ErrUnknownRuleset = errors.New("unknown ruleset")
+	// ErrDeadPointEmpty is returned when a point marked dead has no stone on it.
+	ErrDeadPointEmpty = errors.New("dead stones must be on occupied points")
+)
+
+// AreaScoring reports whether the ruleset counts stones on the board as well as surrounded territory.
+func (r Ruleset) AreaScoring() bool {
+	return r == Chinese || r == AGA
+}
+
+// ValidateRuleset checks that a ruleset is one we know how to score.
+func ValidateRuleset(v *validator.Validator, rules Ruleset) {
+	v.Check(rules != "", "rules", "must be provided")
+	v.Check(validator.PermittedValue(rules, Rulesets...), "rules", "must be one of japanese, korean, chinese or aga")
+}
+
+// ValidateKomi checks that komi is a whole or half point within a sensible range.
+func ValidateKomi(v *validator.Validator, komi float64) {
+	v.Check(math.Abs(komi) <= MaxKomi, "komi", "must not be more than 150 points in either direction")
+	v.Check(math.Mod(komi*2, 1) == 0, "komi", "must be a multiple of 0.5")
+}
+
+// Position is a finished game ready to be counted.
+type Position struct {
+	Board *game.Board
+	// Dead lists stones agreed to be dead. They are removed before counting.
+	Dead []game.Point
+	// BlackCaptures and WhiteCaptures are the stones each player captured during play.
+	BlackCaptures int
+	WhiteCaptures int
+}
+
+// Result is the breakdown of a counted game.
+type Result struct {
+	Rules          Ruleset    `json:"rules"`
+	Komi           float64    `json:"komi"`
+	BlackTerritory int        `json:"black_territory"`
+	WhiteTerritory int        `json:"white_territory"`
+	BlackStones    int        `json:"black_stones"`
+	WhiteStones    int        `json:"white_stones"`
+	BlackPrisoners int        `json:"black_prisoners"`
+	WhitePrisoners int        `json:"white_prisoners"`
+	Dame           int        `json:"dame"`
+	BlackScore     float64    `json:"black_score"`
+	WhiteScore     float64    `json:"white_score"`
+	Winner         game.Color `json:"winner"`
+	Margin         float64    `json:"margin"`
+}
+
+// Score counts a position under the given rules. White receives komi. Under territory rules each player
+// scores their territory plus prisoners, under area rules their territory plus living stones.
+func Score(pos Position, rules Ruleset, komi float64) (*Result, error) {
+	if !validator.PermittedValue(rules, Rulesets...) {
+		return nil, ErrUnknownRuleset
+	}
+
+	board := pos.Board.Clone()
+	res := &Result{
+		Rules:          rules,
+		Komi:           komi,
+		BlackPrisoners: pos.BlackCaptures,
+		WhitePrisoners: pos.WhiteCaptures,
+	}
+
+	removed := make(map[game.Point]game.Color, len(pos.Dead))
+	for _, p := range pos.Dead {
+		c := board.At(p)
+		if c == game.Empty {
+			return nil, ErrDeadPointEmpty
+		}
+		removed[p] = c
+	}
+	board = withoutStones(board, removed)
+	for _, c := range removed {
+		if c == game.Black {
+			res.WhitePrisoners++
+		} else {
+			res.BlackPrisoners++
+		}
+	}
+
+	size := board.Size()
+	for y := range size {
+		for x := range size {
+			switch board.At(game.Point{X: x, Y: y}) {
+			case game.Black:
+				res.BlackStones++
+			case game.White:
+				res.WhiteStones++
+			}
+		}
+	}
+
+	for _, region := range emptyRegions(board) {
+		switch region.owner {
+		case game.Black:
+			res.BlackTerritory += region.size
+		case game.White:
+			res.WhiteTerritory += region.size
+		default:
+			res.Dame += region.size
+		}
+	}
+
+	if rules.AreaScoring() {
+		res.BlackScore = float64(res.BlackTerritory + res.BlackStones)
+		res.WhiteScore = float64(res.WhiteTerritory+res.WhiteStones) + komi
+	} else {
+		res.BlackScore = float64(res.BlackTerritory + res.BlackPrisoners)
+		res.WhiteScore = float64(res.WhiteTerritory+res.WhitePrisoners) + komi
+	}
+
+	switch {
+	case res.BlackScore > res.WhiteScore:
+		res.Winner = game.Black
+	case res.WhiteScore > res.BlackScore:
+		res.Winner = game.White
+	default:
+		res.Winner = game.Empty
+	}
+	res.Margin = math.Abs(res.BlackScore - res.WhiteScore)
+	return res, nil
+}
+
+// withoutStones returns a copy of the board with the given stones removed.
+func withoutStones(b *game.Board, removed map[game.Point]game.Color) *game.Board {
+	if len(removed) == 0 {
+		return b
+	}
+	stripped, _ := game.NewBoard(b.Size())
+	for y := range b.Size() {
+		for x := range b.Size() {
+			p := game.Point{X: x, Y: y}
+			if _, ok := removed[p]; ok {
+				continue
+			}
+			if c := b.At(p); c != game.Empty {
+				_ = stripped.Setup(c, p)
+			}
+		}
+	}
+	return stripped
+}
+
+type region struct {
+	size  int
+	owner game.Color
+}
+
+// emptyRegions flood fills every connected area of empty points. A region belongs to a player if it only
+// borders their stones, otherwise it is neutral.
+func emptyRegions(b *game.Board) []region {
+	size := b.Size()
+	visited := make(map[game.Point]bool)
+	var regions []region
+
+	for y := range size {
+		for x := range size {
+			start := game.Point{X: x, Y: y}
+			if visited[start] || b.At(start) != game.Empty {
+				continue
+			}
+
+			visited[start] = true
+			stack := []game.Point{start}
+			borders := make(map[game.Color]bool)
+			r := region{}
+
+			for len(stack) > 0 {
+				cur := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				r.size++
+
+				for _, n := range []game.Point{
+					{X: cur.X - 1, Y: cur.Y}, {X: cur.X + 1, Y: cur.Y}, {X: cur.X, Y: cur.Y - 1}, {X: cur.X, Y: cur.Y + 1},
+				} {
+					if !b.OnBoard(n) {
+						continue
+					}
+					switch c := b.At(n); c {
+					case game.Empty:
+						if !visited[n] {
+							visited[n] = true
+							stack = append(stack, n)
+						}
+					default:
+						borders[c] = true
+					}
+				}
+			}
+
+			switch {
+			case borders[game.Black] && !borders[game.White]:
+				r.owner = game.Black
+			case borders[game.White] && !borders[game.Black]:
+				r.owner = game.White
+			}
+			regions = append(regions, r)
+		}
+	}
+	return regions
+}
@@ -0,0 +1,217 @@
+package scoring
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/hazzardr/baduk-online/internal/game"
+	"github.com/hazzardr/baduk-online/internal/validator"
+)
+
+// boardFromDiagram builds a board from rows of X (black), O (white) and . (empty).
+func boardFromDiagram(t *testing.T, diagram string) *game.Board {
+	t.Helper()
+	rows := strings.Fields(diagram)
+	b, err := game.NewBoard(len(rows))
+	if err != nil {
+		t.Fatalf("failed to create board: %s", err)
+	}
+	for y, row := range rows {
+		for x, ch := range row {
+			var c game.Color
+			switch ch {
+			case 'X':
+				c = game.Black
+			case 'O':
+				c = game.White
+			default:
+				continue
+			}
+			if err := b.Setup(c, game.Point{X: x, Y: y}); err != nil {
+				t.Fatalf("failed to set up stone at (%d, %d): %s", x, y, err)
+			}
+		}
+	}
+	return b
+}
+
+func TestScore(t *testing.T) {
+	split := ".XO.. .XO.. .XO.. .XO.. .XO.."
+	withDead := ".XO.X .XO.. .XO.. .XO.. .XO.."
+	withDame := ".X.O. .X.O. .X.O. .X.O. .X.O."
+
+	tests := []struct {
+		name          string
+		diagram       string
+		dead          []game.Point
+		blackCaptures int
+		whiteCaptures int
+		rules         Ruleset
+		komi          float64
+		wantBlack     float64
+		wantWhite     float64
+		wantWinner    game.Color
+		wantDame      int
+	}{
+		{
+			name:       "Japanese territory",
+			diagram:    split,
+			rules:      Japanese,
+			komi:       6.5,
+			wantBlack:  5,
+			wantWhite:  16.5,
+			wantWinner: game.White,
+		},
+		{
+			name:          "Korean counts prisoners",
+			diagram:       split,
+			blackCaptures: 12,
+			rules:         Korean,
+			komi:          6.5,
+			wantBlack:     17,
+			wantWhite:     16.5,
+			wantWinner:    game.Black,
+		},
+		{
+			name:       "Chinese area counts stones",
+			diagram:    split,
+			rules:      Chinese,
+			komi:       7.5,
+			wantBlack:  10,
+			wantWhite:  22.5,
+			wantWinner: game.White,
+		},
+		{
+			name:          "AGA area ignores prisoners",
+			diagram:       split,
+			blackCaptures: 12,
+			rules:         AGA,
+			komi:          7.5,
+			wantBlack:     10,
+			wantWhite:     22.5,
+			wantWinner:    game.White,
+		},
+		{
+			name:       "Dead stone becomes territory and prisoner",
+			diagram:    withDead,
+			dead:       []game.Point{{X: 4, Y: 0}},
+			rules:      Japanese,
+			komi:       0.5,
+			wantBlack:  5,
+			wantWhite:  11.5,
+			wantWinner: game.White,
+		},
+		{
+			name:       "Dead stone under area scoring",
+			diagram:    withDead,
+			dead:       []game.Point{{X: 4, Y: 0}},
+			rules:      Chinese,
+			komi:       0,
+			wantBlack:  10,
+			wantWhite:  15,
+			wantWinner: game.White,
+		},
+		{
+			name:       "Dame is not counted",
+			diagram:    withDame,
+			rules:      Japanese,
+			komi:       0,
+			wantBlack:  5,
+			wantWhite:  5,
+			wantWinner: game.Empty,
+			wantDame:   5,
+		},
+		{
+			name:       "Negative komi",
+			diagram:    split,
+			rules:      Japanese,
+			komi:       -5,
+			wantBlack:  5,
+			wantWhite:  5,
+			wantWinner: game.Empty,
+		},
+		{
+			name:       "Empty board is all dame",
+			diagram:    "... ... ...",
+			rules:      Chinese,
+			komi:       0.5,
+			wantBlack:  0,
+			wantWhite:  0.5,
+			wantWinner: game.White,
+			wantDame:   9,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := Score(Position{
+				Board:         boardFromDiagram(t, tt.diagram),
+				Dead:          tt.dead,
+				BlackCaptures: tt.blackCaptures,
+				WhiteCaptures: tt.whiteCaptures,
+			}, tt.rules, tt.komi)
+			if err != nil {
+				t.Fatalf("Score() returned error: %s", err)
+			}
+			if res.BlackScore != tt.wantBlack {
+				t.Errorf("BlackScore = %v, want %v", res.BlackScore, tt.wantBlack)
+			}
+			if res.WhiteScore != tt.wantWhite {
+				t.Errorf("WhiteScore = %v, want %v", res.WhiteScore, tt.wantWhite)
+			}
+			if res.Winner != tt.wantWinner {
+				t.Errorf("Winner = %v, want %v", res.Winner, tt.wantWinner)
+			}
+			if res.Dame != tt.wantDame {
+				t.Errorf("Dame = %d, want %d", res.Dame, tt.wantDame)
+			}
+		})
+	}
+}
+
+func TestScoreErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		dead    []game.Point
+		rules   Ruleset
+		wantErr error
+	}{
+		{"Unknown ruleset", nil, Ruleset("ing"), ErrUnknownRuleset},
+		{"Dead point is empty", []game.Point{{X: 0, Y: 0}}, Japanese, ErrDeadPointEmpty},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Score(Position{Board: boardFromDiagram(t, "... .X. ..."), Dead: tt.dead}, tt.rules, 0)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Score() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateKomi(t *testing.T) {
+	tests := []struct {
+		name string
+		komi float64
+		want bool
+	}{
+		{"Half point", 6.5, true},
+		{"Whole point", 7, true},
+		{"Zero", 0, true},
+		{"Negative", -4.5, true},
+		{"Quarter point", 6.25, false},
+		{"Too large", 200, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			ValidateKomi(v, tt.komi)
+			if v.Valid() != tt.want {
+				t.Errorf("ValidateKomi(%v) valid = %v, want %v", tt.komi, v.Valid(), tt.want)
+			}
+		})
+	}
+}
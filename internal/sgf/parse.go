@@ -0,0 +1,178 @@
+package sgf
+
+import (
+	"fmt"
+	"strings"
+)
+
+type parser struct {
+	data []byte
+	pos  int
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	return &SyntaxError{Offset: p.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) eof() bool {
+	return p.pos >= len(p.data)
+}
+
+func (p *parser) skipWhitespace() {
+	for !p.eof() {
+		switch p.data[p.pos] {
+		case ' ', '\t', '\n', '\r', '\v', '\f':
+			p.pos++
+		default:
+			return
+		}
+	}
+}
+
+// parse walks the input iteratively, keeping the chain of open game trees on a stack so that deeply
+// nested input can't exhaust the goroutine stack.
+func (p *parser) parse() (Collection, error) {
+	var collection Collection
+	var stack []*GameTree
+
+	for {
+		p.skipWhitespace()
+		if p.eof() {
+			break
+		}
+
+		switch ch := p.data[p.pos]; ch {
+		case '(':
+			if len(stack) >= MaxDepth {
+				return nil, ErrTooDeep
+			}
+			tree := &GameTree{}
+			if len(stack) == 0 {
+				collection = append(collection, tree)
+			} else {
+				stack[len(stack)-1].Variations = append(stack[len(stack)-1].Variations, tree)
+			}
+			stack = append(stack, tree)
+			p.pos++
+			p.skipWhitespace()
+			if p.eof() || p.data[p.pos] != ';' {
+				return nil, p.errorf("game tree must start with a node")
+			}
+		case ')':
+			if len(stack) == 0 {
+				return nil, p.errorf("unexpected ')'")
+			}
+			stack[len(stack)-1].normalize()
+			stack = stack[:len(stack)-1]
+			p.pos++
+		case ';':
+			if len(stack) == 0 {
+				return nil, p.errorf("node outside of a game tree")
+			}
+			tree := stack[len(stack)-1]
+			if len(tree.Variations) > 0 {
+				return nil, p.errorf("node must not follow a variation")
+			}
+			p.pos++
+			node, err := p.parseNode()
+			if err != nil {
+				return nil, err
+			}
+			tree.Sequence = append(tree.Sequence, node)
+		default:
+			return nil, p.errorf("unexpected character %q", ch)
+		}
+	}
+
+	if len(stack) > 0 {
+		return nil, p.errorf("unterminated game tree")
+	}
+	if len(collection) == 0 {
+		return nil, ErrEmpty
+	}
+	return collection, nil
+}
+
+func (p *parser) parseNode() (Node, error) {
+	node := Node{}
+	for {
+		p.skipWhitespace()
+		if p.eof() || !isLetter(p.data[p.pos]) {
+			return node, nil
+		}
+
+		start := p.pos
+		var id strings.Builder
+		for !p.eof() && isLetter(p.data[p.pos]) {
+			// Lowercase letters were allowed in identifiers before FF[4] and are ignored.
+			if ch := p.data[p.pos]; ch >= 'A' && ch <= 'Z' {
+				id.WriteByte(ch)
+			}
+			p.pos++
+		}
+		if id.Len() == 0 {
+			p.pos = start
+			return nil, p.errorf("property identifier must contain an uppercase letter")
+		}
+		if _, exists := node[id.String()]; exists {
+			p.pos = start
+			return nil, p.errorf("duplicate property %s", id.String())
+		}
+
+		var values []string
+		for {
+			p.skipWhitespace()
+			if p.eof() || p.data[p.pos] != '[' {
+				break
+			}
+			value, err := p.parseValue()
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		if len(values) == 0 {
+			return nil, p.errorf("property %s must have a value", id.String())
+		}
+		node[id.String()] = values
+	}
+}
+
+// parseValue reads a bracketed value, removing escapes and soft line breaks.
+func (p *parser) parseValue() (string, error) {
+	start := p.pos
+	p.pos++ // '['
+	var value strings.Builder
+	for !p.eof() {
+		ch := p.data[p.pos]
+		switch ch {
+		case ']':
+			p.pos++
+			return value.String(), nil
+		case '\\':
+			p.pos++
+			if p.eof() {
+				continue
+			}
+			next := p.data[p.pos]
+			p.pos++
+			if next == '\n' || next == '\r' {
+				// A soft line break; swallow the other half of a CRLF or LFCR pair.
+				if !p.eof() && (p.data[p.pos] == '\n' || p.data[p.pos] == '\r') && p.data[p.pos] != next {
+					p.pos++
+				}
+				continue
+			}
+			value.WriteByte(next)
+		default:
+			value.WriteByte(ch)
+			p.pos++
+		}
+	}
+	p.pos = start
+	return "", p.errorf("unterminated property value")
+}
+
+func isLetter(ch byte) bool {
+	return (ch >= 'A' && ch <= 'Z') || (ch >= 'a' && ch <= 'z')
+}
@@ -0,0 +1,224 @@
+package sgf
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hazzardr/baduk-online/internal/game"
+)
+
+// DefaultSize is the board size of a game that doesn't specify SZ.
+const DefaultSize = 19
+
+// GameInfo holds the game-info properties of a root node.
+type GameInfo struct {
+	BlackPlayer string  `json:"black_player,omitempty"`
+	WhitePlayer string  `json:"white_player,omitempty"`
+	BlackRank   string  `json:"black_rank,omitempty"`
+	WhiteRank   string  `json:"white_rank,omitempty"`
+	Komi        float64 `json:"komi"`
+	Handicap    int     `json:"handicap,omitempty"`
+	Rules       string  `json:"rules,omitempty"`
+	Size        int     `json:"size"`
+	Date        string  `json:"date,omitempty"`
+	Result      string  `json:"result,omitempty"`
+}
+
+// PropertyError describes a property whose value is malformed.
+type PropertyError struct {
+	Property string
+	Msg      string
+}
+
+func (e *PropertyError) Error() string {
+	return fmt.Sprintf("%s %s", e.Property, e.Msg)
+}
+
+// Get returns the first value of a property and whether it was present.
+func (n Node) Get(id string) (string, bool) {
+	values, ok := n[id]
+	if !ok || len(values) == 0 {
+		return "", false
+	}
+	return values[0], true
+}
+
+// SimpleText returns the first value of a property with line breaks converted to spaces.
+func (n Node) SimpleText(id string) string {
+	v, _ := n.Get(id)
+	return strings.Join(strings.Fields(v), " ")
+}
+
+// Comment returns the node's comment.
+func (n Node) Comment() string {
+	v, _ := n.Get("C")
+	return v
+}
+
+// GameInfo reads the game-info properties from a root node.
+func (n Node) GameInfo() (*GameInfo, error) {
+	info := &GameInfo{
+		BlackPlayer: n.SimpleText("PB"),
+		WhitePlayer: n.SimpleText("PW"),
+		BlackRank:   n.SimpleText("BR"),
+		WhiteRank:   n.SimpleText("WR"),
+		Rules:       n.SimpleText("RU"),
+		Date:        n.SimpleText("DT"),
+		Result:      n.SimpleText("RE"),
+	}
+
+	size, err := n.Size()
+	if err != nil {
+		return nil, err
+	}
+	info.Size = size
+
+	if v, ok := n.Get("KM"); ok && strings.TrimSpace(v) != "" {
+		komi, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return nil, &PropertyError{"KM", "must be a real number"}
+		}
+		info.Komi = komi
+	}
+	if v, ok := n.Get("HA"); ok && strings.TrimSpace(v) != "" {
+		handicap, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil || handicap < 0 {
+			return nil, &PropertyError{"HA", "must be a non-negative number"}
+		}
+		info.Handicap = handicap
+	}
+	return info, nil
+}
+
+// Size returns the board size given by SZ, or DefaultSize if there is none. Rectangular boards are not
+// supported.
+func (n Node) Size() (int, error) {
+	v, ok := n.Get("SZ")
+	if !ok {
+		return DefaultSize, nil
+	}
+	v = strings.TrimSpace(v)
+	if cols, rows, found := strings.Cut(v, ":"); found {
+		if cols != rows {
+			return 0, &PropertyError{"SZ", "must describe a square board"}
+		}
+		v = cols
+	}
+	size, err := strconv.Atoi(v)
+	if err != nil || size < game.MinBoardSize || size > game.MaxBoardSize {
+		return 0, &PropertyError{"SZ", fmt.Sprintf("must be a number between %d and %d", game.MinBoardSize, game.MaxBoardSize)}
+	}
+	return size, nil
+}
+
+// Move returns the move played in a node, if any. The color is game.Empty when the node has no move.
+func (n Node) Move(size int) (game.Color, game.Point, bool, error) {
+	for _, m := range []struct {
+		id    string
+		color game.Color
+	}{{"B", game.Black}, {"W", game.White}} {
+		v, ok := n.Get(m.id)
+		if !ok {
+			continue
+		}
+		if v == "" || (v == "tt" && size <= 19) {
+			return m.color, game.Point{}, true, nil
+		}
+		p, err := ParsePoint(v, size)
+		if err != nil {
+			return game.Empty, game.Point{}, false, &PropertyError{m.id, err.Error()}
+		}
+		return m.color, p, false, nil
+	}
+	return game.Empty, game.Point{}, false, nil
+}
+
+// SetupStones are the points changed by a node's setup properties.
+type SetupStones struct {
+	Black []game.Point `json:"black,omitempty"`
+	White []game.Point `json:"white,omitempty"`
+	Empty []game.Point `json:"empty,omitempty"`
+}
+
+// Setup returns the stones added with AB and AW and the points cleared with AE, expanding compressed point
+// lists.
+func (n Node) Setup(size int) (*SetupStones, error) {
+	setup := &SetupStones{}
+	lists := []struct {
+		id  string
+		dst *[]game.Point
+	}{{"AB", &setup.Black}, {"AW", &setup.White}, {"AE", &setup.Empty}}
+	for _, l := range lists {
+		for _, v := range n[l.id] {
+			points, err := ParsePointList(v, size)
+			if err != nil {
+				return nil, &PropertyError{l.id, err.Error()}
+			}
+			*l.dst = append(*l.dst, points...)
+		}
+	}
+	return setup, nil
+}
+
+// ParsePoint decodes a two letter SGF point such as "dd".
+func ParsePoint(v string, size int) (game.Point, error) {
+	if len(v) != 2 {
+		return game.Point{}, fmt.Errorf("point %q must be two letters", v)
+	}
+	x, okX := coordinate(v[0])
+	y, okY := coordinate(v[1])
+	if !okX || !okY || x >= size || y >= size {
+		return game.Point{}, fmt.Errorf("point %q is not on the board", v)
+	}
+	return game.Point{X: x, Y: y}, nil
+}
+
+// ParsePointList decodes a single point or a compressed rectangle such as "aa:cc".
+func ParsePointList(v string, size int) ([]game.Point, error) {
+	from, to, compressed := strings.Cut(v, ":")
+	start, err := ParsePoint(from, size)
+	if err != nil {
+		return nil, err
+	}
+	if !compressed {
+		return []game.Point{start}, nil
+	}
+	end, err := ParsePoint(to, size)
+	if err != nil {
+		return nil, err
+	}
+	if end.X < start.X || end.Y < start.Y {
+		return nil, fmt.Errorf("compressed point list %q must go from upper left to lower right", v)
+	}
+	points := make([]game.Point, 0, (end.X-start.X+1)*(end.Y-start.Y+1))
+	for y := start.Y; y <= end.Y; y++ {
+		for x := start.X; x <= end.X; x++ {
+			points = append(points, game.Point{X: x, Y: y})
+		}
+	}
+	return points, nil
+}
+
+// FormatPoint encodes a point as two SGF letters.
+func FormatPoint(p game.Point) string {
+	return string([]byte{letter(p.X), letter(p.Y)})
+}
+
+func coordinate(ch byte) (int, bool) {
+	switch {
+	case ch >= 'a' && ch <= 'z':
+		return int(ch - 'a'), true
+	case ch >= 'A' && ch <= 'Z':
+		return int(ch-'A') + 26, true
+	default:
+		return 0, false
+	}
+}
+
+func letter(i int) byte {
+	if i < 26 {
+		return byte('a' + i)
+	}
+	return byte('A' + i - 26)
+}
@@ -0,0 +1,154 @@
+package sgf
+
+import (
+	"fmt"
+
+	"github.com/hazzardr/baduk-online/internal/game"
+)
+
+// Replay plays out every game in the collection. See GameTree.Replay.
+func (c Collection) Replay() error {
+	for _, t := range c {
+		if err := t.Replay(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Replay plays out the setup and moves of every node in the tree, following each variation from where it
+// branches, on a board of the size given by the root node. The spec requires moves to be executed even if the
+// game's rules forbid them, so ko and suicide are allowed. It returns a *PropertyError for the first point
+// that is off the board or move that is played onto a stone.
+func (t *GameTree) Replay() error {
+	size, err := t.Sequence[0].Size()
+	if err != nil {
+		return err
+	}
+	return t.replay(newPosition(size))
+}
+
+func (t *GameTree) replay(pos *position) error {
+	for _, n := range t.Sequence {
+		if err := n.replay(pos); err != nil {
+			return err
+		}
+	}
+	for _, v := range t.Variations {
+		if err := v.replay(pos.clone()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// replay applies a node's setup and then its move to the position.
+func (n Node) replay(pos *position) error {
+	setup, err := n.Setup(pos.size)
+	if err != nil {
+		return err
+	}
+	for _, s := range []struct {
+		color  game.Color
+		points []game.Point
+	}{{game.Empty, setup.Empty}, {game.Black, setup.Black}, {game.White, setup.White}} {
+		for _, p := range s.points {
+			pos.set(p, s.color)
+		}
+	}
+
+	color, p, pass, err := n.Move(pos.size)
+	if err != nil {
+		return err
+	}
+	if color == game.Empty || pass {
+		return nil
+	}
+	if err := pos.play(color, p); err != nil {
+		id := "B"
+		if color == game.White {
+			id = "W"
+		}
+		return &PropertyError{id, fmt.Sprintf("at %s is not a legal move: %s", FormatPoint(p), err)}
+	}
+	return nil
+}
+
+// position is the board a record is replayed on. Unlike game.Board it has no ko and lets a player capture
+// their own group, as records of any ruleset may contain such moves.
+type position struct {
+	size   int
+	stones []game.Color
+}
+
+func newPosition(size int) *position {
+	return &position{size: size, stones: make([]game.Color, size*size)}
+}
+
+func (pos *position) clone() *position {
+	c := &position{size: pos.size, stones: make([]game.Color, len(pos.stones))}
+	copy(c.stones, pos.stones)
+	return c
+}
+
+func (pos *position) at(p game.Point) game.Color {
+	return pos.stones[p.Y*pos.size+p.X]
+}
+
+func (pos *position) set(p game.Point, c game.Color) {
+	pos.stones[p.Y*pos.size+p.X] = c
+}
+
+// play places a stone of color c at p and removes the opposing groups it leaves without liberties, then its
+// own group if that has none left.
+func (pos *position) play(c game.Color, p game.Point) error {
+	if pos.at(p) != game.Empty {
+		return game.ErrOccupied
+	}
+	pos.set(p, c)
+	for _, n := range pos.neighbors(p) {
+		if pos.at(n) == c.Opponent() {
+			pos.captureIfDead(n)
+		}
+	}
+	pos.captureIfDead(p)
+	return nil
+}
+
+// captureIfDead removes the group at p if it has no liberties.
+func (pos *position) captureIfDead(p game.Point) {
+	color := pos.at(p)
+	visited := map[game.Point]bool{p: true}
+	stack := []game.Point{p}
+	var group []game.Point
+	for len(stack) > 0 {
+		cur := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		group = append(group, cur)
+		for _, n := range pos.neighbors(cur) {
+			switch pos.at(n) {
+			case game.Empty:
+				return
+			case color:
+				if !visited[n] {
+					visited[n] = true
+					stack = append(stack, n)
+				}
+			}
+		}
+	}
+	for _, s := range group {
+		pos.set(s, game.Empty)
+	}
+}
+
+func (pos *position) neighbors(p game.Point) []game.Point {
+	candidates := [4]game.Point{{X: p.X - 1, Y: p.Y}, {X: p.X + 1, Y: p.Y}, {X: p.X, Y: p.Y - 1}, {X: p.X, Y: p.Y + 1}}
+	neighbors := make([]game.Point, 0, 4)
+	for _, n := range candidates {
+		if n.X >= 0 && n.X < pos.size && n.Y >= 0 && n.Y < pos.size {
+			neighbors = append(neighbors, n)
+		}
+	}
+	return neighbors
+}
@@ -0,0 +1,103 @@
+// Package sgf reads and writes game records in the Smart Game Format, FF[4].
+//
+// A file is parsed into a Collection of GameTrees mirroring the grammar in the specification: each tree is a
+// sequence of nodes followed by zero or more variations. Trees are normalized as they are parsed so that a
+// tree never has exactly one variation.
+package sgf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// MaxDepth is the deepest level of nested variations that will be parsed or written.
+const MaxDepth = 1000
+
+var (
+	// ErrEmpty is returned when a collection contains no game trees.
+	ErrEmpty = errors.New("sgf must contain at least one game tree")
+	// ErrTooDeep is returned when variations are nested more than MaxDepth levels deep.
+	ErrTooDeep = fmt.Errorf("variations must not be nested more than %d levels deep", MaxDepth)
+
+	propIdentRX = regexp.MustCompile("^[A-Z]+$")
+)
+
+// SyntaxError describes malformed SGF and where it was found.
+type SyntaxError struct {
+	Offset int
+	Msg    string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%s at byte %d", e.Msg, e.Offset)
+}
+
+// Node is a set of properties, keyed by property identifier. Every property has at least one value.
+type Node map[string][]string
+
+// GameTree is a sequence of nodes followed by the variations that branch from its last node.
+type GameTree struct {
+	Sequence   []Node      `json:"sequence"`
+	Variations []*GameTree `json:"variations,omitempty"`
+}
+
+// Collection is the contents of an SGF file.
+type Collection []*GameTree
+
+// Parse reads a collection from SGF text.
+func Parse(data []byte) (Collection, error) {
+	p := &parser{data: bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))}
+	return p.parse()
+}
+
+// Validate checks that a collection, typically decoded from JSON, can be written as SGF.
+func (c Collection) Validate() error {
+	if len(c) == 0 {
+		return ErrEmpty
+	}
+	for _, t := range c {
+		if err := t.validate(1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *GameTree) validate(depth int) error {
+	if t == nil {
+		return errors.New("game tree must not be null")
+	}
+	if depth > MaxDepth {
+		return ErrTooDeep
+	}
+	if len(t.Sequence) == 0 {
+		return errors.New("game tree must contain at least one node")
+	}
+	for _, n := range t.Sequence {
+		for id, values := range n {
+			if !propIdentRX.MatchString(id) {
+				return fmt.Errorf("property identifier %q must only contain uppercase letters", id)
+			}
+			if len(values) == 0 {
+				return fmt.Errorf("property %s must have at least one value", id)
+			}
+		}
+	}
+	for _, v := range t.Variations {
+		if err := v.validate(depth + 1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// normalize folds a lone variation into its parent's sequence, repeatedly.
+func (t *GameTree) normalize() {
+	for len(t.Variations) == 1 {
+		only := t.Variations[0]
+		t.Sequence = append(t.Sequence, only.Sequence...)
+		t.Variations = only.Variations
+	}
+}
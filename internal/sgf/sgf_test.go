@@ -0,0 +1,316 @@
+package sgf
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/hazzardr/baduk-online/internal/game"
+)
+
+const sample = `(;FF[4]GM[1]SZ[19]CA[UTF-8]
+PB[Honinbo Shusaku]PW[Gennan Inseki]BR[4d]WR[8d]KM[0]HA[0]RU[Japanese]DT[1846-09-11]RE[B+2]
+C[The ear-reddening game.]
+;B[qd];W[dc];B[pq]
+(;W[oc]C[Main line \] with escaped bracket];B[mc])
+(;W[po];B[pp]))`
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name           string
+		input          string
+		wantTrees      int
+		wantSequence   int
+		wantVariations int
+		wantErr        bool
+	}{
+		{"Sample game", sample, 1, 4, 2, false},
+		{"Minimal", "(;)", 1, 1, 0, false},
+		{"Collection of two games", "(;GM[1];B[aa])(;GM[1];W[bb])", 2, 2, 0, false},
+		{"Single variation is folded", "(;GM[1](;B[aa](;W[bb])))", 1, 3, 0, false},
+		{"Leading BOM and whitespace", "\xef\xbb\xbf  \n(;GM[1])\n", 1, 1, 0, false},
+		{"Lowercase letters in identifier ignored", "(;GaMe[1])", 1, 1, 0, false},
+		{"Empty input", "", 0, 0, 0, true},
+		{"Unterminated tree", "(;B[aa]", 0, 0, 0, true},
+		{"Unterminated value", "(;C[unterminated)", 0, 0, 0, true},
+		{"Tree without node", "()", 0, 0, 0, true},
+		{"Node outside tree", ";B[aa]", 0, 0, 0, true},
+		{"Property without value", "(;B)", 0, 0, 0, true},
+		{"Duplicate property", "(;B[aa]B[bb])", 0, 0, 0, true},
+		{"Node after variation", "(;B[aa](;W[bb])(;W[cc]);B[dd])", 0, 0, 0, true},
+		{"Unexpected close", "(;B[aa]))", 0, 0, 0, true},
+		{"Junk between trees", "(;B[aa])x(;B[bb])", 0, 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := Parse([]byte(tt.input))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %+v", c)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse() returned error: %s", err)
+			}
+			if len(c) != tt.wantTrees {
+				t.Fatalf("got %d trees, want %d", len(c), tt.wantTrees)
+			}
+			if len(c[0].Sequence) != tt.wantSequence {
+				t.Errorf("got %d nodes in sequence, want %d", len(c[0].Sequence), tt.wantSequence)
+			}
+			if len(c[0].Variations) != tt.wantVariations {
+				t.Errorf("got %d variations, want %d", len(c[0].Variations), tt.wantVariations)
+			}
+		})
+	}
+}
+
+func TestParseValues(t *testing.T) {
+	c, err := Parse([]byte("(;C[a \\] b \\\\ c \\:d]GN[soft\\\nbreak]AB[aa][bb])"))
+	if err != nil {
+		t.Fatalf("Parse() returned error: %s", err)
+	}
+	n := c[0].Sequence[0]
+	if got := n.Comment(); got != `a ] b \ c :d` {
+		t.Errorf("Comment() = %q", got)
+	}
+	if got := n.SimpleText("GN"); got != "softbreak" {
+		t.Errorf("soft line break not removed: %q", got)
+	}
+	if got := len(n["AB"]); got != 2 {
+		t.Errorf("got %d AB values, want 2", got)
+	}
+}
+
+func TestSyntaxErrorOffset(t *testing.T) {
+	_, err := Parse([]byte("(;B[aa] ?)"))
+	var syntaxErr *SyntaxError
+	if !errors.As(err, &syntaxErr) {
+		t.Fatalf("expected SyntaxError, got %v", err)
+	}
+	if syntaxErr.Offset != 8 {
+		t.Errorf("Offset = %d, want 8", syntaxErr.Offset)
+	}
+}
+
+func TestTooDeep(t *testing.T) {
+	input := make([]byte, 0, (MaxDepth+1)*4)
+	for range MaxDepth + 1 {
+		input = append(input, "(;(;"...)
+	}
+	if _, err := Parse(input); !errors.Is(err, ErrTooDeep) {
+		t.Errorf("expected ErrTooDeep, got %v", err)
+	}
+}
+
+func TestGameInfo(t *testing.T) {
+	c, err := Parse([]byte(sample))
+	if err != nil {
+		t.Fatalf("Parse() returned error: %s", err)
+	}
+	info, err := c[0].Sequence[0].GameInfo()
+	if err != nil {
+		t.Fatalf("GameInfo() returned error: %s", err)
+	}
+	want := &GameInfo{
+		BlackPlayer: "Honinbo Shusaku",
+		WhitePlayer: "Gennan Inseki",
+		BlackRank:   "4d",
+		WhiteRank:   "8d",
+		Rules:       "Japanese",
+		Size:        19,
+		Date:        "1846-09-11",
+		Result:      "B+2",
+	}
+	if !reflect.DeepEqual(info, want) {
+		t.Errorf("GameInfo() = %+v, want %+v", info, want)
+	}
+
+	tests := []struct {
+		name     string
+		input    string
+		wantProp string
+	}{
+		{"Bad komi", "(;KM[six])", "KM"},
+		{"Bad handicap", "(;HA[-1])", "HA"},
+		{"Rectangular board", "(;SZ[19:13])", "SZ"},
+		{"Board too large", "(;SZ[52])", "SZ"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := Parse([]byte(tt.input))
+			if err != nil {
+				t.Fatalf("Parse() returned error: %s", err)
+			}
+			_, err = c[0].Sequence[0].GameInfo()
+			var propErr *PropertyError
+			if !errors.As(err, &propErr) || propErr.Property != tt.wantProp {
+				t.Errorf("GameInfo() error = %v, want PropertyError for %s", err, tt.wantProp)
+			}
+		})
+	}
+}
+
+func TestSetupAndMoves(t *testing.T) {
+	c, err := Parse([]byte("(;SZ[9]AB[aa:bb][ii]AW[cc]AE[dd];B[ee];W[];B[tt])"))
+	if err != nil {
+		t.Fatalf("Parse() returned error: %s", err)
+	}
+	setup, err := c[0].Sequence[0].Setup(9)
+	if err != nil {
+		t.Fatalf("Setup() returned error: %s", err)
+	}
+	if len(setup.Black) != 5 || len(setup.White) != 1 || len(setup.Empty) != 1 {
+		t.Errorf("Setup() = %+v", setup)
+	}
+
+	color, p, pass, err := c[0].Sequence[1].Move(9)
+	if err != nil || color != game.Black || p != (game.Point{X: 4, Y: 4}) || pass {
+		t.Errorf("Move() = %v, %v, %v, %v", color, p, pass, err)
+	}
+	for _, n := range c[0].Sequence[2:] {
+		_, _, pass, err := n.Move(9)
+		if err != nil || !pass {
+			t.Errorf("expected pass, got %v, %v", pass, err)
+		}
+	}
+
+	if _, err := c[0].Sequence[0].Setup(2); err == nil {
+		t.Error("expected error for points off a 2x2 board")
+	}
+	if _, err := ParsePointList("cc:aa", 9); err == nil {
+		t.Error("expected error for reversed compressed point list")
+	}
+	if FormatPoint(game.Point{X: 3, Y: 15}) != "dp" {
+		t.Errorf("FormatPoint() = %q, want dp", FormatPoint(game.Point{X: 3, Y: 15}))
+	}
+}
+
+func TestMarshalRoundTrip(t *testing.T) {
+	c, err := Parse([]byte(sample))
+	if err != nil {
+		t.Fatalf("Parse() returned error: %s", err)
+	}
+	text, err := Marshal(c)
+	if err != nil {
+		t.Fatalf("Marshal() returned error: %s", err)
+	}
+	again, err := Parse(text)
+	if err != nil {
+		t.Fatalf("failed to parse marshalled output %q: %s", text, err)
+	}
+	if !reflect.DeepEqual(c, again) {
+		t.Errorf("round trip changed the collection:\n%s", text)
+	}
+
+	js, err := json.Marshal(c)
+	if err != nil {
+		t.Fatalf("json.Marshal() returned error: %s", err)
+	}
+	var decoded Collection
+	if err := json.Unmarshal(js, &decoded); err != nil {
+		t.Fatalf("json.Unmarshal() returned error: %s", err)
+	}
+	if !reflect.DeepEqual(c, decoded) {
+		t.Error("JSON round trip changed the collection")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		c    Collection
+	}{
+		{"Empty collection", Collection{}},
+		{"Empty sequence", Collection{{}}},
+		{"Lowercase identifier", Collection{{Sequence: []Node{{"b": {"aa"}}}}}},
+		{"Property without values", Collection{{Sequence: []Node{{"B": {}}}}}},
+		{"Nil variation", Collection{{Sequence: []Node{{}}, Variations: []*GameTree{nil}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.c.Validate(); err == nil {
+				t.Error("expected validation error")
+			}
+			if _, err := Marshal(tt.c); err == nil {
+				t.Error("expected Marshal() to reject invalid collection")
+			}
+		})
+	}
+}
+
+func FuzzParse(f *testing.F) {
+	f.Add([]byte(sample))
+	f.Add([]byte("(;)"))
+	f.Add([]byte("(;C[a\\]b\\\\]GN[x\\\ny])"))
+	f.Add([]byte("(;B[aa](;W[bb])(;W[cc](;B[dd])))"))
+	f.Add([]byte("(;AB[aa:cc][dd]KM[6.5])(;SZ[9:9])"))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		c, err := Parse(data)
+		if err != nil {
+			return
+		}
+		text, err := Marshal(c)
+		if err != nil {
+			t.Fatalf("failed to marshal parsed collection: %s", err)
+		}
+		again, err := Parse(text)
+		if err != nil {
+			t.Fatalf("failed to parse marshalled output %q: %s", text, err)
+		}
+		if !reflect.DeepEqual(c, again) {
+			t.Fatalf("round trip changed the collection:\n%q\n%q", data, text)
+		}
+		for _, tree := range c {
+			root := tree.Sequence[0]
+			if info, err := root.GameInfo(); err == nil {
+				_, _ = root.Setup(info.Size)
+				_, _, _, _ = root.Move(info.Size)
+			}
+		}
+	})
+}
+
+func TestReplay(t *testing.T) {
+	tests := []struct {
+		name     string
+		sgf      string
+		wantProp string
+	}{
+		{"Valid game", "(;SZ[9];B[ee];W[ef];B[];W[tt])", ""},
+		{"Handicap setup", "(;SZ[9]HA[2]AB[cc][gg];W[ee])", ""},
+		{"Setup replaces stones", "(;SZ[9]AB[aa];AW[aa]AE[bb];B[bb])", ""},
+		{"Capture and play again", "(;SZ[9]AW[aa];B[ba];W[ee];B[ab];W[ff];B[aa])", ""},
+		{"Variations branch from the same position", "(;SZ[9];B[ee](;W[cc])(;W[gg]))", ""},
+		{"Move off the board", "(;SZ[9];B[jj])", "B"},
+		{"Setup off the board", "(;SZ[9]AB[aa:kk])", "AB"},
+		{"Occupied point", "(;SZ[9];B[ee];W[ee])", "W"},
+		{"Suicide removes the group", "(;SZ[9]RU[NZ]AW[ba][ab];B[aa];W[ee];B[aa])", ""},
+		{"Ko is retaken", "(;SZ[9]AB[ba][ab][bc]AW[ca][db][cc][bb];B[cb];W[bb])", ""},
+		{"Illegal move in a later variation", "(;SZ[9];B[ee](;W[cc])(;W[dd];B[dd]))", "B"},
+		{"Illegal move in a later game", "(;SZ[9];B[ee])(;SZ[5];B[cc];W[cc])", "W"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := Parse([]byte(tt.sgf))
+			if err != nil {
+				t.Fatalf("Parse() returned error: %s", err)
+			}
+			err = c.Replay()
+			if tt.wantProp == "" {
+				if err != nil {
+					t.Errorf("Replay() returned error: %s", err)
+				}
+				return
+			}
+			var propErr *PropertyError
+			if !errors.As(err, &propErr) || propErr.Property != tt.wantProp {
+				t.Errorf("Replay() error = %v, want PropertyError for %s", err, tt.wantProp)
+			}
+		})
+	}
+}
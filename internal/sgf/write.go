@@ -0,0 +1,94 @@
+package sgf
+
+import (
+	"bytes"
+	"io"
+	"slices"
+	"strings"
+)
+
+// rootOrder lists the properties conventionally written first in a root node.
+var rootOrder = []string{"FF", "GM", "CA", "AP", "SZ"}
+
+// Marshal returns the SGF text for a collection.
+func Marshal(c Collection) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := Write(&buf, c); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Write validates a collection and writes it as SGF text. Properties are written in a stable order so that
+// equal collections always produce identical output.
+func Write(w io.Writer, c Collection) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	var sb strings.Builder
+	for _, t := range c {
+		writeTree(&sb, t)
+		sb.WriteByte('\n')
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func writeTree(sb *strings.Builder, t *GameTree) {
+	sb.WriteByte('(')
+	for _, n := range t.Sequence {
+		writeNode(sb, n)
+	}
+	for _, v := range t.Variations {
+		sb.WriteByte('\n')
+		writeTree(sb, v)
+	}
+	sb.WriteByte(')')
+}
+
+func writeNode(sb *strings.Builder, n Node) {
+	sb.WriteByte(';')
+	for _, id := range propertyOrder(n) {
+		sb.WriteString(id)
+		for _, v := range n[id] {
+			sb.WriteByte('[')
+			sb.WriteString(escape(v))
+			sb.WriteByte(']')
+		}
+	}
+}
+
+func propertyOrder(n Node) []string {
+	ids := make([]string, 0, len(n))
+	for id := range n {
+		ids = append(ids, id)
+	}
+	slices.SortFunc(ids, func(a, b string) int {
+		ai, bi := slices.Index(rootOrder, a), slices.Index(rootOrder, b)
+		switch {
+		case ai >= 0 && bi >= 0:
+			return ai - bi
+		case ai >= 0:
+			return -1
+		case bi >= 0:
+			return 1
+		default:
+			return strings.Compare(a, b)
+		}
+	})
+	return ids
+}
+
+func escape(v string) string {
+	if !strings.ContainsAny(v, `]\`) {
+		return v
+	}
+	var sb strings.Builder
+	for i := range len(v) {
+		if v[i] == ']' || v[i] == '\\' {
+			sb.WriteByte('\\')
+		}
+		sb.WriteByte(v[i])
+	}
+	return sb.String()
+}
@@ -0,0 +1,361 @@
+// Package superko detects whole-board repetition. It tracks the Zobrist hash of every position reached in a
+// game and reports whether a candidate move would recreate one of them under positional or situational
+// superko, as used by the Chinese, AGA and New Zealand rule sets.
+package superko
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+
+	"github.com/hazzardr/baduk-online/internal/scoring"
+	"github.com/hazzardr/baduk-online/internal/validator"
+)
+
+const (
+	// MinBoardSize is the smallest supported board.
+	MinBoardSize = 2
+	// MaxBoardSize is the largest supported board.
+	MaxBoardSize = 25
+)
+
+var (
+	// ErrInvalidBoardSize is returned when a history is created outside of the supported size range.
+	ErrInvalidBoardSize = fmt.Errorf("board size must be between %d and %d", MinBoardSize, MaxBoardSize)
+	// ErrInvalidColor is returned when a move is made by neither black nor white.
+	ErrInvalidColor = errors.New("stone color must be black or white")
+	// ErrOutOfBounds is returned when a move is made off the board.
+	ErrOutOfBounds = errors.New("point is not on the board")
+	// ErrOccupied is returned when a move is made on a point that already has a stone.
+	ErrOccupied = errors.New("point is already occupied")
+	// ErrSuicide is returned when a move would leave its own group without liberties and suicide is not allowed.
+	ErrSuicide = errors.New("move is suicide")
+	// ErrPositionalSuperko is returned when a move recreates an earlier board position.
+	ErrPositionalSuperko = errors.New("move repeats an earlier board position")
+	// ErrSituationalSuperko is returned when a move recreates an earlier board position with the same player to move.
+	ErrSituationalSuperko = errors.New("move repeats an earlier board position with the same player to move")
+	// ErrSetupAfterMoves is returned when setup stones are placed after the game has started.
+	ErrSetupAfterMoves = errors.New("setup stones must be placed before the first move")
+)
+
+// Rule selects which kind of repetition is forbidden.
+type Rule int
+
+const (
+	// Positional superko forbids recreating any earlier board position.
+	Positional Rule = iota
+	// Situational superko forbids recreating an earlier board position with the same player to move.
+	Situational
+)
+
+// ForRuleset returns the superko rule of a rule set: positional for Chinese rules and situational for AGA
+// rules. It returns false for rule sets, such as Japanese and Korean, that only forbid simple ko.
+func ForRuleset(rules scoring.Ruleset) (Rule, bool) {
+	switch rules {
+	case scoring.Chinese:
+		return Positional, true
+	case scoring.AGA:
+		return Situational, true
+	default:
+		return 0, false
+	}
+}
+
+// Color is the occupant of a point on the board.
+type Color uint8
+
+const (
+	Empty Color = iota
+	Black
+	White
+)
+
+func (c Color) opponent() Color {
+	if c == Black {
+		return White
+	}
+	return Black
+}
+
+// Point is a zero-indexed intersection on the board, with (0, 0) in the top left corner.
+type Point struct {
+	X int
+	Y int
+}
+
+// History is a sequence of board positions along with the position currently on the board.
+type History struct {
+	size         int
+	rule         Rule
+	allowSuicide bool
+	keys         []uint64
+	whiteToMove  uint64
+	stones       []Color
+	hash         uint64
+	toMove       Color
+	seen         map[uint64]int
+	sequence     []uint64
+}
+
+// New returns an empty history for a board of the given size. Set allowSuicide for rule sets, such as
+// New Zealand, in which a player may capture their own group.
+func New(size int, rule Rule, allowSuicide bool) (*History, error) {
+	if size < MinBoardSize || size > MaxBoardSize {
+		return nil, ErrInvalidBoardSize
+	}
+	// A fixed seed keeps hashes stable between processes so they can be persisted and compared later.
+	rng := rand.New(rand.NewPCG(uint64(size), 0x9e3779b97f4a7c15))
+	keys := make([]uint64, size*size*2)
+	for i := range keys {
+		keys[i] = rng.Uint64()
+	}
+	h := &History{
+		size:         size,
+		rule:         rule,
+		allowSuicide: allowSuicide,
+		keys:         keys,
+		whiteToMove:  rng.Uint64(),
+		stones:       make([]Color, size*size),
+		toMove:       Black,
+		seen:         make(map[uint64]int),
+	}
+	h.record()
+	return h, nil
+}
+
+// Hash returns the Zobrist hash of the current board position, ignoring the player to move.
+func (h *History) Hash() uint64 {
+	return h.hash
+}
+
+// Len returns the number of positions in the history, including the starting position.
+func (h *History) Len() int {
+	return len(h.sequence)
+}
+
+// ToMove returns the player whose turn it is.
+func (h *History) ToMove() Color {
+	return h.toMove
+}
+
+// SetToMove overrides the player whose turn it is, e.g. for handicap games where white moves first. It may
+// only be called before the first move.
+func (h *History) SetToMove(c Color) error {
+	if c != Black && c != White {
+		return ErrInvalidColor
+	}
+	if len(h.sequence) > 1 {
+		return ErrSetupAfterMoves
+	}
+	h.forget()
+	h.toMove = c
+	h.record()
+	return nil
+}
+
+// Setup places a stone as part of the starting position. It may only be called before the first move.
+func (h *History) Setup(c Color, p Point) error {
+	if c != Black && c != White {
+		return ErrInvalidColor
+	}
+	if !h.onBoard(p) {
+		return ErrOutOfBounds
+	}
+	if h.stones[h.index(p)] != Empty {
+		return ErrOccupied
+	}
+	if len(h.sequence) > 1 {
+		return ErrSetupAfterMoves
+	}
+	h.forget()
+	h.place(p, c)
+	h.record()
+	return nil
+}
+
+// Check reports whether c may play at p without violating the rules, returning the reason if not. The
+// history is not modified.
+func (h *History) Check(c Color, p Point) error {
+	_, hash, err := h.resolve(c, p)
+	if err != nil {
+		return err
+	}
+	switch h.rule {
+	case Situational:
+		if h.seen[h.situation(hash, c.opponent())] > 0 {
+			return ErrSituationalSuperko
+		}
+	default:
+		if h.seenPosition(hash) {
+			return ErrPositionalSuperko
+		}
+	}
+	return nil
+}
+
+// ValidateMove adds an error to v, keyed by the offending field, if c may not play at p.
+func (h *History) ValidateMove(v *validator.Validator, c Color, p Point) {
+	err := h.Check(c, p)
+	switch {
+	case err == nil:
+		return
+	case errors.Is(err, ErrInvalidColor):
+		v.AddError("color", err.Error())
+	case errors.Is(err, ErrOutOfBounds):
+		v.AddError("point", err.Error())
+	default:
+		v.AddError("move", err.Error())
+	}
+}
+
+// Play checks and applies a move by c at p, recording the resulting position.
+func (h *History) Play(c Color, p Point) error {
+	err := h.Check(c, p)
+	if err != nil {
+		return err
+	}
+	stones, hash, _ := h.resolve(c, p)
+	h.stones = stones
+	h.hash = hash
+	h.toMove = c.opponent()
+	h.record()
+	return nil
+}
+
+// Pass records a pass by c. The board position is unchanged but the player to move is not.
+func (h *History) Pass(c Color) error {
+	if c != Black && c != White {
+		return ErrInvalidColor
+	}
+	h.toMove = c.opponent()
+	h.record()
+	return nil
+}
+
+// resolve computes the stones and hash that would result from c playing at p, including captures.
+func (h *History) resolve(c Color, p Point) ([]Color, uint64, error) {
+	if c != Black && c != White {
+		return nil, 0, ErrInvalidColor
+	}
+	if !h.onBoard(p) {
+		return nil, 0, ErrOutOfBounds
+	}
+	if h.stones[h.index(p)] != Empty {
+		return nil, 0, ErrOccupied
+	}
+
+	stones := make([]Color, len(h.stones))
+	copy(stones, h.stones)
+	hash := h.hash
+
+	stones[h.index(p)] = c
+	hash ^= h.key(p, c)
+
+	for _, n := range h.neighbors(p) {
+		if stones[h.index(n)] != c.opponent() {
+			continue
+		}
+		group, liberties := h.group(stones, n)
+		if liberties == 0 {
+			for _, s := range group {
+				stones[h.index(s)] = Empty
+				hash ^= h.key(s, c.opponent())
+			}
+		}
+	}
+
+	group, liberties := h.group(stones, p)
+	if liberties == 0 {
+		if !h.allowSuicide {
+			return nil, 0, ErrSuicide
+		}
+		for _, s := range group {
+			stones[h.index(s)] = Empty
+			hash ^= h.key(s, c)
+		}
+	}
+	return stones, hash, nil
+}
+
+func (h *History) record() {
+	h.seen[h.situation(h.hash, h.toMove)]++
+	h.sequence = append(h.sequence, h.situation(h.hash, h.toMove))
+}
+
+// forget removes the most recent position so that setup changes replace the starting position.
+func (h *History) forget() {
+	last := h.sequence[len(h.sequence)-1]
+	h.sequence = h.sequence[:len(h.sequence)-1]
+	h.seen[last]--
+	if h.seen[last] == 0 {
+		delete(h.seen, last)
+	}
+}
+
+func (h *History) seenPosition(hash uint64) bool {
+	return h.seen[h.situation(hash, Black)] > 0 || h.seen[h.situation(hash, White)] > 0
+}
+
+// situation combines a position hash with the player to move.
+func (h *History) situation(hash uint64, toMove Color) uint64 {
+	if toMove == White {
+		return hash ^ h.whiteToMove
+	}
+	return hash
+}
+
+func (h *History) place(p Point, c Color) {
+	h.stones[h.index(p)] = c
+	h.hash ^= h.key(p, c)
+}
+
+func (h *History) key(p Point, c Color) uint64 {
+	return h.keys[h.index(p)*2+int(c)-1]
+}
+
+func (h *History) onBoard(p Point) bool {
+	return p.X >= 0 && p.X < h.size && p.Y >= 0 && p.Y < h.size
+}
+
+func (h *History) index(p Point) int {
+	return p.Y*h.size + p.X
+}
+
+func (h *History) neighbors(p Point) []Point {
+	candidates := [4]Point{{p.X - 1, p.Y}, {p.X + 1, p.Y}, {p.X, p.Y - 1}, {p.X, p.Y + 1}}
+	neighbors := make([]Point, 0, 4)
+	for _, n := range candidates {
+		if h.onBoard(n) {
+			neighbors = append(neighbors, n)
+		}
+	}
+	return neighbors
+}
+
+// group flood fills from p over stones, returning the connected stones and their distinct liberties.
+func (h *History) group(stones []Color, p Point) ([]Point, int) {
+	color := stones[h.index(p)]
+	visited := map[Point]bool{p: true}
+	liberties := make(map[Point]bool)
+	stack := []Point{p}
+	var group []Point
+
+	for len(stack) > 0 {
+		cur := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		group = append(group, cur)
+
+		for _, n := range h.neighbors(cur) {
+			switch stones[h.index(n)] {
+			case Empty:
+				liberties[n] = true
+			case color:
+				if !visited[n] {
+					visited[n] = true
+					stack = append(stack, n)
+				}
+			}
+		}
+	}
+	return group, len(liberties)
+}
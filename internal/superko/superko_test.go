@@ -0,0 +1,302 @@
+package superko
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/hazzardr/baduk-online/internal/scoring"
+	"github.com/hazzardr/baduk-online/internal/validator"
+)
+
+// historyFromDiagram builds a history whose starting position is given by rows of X (black), O (white) and
+// . (empty).
+func historyFromDiagram(t *testing.T, diagram string, rule Rule, allowSuicide bool) *History {
+	t.Helper()
+	rows := strings.Fields(diagram)
+	h, err := New(len(rows), rule, allowSuicide)
+	if err != nil {
+		t.Fatalf("failed to create history: %s", err)
+	}
+	for y, row := range rows {
+		for x, ch := range row {
+			var c Color
+			switch ch {
+			case 'X':
+				c = Black
+			case 'O':
+				c = White
+			default:
+				continue
+			}
+			if err := h.Setup(c, Point{x, y}); err != nil {
+				t.Fatalf("failed to set up stone at (%d, %d): %s", x, y, err)
+			}
+		}
+	}
+	return h
+}
+
+func TestForRuleset(t *testing.T) {
+	tests := []struct {
+		rules scoring.Ruleset
+		want  Rule
+		ok    bool
+	}{
+		{scoring.Japanese, 0, false},
+		{scoring.Korean, 0, false},
+		{scoring.Chinese, Positional, true},
+		{scoring.AGA, Situational, true},
+	}
+	for _, tt := range tests {
+		if got, ok := ForRuleset(tt.rules); got != tt.want || ok != tt.ok {
+			t.Errorf("ForRuleset(%q) = %v, %v, want %v, %v", tt.rules, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+type move struct {
+	color Color
+	point Point
+	pass  bool
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		size    int
+		wantErr error
+	}{
+		{"9x9", 9, nil},
+		{"19x19", 19, nil},
+		{"Maximum size", 25, nil},
+		{"Too small", 1, ErrInvalidBoardSize},
+		{"Too large", 26, ErrInvalidBoardSize},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, err := New(tt.size, Positional, false)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("New() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && h.Len() != 1 {
+				t.Errorf("Len() = %d, want 1", h.Len())
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	// Black to capture at (2, 1), creating a ko at (1, 1).
+	ko := ".XO.. XO.O. .XO.. ..... ....."
+
+	tests := []struct {
+		name         string
+		diagram      string
+		rule         Rule
+		allowSuicide bool
+		moves        []move
+		candidate    move
+		wantErr      error
+	}{
+		{
+			name:      "Ko retake violates positional superko",
+			diagram:   ko,
+			rule:      Positional,
+			moves:     []move{{color: Black, point: Point{2, 1}}},
+			candidate: move{color: White, point: Point{1, 1}},
+			wantErr:   ErrPositionalSuperko,
+		},
+		{
+			name:      "Ko retake violates situational superko",
+			diagram:   ko,
+			rule:      Situational,
+			moves:     []move{{color: Black, point: Point{2, 1}}},
+			candidate: move{color: White, point: Point{1, 1}},
+			wantErr:   ErrSituationalSuperko,
+		},
+		{
+			name:    "Ko retake allowed after threats elsewhere",
+			diagram: ko,
+			rule:    Positional,
+			moves: []move{
+				{color: Black, point: Point{2, 1}},
+				{color: White, point: Point{4, 4}},
+				{color: Black, point: Point{4, 3}},
+			},
+			candidate: move{color: White, point: Point{1, 1}},
+		},
+		{
+			name:    "Passes do not reset repetition",
+			diagram: ko,
+			rule:    Situational,
+			moves: []move{
+				{color: Black, pass: true},
+				{color: White, point: Point{4, 4}},
+				{color: Black, point: Point{2, 1}},
+				{color: White, pass: true},
+				{color: Black, pass: true},
+			},
+			candidate: move{color: White, point: Point{1, 1}},
+			wantErr:   ErrSituationalSuperko,
+		},
+		{
+			name:      "Suicide forbidden",
+			diagram:   ".O. O.. ...",
+			rule:      Positional,
+			candidate: move{color: Black, point: Point{0, 0}},
+			wantErr:   ErrSuicide,
+		},
+		{
+			name:         "Single stone suicide repeats the position",
+			diagram:      ".O. O.. ...",
+			rule:         Positional,
+			allowSuicide: true,
+			candidate:    move{color: Black, point: Point{0, 0}},
+			wantErr:      ErrPositionalSuperko,
+		},
+		{
+			name:         "Single stone suicide changes the situation",
+			diagram:      ".O. O.. ...",
+			rule:         Situational,
+			allowSuicide: true,
+			candidate:    move{color: Black, point: Point{0, 0}},
+		},
+		{
+			name:         "Multi stone suicide allowed",
+			diagram:      "X.O.. XO... O.... ..... .....",
+			rule:         Positional,
+			allowSuicide: true,
+			candidate:    move{color: Black, point: Point{1, 0}},
+		},
+		{
+			name:      "Occupied point",
+			diagram:   "X.. ... ...",
+			rule:      Positional,
+			candidate: move{color: White, point: Point{0, 0}},
+			wantErr:   ErrOccupied,
+		},
+		{
+			name:      "Out of bounds",
+			diagram:   "... ... ...",
+			rule:      Positional,
+			candidate: move{color: White, point: Point{3, 3}},
+			wantErr:   ErrOutOfBounds,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := historyFromDiagram(t, tt.diagram, tt.rule, tt.allowSuicide)
+			for _, m := range tt.moves {
+				var err error
+				if m.pass {
+					err = h.Pass(m.color)
+				} else {
+					err = h.Play(m.color, m.point)
+				}
+				if err != nil {
+					t.Fatalf("failed to play %+v: %s", m, err)
+				}
+			}
+
+			hash, length := h.Hash(), h.Len()
+			err := h.Check(tt.candidate.color, tt.candidate.point)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Check() error = %v, want %v", err, tt.wantErr)
+			}
+			if h.Hash() != hash || h.Len() != length {
+				t.Error("Check() should not modify the history")
+			}
+
+			err = h.Play(tt.candidate.color, tt.candidate.point)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Play() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMultiStoneSuicideRemovesGroup(t *testing.T) {
+	h := historyFromDiagram(t, "X.O.. XO... O.... ..... .....", Positional, true)
+	before := historyFromDiagram(t, "..O.. .O... O.... ..... .....", Positional, true)
+	if err := h.Play(Black, Point{1, 0}); err != nil {
+		t.Fatalf("failed to play suicide: %s", err)
+	}
+	if h.Hash() != before.Hash() {
+		t.Error("expected black group to be removed from the board")
+	}
+}
+
+func TestHashIsTransposable(t *testing.T) {
+	a, _ := New(9, Positional, false)
+	b, _ := New(9, Positional, false)
+
+	for _, m := range []move{{Black, Point{2, 2}, false}, {White, Point{6, 6}, false}, {Black, Point{2, 6}, false}} {
+		if err := a.Play(m.color, m.point); err != nil {
+			t.Fatalf("failed to play: %s", err)
+		}
+	}
+	for _, m := range []move{{Black, Point{2, 6}, false}, {White, Point{6, 6}, false}, {Black, Point{2, 2}, false}} {
+		if err := b.Play(m.color, m.point); err != nil {
+			t.Fatalf("failed to play: %s", err)
+		}
+	}
+	if a.Hash() != b.Hash() {
+		t.Error("expected identical positions reached by different orders to have the same hash")
+	}
+}
+
+func TestSetup(t *testing.T) {
+	h, _ := New(9, Positional, false)
+	if err := h.Setup(Black, Point{2, 2}); err != nil {
+		t.Fatalf("failed to set up stone: %s", err)
+	}
+	if h.Len() != 1 {
+		t.Errorf("Len() = %d, want 1 after setup", h.Len())
+	}
+	if err := h.SetToMove(White); err != nil {
+		t.Fatalf("failed to set player to move: %s", err)
+	}
+	if err := h.Play(White, Point{6, 6}); err != nil {
+		t.Fatalf("failed to play: %s", err)
+	}
+	if err := h.Setup(Black, Point{6, 2}); !errors.Is(err, ErrSetupAfterMoves) {
+		t.Errorf("Setup() error = %v, want %v", err, ErrSetupAfterMoves)
+	}
+}
+
+func TestValidateMove(t *testing.T) {
+	tests := []struct {
+		name    string
+		color   Color
+		point   Point
+		wantKey string
+	}{
+		{"Legal move", Black, Point{2, 2}, ""},
+		{"Off the board", Black, Point{9, 0}, "point"},
+		{"Invalid color", Empty, Point{2, 2}, "color"},
+		{"Occupied", Black, Point{4, 4}, "move"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, _ := New(9, Situational, false)
+			if err := h.Play(Black, Point{4, 4}); err != nil {
+				t.Fatalf("failed to play: %s", err)
+			}
+			v := validator.New()
+			h.ValidateMove(v, tt.color, tt.point)
+			if tt.wantKey == "" {
+				if !v.Valid() {
+					t.Errorf("expected no errors, got %v", v.Errors)
+				}
+				return
+			}
+			if _, ok := v.Errors[tt.wantKey]; !ok || len(v.Errors) != 1 {
+				t.Errorf("Errors = %v, want a single error for %q", v.Errors, tt.wantKey)
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+// Package totp implements time-based one-time passwords as described by RFC 6238, and the encryption of their
+// shared secrets at rest.
+package totp
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1" //nolint:gosec // RFC 6238 authenticator apps only reliably support SHA-1
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+const (
+	// Digits is the length of a code.
+	Digits = 6
+	// Period is how long each code is valid for.
+	Period = 30 * time.Second
+	// Skew is how many periods either side of the current one are accepted, to allow for clock drift.
+	Skew = 1
+	// SecretSize is the length of a generated secret in bytes, as recommended by RFC 4226.
+	SecretSize = 20
+	// KeySize is the length of the key used to encrypt secrets.
+	KeySize = 32
+)
+
+var (
+	// ErrInvalidKey is returned when creating a Sealer with a key of the wrong length.
+	ErrInvalidKey = fmt.Errorf("encryption key must be %d bytes", KeySize)
+	// ErrDecrypt is returned when a sealed secret can't be opened, because it was sealed with another key or
+	// has been tampered with.
+	ErrDecrypt = errors.New("failed to decrypt secret")
+)
+
+// encoding is the unpadded base32 alphabet authenticator apps expect secrets in.
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateSecret returns a new random secret.
+func GenerateSecret() ([]byte, error) {
+	secret := make([]byte, SecretSize)
+	_, err := rand.Read(secret)
+	if err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
+// EncodeSecret returns the base32 form of a secret that users can type into an authenticator app.
+func EncodeSecret(secret []byte) string {
+	return encoding.EncodeToString(secret)
+}
+
+// URI returns the otpauth URI for a secret, which authenticator apps read from a QR code.
+func URI(issuer, account string, secret []byte) string {
+	query := url.Values{}
+	query.Set("secret", EncodeSecret(secret))
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(Digits))
+	query.Set("period", fmt.Sprint(int(Period.Seconds())))
+	u := url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + issuer + ":" + account,
+		RawQuery: query.Encode(),
+	}
+	return u.String()
+}
+
+// Counter returns the number of the period that t falls in.
+func Counter(t time.Time) int64 {
+	return t.Unix() / int64(Period.Seconds())
+}
+
+// Code returns the code for the given period, as defined by HOTP in RFC 4226.
+func Code(secret []byte, counter int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(counter)) //nolint:gosec // counters are never negative
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	modulus := uint32(1)
+	for range Digits {
+		modulus *= 10
+	}
+	return fmt.Sprintf("%0*d", Digits, value%modulus)
+}
+
+// Verify checks a code against the periods around now. It returns the counter of the period the code belongs
+// to, which callers should remember so that the same code can't be used twice.
+func Verify(secret []byte, code string, now time.Time) (int64, bool) {
+	if len(code) != Digits {
+		return 0, false
+	}
+	current := Counter(now)
+	for counter := current - Skew; counter <= current+Skew; counter++ {
+		if subtle.ConstantTimeCompare([]byte(Code(secret, counter)), []byte(code)) == 1 {
+			return counter, true
+		}
+	}
+	return 0, false
+}
+
+// Sealer encrypts secrets with AES-GCM so that they are not stored in the clear.
+type Sealer struct {
+	aead cipher.AEAD
+}
+
+// NewSealer returns a Sealer using the given KeySize byte key.
+func NewSealer(key []byte) (*Sealer, error) {
+	if len(key) != KeySize {
+		return nil, ErrInvalidKey
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Sealer{aead: aead}, nil
+}
+
+// Seal encrypts a secret. The random nonce is prepended to the result.
+func (s *Sealer) Seal(secret []byte) ([]byte, error) {
+	nonce := make([]byte, s.aead.NonceSize())
+	_, err := rand.Read(nonce)
+	if err != nil {
+		return nil, err
+	}
+	return s.aead.Seal(nonce, nonce, secret, nil), nil
+}
+
+// Open decrypts a secret encrypted by Seal.
+func (s *Sealer) Open(sealed []byte) ([]byte, error) {
+	if len(sealed) < s.aead.NonceSize() {
+		return nil, ErrDecrypt
+	}
+	nonce, ciphertext := sealed[:s.aead.NonceSize()], sealed[s.aead.NonceSize():]
+	secret, err := s.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return secret, nil
+}
@@ -0,0 +1,127 @@
+package totp
+
+import (
+	"bytes"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 secret used by the test vectors in RFC 6238 appendix B.
+var rfcSecret = []byte("12345678901234567890")
+
+func TestCode(t *testing.T) {
+	// The RFC lists eight digit codes, of which ours are the last six.
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := Code(rfcSecret, Counter(time.Unix(tt.unix, 0))); got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	now := time.Unix(1111111109, 0)
+	current := Counter(now)
+
+	tests := []struct {
+		name        string
+		code        string
+		wantCounter int64
+		wantOK      bool
+	}{
+		{"Current period", Code(rfcSecret, current), current, true},
+		{"Previous period", Code(rfcSecret, current-1), current - 1, true},
+		{"Next period", Code(rfcSecret, current+1), current + 1, true},
+		{"Too old", Code(rfcSecret, current-2), 0, false},
+		{"Too new", Code(rfcSecret, current+2), 0, false},
+		{"Wrong code", "000000", 0, false},
+		{"Too short", "08180", 0, false},
+		{"Empty", "", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counter, ok := Verify(rfcSecret, tt.code, now)
+			if ok != tt.wantOK || counter != tt.wantCounter {
+				t.Errorf("expected (%d, %t), got (%d, %t)", tt.wantCounter, tt.wantOK, counter, ok)
+			}
+		})
+	}
+}
+
+func TestURI(t *testing.T) {
+	uri := URI("baduk.online", "player@example.com", rfcSecret)
+	u, err := url.Parse(uri)
+	if err != nil {
+		t.Fatalf("failed to parse URI: %s", err)
+	}
+	if u.Scheme != "otpauth" || u.Host != "totp" || u.Path != "/baduk.online:player@example.com" {
+		t.Errorf("unexpected URI %s", uri)
+	}
+	query := u.Query()
+	if got := query.Get("secret"); got != "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ" {
+		t.Errorf("unexpected secret %s", got)
+	}
+	if query.Get("issuer") != "baduk.online" || query.Get("digits") != "6" || query.Get("period") != "30" {
+		t.Errorf("unexpected parameters %v", query)
+	}
+}
+
+func TestSealer(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, KeySize)
+	sealer, err := NewSealer(key)
+	if err != nil {
+		t.Fatalf("failed to create sealer: %s", err)
+	}
+
+	sealed, err := sealer.Seal(rfcSecret)
+	if err != nil {
+		t.Fatalf("failed to seal: %s", err)
+	}
+	if bytes.Contains(sealed, rfcSecret) {
+		t.Errorf("expected the secret to be encrypted")
+	}
+	again, _ := sealer.Seal(rfcSecret)
+	if bytes.Equal(sealed, again) {
+		t.Errorf("expected each seal to use a fresh nonce")
+	}
+
+	opened, err := sealer.Open(sealed)
+	if err != nil {
+		t.Fatalf("failed to open: %s", err)
+	}
+	if !bytes.Equal(opened, rfcSecret) {
+		t.Errorf("expected %q, got %q", rfcSecret, opened)
+	}
+
+	other, _ := NewSealer(bytes.Repeat([]byte{8}, KeySize))
+	if _, err := other.Open(sealed); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("expected ErrDecrypt with the wrong key, got %v", err)
+	}
+	sealed[len(sealed)-1] ^= 1
+	if _, err := sealer.Open(sealed); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("expected ErrDecrypt for tampered data, got %v", err)
+	}
+	if _, err := sealer.Open([]byte{1, 2}); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("expected ErrDecrypt for short data, got %v", err)
+	}
+
+	if _, err := NewSealer([]byte("short")); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("expected ErrInvalidKey, got %v", err)
+	}
+}
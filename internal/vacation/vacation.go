@@ -0,0 +1,119 @@
+// Package vacation keeps track of the time players may take off from their correspondence games.
+//
+// Each player has a budget of vacation time that grows by MonthlyAccrual at the start of every month, up to
+// MaxBudget. While a player is on vacation their game clocks are paused and the budget is used up instead.
+package vacation
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+const (
+	// Day is the unit vacation is counted in.
+	Day = 24 * time.Hour
+	// InitialBudget is the vacation time a new player starts with.
+	InitialBudget = 15 * Day
+	// MonthlyAccrual is the vacation time added to a player's budget each month.
+	MonthlyAccrual = 5 * Day
+	// MaxBudget is the most vacation time that can be saved up.
+	MaxBudget = 30 * Day
+)
+
+// ErrNoVacationLeft is returned when starting a vacation with an empty budget.
+var ErrNoVacationLeft = errors.New("no vacation time left")
+
+// Budget is a player's vacation time. Its methods take the current time rather than reading it so that
+// the caller decides which clock is authoritative.
+type Budget struct {
+	// Remaining is the vacation time left as of Since if the player is on vacation, or AccruedAt otherwise.
+	Remaining time.Duration
+	// Active is true while the player is on vacation.
+	Active bool
+	// Since is when the vacation time used so far was last taken from Remaining.
+	Since time.Time
+	// AccruedAt is the start of the month vacation time was last added in.
+	AccruedAt time.Time
+}
+
+// New returns the budget of a player who joined at now.
+func New(now time.Time) Budget {
+	return Budget{Remaining: InitialBudget, AccruedAt: monthStart(now)}
+}
+
+// Settle brings the budget up to date at now: vacation time is added for each month that has started since
+// it was last accrued, and time spent on vacation is taken away. If the budget runs out, the vacation ends
+// and Settle reports true.
+func (b *Budget) Settle(now time.Time) bool {
+	for next := b.AccruedAt.AddDate(0, 1, 0); !now.Before(next); next = next.AddDate(0, 1, 0) {
+		b.Remaining = min(b.Remaining+MonthlyAccrual, MaxBudget)
+		b.AccruedAt = next
+	}
+
+	if !b.Active {
+		return false
+	}
+	used := max(now.Sub(b.Since), 0)
+	b.Since = now
+	if used < b.Remaining {
+		b.Remaining -= used
+		return false
+	}
+	b.Remaining = 0
+	b.Active = false
+	return true
+}
+
+// Start puts the player on vacation at now. It returns ErrNoVacationLeft if the budget is empty. Starting a
+// vacation that is already running has no effect.
+func (b *Budget) Start(now time.Time) error {
+	b.Settle(now)
+	if b.Active {
+		return nil
+	}
+	if b.Remaining <= 0 {
+		return ErrNoVacationLeft
+	}
+	b.Active = true
+	b.Since = now
+	return nil
+}
+
+// End takes the player off vacation at now, charging them for the time they were away.
+func (b *Budget) End(now time.Time) {
+	b.Settle(now)
+	b.Active = false
+}
+
+// Ends returns when the current vacation will run out if the player doesn't return first. It returns false
+// if the player is not on vacation.
+func (b Budget) Ends() (time.Time, bool) {
+	if !b.Active {
+		return time.Time{}, false
+	}
+	return b.Since.Add(b.Remaining), true
+}
+
+// monthStart returns the start of the month containing t, in UTC.
+func monthStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+type budgetJSON struct {
+	RemainingMS int64     `json:"remaining_ms"`
+	Active      bool      `json:"active"`
+	Since       time.Time `json:"since,omitzero"`
+	AccruedAt   time.Time `json:"accrued_at"`
+}
+
+// MarshalJSON encodes the remaining time in milliseconds.
+func (b Budget) MarshalJSON() ([]byte, error) {
+	return json.Marshal(budgetJSON{
+		RemainingMS: b.Remaining.Milliseconds(),
+		Active:      b.Active,
+		Since:       b.Since,
+		AccruedAt:   b.AccruedAt,
+	})
+}
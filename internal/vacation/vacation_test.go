@@ -0,0 +1,125 @@
+package vacation
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSettle(t *testing.T) {
+	joined := time.Date(2025, 3, 14, 9, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		budget        func() Budget
+		at            time.Time
+		wantRemaining time.Duration
+		wantActive    bool
+		wantEnded     bool
+	}{
+		{
+			name:          "New player",
+			budget:        func() Budget { return New(joined) },
+			at:            joined.Add(Day),
+			wantRemaining: InitialBudget,
+		},
+		{
+			name:          "Accrues at the start of each month",
+			budget:        func() Budget { return New(joined) },
+			at:            time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC),
+			wantRemaining: InitialBudget + MonthlyAccrual,
+		},
+		{
+			name:          "Accrues for every month missed",
+			budget:        func() Budget { return New(joined) },
+			at:            time.Date(2025, 5, 20, 0, 0, 0, 0, time.UTC),
+			wantRemaining: InitialBudget + 2*MonthlyAccrual,
+		},
+		{
+			name:          "Accrual is capped",
+			budget:        func() Budget { return New(joined) },
+			at:            time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+			wantRemaining: MaxBudget,
+		},
+		{
+			name: "Vacation uses up the budget",
+			budget: func() Budget {
+				b := New(joined)
+				b.Active, b.Since = true, joined
+				return b
+			},
+			at:            joined.Add(36 * time.Hour),
+			wantRemaining: InitialBudget - 36*time.Hour,
+			wantActive:    true,
+		},
+		{
+			name: "Vacation ends when the budget runs out",
+			budget: func() Budget {
+				b := New(joined)
+				b.Remaining, b.Active, b.Since = 2*Day, true, joined
+				return b
+			},
+			at:        joined.Add(3 * Day),
+			wantEnded: true,
+		},
+		{
+			name: "Clock stepping backwards costs nothing",
+			budget: func() Budget {
+				b := New(joined)
+				b.Active, b.Since = true, joined
+				return b
+			},
+			at:            joined.Add(-time.Hour),
+			wantRemaining: InitialBudget,
+			wantActive:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := tt.budget()
+			ended := b.Settle(tt.at)
+			if ended != tt.wantEnded {
+				t.Errorf("expected ended %v, got %v", tt.wantEnded, ended)
+			}
+			if b.Remaining != tt.wantRemaining || b.Active != tt.wantActive {
+				t.Errorf("expected %v left and active %v, got %+v", tt.wantRemaining, tt.wantActive, b)
+			}
+		})
+	}
+}
+
+func TestStartAndEnd(t *testing.T) {
+	start := time.Date(2025, 6, 10, 12, 0, 0, 0, time.UTC)
+
+	t.Run("charges for the time away", func(t *testing.T) {
+		b := New(start)
+		if err := b.Start(start); err != nil {
+			t.Fatalf("failed to start vacation: %s", err)
+		}
+		if ends, ok := b.Ends(); !ok || !ends.Equal(start.Add(InitialBudget)) {
+			t.Errorf("unexpected end of vacation: %v %v", ends, ok)
+		}
+		if err := b.Start(start.Add(Day)); err != nil {
+			t.Fatalf("failed to start vacation again: %s", err)
+		}
+		b.End(start.Add(3 * Day))
+		if b.Active || b.Remaining != InitialBudget-3*Day {
+			t.Errorf("unexpected budget after vacation: %+v", b)
+		}
+		if _, ok := b.Ends(); ok {
+			t.Error("expected no end while not on vacation")
+		}
+	})
+
+	t.Run("refuses an empty budget", func(t *testing.T) {
+		b := New(start)
+		b.Remaining = 0
+		if err := b.Start(start); !errors.Is(err, ErrNoVacationLeft) {
+			t.Errorf("expected ErrNoVacationLeft, got %v", err)
+		}
+		if b.Active {
+			t.Error("expected vacation not to start")
+		}
+	})
+}
@@ -0,0 +1,405 @@
+// Package webauthn implements the relying party side of the WebAuthn registration and authentication
+// ceremonies, for logging in with passkeys. Only what passkeys need is supported: attestation statements are
+// not verified, since we don't restrict which authenticators users may register.
+package webauthn
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// ChallengeSize is the length of a generated challenge in bytes.
+	ChallengeSize = 32
+	// Timeout is how long browsers should give the user to complete a ceremony.
+	Timeout = 5 * time.Minute
+	// MaxCredentialIDSize is the longest credential ID the specification allows.
+	MaxCredentialIDSize = 1023
+)
+
+// Authenticator data flags.
+const (
+	FlagUserPresent  = 0x01
+	FlagUserVerified = 0x04
+	FlagAttestedData = 0x40
+	FlagExtensions   = 0x80
+)
+
+var (
+	// ErrInvalidOrigin is returned when creating a RelyingParty with an origin that isn't a bare URL.
+	ErrInvalidOrigin = errors.New("origin must be an http or https URL without a path")
+	// ErrVerification is returned when a ceremony's response doesn't match what we asked for or isn't
+	// signed correctly.
+	ErrVerification = errors.New("webauthn verification failed")
+	// ErrSignCount is returned when an authenticator's signature counter has gone backwards, which suggests
+	// that the credential has been cloned.
+	ErrSignCount = errors.New("signature counter did not increase")
+)
+
+// verificationError wraps ErrVerification with the reason a response was rejected.
+func verificationError(reason string) error {
+	return fmt.Errorf("%w: %s", ErrVerification, reason)
+}
+
+// URLEncoded is binary data that is encoded as unpadded base64url in JSON, as WebAuthn's JSON serialization
+// expects.
+type URLEncoded []byte
+
+// MarshalJSON encodes the data as a base64url string.
+func (u URLEncoded) MarshalJSON() ([]byte, error) {
+	return json.Marshal(base64.RawURLEncoding.EncodeToString(u))
+}
+
+// UnmarshalJSON decodes a base64url string, with or without padding.
+func (u *URLEncoded) UnmarshalJSON(data []byte) error {
+	var s string
+	err := json.Unmarshal(data, &s)
+	if err != nil {
+		return err
+	}
+	decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return err
+	}
+	*u = decoded
+	return nil
+}
+
+// RelyingPartyEntity identifies us to the authenticator.
+type RelyingPartyEntity struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// UserEntity identifies the account a credential is registered for.
+type UserEntity struct {
+	ID          URLEncoded `json:"id"`
+	Name        string     `json:"name"`
+	DisplayName string     `json:"displayName"`
+}
+
+// CredentialParameters names a public key algorithm we accept.
+type CredentialParameters struct {
+	Type string `json:"type"`
+	Alg  int    `json:"alg"`
+}
+
+// CredentialDescriptor refers to an existing credential.
+type CredentialDescriptor struct {
+	Type string     `json:"type"`
+	ID   URLEncoded `json:"id"`
+}
+
+// AuthenticatorSelection states what kind of authenticator we want.
+type AuthenticatorSelection struct {
+	ResidentKey      string `json:"residentKey"`
+	UserVerification string `json:"userVerification"`
+}
+
+// CreationOptions are passed to navigator.credentials.create to register a credential.
+type CreationOptions struct {
+	Challenge              URLEncoded             `json:"challenge"`
+	RelyingParty           RelyingPartyEntity     `json:"rp"`
+	User                   UserEntity             `json:"user"`
+	PubKeyCredParams       []CredentialParameters `json:"pubKeyCredParams"`
+	Timeout                int64                  `json:"timeout"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection AuthenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                 `json:"attestation"`
+}
+
+// RequestOptions are passed to navigator.credentials.get to log in with a credential.
+type RequestOptions struct {
+	Challenge        URLEncoded             `json:"challenge"`
+	Timeout          int64                  `json:"timeout"`
+	RelyingPartyID   string                 `json:"rpId"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                 `json:"userVerification"`
+}
+
+// AttestationResponse is the credential returned by navigator.credentials.create.
+type AttestationResponse struct {
+	ID       string     `json:"id"`
+	RawID    URLEncoded `json:"rawId"`
+	Type     string     `json:"type"`
+	Response struct {
+		ClientDataJSON    URLEncoded `json:"clientDataJSON"`
+		AttestationObject URLEncoded `json:"attestationObject"`
+	} `json:"response"`
+}
+
+// AssertionResponse is the credential returned by navigator.credentials.get.
+type AssertionResponse struct {
+	ID       string     `json:"id"`
+	RawID    URLEncoded `json:"rawId"`
+	Type     string     `json:"type"`
+	Response struct {
+		ClientDataJSON    URLEncoded `json:"clientDataJSON"`
+		AuthenticatorData URLEncoded `json:"authenticatorData"`
+		Signature         URLEncoded `json:"signature"`
+		UserHandle        URLEncoded `json:"userHandle"`
+	} `json:"response"`
+}
+
+// Credential is a registered public key credential.
+type Credential struct {
+	ID        []byte
+	PublicKey []byte
+	SignCount uint32
+}
+
+// RelyingParty verifies ceremonies for a single origin.
+type RelyingParty struct {
+	ID     string
+	Name   string
+	Origin string
+}
+
+// NewRelyingParty returns a RelyingParty for the given origin, such as "https://baduk.online". The relying
+// party ID is the origin's host.
+func NewRelyingParty(name, origin string) (*RelyingParty, error) {
+	u, err := url.Parse(origin)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Hostname() == "" ||
+		(u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" {
+		return nil, ErrInvalidOrigin
+	}
+	return &RelyingParty{
+		ID:     u.Hostname(),
+		Name:   name,
+		Origin: u.Scheme + "://" + u.Host,
+	}, nil
+}
+
+// NewChallenge returns a new random challenge. Each challenge must only be accepted once.
+func NewChallenge() ([]byte, error) {
+	challenge := make([]byte, ChallengeSize)
+	_, err := rand.Read(challenge)
+	if err != nil {
+		return nil, err
+	}
+	return challenge, nil
+}
+
+// CreationOptions returns the options for registering a new discoverable credential for user. Credentials
+// in exclude are already registered, and the authenticator won't register them twice.
+func (rp *RelyingParty) CreationOptions(challenge []byte, user UserEntity, exclude [][]byte) *CreationOptions {
+	return &CreationOptions{
+		Challenge:    challenge,
+		RelyingParty: RelyingPartyEntity{ID: rp.ID, Name: rp.Name},
+		User:         user,
+		PubKeyCredParams: []CredentialParameters{
+			{Type: "public-key", Alg: AlgES256},
+			{Type: "public-key", Alg: AlgEdDSA},
+			{Type: "public-key", Alg: AlgRS256},
+		},
+		Timeout:            Timeout.Milliseconds(),
+		ExcludeCredentials: descriptors(exclude),
+		AuthenticatorSelection: AuthenticatorSelection{
+			ResidentKey:      "required",
+			UserVerification: "required",
+		},
+		Attestation: "none",
+	}
+}
+
+// RequestOptions returns the options for logging in. With no allowed credentials, the user picks any of
+// their passkeys for this relying party.
+func (rp *RelyingParty) RequestOptions(challenge []byte, allow [][]byte) *RequestOptions {
+	return &RequestOptions{
+		Challenge:        challenge,
+		Timeout:          Timeout.Milliseconds(),
+		RelyingPartyID:   rp.ID,
+		AllowCredentials: descriptors(allow),
+		UserVerification: "required",
+	}
+}
+
+func descriptors(ids [][]byte) []CredentialDescriptor {
+	list := make([]CredentialDescriptor, 0, len(ids))
+	for _, id := range ids {
+		list = append(list, CredentialDescriptor{Type: "public-key", ID: id})
+	}
+	return list
+}
+
+// clientData is the JSON the browser signs over, recording which ceremony it was for.
+type clientData struct {
+	Type        string     `json:"type"`
+	Challenge   URLEncoded `json:"challenge"`
+	Origin      string     `json:"origin"`
+	CrossOrigin bool       `json:"crossOrigin"`
+}
+
+// verifyClientData checks that the browser performed the ceremony we asked for, on our origin.
+func (rp *RelyingParty) verifyClientData(raw []byte, ceremony string, challenge []byte) error {
+	var cd clientData
+	err := json.Unmarshal(raw, &cd)
+	if err != nil {
+		return verificationError("malformed client data")
+	}
+	if cd.Type != ceremony {
+		return verificationError("wrong ceremony type")
+	}
+	if len(challenge) == 0 || subtle.ConstantTimeCompare(cd.Challenge, challenge) != 1 {
+		return verificationError("wrong challenge")
+	}
+	if cd.Origin != rp.Origin || cd.CrossOrigin {
+		return verificationError("wrong origin")
+	}
+	return nil
+}
+
+// authenticatorData is the data an authenticator signs over.
+type authenticatorData struct {
+	flags        byte
+	signCount    uint32
+	credentialID []byte
+	publicKey    []byte
+}
+
+// parseAuthenticatorData parses authenticator data and checks that it was made for us, with the user present
+// and verified.
+func (rp *RelyingParty) parseAuthenticatorData(raw []byte) (*authenticatorData, error) {
+	if len(raw) < 37 {
+		return nil, verificationError("authenticator data too short")
+	}
+	rpIDHash := sha256.Sum256([]byte(rp.ID))
+	if !bytes.Equal(raw[:32], rpIDHash[:]) {
+		return nil, verificationError("wrong relying party")
+	}
+	ad := &authenticatorData{
+		flags:     raw[32],
+		signCount: binary.BigEndian.Uint32(raw[33:37]),
+	}
+	if ad.flags&FlagUserPresent == 0 {
+		return nil, verificationError("user not present")
+	}
+	if ad.flags&FlagUserVerified == 0 {
+		return nil, verificationError("user not verified")
+	}
+
+	rest := raw[37:]
+	if ad.flags&FlagAttestedData != 0 {
+		// The AAGUID identifies the authenticator model, which we don't use.
+		if len(rest) < 18 {
+			return nil, verificationError("attested credential data too short")
+		}
+		idLen := int(binary.BigEndian.Uint16(rest[16:18]))
+		rest = rest[18:]
+		if idLen > MaxCredentialIDSize || len(rest) < idLen {
+			return nil, verificationError("invalid credential ID")
+		}
+		ad.credentialID, rest = rest[:idLen], rest[idLen:]
+
+		_, after, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, verificationError("malformed public key")
+		}
+		ad.publicKey, rest = rest[:len(rest)-len(after)], after
+	}
+	if ad.flags&FlagExtensions != 0 {
+		_, after, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, verificationError("malformed extensions")
+		}
+		rest = after
+	}
+	if len(rest) != 0 {
+		return nil, verificationError("trailing authenticator data")
+	}
+	return ad, nil
+}
+
+// VerifyRegistration checks the response to a registration ceremony started with challenge, returning the
+// new credential.
+func (rp *RelyingParty) VerifyRegistration(challenge []byte, resp *AttestationResponse) (*Credential, error) {
+	if resp.Type != "public-key" {
+		return nil, verificationError("wrong credential type")
+	}
+	err := rp.verifyClientData(resp.Response.ClientDataJSON, "webauthn.create", challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	item, rest, err := decodeCBOR(resp.Response.AttestationObject)
+	if err != nil || len(rest) != 0 {
+		return nil, verificationError("malformed attestation object")
+	}
+	attestation, _ := item.(map[any]any)
+	format, _ := attestation["fmt"].(string)
+	statement, _ := attestation["attStmt"].(map[any]any)
+	rawAuthData, _ := attestation["authData"].([]byte)
+	if format == "" || statement == nil || rawAuthData == nil {
+		return nil, verificationError("malformed attestation object")
+	}
+	if format == "none" && len(statement) != 0 {
+		return nil, verificationError("unexpected attestation statement")
+	}
+
+	ad, err := rp.parseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return nil, err
+	}
+	if ad.credentialID == nil {
+		return nil, verificationError("missing attested credential data")
+	}
+	if !bytes.Equal(ad.credentialID, resp.RawID) {
+		return nil, verificationError("credential ID mismatch")
+	}
+	_, err = parsePublicKey(ad.publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Credential{
+		ID:        ad.credentialID,
+		PublicKey: ad.publicKey,
+		SignCount: ad.signCount,
+	}, nil
+}
+
+// VerifyAssertion checks the response to an authentication ceremony started with challenge, made with
+// cred. It returns the authenticator's new signature count, which must be stored for the next login.
+func (rp *RelyingParty) VerifyAssertion(challenge []byte, resp *AssertionResponse, cred *Credential) (uint32, error) {
+	if resp.Type != "public-key" {
+		return 0, verificationError("wrong credential type")
+	}
+	if !bytes.Equal(resp.RawID, cred.ID) {
+		return 0, verificationError("credential ID mismatch")
+	}
+	err := rp.verifyClientData(resp.Response.ClientDataJSON, "webauthn.get", challenge)
+	if err != nil {
+		return 0, err
+	}
+	ad, err := rp.parseAuthenticatorData(resp.Response.AuthenticatorData)
+	if err != nil {
+		return 0, err
+	}
+
+	key, err := parsePublicKey(cred.PublicKey)
+	if err != nil {
+		return 0, err
+	}
+	clientDataHash := sha256.Sum256(resp.Response.ClientDataJSON)
+	signed := append(append([]byte{}, resp.Response.AuthenticatorData...), clientDataHash[:]...)
+	if !key.verify(signed, resp.Response.Signature) {
+		return 0, verificationError("invalid signature")
+	}
+
+	// Authenticators that don't keep a counter always report zero. Otherwise the counter must go up with
+	// every signature, or two copies of the credential exist.
+	if (ad.signCount != 0 || cred.SignCount != 0) && ad.signCount <= cred.SignCount {
+		return 0, ErrSignCount
+	}
+	return ad.signCount, nil
+}
@@ -0,0 +1,171 @@
+package webauthn
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestDecodeCBOR(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want any
+	}{
+		{"Small integer", []byte{0x0a}, int64(10)},
+		{"Two byte integer", []byte{0x19, 0x03, 0xe8}, int64(1000)},
+		{"Negative integer", []byte{0x38, 0x63}, int64(-100)},
+		{"Byte string", []byte{0x43, 1, 2, 3}, []byte{1, 2, 3}},
+		{"Text", []byte{0x63, 'f', 'm', 't'}, "fmt"},
+		{"True", []byte{0xf5}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, rest, err := decodeCBOR(append(tt.data, 0xff))
+			if err != nil {
+				t.Fatalf("failed to decode: %s", err)
+			}
+			if !bytes.Equal(rest, []byte{0xff}) {
+				t.Errorf("expected the following bytes to be returned, got %v", rest)
+			}
+			if b, ok := tt.want.([]byte); ok {
+				if !bytes.Equal(got.([]byte), b) {
+					t.Errorf("expected %v, got %v", tt.want, got)
+				}
+			} else if got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	t.Run("Map", func(t *testing.T) {
+		// {1: 2, "a": [-1]}
+		got, _, err := decodeCBOR([]byte{0xa2, 0x01, 0x02, 0x61, 'a', 0x81, 0x20})
+		if err != nil {
+			t.Fatalf("failed to decode: %s", err)
+		}
+		m := got.(map[any]any)
+		if m[int64(1)] != int64(2) || m["a"].([]any)[0] != int64(-1) {
+			t.Errorf("unexpected map %v", m)
+		}
+	})
+
+	invalid := []struct {
+		name string
+		data []byte
+	}{
+		{"Empty", nil},
+		{"Truncated argument", []byte{0x19, 0x03}},
+		{"Truncated string", []byte{0x43, 1, 2}},
+		{"Indefinite length", []byte{0x5f, 0x41, 1, 0xff}},
+		{"Float", []byte{0xf9, 0x3c, 0x00}},
+		{"Huge array", []byte{0x9b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{"Duplicate key", []byte{0xa2, 0x01, 0x02, 0x01, 0x03}},
+		{"Array key", []byte{0xa1, 0x80, 0x01}},
+		{"Too deep", bytes.Repeat([]byte{0x81}, maxCBORDepth+2)},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := decodeCBOR(tt.data); !errors.Is(err, errCBOR) {
+				t.Errorf("expected errCBOR, got %v", err)
+			}
+		})
+	}
+}
+
+func TestParsePublicKey(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		// {1: 2, 3: -7, -1: 1, -2: h'00…', -3: h'00…'}, which is not a point on the curve.
+		{"Invalid point", append(append([]byte{0xa5, 0x01, 0x02, 0x03, 0x26, 0x20, 0x01, 0x21, 0x58, 0x20},
+			make([]byte, 32)...), append([]byte{0x22, 0x58, 0x20}, make([]byte, 32)...)...)},
+		// {1: 2, 3: -36}, ES512.
+		{"Unsupported algorithm", []byte{0xa2, 0x01, 0x02, 0x03, 0x38, 0x23}},
+		// {1: 1, 3: -8, -1: 6, -2: h'0102'}, an Ed25519 key that is too short.
+		{"Short Ed25519 key", []byte{0xa4, 0x01, 0x01, 0x03, 0x27, 0x20, 0x06, 0x21, 0x42, 0x01, 0x02}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parsePublicKey(tt.data); !errors.Is(err, ErrUnsupportedKey) {
+				t.Errorf("expected ErrUnsupportedKey, got %v", err)
+			}
+		})
+	}
+}
+
+func TestNewRelyingParty(t *testing.T) {
+	rp, err := NewRelyingParty("baduk.online", "https://baduk.online:8443/")
+	if err != nil {
+		t.Fatalf("failed to create relying party: %s", err)
+	}
+	if rp.ID != "baduk.online" || rp.Origin != "https://baduk.online:8443" {
+		t.Errorf("unexpected relying party %+v", rp)
+	}
+
+	for _, origin := range []string{"", "baduk.online", "ftp://baduk.online", "https://baduk.online/login"} {
+		if _, err := NewRelyingParty("baduk.online", origin); !errors.Is(err, ErrInvalidOrigin) {
+			t.Errorf("expected ErrInvalidOrigin for %q, got %v", origin, err)
+		}
+	}
+}
+
+func TestParseAuthenticatorData(t *testing.T) {
+	rp := &RelyingParty{ID: "baduk.online", Origin: "https://baduk.online"}
+	rpIDHash := sha256.Sum256([]byte(rp.ID))
+	otherHash := sha256.Sum256([]byte("example.com"))
+	authData := func(hash [32]byte, flags byte, extra ...byte) []byte {
+		data := append(append([]byte{}, hash[:]...), flags, 0, 0, 0, 7)
+		return append(data, extra...)
+	}
+
+	ad, err := rp.parseAuthenticatorData(authData(rpIDHash, FlagUserPresent|FlagUserVerified))
+	if err != nil {
+		t.Fatalf("failed to parse: %s", err)
+	}
+	if ad.signCount != 7 || ad.credentialID != nil {
+		t.Errorf("unexpected authenticator data %+v", ad)
+	}
+
+	invalid := []struct {
+		name string
+		data []byte
+	}{
+		{"Too short", rpIDHash[:]},
+		{"Other relying party", authData(otherHash, FlagUserPresent|FlagUserVerified)},
+		{"User not present", authData(rpIDHash, FlagUserVerified)},
+		{"User not verified", authData(rpIDHash, FlagUserPresent)},
+		{"Trailing data", authData(rpIDHash, FlagUserPresent|FlagUserVerified, 0)},
+		{"Truncated credential", authData(rpIDHash, FlagUserPresent|FlagUserVerified|FlagAttestedData, 0, 1)},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := rp.parseAuthenticatorData(tt.data); !errors.Is(err, ErrVerification) {
+				t.Errorf("expected ErrVerification, got %v", err)
+			}
+		})
+	}
+}
+
+func TestURLEncoded(t *testing.T) {
+	encoded, err := json.Marshal(URLEncoded{0xfb, 0xff})
+	if err != nil {
+		t.Fatalf("failed to marshal: %s", err)
+	}
+	if string(encoded) != `"-_8"` {
+		t.Errorf("expected unpadded base64url, got %s", encoded)
+	}
+
+	for _, s := range []string{`"-_8"`, `"-_8="`} {
+		var decoded URLEncoded
+		if err := json.Unmarshal([]byte(s), &decoded); err != nil {
+			t.Fatalf("failed to unmarshal %s: %s", s, err)
+		}
+		if !bytes.Equal(decoded, []byte{0xfb, 0xff}) {
+			t.Errorf("unexpected value %v for %s", decoded, s)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+// Package webauthntest provides a software authenticator for testing WebAuthn ceremonies without hardware.
+package webauthntest
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"sync"
+
+	"github.com/hazzardr/baduk-online/internal/webauthn"
+)
+
+// ErrNoCredential is returned when the authenticator holds no credential that the options allow.
+var ErrNoCredential = errors.New("no matching credential")
+
+// credential is a key pair held by the authenticator.
+type credential struct {
+	id         []byte
+	rpID       string
+	userHandle []byte
+	key        *ecdsa.PrivateKey
+	signCount  uint32
+}
+
+// Authenticator is a software passkey provider that behaves like a browser and platform authenticator
+// together. It makes discoverable ES256 credentials and always reports the user as present and verified.
+type Authenticator struct {
+	// Origin is reported in client data as the origin of the page running the ceremony.
+	Origin string
+
+	mu          sync.Mutex
+	credentials map[string]*credential
+}
+
+// NewAuthenticator returns an authenticator with no credentials, running ceremonies from origin.
+func NewAuthenticator(origin string) *Authenticator {
+	return &Authenticator{
+		Origin:      origin,
+		credentials: make(map[string]*credential),
+	}
+}
+
+// Clone returns an authenticator holding copies of the same credentials, as if its keys had been extracted.
+func (a *Authenticator) Clone() *Authenticator {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	clone := &Authenticator{Origin: a.Origin, credentials: make(map[string]*credential, len(a.credentials))}
+	for id, cred := range a.credentials {
+		copied := *cred
+		clone.credentials[id] = &copied
+	}
+	return clone
+}
+
+// Create performs a registration ceremony, as navigator.credentials.create would.
+func (a *Authenticator) Create(options *webauthn.CreationOptions) (*webauthn.AttestationResponse, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for _, excluded := range options.ExcludeCredentials {
+		if _, ok := a.credentials[string(excluded.ID)]; ok {
+			return nil, errors.New("credential already registered")
+		}
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	id := make([]byte, 16)
+	_, err = rand.Read(id)
+	if err != nil {
+		return nil, err
+	}
+	cred := &credential{
+		id:         id,
+		rpID:       options.RelyingParty.ID,
+		userHandle: options.User.ID,
+		key:        key,
+	}
+
+	point, err := key.PublicKey.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	publicKey := encodeCBOR(map[int64]any{
+		1:  int64(2),                 // kty: EC2
+		3:  int64(webauthn.AlgES256), // alg
+		-1: int64(1),                 // crv: P-256
+		-2: point[1:33],              // x
+		-3: point[33:],               // y
+	})
+
+	flags := byte(webauthn.FlagUserPresent | webauthn.FlagUserVerified | webauthn.FlagAttestedData)
+	authData := cred.authenticatorData(flags)
+	authData = append(authData, make([]byte, 16)...) // AAGUID
+	authData = binary.BigEndian.AppendUint16(authData, uint16(len(id)))
+	authData = append(authData, id...)
+	authData = append(authData, publicKey...)
+
+	clientDataJSON, err := a.clientData("webauthn.create", options.Challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &webauthn.AttestationResponse{
+		ID:    base64.RawURLEncoding.EncodeToString(id),
+		RawID: id,
+		Type:  "public-key",
+	}
+	resp.Response.ClientDataJSON = clientDataJSON
+	resp.Response.AttestationObject = encodeCBOR(map[string]any{
+		"fmt":      "none",
+		"attStmt":  map[string]any{},
+		"authData": authData,
+	})
+	a.credentials[string(id)] = cred
+	return resp, nil
+}
+
+// Get performs an authentication ceremony, as navigator.credentials.get would. If the options allow any
+// credential, the one with the lowest ID for the relying party is used.
+func (a *Authenticator) Get(options *webauthn.RequestOptions) (*webauthn.AssertionResponse, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	cred := a.find(options)
+	if cred == nil {
+		return nil, ErrNoCredential
+	}
+	cred.signCount++
+
+	authData := cred.authenticatorData(webauthn.FlagUserPresent | webauthn.FlagUserVerified)
+	clientDataJSON, err := a.clientData("webauthn.get", options.Challenge)
+	if err != nil {
+		return nil, err
+	}
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, cred.key, digest[:])
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &webauthn.AssertionResponse{
+		ID:    base64.RawURLEncoding.EncodeToString(cred.id),
+		RawID: cred.id,
+		Type:  "public-key",
+	}
+	resp.Response.ClientDataJSON = clientDataJSON
+	resp.Response.AuthenticatorData = authData
+	resp.Response.Signature = signature
+	resp.Response.UserHandle = cred.userHandle
+	return resp, nil
+}
+
+// find returns the credential to use for options, or nil if there is none.
+func (a *Authenticator) find(options *webauthn.RequestOptions) *credential {
+	if len(options.AllowCredentials) > 0 {
+		for _, allowed := range options.AllowCredentials {
+			cred, ok := a.credentials[string(allowed.ID)]
+			if ok && cred.rpID == options.RelyingPartyID {
+				return cred
+			}
+		}
+		return nil
+	}
+	// Map order is random, so pick deterministically.
+	var found *credential
+	for _, cred := range a.credentials {
+		if cred.rpID != options.RelyingPartyID {
+			continue
+		}
+		if found == nil || bytes.Compare(cred.id, found.id) < 0 {
+			found = cred
+		}
+	}
+	return found
+}
+
+// authenticatorData returns the start of the authenticator data for the credential, up to the signature
+// counter.
+func (c *credential) authenticatorData(flags byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(c.rpID))
+	data := append([]byte{}, rpIDHash[:]...)
+	data = append(data, flags)
+	return binary.BigEndian.AppendUint32(data, c.signCount)
+}
+
+func (a *Authenticator) clientData(ceremony string, challenge []byte) ([]byte, error) {
+	return json.Marshal(map[string]any{
+		"type":        ceremony,
+		"challenge":   base64.RawURLEncoding.EncodeToString(challenge),
+		"origin":      a.Origin,
+		"crossOrigin": false,
+	})
+}
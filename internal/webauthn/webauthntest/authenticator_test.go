@@ -0,0 +1,143 @@
+package webauthntest
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hazzardr/baduk-online/internal/webauthn"
+)
+
+func TestCeremonies(t *testing.T) {
+	rp, err := webauthn.NewRelyingParty("baduk.online", "https://baduk.online")
+	if err != nil {
+		t.Fatalf("failed to create relying party: %s", err)
+	}
+	user := webauthn.UserEntity{ID: []byte{0, 0, 0, 0, 0, 0, 0, 1}, Name: "player@example.com", DisplayName: "Player"}
+
+	register := func(t *testing.T, authenticator *Authenticator) *webauthn.Credential {
+		t.Helper()
+		challenge, _ := webauthn.NewChallenge()
+		resp, err := authenticator.Create(rp.CreationOptions(challenge, user, nil))
+		if err != nil {
+			t.Fatalf("failed to create credential: %s", err)
+		}
+		cred, err := rp.VerifyRegistration(challenge, resp)
+		if err != nil {
+			t.Fatalf("failed to verify registration: %s", err)
+		}
+		return cred
+	}
+	login := func(t *testing.T, authenticator *Authenticator, cred *webauthn.Credential) (uint32, error) {
+		t.Helper()
+		challenge, _ := webauthn.NewChallenge()
+		resp, err := authenticator.Get(rp.RequestOptions(challenge, nil))
+		if err != nil {
+			t.Fatalf("failed to get assertion: %s", err)
+		}
+		return rp.VerifyAssertion(challenge, resp, cred)
+	}
+
+	t.Run("Register and log in", func(t *testing.T) {
+		authenticator := NewAuthenticator(rp.Origin)
+		cred := register(t, authenticator)
+		if cred.SignCount != 0 || len(cred.ID) == 0 {
+			t.Errorf("unexpected credential %+v", cred)
+		}
+		for want := uint32(1); want <= 2; want++ {
+			count, err := login(t, authenticator, cred)
+			if err != nil {
+				t.Fatalf("failed to verify assertion: %s", err)
+			}
+			if count != want {
+				t.Errorf("expected sign count %d, got %d", want, count)
+			}
+			cred.SignCount = count
+		}
+	})
+
+	t.Run("Registration checks the ceremony", func(t *testing.T) {
+		challenge, _ := webauthn.NewChallenge()
+		other, _ := webauthn.NewChallenge()
+		otherRP, _ := webauthn.NewRelyingParty("evil", "https://evil.example")
+
+		tests := []struct {
+			name          string
+			origin        string
+			options       *webauthn.CreationOptions
+			wantChallenge []byte
+		}{
+			{"Wrong challenge", rp.Origin, rp.CreationOptions(challenge, user, nil), other},
+			{"Wrong origin", "https://evil.example", rp.CreationOptions(challenge, user, nil), challenge},
+			{"Wrong relying party", rp.Origin, otherRP.CreationOptions(challenge, user, nil), challenge},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				resp, err := NewAuthenticator(tt.origin).Create(tt.options)
+				if err != nil {
+					t.Fatalf("failed to create credential: %s", err)
+				}
+				if _, err := rp.VerifyRegistration(tt.wantChallenge, resp); !errors.Is(err, webauthn.ErrVerification) {
+					t.Errorf("expected ErrVerification, got %v", err)
+				}
+			})
+		}
+	})
+
+	t.Run("Excluded credentials aren't registered again", func(t *testing.T) {
+		authenticator := NewAuthenticator(rp.Origin)
+		cred := register(t, authenticator)
+		challenge, _ := webauthn.NewChallenge()
+		if _, err := authenticator.Create(rp.CreationOptions(challenge, user, [][]byte{cred.ID})); err == nil {
+			t.Errorf("expected the authenticator to refuse")
+		}
+	})
+
+	t.Run("Assertions check the ceremony", func(t *testing.T) {
+		authenticator := NewAuthenticator(rp.Origin)
+		cred := register(t, authenticator)
+
+		challenge, _ := webauthn.NewChallenge()
+		resp, err := authenticator.Get(rp.RequestOptions(challenge, [][]byte{cred.ID}))
+		if err != nil {
+			t.Fatalf("failed to get assertion: %s", err)
+		}
+		other, _ := webauthn.NewChallenge()
+		if _, err := rp.VerifyAssertion(other, resp, cred); !errors.Is(err, webauthn.ErrVerification) {
+			t.Errorf("expected a wrong challenge to fail, got %v", err)
+		}
+
+		resp.Response.Signature[len(resp.Response.Signature)-1] ^= 1
+		if _, err := rp.VerifyAssertion(challenge, resp, cred); !errors.Is(err, webauthn.ErrVerification) {
+			t.Errorf("expected a bad signature to fail, got %v", err)
+		}
+
+		otherCred := register(t, NewAuthenticator(rp.Origin))
+		resp, err = authenticator.Get(rp.RequestOptions(challenge, [][]byte{cred.ID}))
+		if err != nil {
+			t.Fatalf("failed to get assertion: %s", err)
+		}
+		if _, err := rp.VerifyAssertion(challenge, resp, otherCred); !errors.Is(err, webauthn.ErrVerification) {
+			t.Errorf("expected another credential to fail, got %v", err)
+		}
+
+		_, err = authenticator.Get(rp.RequestOptions(challenge, [][]byte{otherCred.ID}))
+		if !errors.Is(err, ErrNoCredential) {
+			t.Errorf("expected ErrNoCredential, got %v", err)
+		}
+	})
+
+	t.Run("Cloned credentials are detected", func(t *testing.T) {
+		authenticator := NewAuthenticator(rp.Origin)
+		cred := register(t, authenticator)
+		clone := authenticator.Clone()
+
+		count, err := login(t, authenticator, cred)
+		if err != nil {
+			t.Fatalf("failed to verify assertion: %s", err)
+		}
+		cred.SignCount = count
+		if _, err := login(t, clone, cred); !errors.Is(err, webauthn.ErrSignCount) {
+			t.Errorf("expected ErrSignCount, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,53 @@
+package webauthntest
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// encodeCBOR encodes the subset of CBOR that authenticators produce: integers, byte and text strings, and
+// maps keyed by integers or text.
+func encodeCBOR(v any) []byte {
+	switch v := v.(type) {
+	case int64:
+		if v < 0 {
+			return cborHead(1, uint64(-1-v))
+		}
+		return cborHead(0, uint64(v))
+	case []byte:
+		return append(cborHead(2, uint64(len(v))), v...)
+	case string:
+		return append(cborHead(3, uint64(len(v))), v...)
+	case map[int64]any:
+		out := cborHead(5, uint64(len(v)))
+		for key, value := range v {
+			out = append(out, encodeCBOR(key)...)
+			out = append(out, encodeCBOR(value)...)
+		}
+		return out
+	case map[string]any:
+		out := cborHead(5, uint64(len(v)))
+		for key, value := range v {
+			out = append(out, encodeCBOR(key)...)
+			out = append(out, encodeCBOR(value)...)
+		}
+		return out
+	}
+	panic(fmt.Sprintf("webauthntest: can't encode %T as CBOR", v))
+}
+
+// cborHead encodes the initial bytes of an item with the given major type and argument.
+func cborHead(major byte, arg uint64) []byte {
+	major <<= 5
+	switch {
+	case arg < 24:
+		return []byte{major | byte(arg)}
+	case arg <= 0xff:
+		return []byte{major | 24, byte(arg)}
+	case arg <= 0xffff:
+		return binary.BigEndian.AppendUint16([]byte{major | 25}, uint16(arg))
+	case arg <= 0xffffffff:
+		return binary.BigEndian.AppendUint32([]byte{major | 26}, uint32(arg))
+	}
+	return binary.BigEndian.AppendUint64([]byte{major | 27}, arg)
+}
@@ -4,12 +4,15 @@ import (
 	"context"
 	"database/sql"
 	"embed"
+	"encoding/base64"
+	"errors"
 	"flag"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
@@ -18,8 +21,11 @@ import (
 
 	"github.com/charmbracelet/log"
 	"github.com/hazzardr/baduk-online/cmd/api"
+	"github.com/hazzardr/baduk-online/internal/bot"
 	"github.com/hazzardr/baduk-online/internal/data"
+	"github.com/hazzardr/baduk-online/internal/gtp"
 	"github.com/hazzardr/baduk-online/internal/mail"
+	"github.com/hazzardr/baduk-online/internal/oidc"
 )
 
 const version = "0.1.0"
@@ -33,6 +39,20 @@ type config struct {
 	logFmt  string
 	dsn     string
 	migrate bool
+	gtp     struct {
+		engine  string
+		timeout time.Duration
+	}
+	botSeed uint64
+	totpKey string
+	origin  string
+	oidc    struct {
+		issuer       string
+		clientID     string
+		clientSecret string
+		redirectURL  string
+		name         string
+	}
 }
 
 func main() {
@@ -43,6 +63,17 @@ func main() {
 	flag.StringVar(&cfg.logFmt, "logFmt", "text", "Log format (text|json)")
 	flag.StringVar(&cfg.dsn, "dsn", os.Getenv("POSTGRES_URL"), "Database URL")
 	flag.BoolVar(&cfg.migrate, "migrate", false, "Run database migrations and exit")
+	flag.StringVar(&cfg.gtp.engine, "gtp-engine", "", "GTP engine command for bot accounts, e.g. \"gnugo --mode gtp\"")
+	flag.DurationVar(&cfg.gtp.timeout, "gtp-timeout", gtp.DefaultTimeout, "Timeout for each GTP engine command")
+	flag.Uint64Var(&cfg.botSeed, "bot-seed", 1, "Seed for the built in bot, used when no GTP engine is given")
+	flag.StringVar(&cfg.totpKey, "totp-key", os.Getenv("TOTP_KEY"), "Base64 key encrypting two-factor secrets")
+	flag.StringVar(&cfg.origin, "origin", os.Getenv("ORIGIN"), "Origin the frontend is served from, for passkeys")
+	flag.StringVar(&cfg.oidc.issuer, "oidc-issuer", "", "OpenID Connect provider issuer URL for external logins")
+	flag.StringVar(&cfg.oidc.clientID, "oidc-client-id", "", "OpenID Connect client ID")
+	flag.StringVar(&cfg.oidc.clientSecret, "oidc-client-secret", os.Getenv("OIDC_CLIENT_SECRET"),
+		"OpenID Connect client secret")
+	flag.StringVar(&cfg.oidc.redirectURL, "oidc-redirect-url", "", "URL the provider sends users back to after logging in")
+	flag.StringVar(&cfg.oidc.name, "oidc-name", "the identity provider", "Provider name shown to users")
 
 	flag.Parse()
 
@@ -86,6 +117,63 @@ func main() {
 		os.Exit(1)
 	}
 	api := api.NewAPI(cfg.env, version, db, mailer)
+	if cfg.totpKey != "" {
+		key, err := base64.StdEncoding.DecodeString(cfg.totpKey)
+		if err == nil {
+			err = api.SetTOTPKey(key)
+		}
+		if err != nil {
+			slog.Error("invalid TOTP key", "err", err)
+			os.Exit(1)
+		}
+	} else {
+		slog.Warn("no TOTP key given, two-factor authentication is disabled")
+	}
+	if cfg.origin != "" {
+		err = api.SetWebAuthnOrigin(cfg.origin)
+		if err != nil {
+			slog.Error("invalid origin", "err", err)
+			os.Exit(1)
+		}
+	} else {
+		slog.Warn("no origin given, passkeys are disabled")
+	}
+	if cfg.oidc.issuer != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), oidc.DefaultTimeout)
+		provider, err := oidc.Discover(ctx, oidc.Config{
+			Issuer:       cfg.oidc.issuer,
+			ClientID:     cfg.oidc.clientID,
+			ClientSecret: cfg.oidc.clientSecret,
+			RedirectURL:  cfg.oidc.redirectURL,
+			Scopes:       []string{"email", "profile"},
+		})
+		cancel()
+		if err != nil {
+			slog.Error("failed to discover OpenID Connect provider", "err", err)
+			os.Exit(1)
+		}
+		api.SetOIDCProvider(provider, cfg.oidc.name)
+	}
+	api.StartTimeoutSweeper(time.Second)
+	api.StartMatchmaker(2 * time.Second)
+	api.StartVacationSweeper(time.Minute)
+	api.StartReminderScheduler(time.Minute)
+	api.StartAccountPurger(time.Hour)
+	api.StartSessionSweeper(time.Hour)
+	api.StartTopicExpirer(10 * time.Minute)
+
+	var engine *gtp.Client
+	if cfg.gtp.engine != "" {
+		command := strings.Fields(cfg.gtp.engine)
+		engine, err = gtp.Start(gtp.Config{Path: command[0], Args: command[1:], Timeout: cfg.gtp.timeout})
+		if err != nil {
+			slog.Error("failed to start GTP engine", "err", err)
+			os.Exit(1)
+		}
+		api.StartBotPlayer(engine, time.Second)
+	} else {
+		api.StartHeuristicBot(bot.NewHeuristic(cfg.botSeed), time.Second)
+	}
 	srv := &http.Server{
 		Addr:         fmt.Sprintf(":%d", cfg.port),
 		Handler:      api.Routes(),
@@ -93,7 +181,7 @@ func main() {
 		WriteTimeout: 10 * time.Second,
 	}
 
-	errs := make(chan error)
+	errs := make(chan error, 1)
 	go func() {
 		quit := make(chan os.Signal, 1)
 		signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -103,9 +191,10 @@ func main() {
 		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 		defer cancel()
 
-		err = srv.Shutdown(ctx)
+		err := srv.Shutdown(ctx)
 		if err != nil {
 			errs <- err
+			return
 		}
 
 		api.Shutdown(true)
@@ -114,7 +203,25 @@ func main() {
 
 	slog.Info("starting server", "address", srv.Addr, "env", cfg.env)
 	err = srv.ListenAndServe()
-	os.Exit(0)
+	if !errors.Is(err, http.ErrServerClosed) {
+		slog.Error("server failed", "err", err)
+		os.Exit(1)
+	}
+
+	// ListenAndServe returns as soon as shutdown begins, so wait for in-flight work to be drained.
+	err = <-errs
+	if engine != nil {
+		// Tell the engine to quit even if shutdown failed, rather than leaving it orphaned.
+		closeErr := engine.Close()
+		if closeErr != nil {
+			slog.Warn("failed to stop GTP engine", "err", closeErr)
+		}
+	}
+	if err != nil {
+		slog.Error("failed to shut down cleanly", "err", err)
+		os.Exit(1)
+	}
+	slog.Info("stopped server")
 }
 
 func runMigrations(dsn string) error {
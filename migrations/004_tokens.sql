@@ -0,0 +1,13 @@
+-- +goose Up
+CREATE TABLE tokens (
+	hash bytea PRIMARY KEY,
+	user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+	expiry timestamp(0) with time zone NOT NULL,
+	scope text NOT NULL
+);
+
+CREATE INDEX tokens_user_id_scope_idx ON tokens (user_id, scope);
+
+-- +goose Down
+DROP INDEX IF EXISTS tokens_user_id_scope_idx;
+DROP TABLE IF EXISTS tokens;
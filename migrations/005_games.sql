@@ -0,0 +1,37 @@
+-- +goose Up
+CREATE TABLE games (
+	id bigserial PRIMARY KEY,
+	created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+	black_id bigint REFERENCES users ON DELETE SET NULL,
+	white_id bigint REFERENCES users ON DELETE SET NULL,
+	board_size smallint NOT NULL,
+	rules text NOT NULL,
+	komi double precision NOT NULL,
+	handicap smallint NOT NULL DEFAULT 0,
+	status text NOT NULL DEFAULT 'active',
+	result text NOT NULL DEFAULT '',
+	move_count integer NOT NULL DEFAULT 0,
+	ended_at timestamp with time zone,
+	version integer NOT NULL DEFAULT 1,
+	CHECK (black_id <> white_id)
+);
+
+CREATE INDEX games_black_id_idx ON games (black_id);
+CREATE INDEX games_white_id_idx ON games (white_id);
+
+CREATE TABLE moves (
+	game_id bigint NOT NULL REFERENCES games ON DELETE CASCADE,
+	seq integer NOT NULL,
+	color text NOT NULL,
+	x smallint NOT NULL DEFAULT 0,
+	y smallint NOT NULL DEFAULT 0,
+	pass bool NOT NULL DEFAULT false,
+	played_at timestamp with time zone NOT NULL DEFAULT clock_timestamp(),
+	PRIMARY KEY (game_id, seq)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS moves;
+DROP INDEX IF EXISTS games_white_id_idx;
+DROP INDEX IF EXISTS games_black_id_idx;
+DROP TABLE IF EXISTS games;
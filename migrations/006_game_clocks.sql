@@ -0,0 +1,10 @@
+-- +goose Up
+ALTER TABLE games ADD COLUMN clock jsonb;
+ALTER TABLE games ADD COLUMN clock_deadline timestamp with time zone;
+
+CREATE INDEX games_clock_deadline_idx ON games (clock_deadline) WHERE status = 'active';
+
+-- +goose Down
+DROP INDEX IF EXISTS games_clock_deadline_idx;
+ALTER TABLE games DROP COLUMN IF EXISTS clock_deadline;
+ALTER TABLE games DROP COLUMN IF EXISTS clock;
@@ -0,0 +1,31 @@
+-- +goose Up
+ALTER TABLE games ADD COLUMN rated bool NOT NULL DEFAULT false;
+
+CREATE TABLE challenges (
+	id bigserial PRIMARY KEY,
+	created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+	challenger_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+	opponent_id bigint REFERENCES users ON DELETE CASCADE,
+	board_size smallint NOT NULL,
+	rules text NOT NULL,
+	komi double precision NOT NULL,
+	handicap smallint NOT NULL DEFAULT 0,
+	color text NOT NULL DEFAULT 'random',
+	time_control jsonb,
+	rated bool NOT NULL DEFAULT false,
+	status text NOT NULL DEFAULT 'open',
+	game_id bigint REFERENCES games ON DELETE SET NULL,
+	version integer NOT NULL DEFAULT 1,
+	CHECK (challenger_id <> opponent_id)
+);
+
+CREATE INDEX challenges_open_idx ON challenges (created_at) WHERE status = 'open';
+CREATE INDEX challenges_challenger_id_idx ON challenges (challenger_id);
+CREATE INDEX challenges_opponent_id_idx ON challenges (opponent_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS challenges_opponent_id_idx;
+DROP INDEX IF EXISTS challenges_challenger_id_idx;
+DROP INDEX IF EXISTS challenges_open_idx;
+DROP TABLE IF EXISTS challenges;
+ALTER TABLE games DROP COLUMN IF EXISTS rated;
@@ -0,0 +1,22 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN rating double precision NOT NULL DEFAULT 1500;
+ALTER TABLE users ADD COLUMN rating_deviation double precision NOT NULL DEFAULT 350;
+ALTER TABLE users ADD COLUMN rating_volatility double precision NOT NULL DEFAULT 0.06;
+
+CREATE TABLE rating_history (
+	id bigserial PRIMARY KEY,
+	user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+	result_id text NOT NULL,
+	game_id bigint REFERENCES games ON DELETE SET NULL,
+	rating double precision NOT NULL,
+	deviation double precision NOT NULL,
+	volatility double precision NOT NULL,
+	recorded_at timestamp with time zone NOT NULL DEFAULT NOW(),
+	UNIQUE (user_id, result_id)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS rating_history;
+ALTER TABLE users DROP COLUMN IF EXISTS rating_volatility;
+ALTER TABLE users DROP COLUMN IF EXISTS rating_deviation;
+ALTER TABLE users DROP COLUMN IF EXISTS rating;
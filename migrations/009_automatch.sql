@@ -0,0 +1,26 @@
+-- +goose Up
+CREATE TABLE automatch_queue (
+	user_id bigint PRIMARY KEY REFERENCES users ON DELETE CASCADE,
+	joined_at timestamp with time zone NOT NULL DEFAULT clock_timestamp(),
+	board_sizes smallint[] NOT NULL,
+	speed text NOT NULL,
+	rules text NOT NULL,
+	rated bool NOT NULL,
+	min_rank smallint NOT NULL,
+	max_rank smallint NOT NULL,
+	version integer NOT NULL DEFAULT 1
+);
+
+CREATE TABLE automatch_pairings (
+	id bigserial PRIMARY KEY,
+	created_at timestamp with time zone NOT NULL DEFAULT NOW(),
+	first_user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+	second_user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+	game_id bigint REFERENCES games ON DELETE SET NULL,
+	first_waited interval NOT NULL,
+	second_waited interval NOT NULL
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS automatch_pairings;
+DROP TABLE IF EXISTS automatch_queue;
@@ -0,0 +1,21 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN moderator bool NOT NULL DEFAULT false;
+
+CREATE TABLE game_chat (
+	id bigserial PRIMARY KEY,
+	game_id bigint NOT NULL REFERENCES games ON DELETE CASCADE,
+	user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+	channel text NOT NULL,
+	body text NOT NULL,
+	created_at timestamp with time zone NOT NULL DEFAULT clock_timestamp(),
+	deleted_at timestamp with time zone,
+	deleted_by bigint REFERENCES users ON DELETE SET NULL,
+	CHECK (channel IN ('players', 'public'))
+);
+
+CREATE INDEX game_chat_game_id_channel_idx ON game_chat (game_id, channel, id);
+
+-- +goose Down
+DROP INDEX IF EXISTS game_chat_game_id_channel_idx;
+DROP TABLE IF EXISTS game_chat;
+ALTER TABLE users DROP COLUMN IF EXISTS moderator;
@@ -0,0 +1,24 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN vacation_remaining interval NOT NULL DEFAULT '15 days';
+ALTER TABLE users ADD COLUMN on_vacation bool NOT NULL DEFAULT false;
+ALTER TABLE users ADD COLUMN vacation_since timestamp with time zone NOT NULL DEFAULT NOW();
+ALTER TABLE users ADD COLUMN vacation_accrued_at timestamp with time zone NOT NULL
+	DEFAULT date_trunc('month', NOW() AT TIME ZONE 'UTC') AT TIME ZONE 'UTC';
+
+CREATE INDEX users_on_vacation_idx ON users (id) WHERE on_vacation;
+
+CREATE TABLE move_reminders (
+	game_id bigint NOT NULL REFERENCES games ON DELETE CASCADE,
+	move_number integer NOT NULL,
+	user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+	sent_at timestamp with time zone NOT NULL DEFAULT NOW(),
+	PRIMARY KEY (game_id, move_number)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS move_reminders;
+DROP INDEX IF EXISTS users_on_vacation_idx;
+ALTER TABLE users DROP COLUMN IF EXISTS vacation_accrued_at;
+ALTER TABLE users DROP COLUMN IF EXISTS vacation_since;
+ALTER TABLE users DROP COLUMN IF EXISTS on_vacation;
+ALTER TABLE users DROP COLUMN IF EXISTS vacation_remaining;
@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN bot bool NOT NULL DEFAULT false;
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN IF EXISTS bot;
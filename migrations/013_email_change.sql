@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN pending_email citext;
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN IF EXISTS pending_email;
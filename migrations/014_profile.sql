@@ -0,0 +1,11 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN country text NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN bio text NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN preferred_rules text NOT NULL DEFAULT 'japanese';
+ALTER TABLE users ADD COLUMN avatar_url text NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN IF EXISTS avatar_url;
+ALTER TABLE users DROP COLUMN IF EXISTS preferred_rules;
+ALTER TABLE users DROP COLUMN IF EXISTS bio;
+ALTER TABLE users DROP COLUMN IF EXISTS country;
@@ -0,0 +1,8 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN purge_at timestamp with time zone;
+
+CREATE INDEX users_purge_at_idx ON users (purge_at) WHERE purge_at IS NOT NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS users_purge_at_idx;
+ALTER TABLE users DROP COLUMN IF EXISTS purge_at;
@@ -0,0 +1,20 @@
+-- +goose Up
+CREATE TABLE two_factor (
+	user_id bigint PRIMARY KEY REFERENCES users ON DELETE CASCADE,
+	secret bytea NOT NULL, -- encrypted with the server's TOTP key
+	enabled bool NOT NULL DEFAULT false,
+	last_counter bigint NOT NULL DEFAULT 0,
+	created_at timestamp with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE recovery_codes (
+	hash bytea PRIMARY KEY,
+	user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE
+);
+
+CREATE INDEX recovery_codes_user_id_idx ON recovery_codes (user_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS recovery_codes_user_id_idx;
+DROP TABLE IF EXISTS recovery_codes;
+DROP TABLE IF EXISTS two_factor;
@@ -0,0 +1,17 @@
+-- +goose Up
+CREATE TABLE passkeys (
+	id bigserial PRIMARY KEY,
+	user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+	credential_id bytea NOT NULL UNIQUE,
+	public_key bytea NOT NULL, -- COSE encoded
+	sign_count bigint NOT NULL DEFAULT 0,
+	name text NOT NULL,
+	created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+	last_used_at timestamp(0) with time zone
+);
+
+CREATE INDEX passkeys_user_id_idx ON passkeys (user_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS passkeys_user_id_idx;
+DROP TABLE IF EXISTS passkeys;
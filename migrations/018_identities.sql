@@ -0,0 +1,15 @@
+-- +goose Up
+CREATE TABLE identities (
+	issuer text NOT NULL,
+	subject text NOT NULL,
+	user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+	email citext NOT NULL,
+	created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+	PRIMARY KEY (issuer, subject)
+);
+
+CREATE INDEX identities_user_id_idx ON identities (user_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS identities_user_id_idx;
+DROP TABLE IF EXISTS identities;
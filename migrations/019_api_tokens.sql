@@ -0,0 +1,17 @@
+-- +goose Up
+CREATE TABLE api_tokens (
+	id bigserial PRIMARY KEY,
+	hash bytea NOT NULL UNIQUE,
+	user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+	name text NOT NULL,
+	scopes text[] NOT NULL,
+	expiry timestamp(0) with time zone NOT NULL,
+	created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+	last_used_at timestamp(0) with time zone
+);
+
+CREATE INDEX api_tokens_user_id_idx ON api_tokens (user_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS api_tokens_user_id_idx;
+DROP TABLE IF EXISTS api_tokens;
@@ -0,0 +1,19 @@
+-- +goose Up
+CREATE TABLE user_sessions (
+	id bigserial PRIMARY KEY,
+	token text NOT NULL UNIQUE, -- of the session in the sessions table
+	user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+	ip text NOT NULL,
+	user_agent text NOT NULL,
+	created_at timestamp(0) with time zone NOT NULL,
+	last_seen_at timestamp(0) with time zone NOT NULL,
+	expiry timestamptz NOT NULL
+);
+
+CREATE INDEX user_sessions_user_id_idx ON user_sessions (user_id);
+CREATE INDEX user_sessions_expiry_idx ON user_sessions (expiry);
+
+-- +goose Down
+DROP INDEX IF EXISTS user_sessions_expiry_idx;
+DROP INDEX IF EXISTS user_sessions_user_id_idx;
+DROP TABLE IF EXISTS user_sessions;